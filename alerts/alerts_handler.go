@@ -0,0 +1,108 @@
+package alerts
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+type AlertsHandler struct {
+	alertsService *alertsService
+}
+
+var alertsUsers string = `^/alerts/users/([0-9]+)$`
+var alertsUsersEvaluate string = `^/alerts/users/([0-9]+)/evaluate$`
+var alertsUsersAcknowledge string = `^/alerts/users/([0-9]+)/([0-9]+)/acknowledge$`
+var alertsUsersResolve string = `^/alerts/users/([0-9]+)/([0-9]+)/resolve$`
+
+func NewAlertsHandler(alertsService *alertsService) *AlertsHandler {
+	return &AlertsHandler{
+		alertsService: alertsService,
+	}
+}
+
+func (handler *AlertsHandler) ProcessRequest(writer http.ResponseWriter, request *http.Request) {
+	switch {
+	case utils.MatchURL(alertsUsers, request.URL.Path):
+
+		if request.Method != http.MethodGet {
+			writer.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+		state := request.URL.Query().Get("state")
+
+		alerts := handler.alertsService.alerts(userID, state)
+		body, _ := json.Marshal(alerts)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+
+	case utils.MatchURL(alertsUsersEvaluate, request.URL.Path):
+
+		if request.Method != http.MethodPost {
+			writer.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+		endDate := request.URL.Query().Get("endDate")
+
+		raised, evaluateErr := handler.alertsService.evaluate(userID, endDate)
+		if evaluateErr != nil {
+			writer.WriteHeader(http.StatusConflict)
+			writer.Write([]byte(evaluateErr.Error()))
+			return
+		}
+		body, _ := json.Marshal(raised)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+
+	case utils.MatchURL(alertsUsersAcknowledge, request.URL.Path):
+
+		if request.Method != http.MethodPost {
+			writer.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		params := utils.PathParams(alertsUsersAcknowledge, request.URL.Path)
+
+		alert, updated := handler.alertsService.acknowledge(params[0], params[1])
+		if !updated {
+			writer.WriteHeader(http.StatusConflict)
+			writer.Write([]byte("alert not found or not open"))
+			return
+		}
+		body, _ := json.Marshal(alert)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+
+	case utils.MatchURL(alertsUsersResolve, request.URL.Path):
+
+		if request.Method != http.MethodPost {
+			writer.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		params := utils.PathParams(alertsUsersResolve, request.URL.Path)
+
+		alert, updated := handler.alertsService.resolve(params[0], params[1])
+		if !updated {
+			writer.WriteHeader(http.StatusConflict)
+			writer.Write([]byte("alert not found or already resolved"))
+			return
+		}
+		body, _ := json.Marshal(alert)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+
+	default:
+		writer.WriteHeader(http.StatusNotFound)
+		writer.Write([]byte("404 path not found"))
+	}
+}
@@ -0,0 +1,113 @@
+package alerts
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+type alertsService struct {
+	alertRepository         *database.AlertRepository
+	userRepository          *database.UserRepository
+	moodLogRepository       *database.MoodLogRepository
+	sleepLogRepository      *database.SleepLogRepository
+	medicationLogRepository *database.MedicationLogRepository
+	webhookRepository       *database.WebhookRepository
+	userSettingsRepository  *database.UserSettingsRepository
+}
+
+func NewAlertsService(alertRepository *database.AlertRepository, userRepository *database.UserRepository, moodLogRepository *database.MoodLogRepository, sleepLogRepository *database.SleepLogRepository, medicationLogRepository *database.MedicationLogRepository, webhookRepository *database.WebhookRepository, userSettingsRepository *database.UserSettingsRepository) *alertsService {
+	return &alertsService{
+		alertRepository:         alertRepository,
+		userRepository:          userRepository,
+		moodLogRepository:       moodLogRepository,
+		sleepLogRepository:      sleepLogRepository,
+		medicationLogRepository: medicationLogRepository,
+		webhookRepository:       webhookRepository,
+		userSettingsRepository:  userSettingsRepository,
+	}
+}
+
+// Start evaluates the alert rules for every user on each tick
+func (service *alertsService) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			for _, userID := range service.userRepository.UserIDs() {
+				service.evaluateSafely(userID, "")
+			}
+		}
+	}()
+}
+
+// a failing user must not stop the scheduler
+func (service *alertsService) evaluateSafely(userID string, endDate string) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("ERROR evaluating alerts for user", userID, r)
+		}
+	}()
+	if _, evaluateErr := service.evaluate(userID, endDate); evaluateErr != nil {
+		fmt.Println("ERROR evaluating alerts for user", userID, evaluateErr)
+	}
+}
+
+// evaluate runs every rule over the evaluation window ending on endDate and
+// returns the alerts that were newly raised, without an endDate the window ends
+// today in the user's timezone
+func (service *alertsService) evaluate(userID string, endDate string) ([]models.Alert, error) {
+
+	endDateParsed, parseErr := time.Parse("2006-01-02", endDate)
+	if parseErr != nil {
+		today, todayErr := utils.LocalToday(service.userSettingsRepository.Timezone(userID))
+		if todayErr != nil {
+			return nil, todayErr
+		}
+		endDate = today
+		endDateParsed, _ = time.Parse("2006-01-02", endDate)
+	}
+	startDate := endDateParsed.AddDate(0, 0, -(evaluationDays - 1)).Format("2006-01-02")
+
+	raised := make([]models.Alert, 0)
+
+	for _, rule := range alertRules {
+		message, triggerDate, triggered := rule.evaluate(service, userID, startDate, endDate)
+		if !triggered {
+			continue
+		}
+		alertID, isNew := service.alertRepository.RaiseAlert(userID, rule.name, rule.severity, message, triggerDate, rule.coverDays)
+		if !isNew {
+			continue
+		}
+		if alert, found := service.alertRepository.Alert(userID, fmt.Sprint(alertID)); found {
+			payload, _ := json.Marshal(alert)
+			service.webhookRepository.PublishEvent(userID, models.EventAlertRaised, fmt.Sprintf("alert:%d", alertID), payload)
+			raised = append(raised, alert)
+		}
+	}
+
+	return raised, nil
+}
+
+func (service *alertsService) alerts(userID string, state string) []models.Alert {
+	return service.alertRepository.Alerts(userID, state)
+}
+
+func (service *alertsService) acknowledge(userID string, alertID string) (models.Alert, bool) {
+	if updated := service.alertRepository.AcknowledgeAlert(userID, alertID); !updated {
+		return models.Alert{}, false
+	}
+	return service.alertRepository.Alert(userID, alertID)
+}
+
+func (service *alertsService) resolve(userID string, alertID string) (models.Alert, bool) {
+	if updated := service.alertRepository.ResolveAlert(userID, alertID); !updated {
+		return models.Alert{}, false
+	}
+	return service.alertRepository.Alert(userID, alertID)
+}
@@ -0,0 +1,65 @@
+package alerts
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database/dbtest"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+// raised is the alerts of the rule raised by an evaluation
+func raised(t *testing.T, service *alertsService, userID string, endDate string, rule string) []models.Alert {
+	t.Helper()
+	alerts, evaluateErr := service.evaluate(userID, endDate)
+	if evaluateErr != nil {
+		t.Fatal(evaluateErr)
+	}
+	var ofRule []models.Alert
+	for _, alert := range alerts {
+		if alert.Rule == rule {
+			ofRule = append(ofRule, alert)
+		}
+	}
+	return ofRule
+}
+
+func TestResolvedStreakStaysResolved(t *testing.T) {
+	db := dbtest.Open(t)
+
+	// four hours a night from the 1st to the 20th, longer than the window
+	dbtest.Exec(t, db,
+		`INSERT INTO user (user_id, email, password_hash) VALUES (9, 'nine@example.com', '')`,
+		`INSERT INTO sleep_log (user_id, hours_slept, sleep_quality_tag_id, sleep_date)
+		 WITH RECURSIVE nights AS (SELECT DATE '2030-03-01' AS night UNION ALL SELECT night + INTERVAL 1 DAY FROM nights WHERE night < '2030-03-20')
+		 SELECT 9, 4, 4, night FROM nights`,
+	)
+
+	alertRepository := database.NewAlertRepository(db)
+	service := NewAlertsService(
+		alertRepository,
+		database.NewUserRepository(db),
+		database.NewMoodLogRepository(db, nil),
+		database.NewSleepLogRepository(db),
+		database.NewMedicationLogRepository(db),
+		database.NewWebhookRepository(db),
+		database.NewUserSettingsRepository(db),
+	)
+
+	// the window starts on the 3rd, the streak is keyed on the 1st
+	first := raised(t, service, "9", "2030-03-16", "short_sleep")
+	if len(first) != 1 {
+		t.Fatalf("short sleep alerts = %+v, want one", first)
+	}
+	if want := "slept under 5 hours for 16 nights from 2030-03-01 to 2030-03-16"; first[0].Message != want {
+		t.Errorf("message = %q, want %q", first[0].Message, want)
+	}
+
+	alertRepository.ResolveAlert("9", fmt.Sprint(first[0].AlertID))
+	for _, endDate := range []string{"2030-03-17", "2030-03-20"} {
+		if again := raised(t, service, "9", endDate, "short_sleep"); len(again) != 0 {
+			t.Errorf("evaluating to %s raised %+v, want the resolved streak left alone", endDate, again)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package alerts
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+const (
+	evaluationDays           = 14
+	minConsecutiveClinical   = 3
+	moodDropPercent          = 20.0
+	shortSleepHours          = 5.0
+	minConsecutiveShortSleep = 3
+	minAdherencePercentage   = 80.0
+	anomalyRecentDays        = 3
+	historyStart             = "1000-01-01" // the first DATE MySQL has
+)
+
+// evaluate returns the message and the trigger date, the first day of the
+// streak or window that triggered the rule. A streak that reaches into the
+// window is followed back to its real first day, so it keeps one trigger date
+// however long it runs. Once an alert is resolved the rule
+// stays quiet for triggers up to coverDays after its trigger date, 0 for the
+// streak rules so only the same streak is covered and a window less a day for
+// the window rules so they fire again once the window holds none of the days
+// that raised the alert
+type alertRule struct {
+	name      string
+	severity  string
+	coverDays int
+	evaluate  func(service *alertsService, userID string, startDate string, endDate string) (string, string, bool)
+}
+
+var alertRules = []alertRule{
+	{name: "consecutive_clinical_days", severity: "high", coverDays: 0, evaluate: consecutiveClinicalDays},
+	{name: "mood_drop", severity: "medium", coverDays: evaluationDays - 1, evaluate: moodDrop},
+	{name: "short_sleep", severity: "medium", coverDays: 0, evaluate: shortSleep},
+	{name: "low_medication_adherence", severity: "medium", coverDays: evaluationDays - 1, evaluate: lowMedicationAdherence},
+	{name: "mood_anomaly", severity: "medium", coverDays: 0, evaluate: moodAnomaly},
+	{name: "sleep_anomaly", severity: "low", coverDays: 0, evaluate: sleepAnomaly},
+}
+
+// the latest streak still running in the window, an earlier one has been
+// raised already
+func consecutiveClinicalDays(service *alertsService, userID string, startDate string, endDate string) (string, string, bool) {
+	// TODO fix magic strings, same clinical criteria as analyzeMood
+	clinicalStreaks := service.moodLogRepository.StreakRanges(userID, historyStart, endDate, ">=", "1", "5", "50")
+	for i := len(clinicalStreaks) - 1; i >= 0 && clinicalStreaks[i].EndDate >= startDate; i-- {
+		streak := clinicalStreaks[i]
+		if streak.NumDays >= minConsecutiveClinical {
+			return fmt.Sprintf("%d consecutive clinical days from %s to %s", streak.NumDays, streak.StartDate, streak.EndDate), streak.StartDate, true
+		}
+	}
+	return "", "", false
+}
+
+// same calculation as MoodDiff.AvgMoodPercentChange
+func moodDrop(service *alertsService, userID string, startDate string, endDate string) (string, string, bool) {
+	current := service.moodLogRepository.AvgMoodRating(userID, startDate, endDate)
+	previousStart, previousEnd := utils.PreviousDates(startDate, endDate)
+	previous := service.moodLogRepository.AvgMoodRating(userID, previousStart, previousEnd)
+	if current == 0.0 || previous == 0.0 {
+		return "", "", false
+	}
+	percentChange := utils.PercentChange(current, previous)
+	if percentChange > -moodDropPercent {
+		return "", "", false
+	}
+	return fmt.Sprintf("average mood dropped %.1f%% from %.2f to %.2f compared to the previous period", -percentChange, previous, current), startDate, true
+}
+
+func shortSleep(service *alertsService, userID string, startDate string, endDate string) (string, string, bool) {
+	streaks := service.sleepLogRepository.ShortSleepStreaks(userID, historyStart, endDate, shortSleepHours, minConsecutiveShortSleep)
+	if len(streaks) == 0 || streaks[len(streaks)-1].EndDate < startDate {
+		return "", "", false
+	}
+	streak := streaks[len(streaks)-1]
+	return fmt.Sprintf("slept under %.0f hours for %d nights from %s to %s", shortSleepHours, streak.NumDays, streak.StartDate, streak.EndDate), streak.StartDate, true
+}
+
+// adherence is against the doses scheduled in the window, users who logged no
+// medication in it aren't tracking it here and are left out
+func lowMedicationAdherence(service *alertsService, userID string, startDate string, endDate string) (string, string, bool) {
+	adherencePercentage, logged := service.medicationLogRepository.AdherencePercentage(userID, startDate, endDate)
+	if !logged || adherencePercentage >= minAdherencePercentage {
+		return "", "", false
+	}
+	return fmt.Sprintf("medication adherence is %.1f%%, below %.0f%%", adherencePercentage, minAdherencePercentage), startDate, true
+}
+
+// anomaly rules only look at the last few days and are keyed on the day of the
+// anomaly, a resolved alert isn't raised again for the same day
+func moodAnomaly(service *alertsService, userID string, startDate string, endDate string) (string, string, bool) {
+	recentStart := recentStartDate(endDate)
+	dailyAverages := service.moodLogRepository.DailyAverages(userID, utils.AnomalyBaselineStart(recentStart), endDate)
+	anomalies := utils.DetectAnomalies(dailyAverages, recentStart, utils.MinMoodMAD)
+	if len(anomalies) == 0 {
+		return "", "", false
+	}
+	anomaly := anomalies[len(anomalies)-1]
+	return fmt.Sprintf("mood of %.1f on %s is %.1f points %s the usual %.1f", anomaly.Value, anomaly.Date, math.Abs(anomaly.Deviation), aboveOrBelow(anomaly), anomaly.Baseline), anomaly.Date, true
+}
+
+func sleepAnomaly(service *alertsService, userID string, startDate string, endDate string) (string, string, bool) {
+	recentStart := recentStartDate(endDate)
+	dailySleepHours := service.sleepLogRepository.DailySleepHours(userID, utils.AnomalyBaselineStart(recentStart), endDate)
+	anomalies := utils.DetectAnomalies(dailySleepHours, recentStart, utils.MinSleepMAD)
+	if len(anomalies) == 0 {
+		return "", "", false
+	}
+	anomaly := anomalies[len(anomalies)-1]
+	return fmt.Sprintf("slept %.1f hours on %s, %.1f hours %s the usual %.1f", anomaly.Value, anomaly.Date, math.Abs(anomaly.Deviation), aboveOrBelow(anomaly), anomaly.Baseline), anomaly.Date, true
+}
+
+func recentStartDate(endDate string) string {
+	endDateParsed, _ := time.Parse("2006-01-02", endDate)
+	return endDateParsed.AddDate(0, 0, -(anomalyRecentDays - 1)).Format("2006-01-02")
+}
+
+func aboveOrBelow(anomaly models.Anomaly) string {
+	if anomaly.Direction == "low" {
+		return "below"
+	}
+	return "above"
+}
@@ -1,27 +1,51 @@
 package analytics
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/michaeljosephroddy/project-horizon-backend-go/cache"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
 	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
 	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
 )
 
 type AnalyticsHandler struct {
-	analyticsService *analyticsService
+	analyticsService      *analyticsService
+	cache                 cache.Cache
+	dataVersionRepository *database.DataVersionRepository
 }
 
 // TODO need to come up with a better regexp
 var analyticsUsersMood string = `^/analytics/users/([0-9]+)/mood$`
 var analyticsUsersSleep string = `^/analytics/users/([0-9]+)/sleep$`
+var analyticsUsersMoodForecast string = `^/analytics/users/([0-9]+)/mood/forecast$`
+var analyticsUsersMoodPatterns string = `^/analytics/users/([0-9]+)/mood/patterns$`
+var analyticsUsersMoodTags string = `^/analytics/users/([0-9]+)/mood/tags$`
+var analyticsUsersMoodSentiment string = `^/analytics/users/([0-9]+)/mood/sentiment$`
+var analyticsUsersTimeSeries string = `^/analytics/users/([0-9]+)/timeseries$`
+var analyticsUsersEpisodes string = `^/analytics/users/([0-9]+)/episodes$`
+var usersReportsClinician string = `^/users/([0-9]+)/reports/clinician\.pdf$`
+
+var timeSeriesWindows = []int{7, 14, 30}
+var defaultTimeSeriesWindow = 7
+
+var bucketGranularities = []string{"day", "week", "month"}
 
 // var analyticsUsersMedication string = `^/analytics/users/([0-9]+)/medication$`
 
-func NewAnalyticsHandler(analyticsService *analyticsService) *AnalyticsHandler {
+func NewAnalyticsHandler(analyticsService *analyticsService, cache cache.Cache, dataVersionRepository *database.DataVersionRepository) *AnalyticsHandler {
 	return &AnalyticsHandler{
-		analyticsService: analyticsService,
+		analyticsService:      analyticsService,
+		cache:                 cache,
+		dataVersionRepository: dataVersionRepository,
 	}
 }
 
@@ -33,12 +57,32 @@ func (handler *AnalyticsHandler) ProcessRequest(writer http.ResponseWriter, requ
 		startDate := request.URL.Query().Get("startDate")
 		endDate := request.URL.Query().Get("endDate")
 
-		moodMetrics := handler.moodMetrics(userID, startDate, endDate)
-		body, _ := json.Marshal(moodMetrics)
-		fmt.Println("DEBUG ", string(body))
+		window, fillGaps, optionsErr := timeSeriesOptions(request)
+		if optionsErr != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(optionsErr.Error()))
+			return
+		}
+
+		granularity := request.URL.Query().Get("granularity")
+		if granularity != "" && !slices.Contains(bucketGranularities, granularity) {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(fmt.Sprintf("granularity must be one of %v", bucketGranularities)))
+			return
+		}
 
-		writer.Header().Set("Content-Type", "application/json")
-		writer.Write(body)
+		comparisons, compareErr := handler.analyticsService.comparisons(userID, startDate, endDate, request.URL.Query().Get("compare"))
+		if compareErr != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(compareErr.Error()))
+			return
+		}
+
+		handler.writeCached(writer, request, userID, func() []byte {
+			moodMetrics := handler.moodMetrics(userID, startDate, endDate, window, fillGaps, granularity, comparisons)
+			body, _ := json.Marshal(moodMetrics)
+			return body
+		})
 
 	case utils.MatchURL(analyticsUsersSleep, request.URL.Path):
 
@@ -46,12 +90,143 @@ func (handler *AnalyticsHandler) ProcessRequest(writer http.ResponseWriter, requ
 		startDate := request.URL.Query().Get("startDate")
 		endDate := request.URL.Query().Get("endDate")
 
-		sleepMetrics := handler.sleepMetrics(userID, startDate, endDate)
-		body, _ := json.Marshal(sleepMetrics)
-		fmt.Println("DEBUG ", string(body))
+		window, fillGaps, optionsErr := timeSeriesOptions(request)
+		if optionsErr != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(optionsErr.Error()))
+			return
+		}
+
+		granularity := request.URL.Query().Get("granularity")
+		if granularity != "" && !slices.Contains(bucketGranularities, granularity) {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(fmt.Sprintf("granularity must be one of %v", bucketGranularities)))
+			return
+		}
+
+		handler.writeCached(writer, request, userID, func() []byte {
+			sleepMetrics := handler.sleepMetrics(userID, startDate, endDate, window, fillGaps, granularity)
+			body, _ := json.Marshal(sleepMetrics)
+			return body
+		})
+
+	case utils.MatchURL(analyticsUsersMoodForecast, request.URL.Path):
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+		endDate := request.URL.Query().Get("endDate")
+
+		days := defaultForecastDays
+		if daysParam := request.URL.Query().Get("days"); daysParam != "" {
+			parsed, parseErr := strconv.Atoi(daysParam)
+			if parseErr != nil || parsed < 1 || parsed > forecastMaxDays {
+				writer.WriteHeader(http.StatusBadRequest)
+				writer.Write([]byte(fmt.Sprintf("days must be between 1 and %d", forecastMaxDays)))
+				return
+			}
+			days = parsed
+		}
+		useSleep := request.URL.Query().Get("sleep") == "true"
+
+		// without an endDate the forecast starts after today in the user's timezone
+		if _, parseErr := time.Parse("2006-01-02", endDate); parseErr != nil {
+			today, todayErr := handler.analyticsService.today(userID)
+			if todayErr != nil {
+				writer.WriteHeader(http.StatusConflict)
+				writer.Write([]byte(todayErr.Error()))
+				return
+			}
+			endDate = today
+		}
+
+		handler.writeCached(writer, request, userID, func() []byte {
+			moodForecast := handler.analyticsService.forecastMood(userID, endDate, days, useSleep)
+			body, _ := json.Marshal(moodForecast)
+			return body
+		})
+
+	case utils.MatchURL(analyticsUsersMoodPatterns, request.URL.Path):
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+		startDate := request.URL.Query().Get("startDate")
+		endDate := request.URL.Query().Get("endDate")
+
+		handler.writeCached(writer, request, userID, func() []byte {
+			moodPatterns := handler.analyticsService.moodPatterns(userID, startDate, endDate)
+			body, _ := json.Marshal(moodPatterns)
+			return body
+		})
+
+	case utils.MatchURL(analyticsUsersMoodTags, request.URL.Path):
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+		startDate := request.URL.Query().Get("startDate")
+		endDate := request.URL.Query().Get("endDate")
+
+		handler.writeCached(writer, request, userID, func() []byte {
+			tagAssociations := handler.analyticsService.tagAssociations(userID, startDate, endDate)
+			body, _ := json.Marshal(tagAssociations)
+			return body
+		})
+
+	case utils.MatchURL(analyticsUsersMoodSentiment, request.URL.Path):
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+		startDate := request.URL.Query().Get("startDate")
+		endDate := request.URL.Query().Get("endDate")
+
+		handler.writeCached(writer, request, userID, func() []byte {
+			moodSentiment := handler.analyticsService.moodSentiment(userID, startDate, endDate)
+			body, _ := json.Marshal(moodSentiment)
+			return body
+		})
+
+	case utils.MatchURL(analyticsUsersTimeSeries, request.URL.Path):
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+		startDate := request.URL.Query().Get("startDate")
+		endDate := request.URL.Query().Get("endDate")
+
+		window, fillGaps, optionsErr := timeSeriesOptions(request)
+		if optionsErr != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(optionsErr.Error()))
+			return
+		}
+
+		handler.writeCached(writer, request, userID, func() []byte {
+			timeSeries := handler.timeSeries(userID, startDate, endDate, window, fillGaps)
+			body, _ := json.Marshal(timeSeries)
+			return body
+		})
+
+	case utils.MatchURL(analyticsUsersEpisodes, request.URL.Path):
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+		startDate := request.URL.Query().Get("startDate")
+		endDate := request.URL.Query().Get("endDate")
+
+		handler.writeCached(writer, request, userID, func() []byte {
+			episodeMetrics := handler.episodeMetrics(userID, startDate, endDate)
+			body, _ := json.Marshal(episodeMetrics)
+			return body
+		})
+
+	case utils.MatchURL(usersReportsClinician, request.URL.Path):
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+		startDate := request.URL.Query().Get("startDate")
+		endDate := request.URL.Query().Get("endDate")
+
+		report, reportErr := handler.analyticsService.clinicianReport(userID, startDate, endDate)
+		if reportErr != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(reportErr.Error()))
+			return
+		}
 
-		writer.Header().Set("Content-Type", "application/json")
-		writer.Write(body)
+		writer.Header().Set("Content-Type", "application/pdf")
+		writer.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="clinician-report-%s-%s.pdf"`, startDate, endDate))
+		report.WriteTo(writer)
 
 	/* case utils.MatchURL(analyticsUsersMedication, request.URL.Path):
 
@@ -61,7 +236,6 @@ func (handler *AnalyticsHandler) ProcessRequest(writer http.ResponseWriter, requ
 
 	var medication models.Medication
 	body, _ := json.Marshal(medication)
-	fmt.Println("DEBUG ", string(body))
 
 	writer.Header().Set("Content-Type", "application/json")
 	writer.Write(body) */
@@ -71,26 +245,169 @@ func (handler *AnalyticsHandler) ProcessRequest(writer http.ResponseWriter, requ
 	}
 }
 
-func (handler *AnalyticsHandler) moodMetrics(userID string, startDate string, endDate string) *models.MoodMetric {
+// moodMetrics buckets the period when a granularity is given, for week and
+// month the daily series, the day lists and the days of each streak aren't
+// loaded, only counted, to keep the response small. Each comparison range is
+// analyzed once even if it's listed twice or is the previous period used for
+// MoodDiffs
+func (handler *AnalyticsHandler) moodMetrics(userID string, startDate string, endDate string, window int, fillGaps bool, granularity string, comparisons []models.MoodComparison) *models.MoodMetric {
 
-	current := handler.analyticsService.analyzeMood(userID, startDate, endDate)
-	fmt.Println(startDate, " ", endDate, current.MovingAvg)
+	withDays := granularity != "week" && granularity != "month"
 
-	previousStart, previousEnd := utils.PreviousDates(startDate, endDate)
+	current := handler.analyticsService.analyzeMood(userID, startDate, endDate, withDays)
+	current.TimeSeries = make([]models.TimeSeriesPoint, 0)
+	if withDays {
+		current.TimeSeries = handler.analyticsService.moodTimeSeries(userID, startDate, endDate, window, fillGaps)
+	}
+
+	analyzed := make(map[models.DateRange]*models.MoodMetric)
+	analyze := func(dateRange models.DateRange) *models.MoodMetric {
+		if metric, found := analyzed[dateRange]; found {
+			return metric
+		}
+		metric := handler.analyticsService.analyzeMood(userID, dateRange.StartDate, dateRange.EndDate, withDays)
+		analyzed[dateRange] = metric
+		return metric
+	}
 
-	previous := handler.analyticsService.analyzeMood(userID, previousStart, previousEnd)
-	fmt.Println(previousStart, " ", previousEnd, previous.MovingAvg)
+	previousStart, previousEnd := utils.PreviousDates(startDate, endDate)
+	previous := analyze(models.DateRange{StartDate: previousStart, EndDate: previousEnd})
+	current.MoodDiffs = handler.analyticsService.moodDiffs(current, previous)
 
-	diffs := handler.analyticsService.moodDiffs(current, previous)
+	current.Comparisons = make([]models.MoodComparison, 0, len(comparisons))
+	for _, comparison := range comparisons {
+		compared := analyze(models.DateRange{StartDate: comparison.StartDate, EndDate: comparison.EndDate})
+		comparison.MoodDiffs = handler.analyticsService.moodDiffs(current, compared)
+		current.Comparisons = append(current.Comparisons, comparison)
+	}
 
-	current.MoodDiffs = diffs
+	if granularity != "" {
+		current.Granularity = granularity
+		current.Buckets = handler.analyticsService.moodBuckets(userID, startDate, endDate, granularity)
+	}
 
 	return current
 }
 
-func (handler *AnalyticsHandler) sleepMetrics(userID string, startDate string, endDate string) *models.SleepMetric {
+func (handler *AnalyticsHandler) sleepMetrics(userID string, startDate string, endDate string, window int, fillGaps bool, granularity string) *models.SleepMetric {
 
 	current := handler.analyticsService.analyzeSleep(userID, startDate, endDate)
+	current.TimeSeries = make([]models.TimeSeriesPoint, 0)
+	if granularity != "week" && granularity != "month" {
+		current.TimeSeries = handler.analyticsService.sleepTimeSeries(userID, startDate, endDate, window, fillGaps)
+	}
+
+	if granularity != "" {
+		current.Granularity = granularity
+		current.Buckets = handler.analyticsService.sleepBuckets(userID, startDate, endDate, granularity)
+	}
 
 	return current
 }
+
+func (handler *AnalyticsHandler) episodeMetrics(userID string, startDate string, endDate string) *models.EpisodeMetric {
+
+	current := handler.analyticsService.analyzeEpisodes(userID, startDate, endDate)
+
+	return current
+}
+
+func (handler *AnalyticsHandler) timeSeries(userID string, startDate string, endDate string, window int, fillGaps bool) *models.TimeSeries {
+
+	timeSeries := &models.TimeSeries{
+		UserID:    userID,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Window:    window,
+		FillGaps:  fillGaps,
+		Mood:      handler.analyticsService.moodTimeSeries(userID, startDate, endDate, window, fillGaps),
+		Sleep:     handler.analyticsService.sleepTimeSeries(userID, startDate, endDate, window, fillGaps),
+	}
+
+	return timeSeries
+}
+
+func timeSeriesOptions(request *http.Request) (int, bool, error) {
+
+	window := defaultTimeSeriesWindow
+	if windowParam := request.URL.Query().Get("window"); windowParam != "" {
+		parsed, parseErr := strconv.Atoi(windowParam)
+		if parseErr != nil || !slices.Contains(timeSeriesWindows, parsed) {
+			return 0, false, fmt.Errorf("window must be one of %v", timeSeriesWindows)
+		}
+		window = parsed
+	}
+
+	fillGaps := request.URL.Query().Get("fillGaps") == "true"
+
+	return window, fillGaps, nil
+}
+
+// CacheKeyPrefix starts the key of every cached response for the user
+func CacheKeyPrefix(userID string) string {
+	return "analytics:" + userID + ":"
+}
+
+// writeCached serves the response from the cache when the user's data hasn't
+// changed since it was rendered. The key holds the data version, which the
+// database bumps on every write to the user's logs, so stale entries are never
+// read again and age out of the cache. The date is part of the key because
+// some endpoints default to today
+func (handler *AnalyticsHandler) writeCached(writer http.ResponseWriter, request *http.Request, userID string, render func() []byte) {
+
+	version, updatedAt := handler.dataVersionRepository.DataVersion(userID)
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	key := fmt.Sprintf("%s%d:%s:%s?%s", CacheKeyPrefix(userID), version, today.Format("2006-01-02"), request.URL.Path, request.URL.Query().Encode())
+
+	entry, hit := handler.cache.Get(key)
+	if !hit {
+		body := render()
+		hash := sha256.Sum256(body)
+
+		lastModified, parseErr := time.Parse("2006-01-02 15:04:05", updatedAt)
+		if parseErr != nil || lastModified.Before(today) {
+			lastModified = today
+		}
+
+		entry = cache.Entry{
+			Body:         body,
+			ContentType:  "application/json",
+			ETag:         `"` + hex.EncodeToString(hash[:16]) + `"`,
+			LastModified: lastModified,
+		}
+		handler.cache.Set(key, entry)
+	}
+
+	writer.Header().Set("ETag", entry.ETag)
+	writer.Header().Set("Last-Modified", entry.LastModified.Format(http.TimeFormat))
+	writer.Header().Set("Cache-Control", "private, no-cache")
+
+	if notModified(request, entry) {
+		writer.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writer.Header().Set("Content-Type", entry.ContentType)
+	writer.Write(entry.Body)
+}
+
+// notModified follows RFC 9110, If-None-Match wins over If-Modified-Since
+func notModified(request *http.Request, entry cache.Entry) bool {
+
+	if ifNoneMatch := request.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		for _, etag := range strings.Split(ifNoneMatch, ",") {
+			etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+			if etag == entry.ETag || etag == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ifModifiedSince := request.Header.Get("If-Modified-Since"); ifModifiedSince != "" {
+		since, parseErr := http.ParseTime(ifModifiedSince)
+		return parseErr == nil && !entry.LastModified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
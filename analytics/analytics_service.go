@@ -2,8 +2,9 @@ package analytics
 
 import (
 	"fmt"
+	"math"
 	"slices"
-	"strconv"
+	"time"
 
 	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
 
@@ -12,30 +13,38 @@ import (
 )
 
 type analyticsService struct {
-	moodLogRepository  *database.MoodLogRepository
-	sleepLogRepository *database.SleepLogRepository
+	moodLogRepository        *database.MoodLogRepository
+	sleepLogRepository       *database.SleepLogRepository
+	userSettingsRepository   *database.UserSettingsRepository
+	webhookRepository        *database.WebhookRepository
+	medicationLogRepository  *database.MedicationLogRepository
+	baselinePeriodRepository *database.BaselinePeriodRepository
+	sentimentRepository      *database.SentimentRepository
+	userRepository           *database.UserRepository
 }
 
-func NewAnalyticsService(moodLogRepository *database.MoodLogRepository, sleepLogRepository *database.SleepLogRepository) *analyticsService {
+func NewAnalyticsService(moodLogRepository *database.MoodLogRepository, sleepLogRepository *database.SleepLogRepository, userSettingsRepository *database.UserSettingsRepository, webhookRepository *database.WebhookRepository, medicationLogRepository *database.MedicationLogRepository, baselinePeriodRepository *database.BaselinePeriodRepository, sentimentRepository *database.SentimentRepository, userRepository *database.UserRepository) *analyticsService {
 	return &analyticsService{
-		moodLogRepository:  moodLogRepository,
-		sleepLogRepository: sleepLogRepository,
+		moodLogRepository:        moodLogRepository,
+		sleepLogRepository:       sleepLogRepository,
+		userSettingsRepository:   userSettingsRepository,
+		webhookRepository:        webhookRepository,
+		medicationLogRepository:  medicationLogRepository,
+		baselinePeriodRepository: baselinePeriodRepository,
+		sentimentRepository:      sentimentRepository,
+		userRepository:           userRepository,
 	}
 }
 
-func (service *analyticsService) analyzeMood(userID string, startDate string, endDate string) *models.MoodMetric {
+// analyzeMood summarises the period, withDays loads the qualifying days and the
+// days of each streak for a daily view
+func (service *analyticsService) analyzeMood(userID string, startDate string, endDate string, withDays bool) *models.MoodMetric {
 
 	numDays := utils.NumDaysBetween(startDate, endDate)
-	numDaysPreceding := strconv.Itoa(numDays)
 
-	movingAverages := service.moodLogRepository.MovingAverages(userID, startDate, endDate, numDaysPreceding)
-
-	var movingAvg float64
-	if len(movingAverages) >= 2 {
-		movingAvg = movingAverages[len(movingAverages)-1].MovingAvg
-	}
-
-	moodTrend := utils.DetermineTrend(movingAverages)
+	dailyAverages := service.moodLogRepository.DailyAverages(userID, startDate, endDate)
+	movingAvg := periodAverage(dailyAverages)
+	moodTrend := utils.DetermineTrend(dailyAverages)
 
 	standardDeviation := service.moodLogRepository.StandardDeviation(userID, startDate, endDate)
 
@@ -66,28 +75,45 @@ func (service *analyticsService) analyzeMood(userID string, startDate string, en
 		}
 	})
 
-	// TODO fix magic strings
-	positiveDays := service.moodLogRepository.Days(userID, startDate, endDate, ">=", "6", "1", "50")
-	mtfPositiveDays := utils.MoodTagFrequencies(positiveDays)
+	// the days and the days of each streak are only loaded for a daily view,
+	// week and month views only need how many there are
+	positiveDays := make([]models.Day, 0)
+	neutralDays := make([]models.Day, 0)
+	negativeDays := make([]models.Day, 0)
+	clinicalDays := make([]models.Day, 0)
+	var numPositiveDays, numNeutralDays, numNegativeDays, numClinicalDays int
+	var positiveStreaks, neutralStreaks, negativeStreaks, clinicalStreaks []models.Streak
 
 	// TODO fix magic strings
-	neutralDays := service.moodLogRepository.Days(userID, startDate, endDate, "=", "5", "3", "50")
-	mtfNeutralDays := utils.MoodTagFrequencies(neutralDays)
+	if withDays {
+		positiveDays = service.moodLogRepository.Days(userID, startDate, endDate, ">=", "6", "1", "50")
+		neutralDays = service.moodLogRepository.Days(userID, startDate, endDate, "=", "5", "3", "50")
+		negativeDays = service.moodLogRepository.Days(userID, startDate, endDate, "<=", "4", "2", "50")
+		clinicalDays = service.moodLogRepository.Days(userID, startDate, endDate, ">=", "1", "5", "50")
+		numPositiveDays, numNeutralDays, numNegativeDays, numClinicalDays = len(positiveDays), len(neutralDays), len(negativeDays), len(clinicalDays)
+
+		positiveStreaks = service.moodLogRepository.Streaks(userID, startDate, endDate, ">=", "6", "1", "50")
+		neutralStreaks = service.moodLogRepository.Streaks(userID, startDate, endDate, "=", "5", "3", "50")
+		negativeStreaks = service.moodLogRepository.Streaks(userID, startDate, endDate, "<=", "4", "2", "50")
+		clinicalStreaks = service.moodLogRepository.Streaks(userID, startDate, endDate, ">=", "1", "5", "50")
+	} else {
+		numPositiveDays = service.moodLogRepository.NumDays(userID, startDate, endDate, ">=", "6", "1", "50")
+		numNeutralDays = service.moodLogRepository.NumDays(userID, startDate, endDate, "=", "5", "3", "50")
+		numNegativeDays = service.moodLogRepository.NumDays(userID, startDate, endDate, "<=", "4", "2", "50")
+		numClinicalDays = service.moodLogRepository.NumDays(userID, startDate, endDate, ">=", "1", "5", "50")
+
+		positiveStreaks = service.moodLogRepository.StreakRanges(userID, startDate, endDate, ">=", "6", "1", "50")
+		neutralStreaks = service.moodLogRepository.StreakRanges(userID, startDate, endDate, "=", "5", "3", "50")
+		negativeStreaks = service.moodLogRepository.StreakRanges(userID, startDate, endDate, "<=", "4", "2", "50")
+		clinicalStreaks = service.moodLogRepository.StreakRanges(userID, startDate, endDate, ">=", "1", "5", "50")
+	}
 
-	negativeDays := service.moodLogRepository.Days(userID, startDate, endDate, "<=", "4", "2", "50")
+	// empty without the days
+	mtfPositiveDays := utils.MoodTagFrequencies(positiveDays)
+	mtfNeutralDays := utils.MoodTagFrequencies(neutralDays)
 	mtfNegativeDays := utils.MoodTagFrequencies(negativeDays)
-
-	clinicalDays := service.moodLogRepository.Days(userID, startDate, endDate, ">=", "1", "5", "50")
 	mtfClinicalDays := utils.MoodTagFrequencies(clinicalDays)
 
-	positiveStreaks := service.moodLogRepository.Streaks(userID, startDate, endDate, ">=", "6", "1", "50")
-
-	neutralStreaks := service.moodLogRepository.Streaks(userID, startDate, endDate, "=", "5", "3", "50")
-
-	negativeStreaks := service.moodLogRepository.Streaks(userID, startDate, endDate, "<=", "4", "2", "50")
-
-	clinicalStreaks := service.moodLogRepository.Streaks(userID, startDate, endDate, ">=", "1", "5", "50")
-
 	granularity := utils.Granularity(numDays)
 
 	moodMetrics := &models.MoodMetric{
@@ -95,6 +121,11 @@ func (service *analyticsService) analyzeMood(userID string, startDate string, en
 		Granularity:          granularity,
 		StartDate:            startDate,
 		EndDate:              endDate,
+		LoggedDays:           len(dailyAverages),
+		NumPositiveDays:      numPositiveDays,
+		NumNeutralDays:       numNeutralDays,
+		NumNegativeDays:      numNegativeDays,
+		NumClinicalDays:      numClinicalDays,
 		MovingAvg:            movingAvg,
 		MoodTrend:            moodTrend,
 		StdDeviation:         standardDeviation,
@@ -114,11 +145,15 @@ func (service *analyticsService) analyzeMood(userID string, startDate string, en
 		NegativeDays:         negativeDays,
 		ClinicalDays:         clinicalDays,
 		MoodDiffs:            models.MoodDiff{},
+		Anomalies:            service.moodAnomalies(userID, startDate, endDate),
+		Buckets:              make([]models.Bucket, 0),
 	}
 
 	return moodMetrics
 }
 
+// moodDiffs compares the current period against any other, previousPeriod is
+// usually the period just before but can be any range
 func (service *analyticsService) moodDiffs(currentPeriod, previousPeriod *models.MoodMetric) models.MoodDiff {
 
 	var avgMoodPercentChange float64
@@ -126,7 +161,7 @@ func (service *analyticsService) moodDiffs(currentPeriod, previousPeriod *models
 		avgMoodPercentChange = utils.PercentChange(currentPeriod.AvgMoodRating, previousPeriod.AvgMoodRating)
 	}
 
-	trendShift := fmt.Sprintf("%s -> %s", previousPeriod.MoodTrend, currentPeriod.MoodTrend)
+	trendShift := fmt.Sprintf("%s -> %s", previousPeriod.MoodTrend.Direction, currentPeriod.MoodTrend.Direction)
 
 	var movingAvgPercentChange float64
 	if previousPeriod.MovingAvg != 0.0 {
@@ -193,17 +228,17 @@ func (service *analyticsService) moodDiffs(currentPeriod, previousPeriod *models
 		topMoodClinicalDaysPercentChange = fmt.Sprintf("%s %f", currentMood.TagName, percentChange)
 	}
 
-	// TODO same here could break out repetetive len() - len()
-	positiveDaysChange := utils.DifferenceInLength(currentPeriod.PositiveDays, previousPeriod.PositiveDays)
-	neutralDaysChange := utils.DifferenceInLength(currentPeriod.NeutralDays, previousPeriod.NeutralDays)
-	negativeDaysChange := utils.DifferenceInLength(currentPeriod.NeutralDays, previousPeriod.NeutralDays)
-	clinicalDaysChange := utils.DifferenceInLength(currentPeriod.ClinicalDays, previousPeriod.ClinicalDays)
+	// the periods can differ in length, so days are compared as a share of the
+	// logged days and streaks as a share of the period
+	positiveDaysChange := loggedDaysShare(currentPeriod.NumPositiveDays, currentPeriod) - loggedDaysShare(previousPeriod.NumPositiveDays, previousPeriod)
+	neutralDaysChange := loggedDaysShare(currentPeriod.NumNeutralDays, currentPeriod) - loggedDaysShare(previousPeriod.NumNeutralDays, previousPeriod)
+	negativeDaysChange := loggedDaysShare(currentPeriod.NumNegativeDays, currentPeriod) - loggedDaysShare(previousPeriod.NumNegativeDays, previousPeriod)
+	clinicalDaysChange := loggedDaysShare(currentPeriod.NumClinicalDays, currentPeriod) - loggedDaysShare(previousPeriod.NumClinicalDays, previousPeriod)
 
-	// TODO same here could break out repetetive len() - len()
-	longestPositiveStreakChange := utils.DifferenceInLength(currentPeriod.PositiveStreaks, previousPeriod.PositiveStreaks)
-	longestNeutralStreakChange := utils.DifferenceInLength(currentPeriod.NeutralStreaks, previousPeriod.NeutralStreaks)
-	longestNegativeStreakChange := utils.DifferenceInLength(currentPeriod.NegativeStreaks, previousPeriod.NegativeStreaks)
-	longestClinicalStreakChange := utils.DifferenceInLength(currentPeriod.ClinicalStreaks, previousPeriod.ClinicalStreaks)
+	longestPositiveStreakChange := longestStreakShare(currentPeriod.PositiveStreaks, currentPeriod) - longestStreakShare(previousPeriod.PositiveStreaks, previousPeriod)
+	longestNeutralStreakChange := longestStreakShare(currentPeriod.NeutralStreaks, currentPeriod) - longestStreakShare(previousPeriod.NeutralStreaks, previousPeriod)
+	longestNegativeStreakChange := longestStreakShare(currentPeriod.NegativeStreaks, currentPeriod) - longestStreakShare(previousPeriod.NegativeStreaks, previousPeriod)
+	longestClinicalStreakChange := longestStreakShare(currentPeriod.ClinicalStreaks, currentPeriod) - longestStreakShare(previousPeriod.ClinicalStreaks, previousPeriod)
 
 	moodDiffs := models.MoodDiff{
 		AvgMoodPercentChange:             avgMoodPercentChange,
@@ -230,21 +265,47 @@ func (service *analyticsService) moodDiffs(currentPeriod, previousPeriod *models
 	return moodDiffs
 }
 
+// loggedDaysShare is the number of days as a percentage of the period's days
+// with a log
+func loggedDaysShare(numDays int, period *models.MoodMetric) float64 {
+	if period.LoggedDays == 0 {
+		return 0
+	}
+	return float64(numDays) / float64(period.LoggedDays) * 100
+}
+
+// longestStreakShare is the longest of the streaks as a percentage of the days
+// in the period
+func longestStreakShare(streaks []models.Streak, period *models.MoodMetric) float64 {
+	longest := 0
+	for _, streak := range streaks {
+		longest = max(longest, streak.NumDays)
+	}
+	return float64(longest) / float64(utils.NumDaysBetween(period.StartDate, period.EndDate)+1) * 100
+}
+
+// periodAverage is the mean of the daily values, where a moving average over
+// the whole period ends up, 0 with fewer than two days
+func periodAverage(dailyAverages []models.DailyAverage) float64 {
+	if len(dailyAverages) < 2 {
+		return 0
+	}
+	sum := 0.0
+	for _, dailyAverage := range dailyAverages {
+		sum += dailyAverage.DailyAvg
+	}
+	return sum / float64(len(dailyAverages))
+}
+
 func (service *analyticsService) analyzeSleep(userID string, startDate string, endDate string) *models.SleepMetric {
 
 	avgSleepHours := service.sleepLogRepository.AvgSleepHours(userID, startDate, endDate)
 
 	numDays := utils.NumDaysBetween(startDate, endDate)
-	numDaysPreceding := strconv.Itoa(numDays)
-
-	movingAverages := service.sleepLogRepository.MovingAvgSleep(userID, startDate, endDate, numDaysPreceding)
 
-	var movingAvg float64
-	if len(movingAverages) != 1 {
-		movingAvg = movingAverages[len(movingAverages)-1].MovingAvg
-	}
-
-	sleepTrend := utils.DetermineTrend(movingAverages)
+	dailySleepHours := service.sleepLogRepository.DailySleepHours(userID, startDate, endDate)
+	movingAvg := periodAverage(dailySleepHours)
+	sleepTrend := utils.DetermineTrend(dailySleepHours)
 
 	standardDeviation := service.sleepLogRepository.StandardDeviation(userID, startDate, endDate)
 
@@ -261,22 +322,173 @@ func (service *analyticsService) analyzeSleep(userID string, startDate string, e
 		stability = "volatile"
 	}
 
+	targetSleepHours := service.userSettingsRepository.TargetSleepHours(userID)
+
+	sleepDebt := service.sleepLogRepository.SleepDebt(userID, startDate, endDate, targetSleepHours)
+
+	socialJetLag := service.sleepLogRepository.SocialJetLag(userID, startDate, endDate)
+
+	variabilityIndex := service.sleepLogRepository.VariabilityIndex(userID, startDate, endDate)
+
 	granularity := utils.Granularity(numDays)
 
 	// topSleepQualityTags := service.sleepLogRepository.SleepQualityTagFrequency(userID, startDate, endDate)
 
 	sleepMetrics := &models.SleepMetric{
-		UserID:        userID,
-		Granularity:   granularity,
-		StartDate:     startDate,
-		EndDate:       endDate,
-		AvgSleepHours: avgSleepHours,
-		MovingAvg:     movingAvg,
-		SleepTrend:    sleepTrend,
-		StdDeviation:  standardDeviation,
-		Stability:     stability,
+		UserID:           userID,
+		Granularity:      granularity,
+		StartDate:        startDate,
+		EndDate:          endDate,
+		AvgSleepHours:    avgSleepHours,
+		MovingAvg:        movingAvg,
+		SleepTrend:       sleepTrend,
+		StdDeviation:     standardDeviation,
+		Stability:        stability,
+		TargetSleepHours: targetSleepHours,
+		SleepDebt:        sleepDebt,
+		SocialJetLag:     socialJetLag,
+		VariabilityIndex: variabilityIndex,
+		Buckets:          make([]models.Bucket, 0),
+		Anomalies:        service.sleepAnomalies(userID, startDate, endDate),
 	}
 
 	return sleepMetrics
 
 }
+
+func (service *analyticsService) analyzeEpisodes(userID string, startDate string, endDate string) *models.EpisodeMetric {
+
+	clinicalDays := service.moodLogRepository.ClinicalDays(userID, startDate, endDate)
+
+	episodes := detectEpisodes(clinicalDays)
+
+	episodeMetrics := &models.EpisodeMetric{
+		UserID:    userID,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Episodes:  episodes,
+	}
+
+	return episodeMetrics
+}
+
+func (service *analyticsService) moodTimeSeries(userID string, startDate string, endDate string, window int, fillGaps bool) []models.TimeSeriesPoint {
+
+	dailyAverages := service.moodLogRepository.DailyAverages(userID, utils.WindowStart(startDate, window), endDate)
+
+	return utils.TimeSeries(dailyAverages, startDate, endDate, window, fillGaps)
+}
+
+func (service *analyticsService) sleepTimeSeries(userID string, startDate string, endDate string, window int, fillGaps bool) []models.TimeSeriesPoint {
+
+	dailySleepHours := service.sleepLogRepository.DailySleepHours(userID, utils.WindowStart(startDate, window), endDate)
+
+	return utils.TimeSeries(dailySleepHours, startDate, endDate, window, fillGaps)
+}
+
+func (service *analyticsService) moodBuckets(userID string, startDate string, endDate string, granularity string) []models.Bucket {
+
+	buckets := service.moodLogRepository.Buckets(userID, startDate, endDate, granularity)
+	utils.LabelBuckets(buckets, granularity, startDate, endDate)
+
+	return buckets
+}
+
+func (service *analyticsService) sleepBuckets(userID string, startDate string, endDate string, granularity string) []models.Bucket {
+
+	buckets := service.sleepLogRepository.Buckets(userID, startDate, endDate, granularity)
+	utils.LabelBuckets(buckets, granularity, startDate, endDate)
+
+	return buckets
+}
+
+// today is the current date in the user's timezone
+func (service *analyticsService) today(userID string) (string, error) {
+	return utils.LocalToday(service.userSettingsRepository.Timezone(userID))
+}
+
+// forecastMood predicts the daily mood average for the days after endDate from
+// the last forecastHistoryDays of logs
+func (service *analyticsService) forecastMood(userID string, endDate string, days int, useSleep bool) *models.MoodForecast {
+
+	end, _ := time.Parse("2006-01-02", endDate)
+	historyStartDate := end.AddDate(0, 0, -forecastHistoryDays+1).Format("2006-01-02")
+
+	moodPoints := utils.TimeSeries(service.moodLogRepository.DailyAverages(userID, historyStartDate, endDate), historyStartDate, endDate, 1, true)
+	sleepPoints := utils.TimeSeries(service.sleepLogRepository.DailySleepHours(userID, historyStartDate, endDate), historyStartDate, endDate, 1, true)
+
+	// the history starts at the first mood log so a new user isn't modelled
+	// with weeks of empty days
+	first := slices.IndexFunc(moodPoints, func(point models.TimeSeriesPoint) bool { return point.Logged })
+	if first < 0 {
+		first = len(moodPoints)
+	}
+	mood := newDailySeries(moodPoints[first:])
+	sleep := newDailySeries(sleepPoints[first:])
+
+	moodForecast := &models.MoodForecast{
+		UserID:         userID,
+		HistoryEndDate: endDate,
+		Days:           days,
+		Model:          "not enough data",
+		Forecast:       make([]models.ForecastPoint, 0),
+	}
+	if first < len(moodPoints) {
+		moodForecast.HistoryStartDate = moodPoints[first].Date
+	}
+
+	if len(mood.values) < forecastMinDays || mood.numObserved() < forecastMinDays/2 {
+		return moodForecast
+	}
+
+	result := forecastSeries(mood, sleep, days, useSleep)
+
+	// a dip is judged against the last two weeks so it follows the user's
+	// current baseline
+	var recent []float64
+	for t := len(mood.values) - forecastMinDays; t < len(mood.values); t++ {
+		if mood.observed[t] {
+			recent = append(recent, mood.values[t])
+		}
+	}
+	recentMean := mean(recent)
+	deviations := make([]float64, len(recent))
+	for i, value := range recent {
+		deviations[i] = value - recentMean
+	}
+	recentStdDev := math.Sqrt(meanSquare(deviations))
+
+	for h := 0; h < days; h++ {
+		moodForecast.Forecast = append(moodForecast.Forecast, models.ForecastPoint{
+			Date:     end.AddDate(0, 0, h+1).Format("2006-01-02"),
+			Forecast: result.predictions[h],
+			Lower:    result.lower[h],
+			Upper:    result.upper[h],
+			Dip:      result.predictions[h] < recentMean-recentStdDev,
+		})
+	}
+
+	moodForecast.Model = "holt-winters additive, weekly seasonality"
+	moodForecast.Alpha = result.alpha
+	moodForecast.Beta = result.beta
+	moodForecast.Gamma = result.gamma
+	moodForecast.SleepRegressor = result.sleepRegressor
+	moodForecast.SleepCoefficient = result.sleepCoefficient
+	moodForecast.Backtest = backtestForecast(mood, sleep, days, useSleep)
+
+	return moodForecast
+}
+
+func (service *analyticsService) moodAnomalies(userID string, startDate string, endDate string) []models.Anomaly {
+
+	dailyAverages := service.moodLogRepository.DailyAverages(userID, utils.AnomalyBaselineStart(startDate), endDate)
+
+	return utils.DetectAnomalies(dailyAverages, startDate, utils.MinMoodMAD)
+}
+
+func (service *analyticsService) sleepAnomalies(userID string, startDate string, endDate string) []models.Anomaly {
+
+	dailySleepHours := service.sleepLogRepository.DailySleepHours(userID, utils.AnomalyBaselineStart(startDate), endDate)
+
+	return utils.DetectAnomalies(dailySleepHours, startDate, utils.MinSleepMAD)
+}
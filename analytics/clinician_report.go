@@ -0,0 +1,180 @@
+package analytics
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/pdf"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+const (
+	reportMargin          = 50.0
+	reportMovingAvgWindow = "6" // rows preceding, a 7 day moving average
+	reportTopMoods        = 5
+	reportMaxStreaks      = 5
+	chartHeight           = 170.0
+	chartMinRating        = 1.0
+	chartMaxRating        = 10.0
+	reportMaxDays         = 366
+)
+
+// validateReportRange rejects a missing or reversed range and one longer than
+// reportMaxDays, each month is a page and is analyzed on its own
+func validateReportRange(startDate string, endDate string) error {
+	layout := "2006-01-02"
+	start, startErr := time.Parse(layout, startDate)
+	end, endErr := time.Parse(layout, endDate)
+	if startErr != nil || endErr != nil {
+		return fmt.Errorf("startDate and endDate must be dates in the format YYYY-MM-DD")
+	}
+	if end.Before(start) {
+		return fmt.Errorf("startDate must not be after endDate")
+	}
+	if utils.NumDaysBetween(startDate, endDate) >= reportMaxDays {
+		return fmt.Errorf("the report range must not be longer than %d days", reportMaxDays)
+	}
+	return nil
+}
+
+// clinicianReport renders one page per calendar month in the range
+func (service *analyticsService) clinicianReport(userID string, startDate string, endDate string) (*pdf.Document, error) {
+
+	if rangeErr := validateReportRange(startDate, endDate); rangeErr != nil {
+		return nil, rangeErr
+	}
+
+	document := pdf.New()
+
+	for _, month := range utils.MonthRanges(startDate, endDate) {
+		moodMetrics := service.analyzeMood(userID, month.StartDate, month.EndDate, false)
+		sleepMetrics := service.analyzeSleep(userID, month.StartDate, month.EndDate)
+		movingAverages := service.moodLogRepository.MovingAverages(userID, month.StartDate, month.EndDate, reportMovingAvgWindow)
+		adherencePercentage, medicationLogged := service.medicationLogRepository.AdherencePercentage(userID, month.StartDate, month.EndDate)
+
+		page := document.AddPage()
+		y := pdf.A4Height - reportMargin
+
+		monthStart, _ := time.Parse("2006-01-02", month.StartDate)
+		page.Text(reportMargin, y, 18, true, "Clinician summary - "+monthStart.Format("January 2006"))
+		y -= 18
+		page.Text(reportMargin, y, 10, false, fmt.Sprintf("User %s   |   %s to %s   |   generated %s", userID, month.StartDate, month.EndDate, time.Now().Format("2006-01-02")))
+
+		y -= 30
+		page.Text(reportMargin, y, 13, true, "Mood")
+		y -= 16
+		page.Text(reportMargin, y, 10, false, fmt.Sprintf("Average rating %.2f   |   moving average %.2f   |   trend %s (%+.2f per week, p %.3f)", moodMetrics.AvgMoodRating, moodMetrics.MovingAvg, moodMetrics.MoodTrend.Direction, moodMetrics.MoodTrend.SlopePerWeek, moodMetrics.MoodTrend.PValue))
+		y -= 14
+		page.Text(reportMargin, y, 10, false, fmt.Sprintf("Standard deviation %.2f   |   stability %s", moodMetrics.StdDeviation, moodMetrics.Stability))
+
+		y -= 20
+		drawMovingAvgChart(page, y-chartHeight, month, movingAverages)
+		y -= chartHeight + 35
+
+		columnX := pdf.A4Width / 2
+		page.Text(reportMargin, y, 13, true, "Top moods")
+		page.Text(columnX, y, 13, true, "Clinical streaks")
+		y -= 16
+
+		moodsY := y
+		if len(moodMetrics.TopMoods) == 0 {
+			page.Text(reportMargin, moodsY, 10, false, "No mood tags logged")
+		}
+		for i, topMood := range moodMetrics.TopMoods {
+			if i == reportTopMoods {
+				break
+			}
+			page.Text(reportMargin, moodsY, 10, false, fmt.Sprintf("%s   %d (%.1f%%)", topMood.TagName, topMood.Count, topMood.Percentage))
+			moodsY -= 14
+		}
+
+		streaksY := y
+		if len(moodMetrics.ClinicalStreaks) == 0 {
+			page.Text(columnX, streaksY, 10, false, "None")
+		}
+		for i, streak := range moodMetrics.ClinicalStreaks {
+			if i == reportMaxStreaks {
+				break
+			}
+			page.Text(columnX, streaksY, 10, false, fmt.Sprintf("%s to %s   %d days", streak.StartDate, streak.EndDate, streak.NumDays))
+			streaksY -= 14
+		}
+
+		y -= float64(max(reportTopMoods, reportMaxStreaks))*14 + 20
+		page.Text(reportMargin, y, 13, true, "Sleep")
+		y -= 16
+		page.Text(reportMargin, y, 10, false, fmt.Sprintf("Average %.2f h   |   target %.2f h   |   sleep debt %.2f h", sleepMetrics.AvgSleepHours, sleepMetrics.TargetSleepHours, sleepMetrics.SleepDebt))
+		y -= 14
+		page.Text(reportMargin, y, 10, false, fmt.Sprintf("Standard deviation %.2f h   |   stability %s   |   trend %s (%+.2f h per week, p %.3f)", sleepMetrics.StdDeviation, sleepMetrics.Stability, sleepMetrics.SleepTrend.Direction, sleepMetrics.SleepTrend.SlopePerWeek, sleepMetrics.SleepTrend.PValue))
+		y -= 14
+		page.Text(reportMargin, y, 10, false, fmt.Sprintf("Social jet lag %+.2f h   |   night to night variability %.2f h", sleepMetrics.SocialJetLag, sleepMetrics.VariabilityIndex))
+
+		y -= 30
+		page.Text(reportMargin, y, 13, true, "Medication adherence")
+		y -= 16
+		if medicationLogged {
+			page.Text(reportMargin, y, 10, false, fmt.Sprintf("%.1f%% of logged doses taken", adherencePercentage))
+		} else {
+			page.Text(reportMargin, y, 10, false, "No medication logs")
+		}
+
+		page.Text(reportMargin, reportMargin/2, 8, false, "Self-reported data from Project Horizon, not a diagnosis.")
+	}
+
+	return document, nil
+}
+
+func drawMovingAvgChart(page *pdf.Page, bottom float64, month models.DateRange, movingAverages []models.MovingAverage) {
+
+	left := reportMargin + 20
+	width := pdf.A4Width - reportMargin - left
+	numDays := utils.NumDaysBetween(month.StartDate, month.EndDate)
+
+	scaleY := func(rating float64) float64 {
+		return bottom + (rating-chartMinRating)/(chartMaxRating-chartMinRating)*chartHeight
+	}
+	scaleX := func(date string) float64 {
+		if numDays == 0 {
+			return left
+		}
+		return left + float64(utils.NumDaysBetween(month.StartDate, date))/float64(numDays)*width
+	}
+
+	page.SetLineWidth(0.5)
+	page.SetStrokeColor(0.85, 0.85, 0.85)
+	for rating := 2.0; rating <= chartMaxRating; rating += 2 {
+		page.Line(left, scaleY(rating), left+width, scaleY(rating))
+		page.Text(reportMargin, scaleY(rating)-3, 8, false, fmt.Sprintf("%.0f", rating))
+	}
+
+	page.SetStrokeColor(0, 0, 0)
+	page.Rect(left, bottom, width, chartHeight, false)
+	page.Text(left, bottom-12, 8, false, month.StartDate)
+	page.Text(left+width-45, bottom-12, 8, false, month.EndDate)
+	page.Text(left, bottom+chartHeight+6, 9, true, "7 day moving average of daily mood")
+
+	if len(movingAverages) == 0 {
+		page.Text(left+width/2-40, bottom+chartHeight/2, 10, false, "No mood logs")
+		return
+	}
+
+	var xs []float64
+	var ys []float64
+	for _, movingAverage := range movingAverages {
+		xs = append(xs, scaleX(movingAverage.Date))
+		ys = append(ys, scaleY(movingAverage.MovingAvg))
+	}
+
+	page.SetLineWidth(1.5)
+	page.SetStrokeColor(0.2, 0.4, 0.8)
+	page.Polyline(xs, ys)
+
+	page.SetFillColor(0.2, 0.4, 0.8)
+	for i := range xs {
+		page.Rect(xs[i]-1.5, ys[i]-1.5, 3, 3, true)
+	}
+	page.SetFillColor(0, 0, 0)
+	page.SetStrokeColor(0, 0, 0)
+	page.SetLineWidth(1)
+}
@@ -0,0 +1,28 @@
+package analytics
+
+import "testing"
+
+func TestValidateReportRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		startDate string
+		endDate   string
+		wantErr   bool
+	}{
+		{"one day", "2025-01-01", "2025-01-01", false},
+		{"a year", "2025-01-01", "2025-12-31", false},
+		{"leap year", "2024-01-01", "2024-12-31", false},
+		{"missing dates", "", "", true},
+		{"missing end date", "2025-01-01", "", true},
+		{"not a date", "2025-01-01", "2025-13-01", true},
+		{"start after end", "2025-02-01", "2025-01-31", true},
+		{"longer than a year", "2024-01-01", "2025-01-01", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if err := validateReportRange(test.startDate, test.endDate); (err != nil) != test.wantErr {
+				t.Errorf("validateReportRange(%q, %q) = %v, want error %v", test.startDate, test.endDate, err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package analytics
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+// each comparison is a full analyzeMood of its range so the list is capped,
+// and so is each range, the diffs are shares of the period so a range of years
+// against a month says little
+const (
+	maxComparisons    = 5
+	maxComparisonDays = 366
+)
+
+const defaultComparison = "previous"
+
+// comparisons resolves the compare parameter, a comma separated list of
+// "previous" for the period just before, "lastYear" for the same dates a year
+// earlier, "baseline:<name>" for one of the user's baseline periods or an
+// explicit "YYYY-MM-DD..YYYY-MM-DD" range. An explicit range longer than
+// maxComparisonDays is an error, the others are compared over their last
+// maxComparisonDays days
+func (service *analyticsService) comparisons(userID string, startDate string, endDate string, compareParam string) ([]models.MoodComparison, error) {
+
+	if compareParam == "" {
+		compareParam = defaultComparison
+	}
+
+	compares := strings.Split(compareParam, ",")
+	if len(compares) > maxComparisons {
+		return nil, fmt.Errorf("compare accepts at most %d ranges", maxComparisons)
+	}
+
+	comparisons := make([]models.MoodComparison, 0, len(compares))
+	for _, compare := range compares {
+		compare = strings.TrimSpace(compare)
+		comparison := models.MoodComparison{Compare: compare}
+
+		switch {
+		case compare == "previous":
+			comparison.StartDate, comparison.EndDate = utils.PreviousDates(startDate, endDate)
+
+		case compare == "lastYear":
+			comparison.StartDate, comparison.EndDate = utils.LastYearDates(startDate, endDate)
+
+		case strings.HasPrefix(compare, "baseline:"):
+			name := strings.TrimPrefix(compare, "baseline:")
+			baselinePeriod, found := service.baselinePeriodRepository.BaselinePeriod(userID, name)
+			if !found {
+				return nil, fmt.Errorf("unknown baseline period %q", name)
+			}
+			comparison.StartDate, comparison.EndDate = baselinePeriod.StartDate, baselinePeriod.EndDate
+
+		case strings.Contains(compare, ".."):
+			rangeStart, rangeEnd, _ := strings.Cut(compare, "..")
+			layout := "2006-01-02"
+			start, startErr := time.Parse(layout, rangeStart)
+			end, endErr := time.Parse(layout, rangeEnd)
+			if startErr != nil || endErr != nil || end.Before(start) {
+				return nil, fmt.Errorf("compare range %q must be YYYY-MM-DD..YYYY-MM-DD with the start first", compare)
+			}
+			if utils.NumDaysBetween(rangeStart, rangeEnd) >= maxComparisonDays {
+				return nil, fmt.Errorf("compare range %q is longer than %d days", compare, maxComparisonDays)
+			}
+			comparison.StartDate, comparison.EndDate = rangeStart, rangeEnd
+
+		default:
+			return nil, fmt.Errorf("compare must be previous, lastYear, baseline:<name> or YYYY-MM-DD..YYYY-MM-DD, got %q", compare)
+		}
+
+		if utils.NumDaysBetween(comparison.StartDate, comparison.EndDate) >= maxComparisonDays {
+			end, _ := time.Parse("2006-01-02", comparison.EndDate)
+			comparison.StartDate = end.AddDate(0, 0, -(maxComparisonDays - 1)).Format("2006-01-02")
+		}
+
+		comparisons = append(comparisons, comparison)
+	}
+
+	return comparisons, nil
+}
@@ -0,0 +1,71 @@
+package analytics
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+// episodes are looked for over this many days up to today, well beyond the
+// longest minimum duration so an episode is found while its start is in view
+const episodeLookbackDays = 90
+
+// Start publishes an episode.detected event for every new episode on each tick
+func (service *analyticsService) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			for _, userID := range service.userRepository.UserIDs() {
+				service.publishEpisodesSafely(userID)
+			}
+		}
+	}()
+}
+
+// a failing user must not stop the scheduler
+func (service *analyticsService) publishEpisodesSafely(userID string) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("ERROR detecting episodes for user", userID, r)
+		}
+	}()
+	service.publishEpisodes(userID)
+}
+
+// publishEpisodes keys each event on the episode's type and first day, so an
+// episode is published once however many runs see it
+func (service *analyticsService) publishEpisodes(userID string) {
+
+	endDate, todayErr := service.today(userID)
+	if todayErr != nil {
+		fmt.Println("ERROR detecting episodes for user", userID, todayErr)
+		return
+	}
+	endDateParsed, _ := time.Parse("2006-01-02", endDate)
+	startDate := endDateParsed.AddDate(0, 0, -(episodeLookbackDays - 1)).Format("2006-01-02")
+
+	episodes := detectEpisodes(service.moodLogRepository.ClinicalDays(userID, startDate, endDate))
+
+	for _, episode := range unclippedEpisodes(episodes, startDate) {
+		payload, _ := json.Marshal(episode)
+		dedupeKey := fmt.Sprintf("episode:%s:%s:%s", userID, episode.EpisodeType, episode.StartDate)
+		service.webhookRepository.PublishEvent(userID, models.EventEpisodeDetected, dedupeKey, payload)
+	}
+}
+
+// unclippedEpisodes leaves out the episodes that may have begun before the
+// window, a qualifying day up to maxDaysBetweenLogged before it would have
+// joined their run. They were published when their start was in view
+func unclippedEpisodes(episodes []models.Episode, windowStart string) []models.Episode {
+	unclipped := make([]models.Episode, 0)
+	for _, episode := range episodes {
+		if utils.NumDaysBetween(windowStart, episode.StartDate) >= maxDaysBetweenLogged {
+			unclipped = append(unclipped, episode)
+		}
+	}
+	return unclipped
+}
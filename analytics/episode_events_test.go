@@ -0,0 +1,30 @@
+package analytics
+
+import (
+	"testing"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+func TestUnclippedEpisodes(t *testing.T) {
+	episodes := []models.Episode{
+		{EpisodeType: "depressive", StartDate: "2025-05-01"},
+		{EpisodeType: "hypomanic", StartDate: "2025-05-02"},
+		{EpisodeType: "manic", StartDate: "2025-05-03"},
+		{EpisodeType: "depressive", StartDate: "2025-06-20"},
+	}
+
+	// a qualifying day on 30 April would have joined the runs starting on the
+	// 1st and 2nd, the run starting on the 3rd can't reach back past the window
+	got := unclippedEpisodes(episodes, "2025-05-01")
+
+	want := []string{"2025-05-03", "2025-06-20"}
+	if len(got) != len(want) {
+		t.Fatalf("unclippedEpisodes() = %+v, want starts %v", got, want)
+	}
+	for i, episode := range got {
+		if episode.StartDate != want[i] {
+			t.Errorf("episode %d starts %v, want %v", i, episode.StartDate, want[i])
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package analytics
+
+import (
+	"math"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+const (
+	shortSleepHours      = 6.0
+	longSleepHours       = 10.0
+	maxDaysBetweenLogged = 2 // tolerate a single missed day inside an episode
+)
+
+// minimum durations follow the DSM-5 criteria for each episode type,
+// mixed episodes use the manic duration. A day only counts towards an episode
+// when it carries the type's clinical tag, the score then sets how strongly
+// the rest of the day supports it
+type episodeCriteria struct {
+	episodeType string
+	minimumDays int
+	hasTag      func(day models.ClinicalDay) bool
+	dayScore    func(day models.ClinicalDay) float64
+}
+
+// the types are mutually exclusive, a day belongs to the first type whose tag
+// it carries so a mixed day isn't also depressive and a manic day isn't also
+// hypomanic
+var episodeCriterias = []episodeCriteria{
+	{episodeType: "mixed", minimumDays: 7, hasTag: hasMixedTag, dayScore: mixedDayScore},
+	{episodeType: "manic", minimumDays: 7, hasTag: hasManicTag, dayScore: manicDayScore},
+	{episodeType: "hypomanic", minimumDays: 4, hasTag: hasHypomanicTag, dayScore: hypomanicDayScore},
+	{episodeType: "depressive", minimumDays: 14, hasTag: hasDepressedTag, dayScore: depressiveDayScore},
+}
+
+// depressed alongside manic or hypomanic on the same day is a mixed state
+func hasMixedTag(day models.ClinicalDay) bool {
+	return day.MixedStateCount > 0 || (day.DepressedCount > 0 && (day.ManicCount > 0 || day.HypomanicCount > 0))
+}
+
+func hasManicTag(day models.ClinicalDay) bool {
+	return day.ManicCount > 0
+}
+
+func hasHypomanicTag(day models.ClinicalDay) bool {
+	return day.HypomanicCount > 0
+}
+
+func hasDepressedTag(day models.ClinicalDay) bool {
+	return day.DepressedCount > 0
+}
+
+func manicDayScore(day models.ClinicalDay) float64 {
+	score := 0.5
+	if day.IrritableCount > 0 {
+		score += 0.1
+	}
+	if day.DailyAvgRating >= 7 {
+		score += 0.2
+	}
+	if day.SleepLogged && day.HoursSlept < shortSleepHours {
+		score += 0.2
+	}
+	return math.Min(score, 1.0)
+}
+
+func hypomanicDayScore(day models.ClinicalDay) float64 {
+	score := 0.5
+	if day.IrritableCount > 0 {
+		score += 0.1
+	}
+	if day.DailyAvgRating >= 6 {
+		score += 0.2
+	}
+	if day.SleepLogged && day.HoursSlept < shortSleepHours {
+		score += 0.2
+	}
+	return math.Min(score, 1.0)
+}
+
+func depressiveDayScore(day models.ClinicalDay) float64 {
+	score := 0.5
+	if day.DailyAvgRating <= 4 {
+		score += 0.3
+	}
+	// both insomnia and hypersomnia are depressive features
+	if day.SleepLogged && (day.HoursSlept < shortSleepHours || day.HoursSlept > longSleepHours) {
+		score += 0.2
+	}
+	return math.Min(score, 1.0)
+}
+
+func mixedDayScore(day models.ClinicalDay) float64 {
+	score := 0.5
+	if day.MixedStateCount > 0 {
+		score += 0.1
+	}
+	if day.IrritableCount > 0 {
+		score += 0.2
+	}
+	if day.SleepLogged && day.HoursSlept < shortSleepHours {
+		score += 0.2
+	}
+	return math.Min(score, 1.0)
+}
+
+// classifyDay returns the criteria of the one episode type the day counts
+// towards, false for a day without a clinical tag
+func classifyDay(day models.ClinicalDay) (episodeCriteria, bool) {
+	for _, criteria := range episodeCriterias {
+		if criteria.hasTag(day) {
+			return criteria, true
+		}
+	}
+	return episodeCriteria{}, false
+}
+
+// detectEpisodes walks the days once, a run continues while its days are of
+// the same type and no more than maxDaysBetweenLogged apart. A day of another
+// type ends it, so episodes never overlap
+func detectEpisodes(clinicalDays []models.ClinicalDay) []models.Episode {
+	episodes := make([]models.Episode, 0)
+
+	var criteria episodeCriteria
+	var run []models.ClinicalDay
+	var runScore float64
+
+	closeRun := func() {
+		if len(run) == 0 {
+			return
+		}
+		numDays := utils.NumDaysBetween(run[0].Date, run[len(run)-1].Date) + 1
+		if numDays >= criteria.minimumDays {
+			// penalise runs with missing days
+			coverage := float64(len(run)) / float64(numDays)
+			confidence := (runScore / float64(len(run))) * coverage
+			episodes = append(episodes, models.Episode{
+				EpisodeType:    criteria.episodeType,
+				StartDate:      run[0].Date,
+				EndDate:        run[len(run)-1].Date,
+				NumDays:        numDays,
+				MinimumDays:    criteria.minimumDays,
+				Confidence:     math.Round(confidence*100) / 100,
+				SupportingDays: run,
+			})
+		}
+		run = nil
+		runScore = 0.0
+	}
+
+	for _, day := range clinicalDays {
+		dayCriteria, tagged := classifyDay(day)
+		if !tagged {
+			continue
+		}
+		if len(run) > 0 && (dayCriteria.episodeType != criteria.episodeType ||
+			utils.NumDaysBetween(run[len(run)-1].Date, day.Date) > maxDaysBetweenLogged) {
+			closeRun()
+		}
+		criteria = dayCriteria
+		run = append(run, day)
+		runScore += criteria.dayScore(day)
+	}
+	closeRun()
+
+	return episodes
+}
@@ -0,0 +1,133 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+// clinicalDays repeats the day on numDays consecutive dates from startDate
+func clinicalDays(startDate string, numDays int, day models.ClinicalDay) []models.ClinicalDay {
+	start, _ := time.Parse("2006-01-02", startDate)
+	days := make([]models.ClinicalDay, numDays)
+	for i := range days {
+		days[i] = day
+		days[i].Date = start.AddDate(0, 0, i).Format("2006-01-02")
+	}
+	return days
+}
+
+var (
+	depressedDay = models.ClinicalDay{DailyAvgRating: 3, DepressedCount: 1}
+	lowDay       = models.ClinicalDay{DailyAvgRating: 2, SleepLogged: true, HoursSlept: 11}
+	hypomanicDay = models.ClinicalDay{DailyAvgRating: 7, HypomanicCount: 1}
+	manicDay     = models.ClinicalDay{DailyAvgRating: 8, ManicCount: 1, HypomanicCount: 1}
+	mixedDay     = models.ClinicalDay{DailyAvgRating: 5, DepressedCount: 1, HypomanicCount: 1}
+)
+
+func concatDays(runs ...[]models.ClinicalDay) []models.ClinicalDay {
+	var days []models.ClinicalDay
+	for _, run := range runs {
+		days = append(days, run...)
+	}
+	return days
+}
+
+type wantEpisode struct {
+	episodeType string
+	startDate   string
+	endDate     string
+}
+
+func TestDetectEpisodes(t *testing.T) {
+	tests := []struct {
+		name string
+		days []models.ClinicalDay
+		want []wantEpisode
+	}{
+		{
+			name: "depressive",
+			days: clinicalDays("2025-03-01", 14, depressedDay),
+			want: []wantEpisode{{"depressive", "2025-03-01", "2025-03-14"}},
+		},
+		{
+			name: "depressive too short",
+			days: clinicalDays("2025-03-01", 13, depressedDay),
+			want: nil,
+		},
+		{
+			name: "low days without the tag",
+			days: clinicalDays("2025-03-01", 20, lowDay),
+			want: nil,
+		},
+		{
+			name: "untagged days don't extend an episode",
+			days: concatDays(clinicalDays("2025-03-01", 3, lowDay), clinicalDays("2025-03-04", 4, hypomanicDay), clinicalDays("2025-03-08", 3, lowDay)),
+			want: []wantEpisode{{"hypomanic", "2025-03-04", "2025-03-07"}},
+		},
+		{
+			name: "manic days aren't also hypomanic",
+			days: clinicalDays("2025-03-01", 7, manicDay),
+			want: []wantEpisode{{"manic", "2025-03-01", "2025-03-07"}},
+		},
+		{
+			name: "mixed days aren't also depressive or hypomanic",
+			days: clinicalDays("2025-03-01", 14, mixedDay),
+			want: []wantEpisode{{"mixed", "2025-03-01", "2025-03-14"}},
+		},
+		{
+			name: "a day of another type ends the run",
+			days: concatDays(clinicalDays("2025-03-01", 10, depressedDay), clinicalDays("2025-03-11", 1, hypomanicDay), clinicalDays("2025-03-12", 10, depressedDay)),
+			want: nil,
+		},
+		{
+			name: "consecutive episodes don't overlap",
+			days: concatDays(clinicalDays("2025-03-01", 5, hypomanicDay), clinicalDays("2025-03-06", 14, depressedDay)),
+			want: []wantEpisode{{"hypomanic", "2025-03-01", "2025-03-05"}, {"depressive", "2025-03-06", "2025-03-19"}},
+		},
+		{
+			name: "a single missed day is tolerated",
+			days: concatDays(clinicalDays("2025-03-01", 2, hypomanicDay), clinicalDays("2025-03-04", 2, hypomanicDay)),
+			want: []wantEpisode{{"hypomanic", "2025-03-01", "2025-03-05"}},
+		},
+		{
+			name: "two missed days end the run",
+			days: concatDays(clinicalDays("2025-03-01", 2, hypomanicDay), clinicalDays("2025-03-05", 2, hypomanicDay)),
+			want: nil,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := detectEpisodes(test.days)
+			if len(got) != len(test.want) {
+				t.Fatalf("detectEpisodes() = %+v, want %+v", got, test.want)
+			}
+			for i, episode := range got {
+				want := test.want[i]
+				if episode.EpisodeType != want.episodeType || episode.StartDate != want.startDate || episode.EndDate != want.endDate {
+					t.Errorf("episode %d = %s %s to %s, want %+v", i, episode.EpisodeType, episode.StartDate, episode.EndDate, want)
+				}
+				for _, day := range episode.SupportingDays {
+					if criteria, _ := classifyDay(day); criteria.episodeType != episode.EpisodeType {
+						t.Errorf("%s episode supported by a %s day on %s", episode.EpisodeType, criteria.episodeType, day.Date)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestEpisodeConfidence(t *testing.T) {
+	// every feature of a depressive day, then one missed day lowers the coverage
+	fullDay := models.ClinicalDay{DailyAvgRating: 2, DepressedCount: 1, SleepLogged: true, HoursSlept: 4}
+	days := concatDays(clinicalDays("2025-03-01", 7, fullDay), clinicalDays("2025-03-09", 7, fullDay))
+
+	episodes := detectEpisodes(days)
+	if len(episodes) != 1 {
+		t.Fatalf("detectEpisodes() = %+v, want one episode", episodes)
+	}
+	if episodes[0].NumDays != 15 || episodes[0].Confidence != 0.93 {
+		t.Errorf("episode = %d days with confidence %v, want 15 days with 0.93", episodes[0].NumDays, episodes[0].Confidence)
+	}
+}
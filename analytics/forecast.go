@@ -0,0 +1,348 @@
+package analytics
+
+import (
+	"math"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+const (
+	seasonLength          = 7 // weekly seasonality
+	forecastHistoryDays   = 90
+	forecastMinDays       = 2 * seasonLength
+	forecastMaxDays       = 30
+	defaultForecastDays   = 7
+	sleepRegressorMinDays = 14
+	intervalZ             = 1.96 // 95% prediction interval
+)
+
+// smoothing parameters are picked from these grids by the lowest one step
+// ahead squared error, the series are short enough for that to be cheap. The
+// trend grid stays small so a few low days don't extrapolate into a slide
+var smoothingGrid = []float64{0.05, 0.1, 0.2, 0.3, 0.5, 0.7}
+var trendSmoothingGrid = []float64{0.01, 0.05, 0.1, 0.2}
+
+// dailySeries is a run of consecutive days, days without a log are kept with
+// observed set to false so the weekday of every index stays known
+type dailySeries struct {
+	values   []float64
+	observed []bool
+}
+
+func newDailySeries(points []models.TimeSeriesPoint) dailySeries {
+	series := dailySeries{
+		values:   make([]float64, len(points)),
+		observed: make([]bool, len(points)),
+	}
+	for i, point := range points {
+		series.values[i] = point.DailyAvg
+		series.observed[i] = point.Logged
+	}
+	return series
+}
+
+func (series dailySeries) slice(end int) dailySeries {
+	return dailySeries{values: series.values[:end], observed: series.observed[:end]}
+}
+
+func (series dailySeries) numObserved() int {
+	count := 0
+	for _, observed := range series.observed {
+		if observed {
+			count++
+		}
+	}
+	return count
+}
+
+// holtWinters is an additive Holt-Winters model, missing days advance the
+// state by its own forecast without an update
+type holtWinters struct {
+	alpha     float64
+	beta      float64
+	gamma     float64
+	level     float64
+	trend     float64
+	season    [seasonLength]float64
+	length    int       // number of days fitted, the next day has index length
+	residuals []float64 // one step ahead errors on observed days
+	fitted    []float64 // one step ahead forecast for every day
+}
+
+func fitHoltWinters(series dailySeries, alpha float64, beta float64, gamma float64) *holtWinters {
+
+	model := &holtWinters{alpha: alpha, beta: beta, gamma: gamma, length: len(series.values)}
+
+	// initialise from the first two weeks, the level is the first week's mean,
+	// the trend the change in weekly means and the season each weekday's
+	// average deviation from its week's mean
+	var weekMeans [2]float64
+	for week := 0; week < 2; week++ {
+		sum, count := 0.0, 0
+		for i := week * seasonLength; i < (week+1)*seasonLength; i++ {
+			if series.observed[i] {
+				sum += series.values[i]
+				count++
+			}
+		}
+		if count > 0 {
+			weekMeans[week] = sum / float64(count)
+		}
+	}
+	if weekMeans[0] == 0 {
+		weekMeans[0] = weekMeans[1]
+	}
+	if weekMeans[1] == 0 {
+		weekMeans[1] = weekMeans[0]
+	}
+	for position := 0; position < seasonLength; position++ {
+		sum, count := 0.0, 0
+		for week := 0; week < 2; week++ {
+			i := week*seasonLength + position
+			if series.observed[i] {
+				sum += series.values[i] - weekMeans[week]
+				count++
+			}
+		}
+		if count > 0 {
+			model.season[position] = sum / float64(count)
+		}
+	}
+	model.level = weekMeans[0]
+	model.trend = (weekMeans[1] - weekMeans[0]) / seasonLength
+
+	// the level starts at the first day so the first season has to be replayed
+	model.fitted = make([]float64, len(series.values))
+	for t := range series.values {
+		position := t % seasonLength
+		prediction := model.level + model.trend + model.season[position]
+		model.fitted[t] = prediction
+
+		if !series.observed[t] {
+			model.level += model.trend
+			continue
+		}
+
+		value := series.values[t]
+		model.residuals = append(model.residuals, value-prediction)
+
+		previousLevel := model.level
+		model.level = alpha*(value-model.season[position]) + (1-alpha)*(model.level+model.trend)
+		model.trend = beta*(model.level-previousLevel) + (1-beta)*model.trend
+		model.season[position] = gamma*(value-model.level) + (1-gamma)*model.season[position]
+	}
+
+	return model
+}
+
+func (model *holtWinters) sse() float64 {
+	sum := 0.0
+	for _, residual := range model.residuals {
+		sum += residual * residual
+	}
+	return sum
+}
+
+// predict returns the point forecast h days after the last fitted day
+func (model *holtWinters) predict(h int) float64 {
+	return model.level + float64(h)*model.trend + model.season[(model.length+h-1)%seasonLength]
+}
+
+// varianceFactor is how much wider the h step ahead forecast error is than the
+// one step error for the additive model
+func (model *holtWinters) varianceFactor(h int) float64 {
+	factor := 1.0
+	for j := 1; j < h; j++ {
+		c := model.alpha * (1 + float64(j)*model.beta)
+		if j%seasonLength == 0 {
+			c += model.gamma * (1 - model.alpha)
+		}
+		factor += c * c
+	}
+	return factor
+}
+
+func bestHoltWinters(series dailySeries) *holtWinters {
+	var best *holtWinters
+	for _, alpha := range smoothingGrid {
+		for _, beta := range trendSmoothingGrid {
+			for _, gamma := range smoothingGrid {
+				model := fitHoltWinters(series, alpha, beta, gamma)
+				if best == nil || model.sse() < best.sse() {
+					best = model
+				}
+			}
+		}
+	}
+	return best
+}
+
+// sleepRegression fits the model's one step errors against the hours slept the
+// same day, centered on the mean so the coefficient is per hour above average
+type sleepRegression struct {
+	coefficient float64
+	meanHours   float64
+	residuals   []float64
+}
+
+func fitSleepRegression(model *holtWinters, mood dailySeries, sleep dailySeries) (*sleepRegression, bool) {
+
+	var errors []float64
+	var hours []float64
+	for t := range mood.values {
+		if mood.observed[t] && sleep.observed[t] {
+			errors = append(errors, mood.values[t]-model.fitted[t])
+			hours = append(hours, sleep.values[t])
+		}
+	}
+	if len(hours) < sleepRegressorMinDays {
+		return nil, false
+	}
+
+	meanHours := mean(hours)
+	meanError := mean(errors)
+	covariance, variance := 0.0, 0.0
+	for i := range hours {
+		covariance += (hours[i] - meanHours) * (errors[i] - meanError)
+		variance += (hours[i] - meanHours) * (hours[i] - meanHours)
+	}
+	if variance == 0 {
+		return nil, false
+	}
+
+	regression := &sleepRegression{coefficient: covariance / variance, meanHours: meanHours}
+	for i := range hours {
+		regression.residuals = append(regression.residuals, errors[i]-regression.coefficient*(hours[i]-meanHours))
+	}
+
+	return regression, true
+}
+
+// recentSleepHours is the average of the last week of logged sleep, used as the
+// expected sleep on the forecast days
+func recentSleepHours(sleep dailySeries) (float64, bool) {
+	var hours []float64
+	for t := max(0, len(sleep.values)-seasonLength); t < len(sleep.values); t++ {
+		if sleep.observed[t] {
+			hours = append(hours, sleep.values[t])
+		}
+	}
+	return mean(hours), len(hours) > 0
+}
+
+type forecastResult struct {
+	predictions      []float64
+	lower            []float64
+	upper            []float64
+	sleepRegressor   bool
+	sleepCoefficient float64
+	alpha            float64
+	beta             float64
+	gamma            float64
+}
+
+// forecastSeries predicts the next horizon days of mood, when useSleep is set
+// and there is enough overlapping sleep the recent sleep shifts the forecast
+func forecastSeries(mood dailySeries, sleep dailySeries, horizon int, useSleep bool) forecastResult {
+
+	model := bestHoltWinters(mood)
+	residuals := model.residuals
+
+	result := forecastResult{alpha: model.alpha, beta: model.beta, gamma: model.gamma}
+
+	adjustment := 0.0
+	if useSleep {
+		recentHours, sleptRecently := recentSleepHours(sleep)
+		if regression, fitted := fitSleepRegression(model, mood, sleep); fitted && sleptRecently {
+			result.sleepRegressor = true
+			result.sleepCoefficient = regression.coefficient
+			adjustment = regression.coefficient * (recentHours - regression.meanHours)
+			residuals = regression.residuals
+		}
+	}
+
+	sigma := math.Sqrt(meanSquare(residuals))
+	for h := 1; h <= horizon; h++ {
+		prediction := model.predict(h) + adjustment
+		margin := intervalZ * sigma * math.Sqrt(model.varianceFactor(h))
+		result.predictions = append(result.predictions, clampRating(prediction))
+		result.lower = append(result.lower, clampRating(prediction-margin))
+		result.upper = append(result.upper, clampRating(prediction+margin))
+	}
+
+	return result
+}
+
+// backtestForecast holds out the last horizon days, forecasts them from the
+// days before and scores the forecast against the days that were logged
+func backtestForecast(mood dailySeries, sleep dailySeries, horizon int, useSleep bool) models.ForecastAccuracy {
+
+	accuracy := models.ForecastAccuracy{HoldoutDays: horizon}
+
+	trainingLength := len(mood.values) - horizon
+	if trainingLength < forecastMinDays {
+		return accuracy
+	}
+
+	result := forecastSeries(mood.slice(trainingLength), sleep.slice(trainingLength), horizon, useSleep)
+
+	var absErrors, squaredErrors, percentErrors, naiveErrors []float64
+	covered := 0
+	for h := 0; h < horizon; h++ {
+		t := trainingLength + h
+		if !mood.observed[t] {
+			continue
+		}
+		actual := mood.values[t]
+		diff := actual - result.predictions[h]
+		absErrors = append(absErrors, math.Abs(diff))
+		squaredErrors = append(squaredErrors, diff*diff)
+		percentErrors = append(percentErrors, math.Abs(diff)/actual*100)
+		if actual >= result.lower[h] && actual <= result.upper[h] {
+			covered++
+		}
+
+		// seasonal naive, the same weekday from the last week of training
+		for naive := t - seasonLength; naive >= 0; naive -= seasonLength {
+			if naive < trainingLength && mood.observed[naive] {
+				naiveErrors = append(naiveErrors, math.Abs(actual-mood.values[naive]))
+				break
+			}
+		}
+	}
+
+	accuracy.NumScored = len(absErrors)
+	if accuracy.NumScored == 0 {
+		return accuracy
+	}
+	accuracy.MAE = mean(absErrors)
+	accuracy.RMSE = math.Sqrt(mean(squaredErrors))
+	accuracy.MAPE = mean(percentErrors)
+	accuracy.IntervalCoverage = float64(covered) / float64(accuracy.NumScored) * 100
+	accuracy.SeasonalNaiveMAE = mean(naiveErrors)
+
+	return accuracy
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return sum / float64(len(values))
+}
+
+func meanSquare(values []float64) float64 {
+	squares := make([]float64, len(values))
+	for i, value := range values {
+		squares[i] = value * value
+	}
+	return mean(squares)
+}
+
+func clampRating(rating float64) float64 {
+	return math.Max(1, math.Min(10, rating))
+}
@@ -0,0 +1,190 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+)
+
+// weekly deviations that sum to zero, so a series of level plus season keeps
+// its level as each week's mean
+var testSeason = [seasonLength]float64{-2, -1, 0, 1, 2, 1, -1}
+
+func observedSeries(values []float64) dailySeries {
+	series := dailySeries{values: values, observed: make([]bool, len(values))}
+	for i := range series.observed {
+		series.observed[i] = true
+	}
+	return series
+}
+
+// noSleep is a sleep series of the same length without a log
+func noSleep(numDays int) dailySeries {
+	return dailySeries{values: make([]float64, numDays), observed: make([]bool, numDays)}
+}
+
+func seasonalSeries(level float64, numDays int) dailySeries {
+	values := make([]float64, numDays)
+	for t := range values {
+		values[t] = level + testSeason[t%seasonLength]
+	}
+	return observedSeries(values)
+}
+
+func TestFitHoltWinters(t *testing.T) {
+	tests := []struct {
+		name   string
+		series dailySeries
+		// want is the forecast for the next week
+		want [seasonLength]float64
+	}{
+		{
+			name:   "constant",
+			series: observedSeries([]float64{5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5, 5}),
+			want:   [seasonLength]float64{5, 5, 5, 5, 5, 5, 5},
+		},
+		{
+			name:   "weekly season",
+			series: seasonalSeries(5, 28),
+			want:   [seasonLength]float64{3, 4, 5, 6, 7, 6, 4},
+		},
+		{
+			name:   "season continues from a partial week",
+			series: seasonalSeries(5, 31),
+			want:   [seasonLength]float64{6, 7, 6, 4, 3, 4, 5},
+		},
+		{
+			// after the two weeks the model is initialised from
+			name: "missing days",
+			series: func() dailySeries {
+				series := seasonalSeries(5, 28)
+				for _, t := range []int{16, 20, 27} {
+					series.observed[t] = false
+					series.values[t] = 0
+				}
+				return series
+			}(),
+			want: [seasonLength]float64{3, 4, 5, 6, 7, 6, 4},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for _, alpha := range []float64{0.1, 0.5} {
+				model := fitHoltWinters(test.series, alpha, 0.1, 0.2)
+				if sse := model.sse(); sse > 1e-9 {
+					t.Errorf("alpha %v: sse = %v, want 0 on a noiseless series", alpha, sse)
+				}
+				if len(model.residuals) != test.series.numObserved() {
+					t.Errorf("alpha %v: %d residuals, want one per observed day (%d)", alpha, len(model.residuals), test.series.numObserved())
+				}
+				for h := 1; h <= seasonLength; h++ {
+					if got := model.predict(h); math.Abs(got-test.want[h-1]) > 1e-9 {
+						t.Errorf("alpha %v: predict(%d) = %v, want %v", alpha, h, got, test.want[h-1])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFitHoltWintersTrend(t *testing.T) {
+	values := make([]float64, 42)
+	for t := range values {
+		values[t] = 3 + 0.05*float64(t)
+	}
+	model := bestHoltWinters(observedSeries(values))
+
+	for _, h := range []int{1, 7} {
+		want := 3 + 0.05*float64(41+h)
+		if got := model.predict(h); math.Abs(got-want) > 0.05 {
+			t.Errorf("predict(%d) = %v, want about %v", h, got, want)
+		}
+	}
+}
+
+func TestVarianceFactor(t *testing.T) {
+	model := &holtWinters{alpha: 0.5, beta: 0.1, gamma: 0.2}
+	tests := []struct {
+		h    int
+		want float64
+	}{
+		{1, 1},
+		{2, 1 + 0.55*0.55},
+		{3, 1 + 0.55*0.55 + 0.6*0.6},
+		// the seasonal term joins at j = 7
+		{8, 1 + 0.55*0.55 + 0.6*0.6 + 0.65*0.65 + 0.7*0.7 + 0.75*0.75 + 0.8*0.8 + (0.85+0.1)*(0.85+0.1)},
+	}
+	for _, test := range tests {
+		if got := model.varianceFactor(test.h); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("varianceFactor(%d) = %v, want %v", test.h, got, test.want)
+		}
+	}
+}
+
+func TestForecastSeries(t *testing.T) {
+	result := forecastSeries(seasonalSeries(5, 28), noSleep(28), 7, false)
+
+	want := []float64{3, 4, 5, 6, 7, 6, 4}
+	for h := range want {
+		if math.Abs(result.predictions[h]-want[h]) > 1e-9 {
+			t.Errorf("prediction %d = %v, want %v", h+1, result.predictions[h], want[h])
+		}
+		// no error to widen the interval
+		if math.Abs(result.lower[h]-want[h]) > 1e-9 || math.Abs(result.upper[h]-want[h]) > 1e-9 {
+			t.Errorf("interval %d = [%v, %v], want the prediction", h+1, result.lower[h], result.upper[h])
+		}
+	}
+	if result.sleepRegressor {
+		t.Errorf("sleep regressor used without useSleep")
+	}
+
+	clamped := forecastSeries(seasonalSeries(8.5, 28), noSleep(28), 7, false)
+	if clamped.predictions[4] != 10 {
+		t.Errorf("prediction above the scale = %v, want clamped to 10", clamped.predictions[4])
+	}
+}
+
+func TestFitSleepRegression(t *testing.T) {
+	mood := seasonalSeries(5, 28)
+	model := fitHoltWinters(mood, 0.3, 0.1, 0.2)
+
+	sleep := observedSeries(make([]float64, 28))
+	for t := range sleep.values {
+		sleep.values[t] = 7
+	}
+	if _, fitted := fitSleepRegression(model, mood, sleep); fitted {
+		t.Errorf("fitted a regression on constant sleep")
+	}
+
+	// the errors are zero so any spread of sleep explains none of them
+	for t := range sleep.values {
+		sleep.values[t] = 6 + float64(t%3)
+	}
+	regression, fitted := fitSleepRegression(model, mood, sleep)
+	if !fitted {
+		t.Fatalf("no regression on 28 days of sleep")
+	}
+	if math.Abs(regression.coefficient) > 1e-9 || math.Abs(regression.meanHours-7) > 0.1 {
+		t.Errorf("regression = %+v, want coefficient 0 around 7 hours", regression)
+	}
+
+	short := sleep.slice(28)
+	short.observed = make([]bool, 28)
+	for t := 0; t < sleepRegressorMinDays-1; t++ {
+		short.observed[t] = true
+	}
+	if _, fitted := fitSleepRegression(model, mood, short); fitted {
+		t.Errorf("fitted a regression on %d days of sleep", sleepRegressorMinDays-1)
+	}
+}
+
+func TestBacktestForecast(t *testing.T) {
+	accuracy := backtestForecast(seasonalSeries(5, 35), noSleep(35), 7, false)
+	if accuracy.NumScored != 7 || accuracy.MAE > 1e-9 || accuracy.RMSE > 1e-9 || accuracy.SeasonalNaiveMAE != 0 || accuracy.IntervalCoverage != 100 {
+		t.Errorf("backtest of a noiseless season = %+v, want every day scored without error", accuracy)
+	}
+
+	tooShort := backtestForecast(seasonalSeries(5, forecastMinDays+6), noSleep(forecastMinDays+6), 7, false)
+	if tooShort.NumScored != 0 {
+		t.Errorf("backtest with %d training days scored %d days, want none", forecastMinDays-1, tooShort.NumScored)
+	}
+}
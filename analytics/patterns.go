@@ -0,0 +1,122 @@
+package analytics
+
+import (
+	"slices"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+const (
+	patternTopMoods   = 3
+	lowestSlotMinLogs = 3 // a slot needs a few logs before it can be called the worst
+)
+
+var weekdays = []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
+var timesOfDay = []string{"morning", "afternoon", "evening", "night"}
+
+func (service *analyticsService) moodPatterns(userID string, startDate string, endDate string) *models.MoodPatterns {
+
+	cells := service.moodLogRepository.PatternCells(userID, startDate, endDate)
+	swingDays := service.moodLogRepository.SwingDays(userID, startDate, endDate)
+
+	byWeekday := make([]models.MoodPattern, 0)
+	for _, weekday := range weekdays {
+		if pattern, logged := mergePatterns(cells, weekday, ""); logged {
+			byWeekday = append(byWeekday, pattern)
+		}
+	}
+
+	byTimeOfDay := make([]models.MoodPattern, 0)
+	for _, timeOfDay := range timesOfDay {
+		if pattern, logged := mergePatterns(cells, "", timeOfDay); logged {
+			byTimeOfDay = append(byTimeOfDay, pattern)
+		}
+	}
+
+	byWeekdayAndTimeOfDay := make([]models.MoodPattern, 0)
+	var lowestSlot *models.MoodPattern
+	for _, weekday := range weekdays {
+		for _, timeOfDay := range timesOfDay {
+			pattern, logged := mergePatterns(cells, weekday, timeOfDay)
+			if !logged {
+				continue
+			}
+			byWeekdayAndTimeOfDay = append(byWeekdayAndTimeOfDay, pattern)
+			if pattern.LogCount >= lowestSlotMinLogs && (lowestSlot == nil || pattern.AvgMoodRating < lowestSlot.AvgMoodRating) {
+				lowestSlot = &pattern
+			}
+		}
+	}
+
+	var avgSwing float64
+	for _, swingDay := range swingDays {
+		avgSwing += float64(swingDay.Swing)
+	}
+	if len(swingDays) > 0 {
+		avgSwing /= float64(len(swingDays))
+	}
+
+	moodPatterns := &models.MoodPatterns{
+		UserID:                userID,
+		StartDate:             startDate,
+		EndDate:               endDate,
+		ByWeekday:             byWeekday,
+		ByTimeOfDay:           byTimeOfDay,
+		ByWeekdayAndTimeOfDay: byWeekdayAndTimeOfDay,
+		LowestSlot:            lowestSlot,
+		AvgSwing:              avgSwing,
+		SwingDays:             swingDays,
+	}
+
+	return moodPatterns
+}
+
+// mergePatterns combines the weekday and time of day cells that match, an
+// empty weekday or time of day matches every cell
+func mergePatterns(cells []models.MoodPattern, weekday string, timeOfDay string) (models.MoodPattern, bool) {
+
+	merged := models.MoodPattern{Weekday: weekday, TimeOfDay: timeOfDay}
+	ratingSum := 0.0
+	tagCounts := make(map[string]int)
+	totalTags := 0
+
+	for _, cell := range cells {
+		if (weekday != "" && cell.Weekday != weekday) || (timeOfDay != "" && cell.TimeOfDay != timeOfDay) {
+			continue
+		}
+		ratingSum += cell.AvgMoodRating * float64(cell.LogCount)
+		merged.LogCount += cell.LogCount
+		for _, tagFrequency := range cell.TopMoods {
+			tagCounts[tagFrequency.TagName] += tagFrequency.Count
+			totalTags += tagFrequency.Count
+		}
+	}
+
+	if merged.LogCount == 0 {
+		return merged, false
+	}
+	merged.AvgMoodRating = ratingSum / float64(merged.LogCount)
+
+	merged.TopMoods = make([]models.TagFrequency, 0)
+	for tagName, count := range tagCounts {
+		merged.TopMoods = append(merged.TopMoods, models.TagFrequency{
+			TagName:    tagName,
+			Count:      count,
+			Percentage: float64(count) / float64(totalTags) * 100.0,
+		})
+	}
+	slices.SortFunc(merged.TopMoods, func(a, b models.TagFrequency) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		if a.TagName < b.TagName {
+			return -1
+		}
+		return 1
+	})
+	if len(merged.TopMoods) > patternTopMoods {
+		merged.TopMoods = merged.TopMoods[:patternTopMoods]
+	}
+
+	return merged, true
+}
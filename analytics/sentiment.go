@@ -0,0 +1,134 @@
+package analytics
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+const (
+	minDivergence      = 1.0 // half the -1 to 1 scale, e.g. rated 8 (0.56) but wrote clearly negative text (-0.5)
+	maxDivergences     = 10
+	sentimentTopWords  = 10
+	correlationMinLogs = 3
+)
+
+// moodSentiment compares what the notes say with how the logs were rated, the
+// notes are scored in the background so recent logs may not be in yet
+func (service *analyticsService) moodSentiment(userID string, startDate string, endDate string) *models.MoodSentiment {
+
+	scoredMoodLogs := service.sentimentRepository.ScoredMoodLogs(userID, startDate, endDate)
+
+	moodSentiment := &models.MoodSentiment{
+		UserID:      userID,
+		StartDate:   startDate,
+		EndDate:     endDate,
+		NumScored:   len(scoredMoodLogs),
+		Daily:       make([]models.SentimentPoint, 0),
+		Divergences: make([]models.ScoredMoodLog, 0),
+	}
+
+	var sentiments []float64
+	var ratings []float64
+	for i := range scoredMoodLogs {
+		scoredMoodLog := &scoredMoodLogs[i]
+		scoredMoodLog.Divergence = scoredMoodLog.Sentiment - ratingOnSentimentScale(scoredMoodLog.MoodRating)
+		sentiments = append(sentiments, scoredMoodLog.Sentiment)
+		ratings = append(ratings, float64(scoredMoodLog.MoodRating))
+
+		// the logs come in date order
+		last := len(moodSentiment.Daily) - 1
+		if last < 0 || moodSentiment.Daily[last].Date != scoredMoodLog.Date {
+			moodSentiment.Daily = append(moodSentiment.Daily, models.SentimentPoint{Date: scoredMoodLog.Date})
+			last++
+		}
+		point := &moodSentiment.Daily[last]
+		point.AvgSentiment += scoredMoodLog.Sentiment
+		point.AvgMoodRating += float64(scoredMoodLog.MoodRating)
+		point.NumLogs++
+	}
+
+	dailySentiment := make([]models.DailyAverage, len(moodSentiment.Daily))
+	for i := range moodSentiment.Daily {
+		point := &moodSentiment.Daily[i]
+		point.AvgSentiment /= float64(point.NumLogs)
+		point.AvgMoodRating /= float64(point.NumLogs)
+		dailySentiment[i] = models.DailyAverage{Date: point.Date, DailyAvg: point.AvgSentiment}
+	}
+
+	moodSentiment.AvgSentiment = mean(sentiments)
+	moodSentiment.RatingCorrelation = pearson(sentiments, ratings)
+	moodSentiment.SentimentTrendStats = utils.DetermineTrend(dailySentiment)
+	moodSentiment.SentimentTrend = moodSentiment.SentimentTrendStats.Direction
+
+	for _, scoredMoodLog := range scoredMoodLogs {
+		if math.Abs(scoredMoodLog.Divergence) >= minDivergence {
+			moodSentiment.Divergences = append(moodSentiment.Divergences, scoredMoodLog)
+		}
+	}
+	slices.SortStableFunc(moodSentiment.Divergences, func(a, b models.ScoredMoodLog) int {
+		return cmp.Compare(math.Abs(b.Divergence), math.Abs(a.Divergence))
+	})
+	if len(moodSentiment.Divergences) > maxDivergences {
+		moodSentiment.Divergences = moodSentiment.Divergences[:maxDivergences]
+	}
+
+	// the same day definitions as analyzeMood
+	negativeDays := service.moodLogRepository.Days(userID, startDate, endDate, "<=", "4", "2", "50")
+	clinicalDays := service.moodLogRepository.Days(userID, startDate, endDate, ">=", "1", "5", "50")
+	moodSentiment.TopKeywordsNegativeDays = service.topKeywords(userID, startDate, endDate, negativeDays, scoredMoodLogs)
+	moodSentiment.TopKeywordsClinicalDays = service.topKeywords(userID, startDate, endDate, clinicalDays, scoredMoodLogs)
+
+	return moodSentiment
+}
+
+// topKeywords are the keywords of the notes written on the days, the
+// percentage is of the scored notes on those days
+func (service *analyticsService) topKeywords(userID string, startDate string, endDate string, days []models.Day, scoredMoodLogs []models.ScoredMoodLog) []models.KeywordFrequency {
+
+	dates := make([]string, len(days))
+	for i, day := range days {
+		dates[i] = day.Date
+	}
+
+	numNotes := 0
+	for _, scoredMoodLog := range scoredMoodLogs {
+		if slices.Contains(dates, scoredMoodLog.Date) {
+			numNotes++
+		}
+	}
+
+	keywordFrequencies := service.sentimentRepository.KeywordFrequencies(userID, startDate, endDate, dates, sentimentTopWords)
+	for i := range keywordFrequencies {
+		if numNotes > 0 {
+			keywordFrequencies[i].Percentage = float64(keywordFrequencies[i].Count) / float64(numNotes) * 100
+		}
+	}
+
+	return keywordFrequencies
+}
+
+// ratingOnSentimentScale maps a 1 to 10 rating onto -1 to 1
+func ratingOnSentimentScale(rating int) float64 {
+	return (float64(rating) - 5.5) / 4.5
+}
+
+func pearson(xs []float64, ys []float64) float64 {
+	if len(xs) < correlationMinLogs {
+		return 0
+	}
+	meanX, meanY := mean(xs), mean(ys)
+	covariance, varianceX, varianceY := 0.0, 0.0, 0.0
+	for i := range xs {
+		covariance += (xs[i] - meanX) * (ys[i] - meanY)
+		varianceX += (xs[i] - meanX) * (xs[i] - meanX)
+		varianceY += (ys[i] - meanY) * (ys[i] - meanY)
+	}
+	if varianceX == 0 || varianceY == 0 {
+		return 0
+	}
+	return covariance / math.Sqrt(varianceX*varianceY)
+}
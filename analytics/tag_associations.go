@@ -0,0 +1,243 @@
+package analytics
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+const (
+	rankedPairMinCount = 2    // a pair seen once has an inflated lift
+	associationMinLogs = 3    // logs needed with and without the tag
+	associationFDR     = 0.05 // share of the reported associations allowed to be noise
+)
+
+// tagAssociations builds the co-occurrence matrix of the tags logged together
+// and ranks the pairs by lift and the tags by how far the rating of logs with
+// the tag is from the user's average
+func (service *analyticsService) tagAssociations(userID string, startDate string, endDate string) *models.TagAssociations {
+
+	numLogs, avgMoodRating, stdDeviation := service.moodLogRepository.RatingSummary(userID, startDate, endDate)
+	tagRatings := service.moodLogRepository.TagRatings(userID, startDate, endDate)
+	tagPairs := service.moodLogRepository.TagPairs(userID, startDate, endDate)
+
+	tags := make([]string, len(tagRatings))
+	tagIndex := make(map[string]int)
+	matrix := make([][]int, len(tagRatings))
+	for i, tagRating := range tagRatings {
+		tags[i] = tagRating.TagName
+		tagIndex[tagRating.TagName] = i
+		matrix[i] = make([]int, len(tagRatings))
+		matrix[i][i] = tagRating.LogCount
+	}
+
+	pairs := make([]models.TagPair, 0)
+	for _, tagPair := range tagPairs {
+		a, b := tagIndex[tagPair.TagA], tagIndex[tagPair.TagB]
+		matrix[a][b] = tagPair.Count
+		matrix[b][a] = tagPair.Count
+
+		if tagPair.Count < rankedPairMinCount {
+			continue
+		}
+		tagPair.Support = float64(tagPair.Count) / float64(numLogs) * 100
+		tagPair.Lift = float64(tagPair.Count) * float64(numLogs) / float64(tagRatings[a].LogCount*tagRatings[b].LogCount)
+		tagPair.PMI = math.Log2(tagPair.Lift)
+		pairs = append(pairs, tagPair)
+	}
+	slices.SortStableFunc(pairs, func(a, b models.TagPair) int {
+		if a.Lift != b.Lift {
+			return cmp.Compare(b.Lift, a.Lift)
+		}
+		return b.Count - a.Count
+	})
+
+	ratingAssociations := make([]models.TagRatingAssociation, 0, len(tagRatings))
+	for _, tagRating := range tagRatings {
+		ratingAssociations = append(ratingAssociations, ratingAssociation(tagRating, numLogs, avgMoodRating, stdDeviation))
+	}
+	adjustPValues(ratingAssociations)
+	slices.SortStableFunc(ratingAssociations, func(a, b models.TagRatingAssociation) int {
+		return cmp.Compare(b.Difference, a.Difference)
+	})
+
+	return &models.TagAssociations{
+		UserID:             userID,
+		StartDate:          startDate,
+		EndDate:            endDate,
+		NumLogs:            numLogs,
+		AvgMoodRating:      avgMoodRating,
+		Tags:               tags,
+		Matrix:             matrix,
+		Pairs:              pairs,
+		RatingAssociations: ratingAssociations,
+	}
+}
+
+// ratingAssociation compares the logs with the tag against the rest with
+// Welch's t-test, the mean and variance of the rest come from subtracting the
+// tag's sums from the totals. A tag with too few logs either side isn't tested
+// and keeps a p-value of 1
+func ratingAssociation(tagRating models.TagRatingAssociation, numLogs int, avgMoodRating float64, stdDeviation float64) models.TagRatingAssociation {
+
+	tagRating.Difference = tagRating.AvgMoodRating - avgMoodRating
+	tagRating.Association = "none"
+	tagRating.PValue = 1
+	tagRating.AdjustedPValue = 1
+
+	numWith := float64(tagRating.LogCount)
+	numWithout := float64(numLogs - tagRating.LogCount)
+	if numWithout == 0 {
+		return tagRating
+	}
+
+	sumWith := numWith * tagRating.AvgMoodRating
+	squaresWith := numWith * (tagRating.StdDeviation*tagRating.StdDeviation + tagRating.AvgMoodRating*tagRating.AvgMoodRating)
+	sumAll := float64(numLogs) * avgMoodRating
+	squaresAll := float64(numLogs) * (stdDeviation*stdDeviation + avgMoodRating*avgMoodRating)
+
+	meanWithout := (sumAll - sumWith) / numWithout
+	tagRating.AvgMoodRatingWithout = meanWithout
+
+	if numWith < associationMinLogs || numWithout < associationMinLogs {
+		return tagRating
+	}
+
+	// sample variances from the population ones
+	varianceWith := tagRating.StdDeviation * tagRating.StdDeviation * numWith / (numWith - 1)
+	varianceWithout := math.Max(0, (squaresAll-squaresWith)/numWithout-meanWithout*meanWithout) * numWithout / (numWithout - 1)
+
+	errorWith := varianceWith / numWith
+	errorWithout := varianceWithout / numWithout
+	standardError := math.Sqrt(errorWith + errorWithout)
+	if standardError == 0 {
+		return tagRating
+	}
+	tagRating.TStatistic = (tagRating.AvgMoodRating - meanWithout) / standardError
+
+	// Welch–Satterthwaite
+	tagRating.DegreesOfFreedom = (errorWith + errorWithout) * (errorWith + errorWithout) /
+		(errorWith*errorWith/(numWith-1) + errorWithout*errorWithout/(numWithout-1))
+	tagRating.PValue = studentTPValue(tagRating.TStatistic, tagRating.DegreesOfFreedom)
+
+	return tagRating
+}
+
+// adjustPValues applies the Benjamini–Hochberg correction across the tested
+// tags, every tag is a test so with enough tags some would pass on noise
+// alone. Only the tags whose adjusted p-value is within associationFDR are
+// labelled higher or lower
+func adjustPValues(ratingAssociations []models.TagRatingAssociation) {
+
+	var tested []int
+	for i, ratingAssociation := range ratingAssociations {
+		if ratingAssociation.DegreesOfFreedom > 0 {
+			tested = append(tested, i)
+		}
+	}
+	slices.SortStableFunc(tested, func(a, b int) int {
+		return cmp.Compare(ratingAssociations[a].PValue, ratingAssociations[b].PValue)
+	})
+
+	// from the largest p-value down so each adjusted value is the smallest of
+	// the ones ranked after it
+	numTests := float64(len(tested))
+	adjusted := 1.0
+	for rank := len(tested); rank >= 1; rank-- {
+		ratingAssociation := &ratingAssociations[tested[rank-1]]
+		adjusted = math.Min(adjusted, ratingAssociation.PValue*numTests/float64(rank))
+		ratingAssociation.AdjustedPValue = adjusted
+
+		switch {
+		case adjusted > associationFDR:
+		case ratingAssociation.TStatistic > 0:
+			ratingAssociation.Association = "higher"
+		case ratingAssociation.TStatistic < 0:
+			ratingAssociation.Association = "lower"
+		}
+	}
+}
+
+// studentTPValue is the two-sided p-value of t under Student's t distribution
+// with df degrees of freedom, df needn't be whole
+func studentTPValue(t float64, df float64) float64 {
+	return regularizedIncompleteBeta(df/(df+t*t), df/2, 0.5)
+}
+
+// regularizedIncompleteBeta is I_x(a, b), from its continued fraction as in
+// Numerical Recipes' betai
+func regularizedIncompleteBeta(x float64, a float64, b float64) float64 {
+
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+
+	lgammaA, _ := math.Lgamma(a)
+	lgammaB, _ := math.Lgamma(b)
+	lgammaAB, _ := math.Lgamma(a + b)
+	front := math.Exp(lgammaAB - lgammaA - lgammaB + a*math.Log(x) + b*math.Log(1-x))
+
+	// the fraction converges quickly below the mean, above it use the symmetry
+	// I_x(a, b) = 1 - I_1-x(b, a)
+	if x < (a+1)/(a+b+2) {
+		return front * betaContinuedFraction(x, a, b) / a
+	}
+	return 1 - front*betaContinuedFraction(1-x, b, a)/b
+}
+
+func betaContinuedFraction(x float64, a float64, b float64) float64 {
+
+	const (
+		maxIterations = 200
+		epsilon       = 1e-14
+		tiny          = 1e-300
+	)
+
+	c := 1.0
+	d := 1 - (a+b)*x/(a+1)
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	fraction := d
+
+	for m := 1.0; m <= maxIterations; m++ {
+		// even step
+		numerator := m * (b - m) * x / ((a + 2*m - 1) * (a + 2*m))
+		d = 1 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		fraction *= d * c
+
+		// odd step
+		numerator = -(a + m) * (a + b + m) * x / ((a + 2*m) * (a + 2*m + 1))
+		d = 1 + numerator*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + numerator/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		delta := d * c
+		fraction *= delta
+
+		if math.Abs(delta-1) < epsilon {
+			break
+		}
+	}
+
+	return fraction
+}
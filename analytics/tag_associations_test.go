@@ -0,0 +1,111 @@
+package analytics
+
+import (
+	"math"
+	"testing"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+func TestStudentTPValue(t *testing.T) {
+	tests := []struct {
+		name string
+		t    float64
+		df   float64
+		want float64
+	}{
+		{"zero", 0, 10, 1},
+		{"critical value at 10 df", 2.228139, 10, 0.05},
+		{"t of 2 at 10 df", 2, 10, 0.073388},
+		{"negative t is two sided", -2, 10, 0.073388},
+		{"few df", 4.302653, 2, 0.05},
+		{"fractional df", 2.5, 5.5, 0.050120},
+		{"large df approaches the normal", 1.959964, 100000, 0.05},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := studentTPValue(test.t, test.df)
+			if math.Abs(got-test.want) > 5e-4 {
+				t.Errorf("studentTPValue(%v, %v) = %v, want %v", test.t, test.df, got, test.want)
+			}
+		})
+	}
+}
+
+func TestAdjustPValues(t *testing.T) {
+	ratingAssociations := []models.TagRatingAssociation{
+		{TagName: "a", PValue: 0.01, TStatistic: 3, DegreesOfFreedom: 20},
+		{TagName: "b", PValue: 0.04, TStatistic: -2, DegreesOfFreedom: 20},
+		{TagName: "c", PValue: 0.03, TStatistic: 2.5, DegreesOfFreedom: 20},
+		{TagName: "d", PValue: 0.005, TStatistic: -3.5, DegreesOfFreedom: 20},
+		// not tested, it takes no part in the correction
+		{TagName: "e", PValue: 1, AdjustedPValue: 1, Association: "none"},
+	}
+	for i := range ratingAssociations[:4] {
+		ratingAssociations[i].Association = "none"
+	}
+
+	adjustPValues(ratingAssociations)
+
+	want := []struct {
+		adjusted    float64
+		association string
+	}{
+		{0.02, "higher"},
+		{0.04, "lower"},
+		{0.04, "higher"},
+		{0.02, "lower"},
+		{1, "none"},
+	}
+	for i, ratingAssociation := range ratingAssociations {
+		if math.Abs(ratingAssociation.AdjustedPValue-want[i].adjusted) > 1e-9 || ratingAssociation.Association != want[i].association {
+			t.Errorf("tag %s: adjusted %v %s, want %v %s", ratingAssociation.TagName, ratingAssociation.AdjustedPValue, ratingAssociation.Association, want[i].adjusted, want[i].association)
+		}
+	}
+}
+
+func TestAdjustPValuesKeepsNoiseUnlabelled(t *testing.T) {
+	// 20 tags each at p 0.04 would all pass an uncorrected 0.05 test, with 19
+	// of them null the correction keeps them all out
+	ratingAssociations := make([]models.TagRatingAssociation, 20)
+	for i := range ratingAssociations {
+		ratingAssociations[i] = models.TagRatingAssociation{PValue: 0.04 + float64(i)*0.01, TStatistic: 2, DegreesOfFreedom: 10, Association: "none"}
+	}
+
+	adjustPValues(ratingAssociations)
+
+	for i, ratingAssociation := range ratingAssociations {
+		if ratingAssociation.Association != "none" {
+			t.Errorf("tag %d at p %v labelled %s with adjusted p %v", i, ratingAssociation.PValue, ratingAssociation.Association, ratingAssociation.AdjustedPValue)
+		}
+	}
+}
+
+func TestRatingAssociation(t *testing.T) {
+	tests := []struct {
+		name      string
+		tagRating models.TagRatingAssociation
+		numLogs   int
+		avg       float64
+		stdDev    float64
+		tested    bool
+	}{
+		{"too few logs with the tag", models.TagRatingAssociation{LogCount: 2, AvgMoodRating: 9, StdDeviation: 0.5}, 40, 5, 2, false},
+		{"every log has the tag", models.TagRatingAssociation{LogCount: 40, AvgMoodRating: 5, StdDeviation: 2}, 40, 5, 2, false},
+		{"enough logs either side", models.TagRatingAssociation{LogCount: 10, AvgMoodRating: 7, StdDeviation: 1}, 40, 5, 2, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := ratingAssociation(test.tagRating, test.numLogs, test.avg, test.stdDev)
+			if tested := got.DegreesOfFreedom > 0; tested != test.tested {
+				t.Fatalf("tested = %v, want %v", tested, test.tested)
+			}
+			if !test.tested && got.PValue != 1 {
+				t.Errorf("untested tag has p-value %v", got.PValue)
+			}
+			if test.tested && (got.PValue <= 0 || got.PValue >= 0.05 || got.TStatistic <= 0) {
+				t.Errorf("tag rated 2 above the rest has t %v p %v", got.TStatistic, got.PValue)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/auth"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+type AuditHandler struct {
+	auditService *auditService
+}
+
+var usersAudit string = `^/users/([0-9]+)/audit$`
+var auditVerify string = `^/audit/verify$`
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+	maxPage         = 1000000
+)
+
+func NewAuditHandler(auditService *auditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+func (handler *AuditHandler) ProcessRequest(writer http.ResponseWriter, request *http.Request) {
+	switch {
+	case utils.MatchURL(usersAudit, request.URL.Path) && request.Method == http.MethodGet:
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+
+		page, pageErr := utils.IntParam(request, "page", 1, 1, maxPage)
+		if pageErr != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(pageErr.Error()))
+			return
+		}
+		pageSize, pageSizeErr := utils.IntParam(request, "pageSize", defaultPageSize, 1, maxPageSize)
+		if pageSizeErr != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(pageSizeErr.Error()))
+			return
+		}
+
+		auditPage := handler.auditService.entries(userID, page, pageSize)
+		body, _ := json.Marshal(auditPage)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+
+	case utils.MatchURL(auditVerify, request.URL.Path) && request.Method == http.MethodGet:
+
+		// re-hashing the log is for operators, and it's a lot of work to leave open
+		if identity, _ := auth.IdentityFrom(request); !identity.Admin {
+			writer.WriteHeader(http.StatusForbidden)
+			writer.Write([]byte("verifying the audit log needs an admin token"))
+			return
+		}
+
+		verification := handler.auditService.verify(request.URL.Query().Get("full") == "true")
+		body, _ := json.Marshal(verification)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+
+	default:
+		writer.WriteHeader(http.StatusNotFound)
+		writer.Write([]byte("404 path not found"))
+	}
+}
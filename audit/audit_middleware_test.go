@@ -0,0 +1,79 @@
+package audit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/auth"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database/dbtest"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+// servedHandler records whether the request got through
+type servedHandler struct {
+	served bool
+}
+
+func (handler *servedHandler) ProcessRequest(writer http.ResponseWriter, request *http.Request) {
+	handler.served = true
+	writer.WriteHeader(http.StatusOK)
+}
+
+func TestAuditedHandler(t *testing.T) {
+	service, db := testAuditService(t)
+
+	// user 1 shares analytics with user 2 and everything with user 3, user 4
+	// had export until they revoked it
+	dbtest.Exec(t, db,
+		`INSERT INTO sharing_grant (user_id, grantee_user_id, scopes) VALUES (1, 2, 'analytics')`,
+		`INSERT INTO sharing_grant (user_id, grantee_user_id, scopes) VALUES (1, 3, 'analytics,journal,export')`,
+		`INSERT INTO sharing_grant (user_id, grantee_user_id, scopes, revoked_at) VALUES (1, 4, 'export', UTC_TIMESTAMP())`,
+	)
+
+	tests := []struct {
+		name        string
+		method      string
+		viewer      string
+		wantStatus  int
+		wantServed  bool
+		wantEntries int // audit entries after the request
+	}{
+		{"the user themselves", http.MethodGet, "1", http.StatusOK, true, 0},
+		{"a grantee without the scope", http.MethodGet, "2", http.StatusForbidden, false, 0},
+		{"a grantee with the scope", http.MethodGet, "3", http.StatusOK, true, 1},
+		{"a grantee changing the data", http.MethodPost, "3", http.StatusForbidden, false, 1},
+		{"a revoked grant", http.MethodGet, "4", http.StatusForbidden, false, 1},
+		{"someone without a grant", http.MethodGet, "5", http.StatusForbidden, false, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			next := &servedHandler{}
+			handler := NewAuditedHandler(next, service, "export")
+
+			request := httptest.NewRequest(test.method, "/users/1/export?startDate=2025-01-01", nil)
+			request = auth.WithIdentity(request, models.Identity{UserID: test.viewer})
+			recorder := httptest.NewRecorder()
+			handler.ProcessRequest(recorder, request)
+
+			if recorder.Code != test.wantStatus || next.served != test.wantServed {
+				t.Errorf("got status %d served %v, want %d served %v", recorder.Code, next.served, test.wantStatus, test.wantServed)
+			}
+			if entries := dbtest.QueryRows(t, db, `SELECT viewer_user_id, start_date FROM audit_log`); len(entries) != test.wantEntries {
+				t.Errorf("audit log = %q, want %d entries", entries, test.wantEntries)
+			}
+		})
+	}
+}
+
+func TestAuditedHandlerRequiresSignIn(t *testing.T) {
+	next := &servedHandler{}
+	handler := NewAuditedHandler(next, nil, "export")
+
+	recorder := httptest.NewRecorder()
+	handler.ProcessRequest(recorder, httptest.NewRequest(http.MethodGet, "/users/1/export", nil))
+
+	if recorder.Code != http.StatusUnauthorized || next.served {
+		t.Errorf("got status %d served %v, want 401 and not served", recorder.Code, next.served)
+	}
+}
@@ -0,0 +1,159 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+// the previous hash of the first entry, audit_chain_head starts out with it
+var genesisHash = fmt.Sprintf("%064d", 0)
+
+// entries are spread over the chains by subject so reads of different users
+// are recorded in parallel, audit_chain_head has a row for each
+const auditChains = 16
+
+type auditService struct {
+	auditRepository        *database.AuditRepository
+	sharingGrantRepository *database.SharingGrantRepository
+}
+
+func NewAuditService(auditRepository *database.AuditRepository, sharingGrantRepository *database.SharingGrantRepository) *auditService {
+	return &auditService{
+		auditRepository:        auditRepository,
+		sharingGrantRepository: sharingGrantRepository,
+	}
+}
+
+// grant is the subject's active grant to the viewer the read is made under,
+// the one named by grantID or else the first covering the scope
+func (service *auditService) grant(subjectUserID string, viewerUserID string, grantID string, scope string) (models.SharingGrant, error) {
+
+	for _, grant := range service.sharingGrantRepository.ActiveGrants(subjectUserID, viewerUserID) {
+		if grantID != "" && strconv.Itoa(grant.SharingGrantID) != grantID {
+			continue
+		}
+		if slices.Contains(grant.Scopes, scope) {
+			return grant, nil
+		}
+		if grantID != "" {
+			return models.SharingGrant{}, fmt.Errorf("sharing grant %s doesn't cover %s", grantID, scope)
+		}
+	}
+
+	if grantID != "" {
+		return models.SharingGrant{}, fmt.Errorf("sharing grant %s doesn't exist or isn't active for you", grantID)
+	}
+	return models.SharingGrant{}, fmt.Errorf("user %s hasn't shared their %s with you", subjectUserID, scope)
+}
+
+// record appends a read of the subject's data to the audit log
+func (service *auditService) record(entry models.AuditEntry) models.AuditEntry {
+	subjectUserID, _ := strconv.Atoi(entry.SubjectUserID)
+	entry.ChainID = subjectUserID % auditChains
+	entry.AccessedAt = time.Now().UTC().Format("2006-01-02 15:04:05")
+	return service.auditRepository.AppendAuditEntry(entry, entryHash)
+}
+
+func (service *auditService) entries(userID string, page int, pageSize int) *models.AuditPage {
+
+	entries, totalCount := service.auditRepository.AuditEntries(userID, pageSize, utils.PageOffset(page, pageSize))
+
+	return &models.AuditPage{
+		UserID:     userID,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalCount: totalCount,
+		TotalPages: (totalCount + pageSize - 1) / pageSize,
+		Entries:    entries,
+	}
+}
+
+var errChainBroken = errors.New("audit chain broken")
+var errReachedHead = errors.New("reached the chain head")
+
+// verify walks each chain from its checkpoint, or from the first entry when
+// full is set or there's no checkpoint yet. Every entry has to link to the
+// one before it and hash to its stored hash, and the walk has to reach the
+// chain head, which catches entries removed from the end. The head is read
+// first so entries appended during the walk are left to the next one. A
+// chain that's intact gets a new checkpoint, a full verification is the way
+// to check the entries behind the checkpoints again
+func (service *auditService) verify(full bool) models.AuditVerification {
+
+	verification := models.AuditVerification{Full: full}
+
+	for chainID := 0; chainID < auditChains; chainID++ {
+
+		head := service.auditRepository.ChainHead(chainID)
+
+		checkpoint := models.AuditCheckpoint{ChainID: chainID, EntryHash: genesisHash}
+		if saved, found := service.auditRepository.Checkpoint(chainID); found && !full {
+			checkpoint = saved
+		}
+		previous := checkpoint
+
+		walkErr := service.auditRepository.EachChainEntry(chainID, checkpoint.AuditEntryID, func(entry models.AuditEntry) error {
+			if previous.EntryHash == head {
+				return errReachedHead
+			}
+			switch {
+			case entry.PreviousHash != previous.EntryHash:
+				verification.Reason = fmt.Sprintf("entry doesn't link to the entry before it in chain %d", chainID)
+			case entryHash(previous.EntryHash, entry) != entry.EntryHash:
+				verification.Reason = fmt.Sprintf("entry doesn't match its hash in chain %d", chainID)
+			default:
+				verification.EntriesChecked++
+				previous.AuditEntryID = entry.AuditEntryID
+				previous.EntryHash = entry.EntryHash
+				previous.EntriesVerified++
+				return nil
+			}
+			verification.FirstInvalidID = entry.AuditEntryID
+			return errChainBroken
+		})
+		if walkErr == errChainBroken {
+			return verification
+		}
+
+		if previous.EntryHash != head {
+			verification.Reason = fmt.Sprintf("chain head of chain %d doesn't match the last entry", chainID)
+			return verification
+		}
+
+		if previous.AuditEntryID != checkpoint.AuditEntryID {
+			service.auditRepository.SaveCheckpoint(previous)
+		}
+		verification.EntriesVerified += previous.EntriesVerified
+	}
+
+	verification.Verified = true
+	return verification
+}
+
+// entryHash is the sha256 of the previous hash and the entry's fields, the
+// fields are json encoded so no two entries share an input
+func entryHash(previousHash string, entry models.AuditEntry) string {
+	fields, _ := json.Marshal([]string{
+		previousHash,
+		strconv.Itoa(entry.ChainID),
+		entry.SubjectUserID,
+		entry.ViewerUserID,
+		entry.GrantID,
+		entry.Resource,
+		entry.StartDate,
+		entry.EndDate,
+		entry.AccessedAt,
+	})
+	hash := sha256.Sum256(fields)
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,202 @@
+package audit
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database/dbtest"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+func TestEntryHash(t *testing.T) {
+	entry := models.AuditEntry{
+		ChainID:       1,
+		SubjectUserID: "1",
+		ViewerUserID:  "2",
+		GrantID:       "3",
+		Resource:      "/users/1/export",
+		StartDate:     "2025-01-01",
+		EndDate:       "2025-01-31",
+		AccessedAt:    "2025-02-01 09:00:00",
+	}
+	hash := entryHash(genesisHash, entry)
+
+	if len(hash) != 64 || hash != entryHash(genesisHash, entry) {
+		t.Fatalf("entryHash() = %q, want the same 64 hex characters each time", hash)
+	}
+	if entryHash(hash, entry) == hash {
+		t.Error("the hash doesn't depend on the previous hash")
+	}
+
+	// fields moving between columns must not hash the same
+	shifted := entry
+	shifted.StartDate, shifted.EndDate = "", "2025-01-01"
+	edited := entry
+	edited.Resource = "/users/1/fhir"
+	for name, changed := range map[string]models.AuditEntry{"shifted": shifted, "edited": edited} {
+		if entryHash(genesisHash, changed) == hash {
+			t.Errorf("the %s entry hashes the same as the original", name)
+		}
+	}
+}
+
+// testAuditService is the service over the test database, with users 1 to 20
+func testAuditService(t *testing.T) (*auditService, *sql.DB) {
+	db := dbtest.Open(t)
+
+	for userID := 1; userID <= 20; userID++ {
+		id := strconv.Itoa(userID)
+		dbtest.Exec(t, db, `INSERT INTO user (user_id, email, password_hash) VALUES (`+id+`, 'user`+id+`@example.com', '')`)
+	}
+
+	return NewAuditService(database.NewAuditRepository(db), database.NewSharingGrantRepository(db)), db
+}
+
+func recordRead(service *auditService, subjectUserID string, viewerUserID string) models.AuditEntry {
+	return service.record(models.AuditEntry{
+		SubjectUserID: subjectUserID,
+		ViewerUserID:  viewerUserID,
+		GrantID:       "1",
+		Resource:      "/users/" + subjectUserID + "/export",
+	})
+}
+
+func TestRecordChainsEntriesByShard(t *testing.T) {
+	service, db := testAuditService(t)
+
+	// users 1 and 17 share chain 1, user 2 has chain 2 to itself
+	first := recordRead(service, "1", "5")
+	other := recordRead(service, "2", "5")
+	second := recordRead(service, "17", "5")
+
+	if first.ChainID != 1 || second.ChainID != 1 || other.ChainID != 2 {
+		t.Fatalf("chains = %d, %d and %d, want 1, 1 and 2", first.ChainID, second.ChainID, other.ChainID)
+	}
+	if first.PreviousHash != genesisHash || other.PreviousHash != genesisHash {
+		t.Error("the first entry of a chain doesn't chain from the genesis hash")
+	}
+	if second.PreviousHash != first.EntryHash {
+		t.Error("the second entry in chain 1 doesn't chain from the first")
+	}
+	if second.EntryHash != entryHash(first.EntryHash, second) {
+		t.Error("the stored hash isn't the entry's hash")
+	}
+
+	heads := dbtest.QueryRows(t, db, `SELECT chain_id, entry_hash FROM audit_chain_head WHERE chain_id IN (1, 2) ORDER BY chain_id`)
+	want := []string{"1|" + second.EntryHash, "2|" + other.EntryHash}
+	if strings.Join(heads, ",") != strings.Join(want, ",") {
+		t.Errorf("chain heads = %q, want %q", heads, want)
+	}
+}
+
+func TestVerifyFromCheckpoints(t *testing.T) {
+	service, db := testAuditService(t)
+
+	recordRead(service, "1", "5")
+	recordRead(service, "17", "5")
+	recordRead(service, "2", "5")
+
+	verification := service.verify(false)
+	if !verification.Verified || verification.EntriesChecked != 3 || verification.EntriesVerified != 3 {
+		t.Fatalf("first verification = %+v, want 3 entries checked and verified", verification)
+	}
+	if checkpoints := dbtest.QueryRows(t, db, `SELECT chain_id, entries_verified FROM audit_checkpoint ORDER BY chain_id`); strings.Join(checkpoints, ",") != "1|2,2|1" {
+		t.Errorf("checkpoints = %q, want chain 1 at 2 entries and chain 2 at 1", checkpoints)
+	}
+
+	recordRead(service, "33", "5")
+
+	verification = service.verify(false)
+	if !verification.Verified || verification.EntriesChecked != 1 || verification.EntriesVerified != 4 {
+		t.Errorf("verification from the checkpoints = %+v, want 1 entry checked and 4 verified", verification)
+	}
+
+	verification = service.verify(true)
+	if !verification.Verified || verification.EntriesChecked != 4 {
+		t.Errorf("full verification = %+v, want all 4 entries checked", verification)
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	tests := []struct {
+		name       string
+		tamper     func(t *testing.T, db *sql.DB, entries []models.AuditEntry)
+		wantReason string
+		wantFirst  int // index of the first invalid entry, -1 for none
+	}{
+		{
+			name: "an edited entry",
+			tamper: func(t *testing.T, db *sql.DB, entries []models.AuditEntry) {
+				dbtest.Exec(t, db, `DROP TRIGGER trg_audit_log_no_update`,
+					`UPDATE audit_log SET resource = '/users/1/fhir' WHERE audit_log_id = `+strconv.Itoa(entries[1].AuditEntryID))
+			},
+			wantReason: "doesn't match its hash in chain 1",
+			wantFirst:  1,
+		},
+		{
+			name: "an entry removed from the middle",
+			tamper: func(t *testing.T, db *sql.DB, entries []models.AuditEntry) {
+				dbtest.Exec(t, db, `DROP TRIGGER trg_audit_log_no_delete`,
+					`DELETE FROM audit_log WHERE audit_log_id = `+strconv.Itoa(entries[1].AuditEntryID))
+			},
+			wantReason: "doesn't link to the entry before it in chain 1",
+			wantFirst:  2,
+		},
+		{
+			name: "the newest entry removed",
+			tamper: func(t *testing.T, db *sql.DB, entries []models.AuditEntry) {
+				dbtest.Exec(t, db, `DROP TRIGGER trg_audit_log_no_delete`,
+					`DELETE FROM audit_log WHERE audit_log_id = `+strconv.Itoa(entries[2].AuditEntryID))
+			},
+			wantReason: "chain head of chain 1 doesn't match",
+			wantFirst:  -1,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			service, db := testAuditService(t)
+
+			entries := []models.AuditEntry{
+				recordRead(service, "1", "5"),
+				recordRead(service, "17", "5"),
+				recordRead(service, "1", "6"),
+			}
+			test.tamper(t, db, entries)
+
+			verification := service.verify(true)
+			if verification.Verified || !strings.Contains(verification.Reason, test.wantReason) {
+				t.Fatalf("verification = %+v, want it to fail with %q", verification, test.wantReason)
+			}
+			wantFirstID := 0
+			if test.wantFirst >= 0 {
+				wantFirstID = entries[test.wantFirst].AuditEntryID
+			}
+			if verification.FirstInvalidID != wantFirstID {
+				t.Errorf("first invalid id = %d, want %d", verification.FirstInvalidID, wantFirstID)
+			}
+		})
+	}
+}
+
+func TestVerifyChecksBehindCheckpointsWhenFull(t *testing.T) {
+	service, db := testAuditService(t)
+
+	first := recordRead(service, "1", "5")
+	recordRead(service, "17", "5")
+	if verification := service.verify(false); !verification.Verified {
+		t.Fatalf("verification = %+v, want the untouched chains verified", verification)
+	}
+
+	dbtest.Exec(t, db, `DROP TRIGGER trg_audit_log_no_update`,
+		`UPDATE audit_log SET viewer_user_id = 6 WHERE audit_log_id = `+strconv.Itoa(first.AuditEntryID))
+
+	if verification := service.verify(false); !verification.Verified {
+		t.Errorf("verification from the checkpoints = %+v, want the entries behind them skipped", verification)
+	}
+	if verification := service.verify(true); verification.Verified || verification.FirstInvalidID != first.AuditEntryID {
+		t.Errorf("full verification = %+v, want entry %d found edited", verification, first.AuditEntryID)
+	}
+}
@@ -0,0 +1,128 @@
+// Package auth works out who is calling from their bearer token and keeps
+// everyone but the user themselves away from the user's routes. Tokens are
+// issued with cmd/issue-token
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+// how long a token is trusted before it's looked up again, so a revoked
+// token stops working within a minute
+const identityCacheTTL = time.Minute
+
+type identityKey struct{}
+
+type cachedIdentity struct {
+	identity  models.Identity
+	fetchedAt time.Time
+}
+
+type Authenticator struct {
+	apiTokenRepository *database.ApiTokenRepository
+	mutex              sync.Mutex
+	identities         map[string]cachedIdentity // by token hash, only valid tokens are kept
+}
+
+func NewAuthenticator(apiTokenRepository *database.ApiTokenRepository) *Authenticator {
+	return &Authenticator{
+		apiTokenRepository: apiTokenRepository,
+		identities:         make(map[string]cachedIdentity),
+	}
+}
+
+// Authenticate attaches the identity of the bearer token to the request. A
+// request without a token goes on anonymous so the rate limiter still counts
+// it against its IP, the handlers then turn it away. A token that isn't
+// valid is turned away here, after ratelimit's LimitIP has charged its IP so
+// guessing tokens is rate limited too
+func (authenticator *Authenticator) Authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+
+		authorization := request.Header.Get("Authorization")
+		if authorization == "" {
+			next(writer, request)
+			return
+		}
+
+		token, found := strings.CutPrefix(authorization, "Bearer ")
+		if !found || token == "" {
+			unauthorized(writer, "the Authorization header must be a bearer token")
+			return
+		}
+
+		identity, valid := authenticator.identity(TokenHash(token))
+		if !valid {
+			unauthorized(writer, "the token is unknown, expired or revoked")
+			return
+		}
+
+		next(writer, WithIdentity(request, identity))
+	}
+}
+
+func (authenticator *Authenticator) identity(tokenHash string) (models.Identity, bool) {
+
+	now := time.Now()
+
+	authenticator.mutex.Lock()
+	cached, exists := authenticator.identities[tokenHash]
+	authenticator.mutex.Unlock()
+	if exists && now.Sub(cached.fetchedAt) < identityCacheTTL {
+		return cached.identity, true
+	}
+
+	identity, valid := authenticator.apiTokenRepository.Identity(tokenHash)
+
+	authenticator.mutex.Lock()
+	defer authenticator.mutex.Unlock()
+	if !valid {
+		delete(authenticator.identities, tokenHash)
+		return models.Identity{}, false
+	}
+	authenticator.identities[tokenHash] = cachedIdentity{identity: identity, fetchedAt: now}
+
+	return identity, true
+}
+
+// TokenHash is what's stored in api_token for the token
+func TokenHash(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// WithIdentity is the request authenticated as the identity
+func WithIdentity(request *http.Request, identity models.Identity) *http.Request {
+	return request.WithContext(context.WithValue(request.Context(), identityKey{}, identity))
+}
+
+// IdentityFrom is who the request was authenticated as, false when it's
+// anonymous
+func IdentityFrom(request *http.Request) (models.Identity, bool) {
+	identity, found := request.Context().Value(identityKey{}).(models.Identity)
+	return identity, found
+}
+
+// RequireIdentity is IdentityFrom, answering 401 when the request is anonymous
+func RequireIdentity(writer http.ResponseWriter, request *http.Request) (models.Identity, bool) {
+	identity, found := IdentityFrom(request)
+	if !found {
+		unauthorized(writer, "sign in with a bearer token")
+	}
+	return identity, found
+}
+
+func unauthorized(writer http.ResponseWriter, message string) {
+	writer.Header().Set("WWW-Authenticate", `Bearer realm="horizon"`)
+	writer.WriteHeader(http.StatusUnauthorized)
+	writer.Write([]byte(message))
+}
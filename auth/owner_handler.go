@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+var subjectPath = `/users/([0-9]+)(/|$)`
+
+type Handler interface {
+	ProcessRequest(writer http.ResponseWriter, request *http.Request)
+}
+
+// OwnerHandler passes on requests by the user in the path and nobody else,
+// paths without a user only need the caller to be signed in
+type OwnerHandler struct {
+	next Handler
+}
+
+func NewOwnerHandler(next Handler) *OwnerHandler {
+	return &OwnerHandler{
+		next: next,
+	}
+}
+
+func (handler *OwnerHandler) ProcessRequest(writer http.ResponseWriter, request *http.Request) {
+
+	identity, signedIn := RequireIdentity(writer, request)
+	if !signedIn {
+		return
+	}
+
+	if subjectUserID, found := SubjectUserID(request.URL.Path); found && subjectUserID != identity.UserID {
+		writer.WriteHeader(http.StatusForbidden)
+		writer.Write([]byte("only user " + subjectUserID + " can use this route"))
+		return
+	}
+
+	handler.next.ProcessRequest(writer, request)
+}
+
+// SubjectUserID is the user whose data the path is about
+func SubjectUserID(path string) (string, bool) {
+	params := utils.PathParams(subjectPath, path)
+	if len(params) == 0 {
+		return "", false
+	}
+	return params[0], true
+}
@@ -0,0 +1,92 @@
+// Package cache holds rendered responses, the in-process LRU is the default
+// and a shared cache only has to implement Cache
+package cache
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Entry struct {
+	Body         []byte
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// Cache is implemented by the in-process LRU and by any shared cache (e.g.
+// redis or memcached) used when running several instances
+type Cache interface {
+	Get(key string) (Entry, bool)
+	Set(key string, entry Entry)
+	// DeletePrefix drops every entry whose key starts with prefix and returns
+	// how many there were, used to purge a user's responses when they're erased
+	DeletePrefix(prefix string) int
+}
+
+type LRU struct {
+	mutex    sync.Mutex
+	capacity int
+	order    *list.List // front is the most recently used
+	items    map[string]*list.Element
+}
+
+type lruItem struct {
+	key   string
+	entry Entry
+}
+
+func NewLRU(capacity int) *LRU {
+	return &LRU{
+		capacity: capacity,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+func (lru *LRU) Get(key string) (Entry, bool) {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	element, exists := lru.items[key]
+	if !exists {
+		return Entry{}, false
+	}
+	lru.order.MoveToFront(element)
+	return element.Value.(*lruItem).entry, true
+}
+
+func (lru *LRU) Set(key string, entry Entry) {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	if element, exists := lru.items[key]; exists {
+		element.Value.(*lruItem).entry = entry
+		lru.order.MoveToFront(element)
+		return
+	}
+
+	lru.items[key] = lru.order.PushFront(&lruItem{key: key, entry: entry})
+	if lru.order.Len() > lru.capacity {
+		oldest := lru.order.Back()
+		lru.order.Remove(oldest)
+		delete(lru.items, oldest.Value.(*lruItem).key)
+	}
+}
+
+func (lru *LRU) DeletePrefix(prefix string) int {
+	lru.mutex.Lock()
+	defer lru.mutex.Unlock()
+
+	deleted := 0
+	for key, element := range lru.items {
+		if strings.HasPrefix(key, prefix) {
+			lru.order.Remove(element)
+			delete(lru.items, key)
+			deleted++
+		}
+	}
+	return deleted
+}
@@ -0,0 +1,37 @@
+// backfill-summaries rebuilds the daily mood and sleep rollups from the logs,
+// run it once after adding the rollup tables to an existing database or to
+// repair them for a single user
+//
+//	go run ./cmd/backfill-summaries -user 2
+package main
+
+import (
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
+)
+
+func main() {
+
+	userID := flag.String("user", "", "only rebuild this user, every user when empty")
+	flag.Parse()
+
+	dbConnection := database.NewDatabaseConnection()
+	defer dbConnection.Close()
+
+	userRepository := database.NewUserRepository(dbConnection)
+	dailySummaryRepository := database.NewDailySummaryRepository(dbConnection)
+
+	userIDs := []string{*userID}
+	if *userID == "" {
+		userIDs = userRepository.UserIDs()
+	}
+
+	for _, id := range userIDs {
+		started := time.Now()
+		dailySummaryRepository.RebuildDailySummaries(id)
+		fmt.Println("rebuilt daily summaries for user", id, "in", time.Since(started))
+	}
+}
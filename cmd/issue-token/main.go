@@ -0,0 +1,49 @@
+// issue-token creates an api token for a user and prints it, only its hash is
+// stored so it can't be shown again. Admin tokens can verify the audit chain
+//
+//	go run ./cmd/issue-token -user 2
+//	go run ./cmd/issue-token -user 1 -admin -days 7
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/auth"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
+)
+
+func main() {
+
+	userID := flag.String("user", "", "the user the token signs in as")
+	admin := flag.Bool("admin", false, "issue an operator token")
+	days := flag.Int("days", 90, "days until the token expires, 0 for never")
+	flag.Parse()
+
+	if *userID == "" || *days < 0 {
+		fmt.Println("ERROR -user is required and -days can't be negative")
+		return
+	}
+
+	dbConnection := database.NewDatabaseConnection()
+	defer dbConnection.Close()
+
+	userRepository := database.NewUserRepository(dbConnection)
+	if _, found := userRepository.Profile(*userID); !found {
+		fmt.Println("ERROR user", *userID, "not found")
+		return
+	}
+
+	secret := make([]byte, 32)
+	if _, readErr := rand.Read(secret); readErr != nil {
+		panic(readErr)
+	}
+	token := base64.RawURLEncoding.EncodeToString(secret)
+
+	apiTokenRepository := database.NewApiTokenRepository(dbConnection)
+	apiTokenRepository.IssueToken(auth.TokenHash(token), *userID, *admin, *days)
+
+	fmt.Println(token)
+}
@@ -0,0 +1,74 @@
+// rotate-keys rotates the data keys that encrypt journal notes, or shreds them
+// so a user's notes can never be decrypted again, then re-encrypts the notes
+// straight away instead of leaving it to the server's background job. Run it
+// with no flags after putting a new master key first in the configuration to
+// rewrap every data key under it
+//
+//	go run ./cmd/rotate-keys -user 2
+//	go run ./cmd/rotate-keys -all
+//	go run ./cmd/rotate-keys -user 2 -shred
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/encryption"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/envelope"
+)
+
+func main() {
+
+	userID := flag.String("user", "", "rotate this user's data key")
+	all := flag.Bool("all", false, "rotate every user's data key")
+	shred := flag.Bool("shred", false, "destroy the user's data keys instead of rotating them")
+	flag.Parse()
+
+	if *shred && *userID == "" {
+		fmt.Println("ERROR -shred needs -user")
+		return
+	}
+
+	masterKeys, masterKeysErr := envelope.LoadMasterKeys()
+	if masterKeysErr != nil {
+		panic(masterKeysErr)
+	}
+
+	dbConnection := database.NewDatabaseConnection()
+	defer dbConnection.Close()
+
+	noteCipher := database.NewNoteCipher(dbConnection, masterKeys)
+	userRepository := database.NewUserRepository(dbConnection)
+
+	if *shred {
+		fmt.Println("shredded", noteCipher.ShredDataKeys(*userID), "data keys for user", *userID)
+		return
+	}
+
+	var userIDs []string
+	if *all {
+		userIDs = userRepository.UserIDs()
+	} else if *userID != "" {
+		userIDs = []string{*userID}
+	}
+
+	for _, id := range userIDs {
+		fmt.Println("rotated user", id, "to data key version", noteCipher.RotateDataKey(id))
+	}
+
+	encryptionService := encryption.NewEncryptionService(noteCipher)
+	total := 0
+	for {
+		changed, reencryptErr := encryptionService.Reencrypt()
+		total += changed
+		if reencryptErr != nil {
+			fmt.Println("ERROR", reencryptErr, "after re-encrypting or rewrapping", total, "rows")
+			return
+		}
+		if changed == 0 {
+			break
+		}
+	}
+	fmt.Println("re-encrypted or rewrapped", total, "rows")
+}
@@ -0,0 +1,47 @@
+// webhook-receiver is a local stand-in for a partner endpoint, it verifies the
+// signature of every delivery and prints it
+//
+//	go run ./cmd/webhook-receiver -secret <subscription secret> -fail-every 3
+package main
+
+import (
+	"crypto/hmac"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/webhooks"
+)
+
+func main() {
+
+	addr := flag.String("addr", ":9096", "listen address")
+	secret := flag.String("secret", "", "subscription secret used to verify signatures")
+	failEvery := flag.Int("fail-every", 0, "respond 500 to every nth delivery to exercise retries")
+	flag.Parse()
+
+	received := 0
+
+	http.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
+		received++
+		body, _ := io.ReadAll(request.Body)
+
+		timestamp := request.Header.Get("X-Horizon-Timestamp")
+		expected := "sha256=" + webhooks.Sign(*secret, timestamp, body)
+		valid := hmac.Equal([]byte(expected), []byte(request.Header.Get("X-Horizon-Signature")))
+
+		fmt.Println("RECEIVED", request.Header.Get("X-Horizon-Event"), "valid signature:", valid, string(body))
+
+		switch {
+		case !valid:
+			writer.WriteHeader(http.StatusUnauthorized)
+		case *failEvery > 0 && received%*failEvery == 0:
+			writer.WriteHeader(http.StatusInternalServerError)
+		default:
+			writer.WriteHeader(http.StatusNoContent)
+		}
+	})
+
+	http.ListenAndServe(*addr, nil)
+}
@@ -0,0 +1,70 @@
+package database
+
+// active_key is only set while an alert is open or acknowledged so the unique
+// key de-duplicates active alerts but still allows a rule to fire again once resolved
+var raiseAlertQuery = `INSERT INTO alert
+            (user_id,
+             rule,
+             severity,
+             message,
+             active_key,
+             trigger_date)
+VALUES      (?, ?, ?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE alert_id = Last_insert_id(alert_id),
+                        message = VALUES(message),
+                        trigger_date = VALUES(trigger_date),
+                        last_triggered_at = CURRENT_TIMESTAMP;`
+
+// a resolved alert covers the triggers starting on its trigger_date or up to
+// the given number of days after it
+var resolvedAlertCoversQuery = `SELECT Count(*)
+FROM   alert
+WHERE  user_id = ?
+       AND rule = ?
+       AND state = 'resolved'
+       AND trigger_date BETWEEN Date_sub(?, INTERVAL ? day) AND ?;`
+
+var alertsQuery = `SELECT alert_id,
+       user_id,
+       rule,
+       severity,
+       message,
+       state,
+       triggered_at,
+       last_triggered_at,
+       acknowledged_at,
+       resolved_at
+FROM   alert
+WHERE  user_id = ?
+       AND ( ? = '' OR state = ? )
+ORDER  BY triggered_at DESC,
+          alert_id DESC;`
+
+var alertQuery = `SELECT alert_id,
+       user_id,
+       rule,
+       severity,
+       message,
+       state,
+       triggered_at,
+       last_triggered_at,
+       acknowledged_at,
+       resolved_at
+FROM   alert
+WHERE  user_id = ?
+       AND alert_id = ?;`
+
+var acknowledgeAlertQuery = `UPDATE alert
+SET    state = 'acknowledged',
+       acknowledged_at = CURRENT_TIMESTAMP
+WHERE  user_id = ?
+       AND alert_id = ?
+       AND state = 'open';`
+
+var resolveAlertQuery = `UPDATE alert
+SET    state = 'resolved',
+       resolved_at = CURRENT_TIMESTAMP,
+       active_key = NULL
+WHERE  user_id = ?
+       AND alert_id = ?
+       AND state IN ( 'open', 'acknowledged' );`
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+type AlertRepository struct {
+	db *sql.DB
+}
+
+func NewAlertRepository(dbConnection *sql.DB) *AlertRepository {
+	return &AlertRepository{
+		db: dbConnection,
+	}
+}
+
+// returns the alert id and true when a new alert was raised, or the id of the
+// already active alert and false when it was de-duplicated. triggerDate is the
+// first day of the streak or window that raised it, a resolved alert covers
+// triggers from its own trigger date to coverDays later and returns 0 and false
+// for them so the same streak isn't raised again
+func (ar *AlertRepository) RaiseAlert(userID string, rule string, severity string, message string, triggerDate string, coverDays int) (int, bool) {
+
+	rows, queryErr := ar.db.Query(resolvedAlertCoversQuery, userID, rule, triggerDate, coverDays, triggerDate)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var numCovering int
+	if next := rows.Next(); next {
+		if scanErr := rows.Scan(&numCovering); scanErr != nil {
+			panic(scanErr)
+		}
+	}
+	if numCovering > 0 {
+		return 0, false
+	}
+
+	result, execErr := ar.db.Exec(raiseAlertQuery, userID, rule, severity, message, rule, triggerDate)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	alertID, idErr := result.LastInsertId()
+	if idErr != nil {
+		panic(idErr)
+	}
+
+	// mysql reports 1 affected row for an insert and 2 for an update
+	rowsAffected, rowsErr := result.RowsAffected()
+	if rowsErr != nil {
+		panic(rowsErr)
+	}
+
+	return int(alertID), rowsAffected == 1
+}
+
+func (ar *AlertRepository) Alerts(userID string, state string) []models.Alert {
+
+	rows, queryErr := ar.db.Query(alertsQuery, userID, state, state)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var alerts []models.Alert
+
+	for rows.Next() {
+		alerts = append(alerts, scanAlert(rows))
+	}
+
+	if alerts == nil {
+		return make([]models.Alert, 0)
+	}
+
+	return alerts
+}
+
+func (ar *AlertRepository) Alert(userID string, alertID string) (models.Alert, bool) {
+
+	rows, queryErr := ar.db.Query(alertQuery, userID, alertID)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	if next := rows.Next(); !next {
+		return models.Alert{}, false
+	}
+
+	return scanAlert(rows), true
+}
+
+func (ar *AlertRepository) AcknowledgeAlert(userID string, alertID string) bool {
+	return ar.updateAlert(acknowledgeAlertQuery, userID, alertID)
+}
+
+func (ar *AlertRepository) ResolveAlert(userID string, alertID string) bool {
+	return ar.updateAlert(resolveAlertQuery, userID, alertID)
+}
+
+func (ar *AlertRepository) updateAlert(query string, userID string, alertID string) bool {
+
+	result, execErr := ar.db.Exec(query, userID, alertID)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	rowsAffected, rowsErr := result.RowsAffected()
+	if rowsErr != nil {
+		panic(rowsErr)
+	}
+
+	return rowsAffected == 1
+}
+
+func scanAlert(rows *sql.Rows) models.Alert {
+	var alert models.Alert
+	var acknowledgedAt sql.NullString
+	var resolvedAt sql.NullString
+
+	scanErr := rows.Scan(
+		&alert.AlertID,
+		&alert.UserID,
+		&alert.Rule,
+		&alert.Severity,
+		&alert.Message,
+		&alert.State,
+		&alert.TriggeredAt,
+		&alert.LastTriggeredAt,
+		&acknowledgedAt,
+		&resolvedAt,
+	)
+	if scanErr != nil {
+		panic(scanErr)
+	}
+
+	alert.AcknowledgedAt = acknowledgedAt.String
+	alert.ResolvedAt = resolvedAt.String
+
+	return alert
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRaiseAlert(t *testing.T) {
+	db := testDatabase(t)
+	alertRepository := NewAlertRepository(db)
+
+	if _, execErr := db.Exec(`DELETE FROM alert`); execErr != nil {
+		t.Fatal(execErr)
+	}
+
+	// a streak rule, only the same streak is covered once resolved
+	firstID, isNew := alertRepository.RaiseAlert("1", "short_sleep", "medium", "3 nights", "2025-08-01", 0)
+	if !isNew {
+		t.Fatal("first trigger wasn't raised")
+	}
+	if againID, isNew := alertRepository.RaiseAlert("1", "short_sleep", "medium", "4 nights", "2025-08-01", 0); isNew || againID != firstID {
+		t.Errorf("open alert raised again as %d, want de-duplicated into %d", againID, firstID)
+	}
+	if alert, _ := alertRepository.Alert("1", fmt.Sprint(firstID)); alert.Message != "4 nights" {
+		t.Errorf("message = %q, want it updated by the repeat trigger", alert.Message)
+	}
+
+	alertRepository.ResolveAlert("1", fmt.Sprint(firstID))
+	if _, isNew := alertRepository.RaiseAlert("1", "short_sleep", "medium", "5 nights", "2025-08-01", 0); isNew {
+		t.Error("resolved streak raised again")
+	}
+	if _, isNew := alertRepository.RaiseAlert("1", "short_sleep", "medium", "3 nights", "2025-08-09", 0); !isNew {
+		t.Error("new streak after the resolved one wasn't raised")
+	}
+
+	// a window rule stays quiet until its window no longer overlaps the resolved one
+	windowID, _ := alertRepository.RaiseAlert("1", "mood_drop", "medium", "dropped", "2025-08-01", 13)
+	alertRepository.ResolveAlert("1", fmt.Sprint(windowID))
+	if _, isNew := alertRepository.RaiseAlert("1", "mood_drop", "medium", "dropped", "2025-08-14", 13); isNew {
+		t.Error("window overlapping the resolved one raised again")
+	}
+	if _, isNew := alertRepository.RaiseAlert("1", "mood_drop", "medium", "dropped", "2025-08-15", 13); !isNew {
+		t.Error("window after the resolved one wasn't raised")
+	}
+
+	// other users aren't covered
+	if _, isNew := alertRepository.RaiseAlert("2", "short_sleep", "medium", "3 nights", "2025-08-01", 0); !isNew {
+		t.Error("another user's streak wasn't raised")
+	}
+}
@@ -0,0 +1,16 @@
+package database
+
+var identityQuery = `SELECT user_id,
+       admin
+FROM   api_token
+WHERE  token_hash = ?
+       AND revoked_at IS NULL
+       AND ( expires_at IS NULL
+              OR expires_at > CURRENT_TIMESTAMP );`
+
+var issueTokenQuery = `INSERT INTO api_token
+            (token_hash,
+             user_id,
+             admin,
+             expires_at)
+VALUES      (?, ?, ?, IF(? > 0, CURRENT_TIMESTAMP + INTERVAL ? DAY, NULL));`
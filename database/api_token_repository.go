@@ -0,0 +1,49 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+type ApiTokenRepository struct {
+	db *sql.DB
+}
+
+func NewApiTokenRepository(dbConnection *sql.DB) *ApiTokenRepository {
+	return &ApiTokenRepository{
+		db: dbConnection,
+	}
+}
+
+// Identity is who the token belongs to, false when it's unknown, expired or
+// revoked
+func (atr *ApiTokenRepository) Identity(tokenHash string) (models.Identity, bool) {
+
+	rows, queryErr := atr.db.Query(identityQuery, tokenHash)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	if next := rows.Next(); !next {
+		return models.Identity{}, false
+	}
+
+	var identity models.Identity
+	scanErr := rows.Scan(&identity.UserID, &identity.Admin)
+	if scanErr != nil {
+		panic(scanErr)
+	}
+
+	return identity, true
+}
+
+// IssueToken stores the hash of a new token, it never expires when days is 0
+func (atr *ApiTokenRepository) IssueToken(tokenHash string, userID string, admin bool, days int) {
+
+	_, execErr := atr.db.Exec(issueTokenQuery, tokenHash, userID, admin, days, days)
+	if execErr != nil {
+		panic(execErr)
+	}
+}
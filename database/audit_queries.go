@@ -0,0 +1,84 @@
+package database
+
+var lockAuditChainHeadQuery = `SELECT entry_hash
+FROM   audit_chain_head
+WHERE  chain_id = ?
+FOR UPDATE;`
+
+var auditChainHeadQuery = `SELECT entry_hash
+FROM   audit_chain_head
+WHERE  chain_id = ?;`
+
+var insertAuditEntryQuery = `INSERT INTO audit_log
+            (chain_id,
+             subject_user_id,
+             viewer_user_id,
+             grant_id,
+             resource,
+             start_date,
+             end_date,
+             accessed_at,
+             previous_hash,
+             entry_hash)
+VALUES      (?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`
+
+var updateAuditChainHeadQuery = `UPDATE audit_chain_head
+SET    entry_hash = ?
+WHERE  chain_id = ?;`
+
+var auditEntryColumns = `audit_log_id,
+       chain_id,
+       subject_user_id,
+       viewer_user_id,
+       grant_id,
+       resource,
+       COALESCE(start_date, ''),
+       COALESCE(end_date, ''),
+       accessed_at,
+       previous_hash,
+       entry_hash`
+
+var auditEntriesQuery = `SELECT ` + auditEntryColumns + `
+FROM   audit_log
+WHERE  subject_user_id = ?
+ORDER  BY audit_log_id DESC
+LIMIT  ? offset ?;`
+
+var countAuditEntriesQuery = `SELECT Count(*)
+FROM   audit_log
+WHERE  subject_user_id = ?;`
+
+var chainAuditEntriesQuery = `SELECT ` + auditEntryColumns + `
+FROM   audit_log
+WHERE  chain_id = ?
+       AND audit_log_id > ?
+ORDER  BY audit_log_id;`
+
+// reads of the user's data and the user's reads of others
+var userAuditEntriesQuery = `SELECT ` + auditEntryColumns + `
+FROM   audit_log
+WHERE  subject_user_id = ?
+        OR viewer_user_id = ?
+ORDER  BY audit_log_id;`
+
+var countUserAuditEntriesQuery = `SELECT Count(*)
+FROM   audit_log
+WHERE  subject_user_id = ?
+        OR viewer_user_id = ?;`
+
+var auditCheckpointQuery = `SELECT chain_id,
+       audit_log_id,
+       entry_hash,
+       entries_verified
+FROM   audit_checkpoint
+WHERE  chain_id = ?;`
+
+var saveAuditCheckpointQuery = `INSERT INTO audit_checkpoint
+            (chain_id,
+             audit_log_id,
+             entry_hash,
+             entries_verified)
+VALUES      (?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE audit_log_id = VALUES(audit_log_id),
+                        entry_hash = VALUES(entry_hash),
+                        entries_verified = VALUES(entries_verified);`
@@ -0,0 +1,237 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(dbConnection *sql.DB) *AuditRepository {
+	return &AuditRepository{
+		db: dbConnection,
+	}
+}
+
+// AppendAuditEntry chains the entry onto the newest one in its chain and
+// stores it, hash computes the entry's hash from the previous hash. The chain
+// head is locked for the transaction so concurrent appends can't fork the
+// chain, appends to the other chains go ahead
+func (ar *AuditRepository) AppendAuditEntry(entry models.AuditEntry, hash func(previousHash string, entry models.AuditEntry) string) models.AuditEntry {
+
+	tx, txErr := ar.db.Begin()
+	if txErr != nil {
+		panic(txErr)
+	}
+	defer tx.Rollback()
+
+	rows, queryErr := tx.Query(lockAuditChainHeadQuery, entry.ChainID)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	if next := rows.Next(); !next {
+		rows.Close()
+		panic(fmt.Errorf("audit_chain_head has no row for chain %d, the schema wasn't loaded in full", entry.ChainID))
+	}
+	scanErr := rows.Scan(&entry.PreviousHash)
+	rows.Close()
+	if scanErr != nil {
+		panic(scanErr)
+	}
+
+	entry.EntryHash = hash(entry.PreviousHash, entry)
+
+	result, execErr := tx.Exec(insertAuditEntryQuery,
+		entry.ChainID,
+		entry.SubjectUserID,
+		entry.ViewerUserID,
+		entry.GrantID,
+		entry.Resource,
+		sql.NullString{String: entry.StartDate, Valid: entry.StartDate != ""},
+		sql.NullString{String: entry.EndDate, Valid: entry.EndDate != ""},
+		entry.AccessedAt,
+		entry.PreviousHash,
+		entry.EntryHash,
+	)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	auditEntryID, idErr := result.LastInsertId()
+	if idErr != nil {
+		panic(idErr)
+	}
+	entry.AuditEntryID = int(auditEntryID)
+
+	_, execErr = tx.Exec(updateAuditChainHeadQuery, entry.EntryHash, entry.ChainID)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	commitErr := tx.Commit()
+	if commitErr != nil {
+		panic(commitErr)
+	}
+
+	return entry
+}
+
+// AuditEntries returns a page of the reads of the user's data, newest first,
+// and the total count
+func (ar *AuditRepository) AuditEntries(subjectUserID string, limit int, offset int) ([]models.AuditEntry, int) {
+
+	countRows, countQueryErr := ar.db.Query(countAuditEntriesQuery, subjectUserID)
+	if countQueryErr != nil {
+		panic(countQueryErr)
+	}
+	defer countRows.Close()
+
+	var totalCount int
+	if next := countRows.Next(); next {
+		scanErr := countRows.Scan(&totalCount)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+	}
+
+	rows, queryErr := ar.db.Query(auditEntriesQuery, subjectUserID, limit, offset)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	entries := make([]models.AuditEntry, 0)
+
+	for rows.Next() {
+		entries = append(entries, scanAuditEntry(rows))
+	}
+
+	return entries, totalCount
+}
+
+// EachChainEntry hands the chain's entries after afterID to the callback
+// oldest first, the callback stops the iteration by returning an error
+func (ar *AuditRepository) EachChainEntry(chainID int, afterID int, callback func(models.AuditEntry) error) error {
+	return ar.eachAuditEntry(callback, chainAuditEntriesQuery, chainID, afterID)
+}
+
+// EachUserAuditEntry hands the reads of the user's data and the user's reads
+// of others to the callback oldest first
+func (ar *AuditRepository) EachUserAuditEntry(userID string, callback func(models.AuditEntry) error) error {
+	return ar.eachAuditEntry(callback, userAuditEntriesQuery, userID, userID)
+}
+
+func (ar *AuditRepository) CountUserAuditEntries(userID string) int {
+
+	rows, queryErr := ar.db.Query(countUserAuditEntriesQuery, userID, userID)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var count int
+	if next := rows.Next(); next {
+		scanErr := rows.Scan(&count)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+	}
+
+	return count
+}
+
+func (ar *AuditRepository) eachAuditEntry(callback func(models.AuditEntry) error, query string, args ...any) error {
+
+	rows, queryErr := ar.db.Query(query, args...)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if callbackErr := callback(scanAuditEntry(rows)); callbackErr != nil {
+			return callbackErr
+		}
+	}
+
+	if rowsErr := rows.Err(); rowsErr != nil {
+		panic(rowsErr)
+	}
+
+	return nil
+}
+
+func (ar *AuditRepository) ChainHead(chainID int) string {
+
+	rows, queryErr := ar.db.Query(auditChainHeadQuery, chainID)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var entryHash string
+	if next := rows.Next(); next {
+		scanErr := rows.Scan(&entryHash)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+	}
+
+	return entryHash
+}
+
+func (ar *AuditRepository) Checkpoint(chainID int) (models.AuditCheckpoint, bool) {
+
+	rows, queryErr := ar.db.Query(auditCheckpointQuery, chainID)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	if next := rows.Next(); !next {
+		return models.AuditCheckpoint{}, false
+	}
+
+	var checkpoint models.AuditCheckpoint
+	scanErr := rows.Scan(&checkpoint.ChainID, &checkpoint.AuditEntryID, &checkpoint.EntryHash, &checkpoint.EntriesVerified)
+	if scanErr != nil {
+		panic(scanErr)
+	}
+
+	return checkpoint, true
+}
+
+func (ar *AuditRepository) SaveCheckpoint(checkpoint models.AuditCheckpoint) {
+
+	_, execErr := ar.db.Exec(saveAuditCheckpointQuery, checkpoint.ChainID, checkpoint.AuditEntryID, checkpoint.EntryHash, checkpoint.EntriesVerified)
+	if execErr != nil {
+		panic(execErr)
+	}
+}
+
+func scanAuditEntry(rows *sql.Rows) models.AuditEntry {
+	var entry models.AuditEntry
+
+	scanErr := rows.Scan(
+		&entry.AuditEntryID,
+		&entry.ChainID,
+		&entry.SubjectUserID,
+		&entry.ViewerUserID,
+		&entry.GrantID,
+		&entry.Resource,
+		&entry.StartDate,
+		&entry.EndDate,
+		&entry.AccessedAt,
+		&entry.PreviousHash,
+		&entry.EntryHash,
+	)
+	if scanErr != nil {
+		panic(scanErr)
+	}
+
+	return entry
+}
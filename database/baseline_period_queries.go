@@ -0,0 +1,30 @@
+package database
+
+var baselinePeriodsQuery = `SELECT user_id,
+       name,
+       start_date,
+       end_date
+FROM   baseline_period
+WHERE  user_id = ?
+ORDER  BY start_date;`
+
+var baselinePeriodQuery = `SELECT user_id,
+       name,
+       start_date,
+       end_date
+FROM   baseline_period
+WHERE  user_id = ?
+       AND name = ?;`
+
+var saveBaselinePeriodQuery = `INSERT INTO baseline_period
+            (user_id,
+             name,
+             start_date,
+             end_date)
+VALUES      (?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE start_date = VALUES(start_date),
+                        end_date = VALUES(end_date);`
+
+var deleteBaselinePeriodQuery = `DELETE FROM baseline_period
+WHERE  user_id = ?
+       AND name = ?;`
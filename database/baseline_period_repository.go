@@ -0,0 +1,89 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+type BaselinePeriodRepository struct {
+	db *sql.DB
+}
+
+func NewBaselinePeriodRepository(dbConnection *sql.DB) *BaselinePeriodRepository {
+	return &BaselinePeriodRepository{
+		db: dbConnection,
+	}
+}
+
+func (bpr *BaselinePeriodRepository) BaselinePeriods(userID string) []models.BaselinePeriod {
+
+	rows, queryErr := bpr.db.Query(baselinePeriodsQuery, userID)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var baselinePeriods []models.BaselinePeriod
+
+	for rows.Next() {
+		baselinePeriods = append(baselinePeriods, scanBaselinePeriod(rows))
+	}
+
+	if baselinePeriods == nil {
+		return make([]models.BaselinePeriod, 0)
+	}
+
+	return baselinePeriods
+}
+
+func (bpr *BaselinePeriodRepository) BaselinePeriod(userID string, name string) (models.BaselinePeriod, bool) {
+
+	rows, queryErr := bpr.db.Query(baselinePeriodQuery, userID, name)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	if next := rows.Next(); !next {
+		return models.BaselinePeriod{}, false
+	}
+
+	return scanBaselinePeriod(rows), true
+}
+
+// SaveBaselinePeriod creates the period or moves the dates of the user's period
+// with the same name
+func (bpr *BaselinePeriodRepository) SaveBaselinePeriod(baselinePeriod models.BaselinePeriod) {
+
+	_, execErr := bpr.db.Exec(saveBaselinePeriodQuery, baselinePeriod.UserID, baselinePeriod.Name, baselinePeriod.StartDate, baselinePeriod.EndDate)
+	if execErr != nil {
+		panic(execErr)
+	}
+}
+
+func (bpr *BaselinePeriodRepository) DeleteBaselinePeriod(userID string, name string) bool {
+
+	result, execErr := bpr.db.Exec(deleteBaselinePeriodQuery, userID, name)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	rowsAffected, rowsErr := result.RowsAffected()
+	if rowsErr != nil {
+		panic(rowsErr)
+	}
+
+	return rowsAffected == 1
+}
+
+func scanBaselinePeriod(rows *sql.Rows) models.BaselinePeriod {
+
+	var baselinePeriod models.BaselinePeriod
+	scanErr := rows.Scan(&baselinePeriod.UserID, &baselinePeriod.Name, &baselinePeriod.StartDate, &baselinePeriod.EndDate)
+	if scanErr != nil {
+		panic(scanErr)
+	}
+
+	return baselinePeriod
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+// periodStartExpressions truncate a date column to the first day of its
+// bucket, weeks are ISO weeks starting on a monday
+var periodStartExpressions = map[string]string{
+	"day":   "DATE(%[1]s)",
+	"week":  "DATE_SUB(DATE(%[1]s), INTERVAL WEEKDAY(%[1]s) DAY)",
+	"month": "DATE_FORMAT(%[1]s, '%%Y-%%m-01')",
+}
+
+func periodStartExpression(granularity string, column string) string {
+	expression, exists := periodStartExpressions[granularity]
+	if !exists {
+		panic(fmt.Sprintf("unknown granularity %q", granularity))
+	}
+	return fmt.Sprintf(expression, column)
+}
+
+// scanBuckets reads rows of period_start, avg, std_dev, log_count and top tag,
+// StartDate is set to the start of the period and Period is left to the caller
+func scanBuckets(rows *sql.Rows) []models.Bucket {
+
+	var buckets []models.Bucket
+
+	for rows.Next() {
+		var bucket models.Bucket
+		var topTag sql.NullString
+
+		scanErr := rows.Scan(
+			&bucket.StartDate,
+			&bucket.Avg,
+			&bucket.StdDeviation,
+			&bucket.LogCount,
+			&topTag,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+
+		bucket.TopTag = topTag.String
+		buckets = append(buckets, bucket)
+	}
+
+	if buckets == nil {
+		return make([]models.Bucket, 0)
+	}
+
+	return buckets
+}
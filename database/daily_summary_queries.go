@@ -0,0 +1,3 @@
+package database
+
+var rebuildDailySummariesQuery = `CALL rebuild_daily_summaries(?);`
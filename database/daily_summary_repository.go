@@ -0,0 +1,25 @@
+package database
+
+import (
+	"database/sql"
+)
+
+// DailySummaryRepository maintains daily_mood_summary and daily_sleep_summary,
+// the triggers in db.sql keep them current so this is only needed to backfill
+type DailySummaryRepository struct {
+	db *sql.DB
+}
+
+func NewDailySummaryRepository(dbConnection *sql.DB) *DailySummaryRepository {
+	return &DailySummaryRepository{
+		db: dbConnection,
+	}
+}
+
+func (dsr *DailySummaryRepository) RebuildDailySummaries(userID string) {
+
+	_, execErr := dsr.db.Exec(rebuildDailySummariesQuery, userID)
+	if execErr != nil {
+		panic(execErr)
+	}
+}
@@ -0,0 +1,180 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database/dbtest"
+)
+
+// The rollups are maintained in SQL so these tests need a MySQL database,
+// see dbtest.Open
+func testDatabase(t *testing.T) *sql.DB {
+	return dbtest.Open(t)
+}
+
+// queryRows returns each row as its columns joined by |
+func queryRows(t *testing.T, db *sql.DB, query string) []string {
+	return dbtest.QueryRows(t, db, query)
+}
+
+// the rollups worked out from the logs directly, converting with CONVERT_TZ
+// rather than local_datetime
+var expectedMoodSummaryQuery = `WITH local_logs
+     AS (SELECT ml.mood_log_id,
+                ml.user_id,
+                ml.mood_rating,
+                Date(CONVERT_TZ(ml.created_at, '+00:00', COALESCE(us.timezone, 'UTC'))) AS summary_date
+         FROM   mood_log ml
+                LEFT JOIN user_settings us
+                       ON ml.user_id = us.user_id)
+SELECT ll.user_id,
+       ll.summary_date,
+       Cast(Sum(ll.mood_rating) AS SIGNED),
+       Count(*),
+       Cast(COALESCE(Sum((SELECT Count(*) FROM mood_log_mood_tag mlmt WHERE mlmt.mood_log_id = ll.mood_log_id)), 0) AS SIGNED)
+FROM   local_logs ll
+GROUP  BY ll.user_id,
+          ll.summary_date
+ORDER  BY 1, 2;`
+
+var actualMoodSummaryQuery = `SELECT user_id, summary_date, rating_sum, log_count, tag_count
+FROM   daily_mood_summary
+ORDER  BY 1, 2;`
+
+var expectedCategoryCountQuery = `SELECT ml.user_id,
+       Date(CONVERT_TZ(ml.created_at, '+00:00', COALESCE(us.timezone, 'UTC'))) AS summary_date,
+       mt.mood_category_id,
+       Count(*)
+FROM   mood_log ml
+       JOIN mood_log_mood_tag mlmt
+         ON ml.mood_log_id = mlmt.mood_log_id
+       JOIN mood_tag mt
+         ON mlmt.mood_tag_id = mt.mood_tag_id
+       LEFT JOIN user_settings us
+              ON ml.user_id = us.user_id
+GROUP  BY 1, 2, 3
+ORDER  BY 1, 2, 3;`
+
+var actualCategoryCountQuery = `SELECT user_id, summary_date, mood_category_id, tag_count
+FROM   daily_mood_category_count
+ORDER  BY 1, 2, 3;`
+
+var expectedSleepSummaryQuery = `SELECT user_id, sleep_date, Cast(Sum(hours_slept) AS DECIMAL(6,2)), Count(*)
+FROM   sleep_log
+GROUP  BY user_id,
+          sleep_date
+ORDER  BY 1, 2;`
+
+var actualSleepSummaryQuery = `SELECT user_id, sleep_date, hours_slept, log_count
+FROM   daily_sleep_summary
+ORDER  BY 1, 2;`
+
+func assertRollups(t *testing.T, db *sql.DB, when string) {
+	t.Helper()
+
+	pairs := []struct {
+		table    string
+		expected string
+		actual   string
+	}{
+		{"daily_mood_summary", expectedMoodSummaryQuery, actualMoodSummaryQuery},
+		{"daily_mood_category_count", expectedCategoryCountQuery, actualCategoryCountQuery},
+		{"daily_sleep_summary", expectedSleepSummaryQuery, actualSleepSummaryQuery},
+	}
+	for _, pair := range pairs {
+		expected := queryRows(t, db, pair.expected)
+		actual := queryRows(t, db, pair.actual)
+		if strings.Join(actual, "\n") != strings.Join(expected, "\n") {
+			t.Errorf("%s: %s =\n%s\nwant\n%s", when, pair.table, strings.Join(actual, "\n"), strings.Join(expected, "\n"))
+		}
+	}
+}
+
+func TestDailySummaries(t *testing.T) {
+	db := testDatabase(t)
+	dailySummaryRepository := NewDailySummaryRepository(db)
+
+	// the steps run in order against the seed data, after each the triggers
+	// must have left the rollups matching the logs and a rebuild must agree
+	steps := []struct {
+		name       string
+		statements []string
+	}{
+		{"seed data", nil},
+		{"log after midnight UTC on the previous New York day", []string{
+			`INSERT INTO mood_log (user_id, mood_rating, created_at) VALUES (2, 4, '2025-08-10 02:30:00')`,
+		}},
+		{"tags added to a log", []string{
+			`INSERT INTO mood_log_mood_tag (mood_log_id, mood_tag_id)
+			 SELECT Max(mood_log_id), 19 FROM mood_log WHERE user_id = 2`,
+			`INSERT INTO mood_log_mood_tag (mood_log_id, mood_tag_id)
+			 SELECT Max(mood_log_id), 6 FROM mood_log WHERE user_id = 2`,
+		}},
+		{"tag removed from a log", []string{
+			`DELETE FROM mood_log_mood_tag
+			 WHERE  mood_tag_id = 6
+			        AND mood_log_id = (SELECT id FROM (SELECT Max(mood_log_id) AS id FROM mood_log WHERE user_id = 2) latest)`,
+		}},
+		{"rating changed", []string{
+			`UPDATE mood_log SET mood_rating = 9 WHERE mood_log_id = 1`,
+		}},
+		{"log moved to the next day", []string{
+			`UPDATE mood_log SET created_at = created_at + INTERVAL 1 DAY WHERE mood_log_id = 2`,
+		}},
+		{"note rewritten without moving the log", []string{
+			`UPDATE mood_log SET note = 'rewritten' WHERE mood_log_id = 3`,
+		}},
+		{"log deleted with its tags", []string{
+			`DELETE FROM mood_log WHERE mood_log_id = 4`,
+		}},
+		{"timezone changed", []string{
+			`UPDATE user_settings SET timezone = 'Asia/Tokyo' WHERE user_id = 1`,
+		}},
+		{"settings created for a user in a named zone", []string{
+			`DELETE FROM user_settings WHERE user_id = 3`,
+			`INSERT INTO user_settings (user_id, timezone) VALUES (3, 'America/Los_Angeles')`,
+		}},
+		{"sleep logs edited and deleted", []string{
+			`INSERT INTO sleep_log (user_id, hours_slept, sleep_quality_tag_id, sleep_date) VALUES (1, 1.5, 4, '2025-08-01')`,
+			`UPDATE sleep_log SET hours_slept = 9 WHERE sleep_log_id = 2`,
+			`UPDATE sleep_log SET sleep_date = '2025-07-31' WHERE sleep_log_id = 3`,
+			`DELETE FROM sleep_log WHERE sleep_log_id = 5`,
+		}},
+	}
+
+	for _, step := range steps {
+		for _, statement := range step.statements {
+			if _, execErr := db.Exec(statement); execErr != nil {
+				t.Fatalf("%s: %v", step.name, execErr)
+			}
+		}
+		assertRollups(t, db, step.name)
+
+		for _, userID := range []string{"1", "2", "3"} {
+			dailySummaryRepository.RebuildDailySummaries(userID)
+		}
+		assertRollups(t, db, step.name+", rebuilt")
+	}
+}
+
+func TestRebuildDailySummariesFromEmpty(t *testing.T) {
+	db := testDatabase(t)
+	dailySummaryRepository := NewDailySummaryRepository(db)
+
+	// as after adding the rollup tables to an existing database
+	for _, statement := range []string{`DELETE FROM daily_mood_summary`, `DELETE FROM daily_sleep_summary`} {
+		if _, execErr := db.Exec(statement); execErr != nil {
+			t.Fatal(execErr)
+		}
+	}
+	if rows := queryRows(t, db, actualMoodSummaryQuery); len(rows) != 0 {
+		t.Fatalf("%d rollup rows left after clearing", len(rows))
+	}
+
+	for _, userID := range []string{"1", "2", "3"} {
+		dailySummaryRepository.RebuildDailySummaries(userID)
+	}
+	assertRollups(t, db, "rebuilt from empty")
+}
@@ -0,0 +1,6 @@
+package database
+
+var dataVersionQuery = `SELECT version,
+       updated_at
+FROM   user_data_version
+WHERE  user_id = ?;`
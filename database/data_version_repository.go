@@ -0,0 +1,37 @@
+package database
+
+import (
+	"database/sql"
+)
+
+type DataVersionRepository struct {
+	db *sql.DB
+}
+
+func NewDataVersionRepository(dbConnection *sql.DB) *DataVersionRepository {
+	return &DataVersionRepository{
+		db: dbConnection,
+	}
+}
+
+// DataVersion returns the user's data version and when it last changed in UTC,
+// a user who has never written anything is at version 0 with no updated at
+func (dvr *DataVersionRepository) DataVersion(userID string) (int64, string) {
+
+	rows, queryErr := dvr.db.Query(dataVersionQuery, userID)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var version int64
+	var updatedAt sql.NullString
+	if next := rows.Next(); next {
+		scanErr := rows.Scan(&version, &updatedAt)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+	}
+
+	return version, updatedAt.String
+}
@@ -2,15 +2,25 @@ package database
 
 import (
 	"database/sql"
+	"time"
+
 	_ "github.com/go-sql-driver/mysql"
 )
 
+// cap on connections to MySQL, requests beyond it wait for a free one
+const maxOpenConnections = 20
+
 func NewDatabaseConnection() *sql.DB {
-	db, connectErr := sql.Open("mysql", "demouser:demouserpassword@/project_horizon")
+	// sessions run in UTC so TIMESTAMP columns come back as UTC, see local_datetime in db.sql
+	db, connectErr := sql.Open("mysql", "demouser:demouserpassword@/project_horizon?time_zone=%27%2B00%3A00%27")
 	if connectErr != nil {
 		panic(connectErr)
 	}
 
+	db.SetMaxOpenConns(maxOpenConnections)
+	db.SetMaxIdleConns(maxOpenConnections)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
 	pingErr := db.Ping()
 	if pingErr != nil {
 		panic(pingErr)
@@ -0,0 +1,151 @@
+// Package dbtest gives tests a MySQL database loaded with db.sql, for the
+// behaviour that lives in SQL: rollups, triggers, the audit chain and erasure.
+// The database is dropped and recreated, point it at one used for nothing
+// else, with the time zone tables loaded, e.g.
+//
+//	HORIZON_TEST_DSN='root@/project_horizon_test' go test ./...
+//
+// Tests using it are skipped when HORIZON_TEST_DSN isn't set
+package dbtest
+
+import (
+	"bufio"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Open connects to the test database and loads the schema
+func Open(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv("HORIZON_TEST_DSN")
+	if dsn == "" {
+		t.Skip("HORIZON_TEST_DSN not set")
+	}
+	config, parseErr := mysql.ParseDSN(dsn)
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+	if config.Params == nil {
+		config.Params = map[string]string{}
+	}
+	config.Params["time_zone"] = "'+00:00'"
+
+	db, openErr := sql.Open("mysql", config.FormatDSN())
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+
+	loadSchema(t, db)
+
+	return db
+}
+
+// schemaPath is db.sql at the root of the module, wherever the test runs from
+func schemaPath() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "db.sql")
+}
+
+// loadSchema runs db.sql against the test database, skipping the statements
+// that pick the database and create the application's MySQL user
+func loadSchema(t *testing.T, db *sql.DB) {
+	t.Helper()
+
+	file, openErr := os.Open(schemaPath())
+	if openErr != nil {
+		t.Fatal(openErr)
+	}
+	defer file.Close()
+
+	delimiter := ";"
+	var statement strings.Builder
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if strings.HasPrefix(trimmed, "DELIMITER ") {
+			delimiter = strings.TrimPrefix(trimmed, "DELIMITER ")
+			continue
+		}
+		if statement.Len() == 0 && (trimmed == "" || strings.HasPrefix(trimmed, "--")) {
+			continue
+		}
+
+		statement.WriteString(line)
+		statement.WriteString("\n")
+		if !strings.HasSuffix(trimmed, delimiter) {
+			continue
+		}
+
+		query := strings.TrimSuffix(strings.TrimSpace(statement.String()), delimiter)
+		statement.Reset()
+
+		upper := strings.ToUpper(query)
+		if strings.HasPrefix(upper, "CREATE DATABASE") || strings.HasPrefix(upper, "USE ") ||
+			strings.HasPrefix(upper, "CREATE USER") || strings.HasPrefix(upper, "GRANT") || strings.HasPrefix(upper, "FLUSH") {
+			continue
+		}
+		if _, execErr := db.Exec(query); execErr != nil {
+			t.Fatalf("loading db.sql: %v\n%s", execErr, query)
+		}
+	}
+	if scanErr := scanner.Err(); scanErr != nil {
+		t.Fatal(scanErr)
+	}
+}
+
+// Exec runs each statement, failing the test on the first error
+func Exec(t *testing.T, db *sql.DB, statements ...string) {
+	t.Helper()
+
+	for _, statement := range statements {
+		if _, execErr := db.Exec(statement); execErr != nil {
+			t.Fatalf("%v\n%s", execErr, statement)
+		}
+	}
+}
+
+// QueryRows returns each row as its columns joined by |
+func QueryRows(t *testing.T, db *sql.DB, query string) []string {
+	t.Helper()
+
+	rows, queryErr := db.Query(query)
+	if queryErr != nil {
+		t.Fatal(queryErr)
+	}
+	defer rows.Close()
+
+	columns, _ := rows.Columns()
+	values := make([]sql.NullString, len(columns))
+	targets := make([]any, len(columns))
+	for i := range values {
+		targets[i] = &values[i]
+	}
+
+	var result []string
+	for rows.Next() {
+		if scanErr := rows.Scan(targets...); scanErr != nil {
+			t.Fatal(scanErr)
+		}
+		fields := make([]string, len(values))
+		for i, value := range values {
+			fields[i] = value.String
+		}
+		result = append(result, strings.Join(fields, "|"))
+	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		t.Fatal(rowsErr)
+	}
+
+	return result
+}
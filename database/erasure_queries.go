@@ -0,0 +1,180 @@
+package database
+
+var scheduleErasureQuery = `INSERT INTO erasure_request
+            (user_id,
+             scheduled_for)
+VALUES      (?, CURRENT_TIMESTAMP + INTERVAL ? DAY);`
+
+var erasureRequestColumns = `erasure_request_id,
+       user_id,
+       status,
+       requested_at,
+       scheduled_for,
+       COALESCE(cancelled_at, ''),
+       COALESCE(completed_at, ''),
+       attempts,
+       COALESCE(next_attempt_at, ''),
+       report`
+
+var pendingErasureQuery = `SELECT ` + erasureRequestColumns + `
+FROM   erasure_request
+WHERE  user_id = ?
+       AND status IN ( 'scheduled', 'running', 'failed', 'abandoned' )
+ORDER  BY erasure_request_id DESC
+LIMIT  1;`
+
+var latestErasureQuery = `SELECT ` + erasureRequestColumns + `
+FROM   erasure_request
+WHERE  user_id = ?
+ORDER  BY erasure_request_id DESC
+LIMIT  1;`
+
+var cancelErasureQuery = `UPDATE erasure_request
+SET    status = 'cancelled',
+       cancelled_at = CURRENT_TIMESTAMP
+WHERE  user_id = ?
+       AND status = 'scheduled';`
+
+// failed erasures are due again once their backoff is over
+var dueErasuresQuery = `SELECT ` + erasureRequestColumns + `
+FROM   erasure_request
+WHERE  ( status = 'scheduled'
+         AND scheduled_for <= CURRENT_TIMESTAMP )
+        OR ( status = 'failed'
+             AND next_attempt_at <= CURRENT_TIMESTAMP )
+ORDER  BY scheduled_for
+LIMIT  ?;`
+
+var failingErasuresQuery = `SELECT ` + erasureRequestColumns + `
+FROM   erasure_request
+WHERE  status IN ( 'failed', 'abandoned' )
+ORDER  BY erasure_request_id;`
+
+var claimErasureQuery = `UPDATE erasure_request
+SET    status = 'running',
+       attempts = attempts + 1
+WHERE  erasure_request_id = ?
+       AND status IN ( 'scheduled', 'failed' );`
+
+var completeErasureQuery = `UPDATE erasure_request
+SET    status = 'completed',
+       completed_at = CURRENT_TIMESTAMP,
+       next_attempt_at = NULL,
+       report = ?
+WHERE  erasure_request_id = ?;`
+
+// the backoff doubles from an hour with each attempt, after the last one the
+// erasure is left for an operator
+var failErasureQuery = `UPDATE erasure_request
+SET    status = IF(attempts >= ?, 'abandoned', 'failed'),
+       next_attempt_at = IF(attempts >= ?, NULL, CURRENT_TIMESTAMP + INTERVAL Pow(2, attempts - 1) HOUR),
+       report = ?
+WHERE  erasure_request_id = ?;`
+
+var retryErasureQuery = `UPDATE erasure_request
+SET    status = 'failed',
+       attempts = 0,
+       next_attempt_at = CURRENT_TIMESTAMP
+WHERE  erasure_request_id = ?
+       AND status = 'abandoned';`
+
+var erasureRequestQuery = `SELECT ` + erasureRequestColumns + `
+FROM   erasure_request
+WHERE  erasure_request_id = ?;`
+
+type erasureStatement struct {
+	table string
+	query string
+}
+
+// erasureStatements delete everything held about a user, children first so
+// each table's count is its own rather than hidden in a cascade. Every ? is
+// the user id. The rollups and data version go after the logs because the log
+// triggers write to them
+var erasureStatements = []erasureStatement{
+	{"webhook_dead_letter", `DELETE wdl
+FROM   webhook_dead_letter wdl
+       JOIN webhook_delivery wd
+         ON wdl.delivery_id = wd.delivery_id
+       JOIN webhook_event we
+         ON wd.event_id = we.event_id
+       JOIN webhook_subscription ws
+         ON wd.subscription_id = ws.subscription_id
+WHERE  we.user_id = ?
+        OR ws.user_id = ?;`},
+	{"webhook_delivery_attempt", `DELETE wda
+FROM   webhook_delivery_attempt wda
+       JOIN webhook_delivery wd
+         ON wda.delivery_id = wd.delivery_id
+       JOIN webhook_event we
+         ON wd.event_id = we.event_id
+       JOIN webhook_subscription ws
+         ON wd.subscription_id = ws.subscription_id
+WHERE  we.user_id = ?
+        OR ws.user_id = ?;`},
+	{"webhook_delivery", `DELETE wd
+FROM   webhook_delivery wd
+       JOIN webhook_event we
+         ON wd.event_id = we.event_id
+       JOIN webhook_subscription ws
+         ON wd.subscription_id = ws.subscription_id
+WHERE  we.user_id = ?
+        OR ws.user_id = ?;`},
+	{"webhook_event", `DELETE FROM webhook_event WHERE user_id = ?;`},
+	{"webhook_subscription", `DELETE FROM webhook_subscription WHERE user_id = ?;`},
+	{"mood_log_note_token", `DELETE FROM mood_log_note_token WHERE user_id = ?;`},
+	{"mood_log_keyword", `DELETE mlk
+FROM   mood_log_keyword mlk
+       JOIN mood_log_sentiment mls
+         ON mlk.mood_log_id = mls.mood_log_id
+WHERE  mls.user_id = ?;`},
+	{"mood_log_sentiment", `DELETE FROM mood_log_sentiment WHERE user_id = ?;`},
+	{"mood_log_mood_tag", `DELETE mlmt
+FROM   mood_log_mood_tag mlmt
+       JOIN mood_log ml
+         ON mlmt.mood_log_id = ml.mood_log_id
+WHERE  ml.user_id = ?;`},
+	{"mood_log", `DELETE FROM mood_log WHERE user_id = ?;`},
+	{"sleep_log", `DELETE FROM sleep_log WHERE user_id = ?;`},
+	{"medication_log", `DELETE FROM medication_log WHERE user_id = ?;`},
+	{"user_medication", `DELETE FROM user_medication WHERE user_id = ?;`},
+	{"alert", `DELETE FROM alert WHERE user_id = ?;`},
+	{"import_job", `DELETE FROM import_job WHERE user_id = ?;`},
+	{"import_tag_mapping", `DELETE FROM import_tag_mapping WHERE user_id = ?;`},
+	{"baseline_period", `DELETE FROM baseline_period WHERE user_id = ?;`},
+	{"organisation_member", `DELETE FROM organisation_member WHERE user_id = ?;`},
+	{"sharing_grant", `DELETE FROM sharing_grant WHERE user_id = ? OR grantee_user_id = ?;`},
+	{"api_token", `DELETE FROM api_token WHERE user_id = ?;`},
+	{"user_settings", `DELETE FROM user_settings WHERE user_id = ?;`},
+	{"daily_mood_category_count", `DELETE FROM daily_mood_category_count WHERE user_id = ?;`},
+	{"daily_mood_summary", `DELETE FROM daily_mood_summary WHERE user_id = ?;`},
+	{"daily_sleep_summary", `DELETE FROM daily_sleep_summary WHERE user_id = ?;`},
+	{"user_data_key", `DELETE FROM user_data_key WHERE user_id = ?;`},
+	{"user_data_version", `DELETE FROM user_data_version WHERE user_id = ?;`},
+	{"user", `DELETE FROM user WHERE user_id = ?;`},
+}
+
+// remainingUserDataQuery counts what's left in every table holding a user id,
+// the tables without one only hold rows of these parents and cascade with them
+var remainingUserDataQuery = `SELECT 'user', Count(*) FROM user WHERE user_id = ?
+UNION ALL SELECT 'user_settings', Count(*) FROM user_settings WHERE user_id = ?
+UNION ALL SELECT 'user_medication', Count(*) FROM user_medication WHERE user_id = ?
+UNION ALL SELECT 'medication_log', Count(*) FROM medication_log WHERE user_id = ?
+UNION ALL SELECT 'mood_log', Count(*) FROM mood_log WHERE user_id = ?
+UNION ALL SELECT 'sleep_log', Count(*) FROM sleep_log WHERE user_id = ?
+UNION ALL SELECT 'alert', Count(*) FROM alert WHERE user_id = ?
+UNION ALL SELECT 'organisation_member', Count(*) FROM organisation_member WHERE user_id = ?
+UNION ALL SELECT 'sharing_grant', Count(*) FROM sharing_grant WHERE user_id = ? OR grantee_user_id = ?
+UNION ALL SELECT 'api_token', Count(*) FROM api_token WHERE user_id = ?
+UNION ALL SELECT 'webhook_subscription', Count(*) FROM webhook_subscription WHERE user_id = ?
+UNION ALL SELECT 'webhook_event', Count(*) FROM webhook_event WHERE user_id = ?
+UNION ALL SELECT 'import_tag_mapping', Count(*) FROM import_tag_mapping WHERE user_id = ?
+UNION ALL SELECT 'import_job', Count(*) FROM import_job WHERE user_id = ?
+UNION ALL SELECT 'daily_mood_summary', Count(*) FROM daily_mood_summary WHERE user_id = ?
+UNION ALL SELECT 'daily_mood_category_count', Count(*) FROM daily_mood_category_count WHERE user_id = ?
+UNION ALL SELECT 'daily_sleep_summary', Count(*) FROM daily_sleep_summary WHERE user_id = ?
+UNION ALL SELECT 'baseline_period', Count(*) FROM baseline_period WHERE user_id = ?
+UNION ALL SELECT 'mood_log_sentiment', Count(*) FROM mood_log_sentiment WHERE user_id = ?
+UNION ALL SELECT 'user_data_key', Count(*) FROM user_data_key WHERE user_id = ?
+UNION ALL SELECT 'mood_log_note_token', Count(*) FROM mood_log_note_token WHERE user_id = ?
+UNION ALL SELECT 'user_data_version', Count(*) FROM user_data_version WHERE user_id = ?;`
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type schemaTable struct {
+	columns    []string
+	references []string // the tables its foreign keys point at
+}
+
+var (
+	createTablePattern = regexp.MustCompile(`(?s)CREATE TABLE IF NOT EXISTS (\w+) \((.*?)\n\);`)
+	referencesPattern  = regexp.MustCompile(`REFERENCES (\w+)\(`)
+	joinPattern        = regexp.MustCompile(`(?:FROM|JOIN)\s+(\w+)`)
+)
+
+// schemaTables reads the tables, their columns and foreign keys from db.sql
+func schemaTables(t *testing.T) map[string]schemaTable {
+	t.Helper()
+
+	schema, readErr := os.ReadFile("../db.sql")
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+
+	tables := make(map[string]schemaTable)
+	for _, match := range createTablePattern.FindAllStringSubmatch(string(schema), -1) {
+		var table schemaTable
+		for _, line := range strings.Split(match[2], "\n") {
+			fields := strings.Fields(line)
+			if len(fields) == 0 || strings.ToUpper(fields[0]) == fields[0] {
+				continue
+			}
+			table.columns = append(table.columns, fields[0])
+		}
+		for _, reference := range referencesPattern.FindAllStringSubmatch(match[2], -1) {
+			table.references = append(table.references, reference[1])
+		}
+		tables[match[1]] = table
+	}
+
+	return tables
+}
+
+func erasureOrder() []string {
+	order := make([]string, len(erasureStatements))
+	for i, statement := range erasureStatements {
+		order[i] = statement.table
+	}
+	return order
+}
+
+func TestErasureStatementOrder(t *testing.T) {
+	tables := schemaTables(t)
+	order := erasureOrder()
+
+	if order[len(order)-1] != "user" {
+		t.Errorf("the user is erased before %s, want the user row last", order[len(order)-1])
+	}
+
+	for i, table := range order {
+		if _, exists := tables[table]; !exists {
+			t.Errorf("%s isn't a table in db.sql", table)
+			continue
+		}
+		if slices.Index(order, table) != i {
+			t.Errorf("%s is erased twice", table)
+		}
+
+		// rows go before the rows they reference, a cascade would otherwise
+		// remove them uncounted
+		for _, parent := range tables[table].references {
+			if parentIndex := slices.Index(order, parent); parentIndex >= 0 && parentIndex < i {
+				t.Errorf("%s is erased after %s, which it references", table, parent)
+			}
+		}
+
+		// a statement finding its rows through another table has to run
+		// while that table's rows are still there
+		for _, joined := range joinPattern.FindAllStringSubmatch(erasureStatements[i].query, -1) {
+			if joinedIndex := slices.Index(order, joined[1]); joinedIndex >= 0 && joinedIndex < i {
+				t.Errorf("%s is found through %s, which is erased before it", table, joined[1])
+			}
+		}
+	}
+}
+
+func TestErasureCoversUserTables(t *testing.T) {
+	// kept on purpose: the record of the erasure and of who read the user's data
+	retained := []string{"erasure_request", "audit_log"}
+
+	order := erasureOrder()
+	for name, table := range schemaTables(t) {
+		holdsUser := slices.ContainsFunc(table.columns, func(column string) bool {
+			return strings.HasSuffix(column, "user_id")
+		})
+		if !holdsUser || slices.Contains(retained, name) {
+			continue
+		}
+
+		if !slices.Contains(order, name) {
+			t.Errorf("%s holds user ids but isn't erased", name)
+		}
+		if !strings.Contains(remainingUserDataQuery, "'"+name+"'") {
+			t.Errorf("%s holds user ids but isn't checked after the erasure", name)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+type ErasureRepository struct {
+	db *sql.DB
+}
+
+func NewErasureRepository(dbConnection *sql.DB) *ErasureRepository {
+	return &ErasureRepository{
+		db: dbConnection,
+	}
+}
+
+// ScheduleErasure schedules the user's erasure for the end of the grace
+// period, returning the request already in progress and false if there is one
+func (er *ErasureRepository) ScheduleErasure(userID string, graceDays int) (models.ErasureRequest, bool) {
+
+	if pending, found := er.erasureRequest(pendingErasureQuery, userID); found {
+		return pending, false
+	}
+
+	_, execErr := er.db.Exec(scheduleErasureQuery, userID, graceDays)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	scheduled, _ := er.erasureRequest(pendingErasureQuery, userID)
+	return scheduled, true
+}
+
+func (er *ErasureRepository) LatestErasure(userID string) (models.ErasureRequest, bool) {
+	return er.erasureRequest(latestErasureQuery, userID)
+}
+
+// CancelErasure cancels a scheduled erasure, once it's running it can't be
+func (er *ErasureRepository) CancelErasure(userID string) bool {
+
+	result, execErr := er.db.Exec(cancelErasureQuery, userID)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	rowsAffected, rowsErr := result.RowsAffected()
+	if rowsErr != nil {
+		panic(rowsErr)
+	}
+
+	return rowsAffected > 0
+}
+
+func (er *ErasureRepository) DueErasures(limit int) []models.ErasureRequest {
+
+	rows, queryErr := er.db.Query(dueErasuresQuery, limit)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var erasureRequests []models.ErasureRequest
+
+	for rows.Next() {
+		erasureRequests = append(erasureRequests, scanErasureRequest(rows))
+	}
+
+	return erasureRequests
+}
+
+// ClaimErasure marks the request running, false means another instance got
+// to it first or it was cancelled in the meantime
+func (er *ErasureRepository) ClaimErasure(erasureRequestID int) bool {
+
+	result, execErr := er.db.Exec(claimErasureQuery, erasureRequestID)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	rowsAffected, rowsErr := result.RowsAffected()
+	if rowsErr != nil {
+		panic(rowsErr)
+	}
+
+	return rowsAffected > 0
+}
+
+// EraseUserData deletes every row held about the user in a single transaction
+// and returns how many rows went from each table
+func (er *ErasureRepository) EraseUserData(userID string) []models.ErasureStep {
+
+	tx, txErr := er.db.Begin()
+	if txErr != nil {
+		panic(txErr)
+	}
+	defer tx.Rollback()
+
+	var steps []models.ErasureStep
+
+	for _, statement := range erasureStatements {
+		args := make([]any, strings.Count(statement.query, "?"))
+		for i := range args {
+			args[i] = userID
+		}
+
+		result, execErr := tx.Exec(statement.query, args...)
+		if execErr != nil {
+			panic(execErr)
+		}
+
+		rowsAffected, rowsErr := result.RowsAffected()
+		if rowsErr != nil {
+			panic(rowsErr)
+		}
+
+		steps = append(steps, models.ErasureStep{Name: statement.table, Removed: int(rowsAffected)})
+	}
+
+	commitErr := tx.Commit()
+	if commitErr != nil {
+		panic(commitErr)
+	}
+
+	completedAt := time.Now().UTC().Format("2006-01-02 15:04:05")
+	for i := range steps {
+		steps[i].CompletedAt = completedAt
+	}
+
+	return steps
+}
+
+// RemainingUserData counts the rows still held about the user in each table
+func (er *ErasureRepository) RemainingUserData(userID string) []models.TableCount {
+
+	args := make([]any, strings.Count(remainingUserDataQuery, "?"))
+	for i := range args {
+		args[i] = userID
+	}
+
+	rows, queryErr := er.db.Query(remainingUserDataQuery, args...)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	tableCounts := make([]models.TableCount, 0)
+
+	for rows.Next() {
+		var tableCount models.TableCount
+		scanErr := rows.Scan(&tableCount.Table, &tableCount.Rows)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+		tableCounts = append(tableCounts, tableCount)
+	}
+
+	return tableCounts
+}
+
+// CompleteErasure records a verified erasure
+func (er *ErasureRepository) CompleteErasure(erasureRequestID int, report models.ErasureReport) {
+
+	reportJSON, _ := json.Marshal(report)
+
+	_, execErr := er.db.Exec(completeErasureQuery, reportJSON, erasureRequestID)
+	if execErr != nil {
+		panic(execErr)
+	}
+}
+
+// FailErasure records a failed attempt and schedules the next one, or
+// abandons the erasure once it has had maxAttempts. It returns the request as
+// it now stands
+func (er *ErasureRepository) FailErasure(erasureRequestID int, report models.ErasureReport, maxAttempts int) models.ErasureRequest {
+
+	reportJSON, _ := json.Marshal(report)
+
+	_, execErr := er.db.Exec(failErasureQuery, maxAttempts, maxAttempts, reportJSON, erasureRequestID)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	erasureRequest, _ := er.erasureRequest(erasureRequestQuery, erasureRequestID)
+	return erasureRequest
+}
+
+// FailingErasures are the erasures waiting to be run again or abandoned
+func (er *ErasureRepository) FailingErasures() []models.ErasureRequest {
+
+	rows, queryErr := er.db.Query(failingErasuresQuery)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	erasureRequests := make([]models.ErasureRequest, 0)
+
+	for rows.Next() {
+		erasureRequests = append(erasureRequests, scanErasureRequest(rows))
+	}
+
+	return erasureRequests
+}
+
+// RetryErasure gives an abandoned erasure its attempts back and makes it due
+func (er *ErasureRepository) RetryErasure(erasureRequestID string) bool {
+
+	result, execErr := er.db.Exec(retryErasureQuery, erasureRequestID)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	rowsAffected, rowsErr := result.RowsAffected()
+	if rowsErr != nil {
+		panic(rowsErr)
+	}
+
+	return rowsAffected == 1
+}
+
+func (er *ErasureRepository) erasureRequest(query string, arg any) (models.ErasureRequest, bool) {
+
+	rows, queryErr := er.db.Query(query, arg)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	if next := rows.Next(); !next {
+		return models.ErasureRequest{}, false
+	}
+
+	return scanErasureRequest(rows), true
+}
+
+func scanErasureRequest(rows *sql.Rows) models.ErasureRequest {
+	var erasureRequest models.ErasureRequest
+	var report []byte
+
+	scanErr := rows.Scan(
+		&erasureRequest.ErasureRequestID,
+		&erasureRequest.UserID,
+		&erasureRequest.Status,
+		&erasureRequest.RequestedAt,
+		&erasureRequest.ScheduledFor,
+		&erasureRequest.CancelledAt,
+		&erasureRequest.CompletedAt,
+		&erasureRequest.Attempts,
+		&erasureRequest.NextAttemptAt,
+		&report,
+	)
+	if scanErr != nil {
+		panic(scanErr)
+	}
+
+	if report != nil {
+		json.Unmarshal(report, &erasureRequest.Report)
+	}
+
+	return erasureRequest
+}
@@ -0,0 +1,139 @@
+package database
+
+// same join as daysQuery but a left join so logs without tags are exported too
+var exportMoodLogsQuery = `SELECT ml.mood_log_id,
+       ml.user_id,
+       ml.mood_rating,
+       ml.note,
+       ml.note_key_version IS NOT NULL AS note_encrypted,
+       ml.created_at,
+       group_concat(mt.NAME ORDER BY mt.NAME separator ',') AS mood_tags
+FROM   mood_log ml
+       LEFT JOIN mood_log_mood_tag mlmt
+              ON ml.mood_log_id = mlmt.mood_log_id
+       LEFT JOIN mood_tag mt
+              ON mlmt.mood_tag_id = mt.mood_tag_id
+WHERE  ml.user_id = ?
+       AND ml.created_at >= ? AND ml.created_at < ?
+GROUP  BY ml.mood_log_id,
+          ml.user_id,
+          ml.mood_rating,
+          ml.note,
+          ml.note_key_version,
+          ml.created_at
+ORDER  BY ml.created_at;`
+
+var exportSleepLogsQuery = `SELECT sl.sleep_log_id,
+       sl.user_id,
+       sl.hours_slept,
+       sqt.NAME AS sleep_quality,
+       sl.notes,
+       sl.notes_key_version IS NOT NULL AS notes_encrypted,
+       sl.sleep_date,
+       sl.created_at
+FROM   sleep_log sl
+       INNER JOIN sleep_quality_tag sqt
+               ON sl.sleep_quality_tag_id = sqt.sleep_quality_tag_id
+WHERE  sl.user_id = ?
+       AND sl.sleep_date BETWEEN ? AND ?
+ORDER  BY sl.sleep_date;`
+
+var exportMedicationLogsQuery = `SELECT mdl.medication_log_id,
+       mdl.user_id,
+       mdl.medication_id,
+       m.NAME AS medication_name,
+       mdl.taken_at,
+       mdl.taken,
+       mdl.dosage,
+       mdl.notes
+FROM   medication_log mdl
+       INNER JOIN medication m
+               ON mdl.medication_id = m.medication_id
+WHERE  mdl.user_id = ?
+       AND Date(mdl.taken_at) BETWEEN ? AND ?
+ORDER  BY mdl.taken_at;`
+
+var exportDailyAggregatesQuery = `WITH mood
+     AS (SELECT Date(local_datetime(user_id, created_at)) AS date,
+                Avg(mood_rating) AS daily_avg_rating,
+                Count(*)         AS mood_log_count
+         FROM   mood_log
+         WHERE  user_id = ?
+                AND created_at >= ? AND created_at < ?
+         GROUP  BY Date(local_datetime(user_id, created_at))),
+     sleep
+     AS (SELECT sleep_date       AS date,
+                Sum(hours_slept) AS hours_slept
+         FROM   sleep_log
+         WHERE  user_id = ?
+                AND sleep_date BETWEEN ? AND ?
+         GROUP  BY sleep_date),
+     medication
+     AS (SELECT Date(taken_at) AS date,
+                Sum(taken)     AS doses_taken
+         FROM   medication_log
+         WHERE  user_id = ?
+                AND Date(taken_at) BETWEEN ? AND ?
+         GROUP  BY Date(taken_at)),
+     dates
+     AS (SELECT date FROM mood
+         UNION
+         SELECT date FROM sleep
+         UNION
+         SELECT date FROM medication)
+SELECT d.date,
+       COALESCE(m.daily_avg_rating, 0),
+       COALESCE(m.mood_log_count, 0),
+       s.hours_slept,
+       COALESCE(md.doses_taken, 0)
+FROM   dates d
+       LEFT JOIN mood m
+              ON d.date = m.date
+       LEFT JOIN sleep s
+              ON d.date = s.date
+       LEFT JOIN medication md
+              ON d.date = md.date
+ORDER  BY d.date;`
+
+var exportUserMedicationsQuery = `SELECT um.user_medication_id,
+       um.user_id,
+       um.medication_id,
+       m.NAME AS medication_name,
+       um.dosage,
+       um.start_date,
+       um.end_date,
+       um.stopped,
+       um.notes
+FROM   user_medication um
+       INNER JOIN medication m
+               ON um.medication_id = m.medication_id
+WHERE  um.user_id = ?
+       AND um.start_date <= ?
+       AND ( um.end_date IS NULL
+              OR um.end_date >= ? )
+ORDER  BY um.start_date;`
+
+var exportWebhookEventsQuery = `SELECT event_id,
+       user_id,
+       event_type,
+       created_at,
+       payload
+FROM   webhook_event
+WHERE  user_id = ?
+ORDER  BY event_id;`
+
+// the keywords are encrypted, their base64 never holds a comma
+var exportMoodLogSentimentQuery = `SELECT mls.mood_log_id,
+       mls.sentiment_score,
+       mls.analyzer_version,
+       mls.analyzed_at,
+       group_concat(mlk.keyword ORDER BY mlk.keyword_count DESC separator ',') AS keywords
+FROM   mood_log_sentiment mls
+       LEFT JOIN mood_log_keyword mlk
+              ON mls.mood_log_id = mlk.mood_log_id
+WHERE  mls.user_id = ?
+GROUP  BY mls.mood_log_id,
+          mls.sentiment_score,
+          mls.analyzer_version,
+          mls.analyzed_at
+ORDER  BY mls.mood_log_id;`
@@ -0,0 +1,295 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+// ExportRepository hands rows to a callback one at a time instead of building
+// slices so exports of long ranges are streamed, the callback stops the
+// iteration by returning an error
+type ExportRepository struct {
+	db         *sql.DB
+	noteCipher *NoteCipher
+}
+
+func NewExportRepository(dbConnection *sql.DB, noteCipher *NoteCipher) *ExportRepository {
+	return &ExportRepository{
+		db:         dbConnection,
+		noteCipher: noteCipher,
+	}
+}
+
+func (er *ExportRepository) EachMoodLog(userID string, startDate string, endDate string, callback func(models.MoodLog) error) error {
+
+	from, to := utcBounds(er.db, userID, startDate, endDate)
+
+	rows, queryErr := er.db.Query(exportMoodLogsQuery, userID, from, to)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var moodLog models.MoodLog
+		var note sql.NullString
+		var noteEncrypted bool
+		var moodTags sql.NullString
+
+		scanErr := rows.Scan(
+			&moodLog.MoodLogID,
+			&moodLog.UserID,
+			&moodLog.MoodRating,
+			&note,
+			&noteEncrypted,
+			&moodLog.CreatedAt,
+			&moodTags,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+
+		plaintext, decryptErr := er.noteCipher.Decrypt(userID, MoodLogNote(moodLog.MoodLogID), note.String, noteEncrypted)
+		if decryptErr != nil {
+			return decryptErr
+		}
+		moodLog.Note = plaintext
+		moodLog.MoodTags = make([]string, 0)
+		if moodTags.Valid {
+			moodLog.MoodTags = strings.Split(moodTags.String, ",")
+		}
+
+		if callbackErr := callback(moodLog); callbackErr != nil {
+			return callbackErr
+		}
+	}
+
+	// a dropped connection ends the loop early, the export must fail rather
+	// than end as if it was complete
+	return rows.Err()
+}
+
+func (er *ExportRepository) EachSleepLog(userID string, startDate string, endDate string, callback func(models.SleepLog) error) error {
+
+	rows, queryErr := er.db.Query(exportSleepLogsQuery, userID, startDate, endDate)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sleepLog models.SleepLog
+		var notes sql.NullString
+		var notesEncrypted bool
+
+		scanErr := rows.Scan(
+			&sleepLog.SleepLogID,
+			&sleepLog.UserID,
+			&sleepLog.HoursSlept,
+			&sleepLog.SleepQuality,
+			&notes,
+			&notesEncrypted,
+			&sleepLog.SleepDate,
+			&sleepLog.CreatedAt,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+
+		plaintext, decryptErr := er.noteCipher.Decrypt(userID, SleepLogNotes(sleepLog.SleepLogID), notes.String, notesEncrypted)
+		if decryptErr != nil {
+			return decryptErr
+		}
+		sleepLog.Notes = plaintext
+
+		if callbackErr := callback(sleepLog); callbackErr != nil {
+			return callbackErr
+		}
+	}
+
+	return rows.Err()
+}
+
+func (er *ExportRepository) EachMedicationLog(userID string, startDate string, endDate string, callback func(models.MedicationLog) error) error {
+
+	rows, queryErr := er.db.Query(exportMedicationLogsQuery, userID, startDate, endDate)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var medicationLog models.MedicationLog
+		var notes sql.NullString
+
+		scanErr := rows.Scan(
+			&medicationLog.MedicationLogID,
+			&medicationLog.UserID,
+			&medicationLog.MedicationID,
+			&medicationLog.MedicationName,
+			&medicationLog.TakenAt,
+			&medicationLog.Taken,
+			&medicationLog.Dosage,
+			&notes,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+
+		medicationLog.Notes = notes.String
+
+		if callbackErr := callback(medicationLog); callbackErr != nil {
+			return callbackErr
+		}
+	}
+
+	return rows.Err()
+}
+
+func (er *ExportRepository) EachDailyAggregate(userID string, startDate string, endDate string, callback func(models.DailyAggregate) error) error {
+
+	from, to := utcBounds(er.db, userID, startDate, endDate)
+
+	rows, queryErr := er.db.Query(exportDailyAggregatesQuery, userID, from, to, userID, startDate, endDate, userID, startDate, endDate)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var dailyAggregate models.DailyAggregate
+		var hoursSlept sql.NullFloat64
+
+		scanErr := rows.Scan(
+			&dailyAggregate.Date,
+			&dailyAggregate.DailyAvgRating,
+			&dailyAggregate.MoodLogCount,
+			&hoursSlept,
+			&dailyAggregate.DosesTaken,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+
+		dailyAggregate.HoursSlept = hoursSlept.Float64
+		dailyAggregate.SleepLogged = hoursSlept.Valid
+
+		if callbackErr := callback(dailyAggregate); callbackErr != nil {
+			return callbackErr
+		}
+	}
+
+	return rows.Err()
+}
+
+// medications that were prescribed at any point during the range
+func (er *ExportRepository) EachUserMedication(userID string, startDate string, endDate string, callback func(models.UserMedication) error) error {
+
+	rows, queryErr := er.db.Query(exportUserMedicationsQuery, userID, endDate, startDate)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var userMedication models.UserMedication
+		var dosage sql.NullString
+		var medicationEndDate sql.NullString
+		var notes sql.NullString
+
+		scanErr := rows.Scan(
+			&userMedication.UserMedicationID,
+			&userMedication.UserID,
+			&userMedication.MedicationID,
+			&userMedication.MedicationName,
+			&dosage,
+			&userMedication.StartDate,
+			&medicationEndDate,
+			&userMedication.Stopped,
+			&notes,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+
+		userMedication.Dosage = dosage.String
+		userMedication.EndDate = medicationEndDate.String
+		userMedication.Notes = notes.String
+
+		if callbackErr := callback(userMedication); callbackErr != nil {
+			return callbackErr
+		}
+	}
+
+	return rows.Err()
+}
+
+func (er *ExportRepository) EachWebhookEvent(userID string, callback func(models.WebhookEvent) error) error {
+
+	rows, queryErr := er.db.Query(exportWebhookEventsQuery, userID)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event models.WebhookEvent
+		scanErr := rows.Scan(&event.EventID, &event.UserID, &event.EventType, &event.CreatedAt, &event.Data)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+
+		if callbackErr := callback(event); callbackErr != nil {
+			return callbackErr
+		}
+	}
+
+	return rows.Err()
+}
+
+func (er *ExportRepository) EachMoodLogSentiment(userID string, callback func(models.MoodLogSentiment) error) error {
+
+	rows, queryErr := er.db.Query(exportMoodLogSentimentQuery, userID)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var moodLogSentiment models.MoodLogSentiment
+		var sentiment sql.NullFloat64
+		var keywords sql.NullString
+
+		scanErr := rows.Scan(
+			&moodLogSentiment.MoodLogID,
+			&sentiment,
+			&moodLogSentiment.AnalyzerVersion,
+			&moodLogSentiment.AnalyzedAt,
+			&keywords,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+
+		moodLogSentiment.Scored = sentiment.Valid
+		moodLogSentiment.Sentiment = sentiment.Float64
+		moodLogSentiment.Keywords = make([]string, 0)
+		if keywords.Valid {
+			for _, keyword := range strings.Split(keywords.String, ",") {
+				plaintext, decryptErr := er.noteCipher.DecryptKeyword(userID, keyword)
+				if decryptErr != nil {
+					return decryptErr
+				}
+				moodLogSentiment.Keywords = append(moodLogSentiment.Keywords, plaintext)
+			}
+		}
+
+		if callbackErr := callback(moodLogSentiment); callbackErr != nil {
+			return callbackErr
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,98 @@
+package database
+
+var moodTagsQuery = `SELECT mood_tag_id,
+       NAME,
+       mood_category_id
+FROM   mood_tag
+ORDER  BY mood_tag_id;`
+
+var tagMappingsQuery = `SELECT itm.source_format,
+       itm.external_label,
+       COALESCE(itm.mood_tag_id, 0),
+       COALESCE(mt.NAME, '')
+FROM   import_tag_mapping itm
+       LEFT JOIN mood_tag mt
+              ON itm.mood_tag_id = mt.mood_tag_id
+WHERE  itm.user_id = ?
+       AND itm.source_format = ?
+ORDER  BY itm.external_label;`
+
+var saveTagMappingQuery = `INSERT INTO import_tag_mapping
+            (user_id,
+             source_format,
+             external_label,
+             mood_tag_id)
+VALUES      (?, ?, ?, ?)
+ON DUPLICATE KEY UPDATE mood_tag_id = VALUES(mood_tag_id);`
+
+var existingMoodLogTimesQuery = `SELECT created_at
+FROM   mood_log
+WHERE  user_id = ?
+       AND created_at BETWEEN ? AND ?;`
+
+var createImportJobQuery = `INSERT INTO import_job
+            (user_id,
+             source_format,
+             dry_run,
+             total_rows)
+VALUES      (?, ?, ?, ?);`
+
+var updateImportJobProgressQuery = `UPDATE import_job
+SET    status = 'running',
+       processed_rows = ?,
+       imported_count = ?,
+       duplicate_count = ?,
+       skipped_count = ?
+WHERE  import_job_id = ?;`
+
+// user variables outlive the transaction on the pooled connection, so the batch
+// has to be ended before its connection is given back
+var startImportBatchQuery = `SET @import_job_id = ?;`
+
+var endImportBatchQuery = `SET @import_job_id = NULL;`
+
+var completeImportJobQuery = `UPDATE import_job
+SET    status = 'completed',
+       report = ?
+WHERE  import_job_id = ?;`
+
+var failImportJobQuery = `UPDATE import_job
+SET    status = 'failed',
+       error = ?
+WHERE  import_job_id = ?;`
+
+var importJobQuery = `SELECT import_job_id,
+       user_id,
+       source_format,
+       dry_run,
+       status,
+       total_rows,
+       processed_rows,
+       imported_count,
+       duplicate_count,
+       skipped_count,
+       COALESCE(error, ''),
+       report,
+       created_at,
+       updated_at
+FROM   import_job
+WHERE  user_id = ?
+       AND import_job_id = ?;`
+
+var importJobsQuery = `SELECT import_job_id,
+       user_id,
+       source_format,
+       dry_run,
+       status,
+       total_rows,
+       processed_rows,
+       imported_count,
+       duplicate_count,
+       skipped_count,
+       COALESCE(error, ''),
+       report,
+       created_at,
+       updated_at
+FROM   import_job
+WHERE  user_id = ?
+ORDER  BY import_job_id;`
@@ -0,0 +1,237 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+type ImportRepository struct {
+	db *sql.DB
+}
+
+func NewImportRepository(dbConnection *sql.DB) *ImportRepository {
+	return &ImportRepository{
+		db: dbConnection,
+	}
+}
+
+func (ir *ImportRepository) MoodTags() []models.MoodTag {
+
+	rows, queryErr := ir.db.Query(moodTagsQuery)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var moodTags []models.MoodTag
+
+	for rows.Next() {
+		var moodTag models.MoodTag
+		scanErr := rows.Scan(
+			&moodTag.MoodTagID,
+			&moodTag.Name,
+			&moodTag.MoodCategoryID,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+		moodTags = append(moodTags, moodTag)
+	}
+
+	if moodTags == nil {
+		return make([]models.MoodTag, 0)
+	}
+
+	return moodTags
+}
+
+func (ir *ImportRepository) TagMappings(userID string, sourceFormat string) []models.TagMapping {
+
+	rows, queryErr := ir.db.Query(tagMappingsQuery, userID, sourceFormat)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var tagMappings []models.TagMapping
+
+	for rows.Next() {
+		var tagMapping models.TagMapping
+		scanErr := rows.Scan(
+			&tagMapping.SourceFormat,
+			&tagMapping.ExternalLabel,
+			&tagMapping.MoodTagID,
+			&tagMapping.MoodTagName,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+		tagMappings = append(tagMappings, tagMapping)
+	}
+
+	if tagMappings == nil {
+		return make([]models.TagMapping, 0)
+	}
+
+	return tagMappings
+}
+
+func (ir *ImportRepository) SaveTagMappings(userID string, tagMappings []models.TagMapping) {
+
+	tx, txErr := ir.db.Begin()
+	if txErr != nil {
+		panic(txErr)
+	}
+	defer tx.Rollback()
+
+	for _, tagMapping := range tagMappings {
+		var moodTagID sql.NullInt64
+		if tagMapping.MoodTagID != 0 {
+			moodTagID = sql.NullInt64{Int64: int64(tagMapping.MoodTagID), Valid: true}
+		}
+
+		_, execErr := tx.Exec(saveTagMappingQuery, userID, tagMapping.SourceFormat, tagMapping.ExternalLabel, moodTagID)
+		if execErr != nil {
+			panic(execErr)
+		}
+	}
+
+	commitErr := tx.Commit()
+	if commitErr != nil {
+		panic(commitErr)
+	}
+}
+
+// ExistingMoodLogTimes returns the created_at of every mood log between from
+// and to, used to skip imported entries that are already logged
+func (ir *ImportRepository) ExistingMoodLogTimes(userID string, from string, to string) map[string]bool {
+
+	rows, queryErr := ir.db.Query(existingMoodLogTimesQuery, userID, from, to)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+
+	for rows.Next() {
+		var createdAt string
+		scanErr := rows.Scan(&createdAt)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+		existing[createdAt] = true
+	}
+
+	return existing
+}
+
+func (ir *ImportRepository) CreateJob(userID string, sourceFormat string, dryRun bool, totalRows int) int {
+
+	result, execErr := ir.db.Exec(createImportJobQuery, userID, sourceFormat, dryRun, totalRows)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	jobID, idErr := result.LastInsertId()
+	if idErr != nil {
+		panic(idErr)
+	}
+
+	return int(jobID)
+}
+
+func (ir *ImportRepository) UpdateJobProgress(job models.ImportJob) {
+
+	_, execErr := ir.db.Exec(updateImportJobProgressQuery, job.ProcessedRows, job.ImportedCount, job.DuplicateCount, job.SkippedCount, job.JobID)
+	if execErr != nil {
+		panic(execErr)
+	}
+}
+
+func (ir *ImportRepository) CompleteJob(jobID int, report models.ImportReport) {
+
+	reportJSON, _ := json.Marshal(report)
+
+	_, execErr := ir.db.Exec(completeImportJobQuery, reportJSON, jobID)
+	if execErr != nil {
+		panic(execErr)
+	}
+}
+
+func (ir *ImportRepository) FailJob(jobID int, jobErr string) {
+
+	_, execErr := ir.db.Exec(failImportJobQuery, jobErr, jobID)
+	if execErr != nil {
+		panic(execErr)
+	}
+}
+
+func (ir *ImportRepository) Job(userID string, jobID string) (models.ImportJob, bool) {
+
+	rows, queryErr := ir.db.Query(importJobQuery, userID, jobID)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	if next := rows.Next(); !next {
+		return models.ImportJob{}, false
+	}
+
+	return scanImportJob(rows), true
+}
+
+func (ir *ImportRepository) Jobs(userID string) []models.ImportJob {
+
+	rows, queryErr := ir.db.Query(importJobsQuery, userID)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var jobs []models.ImportJob
+
+	for rows.Next() {
+		jobs = append(jobs, scanImportJob(rows))
+	}
+
+	if jobs == nil {
+		return make([]models.ImportJob, 0)
+	}
+
+	return jobs
+}
+
+func scanImportJob(rows *sql.Rows) models.ImportJob {
+	var job models.ImportJob
+	var report []byte
+
+	scanErr := rows.Scan(
+		&job.JobID,
+		&job.UserID,
+		&job.SourceFormat,
+		&job.DryRun,
+		&job.Status,
+		&job.TotalRows,
+		&job.ProcessedRows,
+		&job.ImportedCount,
+		&job.DuplicateCount,
+		&job.SkippedCount,
+		&job.Error,
+		&report,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+	)
+	if scanErr != nil {
+		panic(scanErr)
+	}
+
+	if report != nil {
+		json.Unmarshal(report, &job.Report)
+	}
+
+	return job
+}
@@ -0,0 +1,29 @@
+package database
+
+import "database/sql"
+
+// utcBounds is the UTC range [from, to) covering the user's local dates
+// startDate to endDate. Mood queries filter created_at on it rather than on
+// the local date of every row, so the (user_id, created_at) index is used and
+// only the rows in range are converted
+func utcBounds(db *sql.DB, userID string, startDate string, endDate string) (string, string) {
+
+	rows, queryErr := db.Query(utcBoundsQuery, userID, startDate, userID, endDate)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var from, to string
+	if next := rows.Next(); next {
+		scanErr := rows.Scan(&from, &to)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+	}
+	if rowsErr := rows.Err(); rowsErr != nil {
+		panic(rowsErr)
+	}
+
+	return from, to
+}
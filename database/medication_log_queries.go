@@ -0,0 +1,40 @@
+package database
+
+// one dose a day of every medication the user was on, a dose counts when any
+// log that day says it was taken. Medications stopped without an end date have
+// no known schedule and are left out. NULL when nothing was scheduled or
+// nothing was logged
+var medicationAdherenceQuery = `WITH RECURSIVE days
+     AS (SELECT Cast(? AS DATE) AS day
+         UNION ALL
+         SELECT day + INTERVAL 1 day
+         FROM   days
+         WHERE  day < ?),
+     scheduled
+     AS (SELECT DISTINCT um.medication_id,
+                         d.day
+         FROM   user_medication um
+                JOIN days d
+                  ON d.day >= um.start_date
+                     AND ( um.end_date IS NULL
+                            OR d.day <= um.end_date )
+         WHERE  um.user_id = ?
+                AND NOT ( um.stopped = 1
+                          AND um.end_date IS NULL )),
+     logged
+     AS (SELECT medication_id,
+                Date(taken_at) AS day,
+                Max(taken)     AS taken
+         FROM   medication_log
+         WHERE  user_id = ?
+                AND Date(taken_at) BETWEEN ? AND ?
+         GROUP  BY medication_id,
+                   day)
+SELECT CASE
+         WHEN EXISTS (SELECT 1
+                      FROM   logged) THEN ( Sum(COALESCE(l.taken, 0)) / NULLIF(Count(*), 0) ) * 100
+       END AS adherence_percentage
+FROM   scheduled s
+       LEFT JOIN logged l
+              ON l.medication_id = s.medication_id
+                 AND l.day = s.day;`
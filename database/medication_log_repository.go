@@ -0,0 +1,37 @@
+package database
+
+import (
+	"database/sql"
+)
+
+type MedicationLogRepository struct {
+	db *sql.DB
+}
+
+func NewMedicationLogRepository(dbConnection *sql.DB) *MedicationLogRepository {
+	return &MedicationLogRepository{
+		db: dbConnection,
+	}
+}
+
+// AdherencePercentage is the share of the doses scheduled in the range that
+// were taken, false when nothing was scheduled or no medication was logged
+func (mlr *MedicationLogRepository) AdherencePercentage(userID string, startDate string, endDate string) (float64, bool) {
+
+	rows, queryErr := mlr.db.Query(medicationAdherenceQuery, startDate, endDate, userID, userID, startDate, endDate)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var adherencePercentage sql.NullFloat64
+
+	if next := rows.Next(); next {
+		scanErr := rows.Scan(&adherencePercentage)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+	}
+
+	return adherencePercentage.Float64, adherencePercentage.Valid
+}
@@ -0,0 +1,51 @@
+package database
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAdherencePercentage(t *testing.T) {
+	db := testDatabase(t)
+	medicationLogRepository := NewMedicationLogRepository(db)
+
+	// medication 2 is scheduled from the 1st to the 10th and 3 from the 6th,
+	// 4 was stopped on an unknown day. 8 doses of 2 and 2 of 3 were taken
+	if _, execErr := db.Exec(`INSERT INTO user (user_id, email, password_hash) VALUES (9, 'nine@example.com', '')`); execErr != nil {
+		t.Fatal(execErr)
+	}
+	for _, statement := range []string{
+		`INSERT INTO user_medication (user_id, medication_id, dosage, start_date, end_date, stopped) VALUES
+		 (9, 2, '20mg', '2030-01-01', '2030-01-10', 1),
+		 (9, 3, '10mg', '2030-01-06', NULL, 0),
+		 (9, 4, '5mg', '2029-06-01', NULL, 1)`,
+		`INSERT INTO medication_log (user_id, medication_id, taken_at, taken, dosage) VALUES
+		 (9, 2, '2030-01-01 08:00:00', 1, '20mg'),
+		 (9, 2, '2030-01-02 08:00:00', 1, '20mg'),
+		 (9, 2, '2030-01-03 08:00:00', 0, '20mg'),
+		 (9, 2, '2030-01-03 12:00:00', 1, '20mg'),
+		 (9, 2, '2030-01-04 08:00:00', 1, '20mg'),
+		 (9, 2, '2030-01-05 08:00:00', 1, '20mg'),
+		 (9, 2, '2030-01-06 08:00:00', 1, '20mg'),
+		 (9, 2, '2030-01-07 08:00:00', 1, '20mg'),
+		 (9, 2, '2030-01-08 08:00:00', 1, '20mg'),
+		 (9, 2, '2030-01-09 08:00:00', 0, '20mg'),
+		 (9, 3, '2030-01-06 20:00:00', 1, '10mg'),
+		 (9, 3, '2030-01-07 20:00:00', 1, '10mg'),
+		 (9, 4, '2030-01-02 20:00:00', 1, '5mg')`,
+	} {
+		if _, execErr := db.Exec(statement); execErr != nil {
+			t.Fatal(execErr)
+		}
+	}
+
+	// 10 of the 15 scheduled doses, the unlogged ones count against it
+	if adherence, logged := medicationLogRepository.AdherencePercentage("9", "2030-01-01", "2030-01-10"); !logged || math.Abs(adherence-200.0/3) > 1e-4 {
+		t.Errorf("adherence = %v, %v, want 66.67%%", adherence, logged)
+	}
+
+	// nothing logged, the user isn't tracking their medication
+	if adherence, logged := medicationLogRepository.AdherencePercentage("9", "2030-02-01", "2030-02-05"); logged {
+		t.Errorf("adherence without logs = %v, want none", adherence)
+	}
+}
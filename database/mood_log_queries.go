@@ -1,137 +1,121 @@
 package database
 
 
-var streaksQuery = `WITH qualifying_days AS
-(
-         SELECT   date,
-                  created_at,
-                  mood_log_id,
-                  mood_rating,
-                  note,
-                  mood_tags,
-                  mood_tag_ids,
-                  daily_avg_rating,
-                  daily_target_count,
-                  daily_total_count,
-                  daily_target_percentage
-         FROM     (
-                           SELECT   Date(ml.created_at) AS date,
-                                    ml.created_at,
-                                    ml.mood_log_id,
-                                    ml.mood_rating,
-                                    ml.note,
-                                    group_concat(mt.NAME order BY mt.NAME separator ',')              AS mood_tags,
-                                    group_concat(mt.mood_tag_id ORDER BY mt.mood_tag_id separator ',') AS mood_tag_ids,
-                                    avg(ml.mood_rating) OVER (partition BY date(ml.created_at))        AS daily_avg_rating,
-                                    sum(
-                                    CASE
-                                             WHEN mt.mood_category_id = ? THEN 1
-                                             ELSE 0
-                                    END) OVER (partition BY                  date(ml.created_at)) AS daily_target_count,
-                                    count(mt.mood_tag_id) OVER (partition BY date(ml.created_at)) AS daily_total_count,
-                                    (sum(
-                                    CASE
-                                             WHEN mt.mood_category_id = ? THEN 1
-                                             ELSE 0
-                                    END) OVER (partition BY date(ml.created_at)) * 100.0 / count(mt.mood_tag_id) OVER (partition BY date(ml.created_at))) AS daily_target_percentage
-                           FROM     mood_log ml 
-                           JOIN     mood_log_mood_tag mlmt
-                           ON       ml.mood_log_id = mlmt.mood_log_id
-                           JOIN     mood_tag mt
-                           ON       mlmt.mood_tag_id = mt.mood_tag_id
-                           WHERE    ml.user_id = ?
-                           AND      date(ml.created_at) BETWEEN ? AND      ?
-                           GROUP BY date(ml.created_at),
-                                    ml.mood_log_id,
-                                    ml.created_at,
-                                    ml.mood_rating,
-                                    ml.note) AS daily_data
-         WHERE    daily_avg_rating %s ?
-         AND      daily_target_percentage >= ?
-         ORDER BY date,
-                  created_at), second_query AS
-(
-         SELECT   date,
-                  daily_avg_rating,
-                  row_number() OVER( ORDER BY date) AS rn
-         FROM     qualifying_days), third_query AS
-(
-         SELECT   date                              AS start_date,
-                  count(*)                          AS streak_length,
-                  date_add(date, interval - rn day) AS consec_groups
-         FROM     second_query
-         GROUP BY consec_groups), fourth_query AS
-(
-       SELECT *,
-              date_add(start_date, interval + streak_length - 1 day) AS end_date
-       FROM   third_query)
-SELECT   start_date,
-         end_date,
-         streak_length
-FROM     fourth_query
-WHERE    streak_length >= 2
-ORDER BY start_date;`
-
-var daysQuery = `SELECT   date,
-         created_at,
-         mood_log_id,
-         mood_rating,
-         note,
-         mood_tags,
-         mood_tag_ids,
-         daily_avg_rating,
-         daily_target_count,
-         daily_total_count,
-         daily_target_percentage
-FROM     (
-                  SELECT   Date(ml.created_at) AS date,
-                           ml.created_at,
-                           ml.mood_log_id,
-                           ml.mood_rating,
-                           ml.note,
-                           group_concat(mt.NAME order BY mt.NAME separator ', ')              AS mood_tags,
-                           group_concat(mt.mood_tag_id ORDER BY mt.mood_tag_id separator ',') AS mood_tag_ids,
-                           avg(ml.mood_rating) OVER (partition BY date(ml.created_at))        AS daily_avg_rating,
-                           sum(
-                           CASE
-                                    WHEN mt.mood_category_id = ? THEN 1
-                                    ELSE 0
-                           END) OVER (partition BY                  date(ml.created_at)) AS daily_target_count,
-                           count(mt.mood_tag_id) OVER (partition BY date(ml.created_at)) AS daily_total_count,
-                           (sum(
-                           CASE
-                                    WHEN mt.mood_category_id = ? THEN 1
-                                    ELSE 0
-                           END) OVER (partition BY date(ml.created_at)) * 100.0 / count(mt.mood_tag_id) OVER (partition BY date(ml.created_at))) AS daily_target_percentage
-                  FROM     mood_log ml
-                  JOIN     mood_log_mood_tag mlmt
-                  ON       ml.mood_log_id = mlmt.mood_log_id
-                  JOIN     mood_tag mt
-                  ON       mlmt.mood_tag_id = mt.mood_tag_id
-                  WHERE    ml.user_id = ?
-                  AND      date(ml.created_at) BETWEEN ? AND      ?
-                  GROUP BY date(ml.created_at),
-                           ml.mood_log_id,
-                           ml.created_at,
-                           ml.mood_rating,
-                           ml.note ) AS daily_data
-WHERE    daily_avg_rating %s ?
-AND      daily_target_percentage >= ?
-ORDER BY date,
-         created_at;`
+var streaksQuery = `WITH daily_data
+     AS (SELECT ds.summary_date                                     AS date,
+                ds.avg_rating                                       AS daily_avg_rating,
+                COALESCE(dc.tag_count, 0)                           AS daily_target_count,
+                ds.tag_count                                        AS daily_total_count,
+                COALESCE(dc.tag_count, 0) * 100.0 / ds.tag_count    AS daily_target_percentage
+         FROM   daily_mood_summary ds
+                LEFT JOIN daily_mood_category_count dc
+                       ON dc.user_id = ds.user_id
+                          AND dc.summary_date = ds.summary_date
+                          AND dc.mood_category_id = ?
+         WHERE  ds.user_id = ?
+                AND ds.summary_date BETWEEN ? AND ?
+                AND ds.tag_count > 0),
+     qualifying_days
+     AS (SELECT *
+         FROM   daily_data
+         WHERE  daily_avg_rating %s ?
+                AND daily_target_percentage >= ?),
+     numbered_days
+     AS (SELECT date,
+                Row_number()
+                  OVER(
+                    ORDER BY date) AS rn
+         FROM   qualifying_days),
+     streaks
+     AS (SELECT Min(date) AS start_date,
+                Max(date) AS end_date,
+                Count(*)  AS streak_length
+         FROM   numbered_days
+         GROUP  BY Date_add(date, interval - rn day))
+SELECT start_date,
+       end_date,
+       streak_length
+FROM   streaks
+WHERE  streak_length >= 2
+ORDER  BY start_date;`
+
+var daysQuery = `WITH daily_data
+     AS (SELECT ds.summary_date                                     AS date,
+                ds.avg_rating                                       AS daily_avg_rating,
+                COALESCE(dc.tag_count, 0)                           AS daily_target_count,
+                ds.tag_count                                        AS daily_total_count,
+                COALESCE(dc.tag_count, 0) * 100.0 / ds.tag_count    AS daily_target_percentage
+         FROM   daily_mood_summary ds
+                LEFT JOIN daily_mood_category_count dc
+                       ON dc.user_id = ds.user_id
+                          AND dc.summary_date = ds.summary_date
+                          AND dc.mood_category_id = ?
+         WHERE  ds.user_id = ?
+                AND ds.summary_date BETWEEN ? AND ?
+                AND ds.tag_count > 0),
+     qualifying_days
+     AS (SELECT *
+         FROM   daily_data
+         WHERE  daily_avg_rating %s ?
+                AND daily_target_percentage >= ?)
+SELECT   qd.date,
+         ml.created_at,
+         ml.mood_log_id,
+         ml.mood_rating,
+         ml.note,
+         ml.note_key_version IS NOT NULL                                    AS note_encrypted,
+         group_concat(mt.NAME ORDER BY mt.NAME separator ', ')              AS mood_tags,
+         group_concat(mt.mood_tag_id ORDER BY mt.mood_tag_id separator ',') AS mood_tag_ids,
+         qd.daily_avg_rating,
+         qd.daily_target_count,
+         qd.daily_total_count,
+         qd.daily_target_percentage
+FROM     qualifying_days qd
+JOIN     mood_log ml
+ON       ml.user_id = ?
+AND      ml.created_at BETWEEN qd.date - interval 1 day AND qd.date + interval 2 day
+AND      date(local_datetime(ml.user_id, ml.created_at)) = qd.date
+JOIN     mood_log_mood_tag mlmt
+ON       ml.mood_log_id = mlmt.mood_log_id
+JOIN     mood_tag mt
+ON       mlmt.mood_tag_id = mt.mood_tag_id
+GROUP BY qd.date,
+         ml.mood_log_id,
+         ml.created_at,
+         ml.mood_rating,
+         ml.note,
+         ml.note_key_version,
+         qd.daily_avg_rating,
+         qd.daily_target_count,
+         qd.daily_total_count,
+         qd.daily_target_percentage
+ORDER BY qd.date,
+         ml.created_at;`
+
+// numDaysQuery counts the days daysQuery would return without loading them
+var numDaysQuery = `SELECT Count(*)
+FROM   daily_mood_summary ds
+       LEFT JOIN daily_mood_category_count dc
+              ON dc.user_id = ds.user_id
+                 AND dc.summary_date = ds.summary_date
+                 AND dc.mood_category_id = ?
+WHERE  ds.user_id = ?
+       AND ds.summary_date BETWEEN ? AND ?
+       AND ds.tag_count > 0
+       AND ds.avg_rating %s ?
+       AND COALESCE(dc.tag_count, 0) * 100.0 / ds.tag_count >= ?;`
 
 var stdDevQuery = `SELECT Stddev_pop(mood_rating) AS std_dev
 FROM   mood_log
 WHERE  user_id = ?
-       AND Date(created_at) BETWEEN ? AND ?;`
+       AND created_at >= ? AND created_at < ?;`
 
 var moodMovingAvgQuery = `WITH first_query
-     AS (SELECT DATE(created_at) AS DATE,
-                Avg(mood_rating) AS daily_avg
-         FROM   mood_log
+     AS (SELECT summary_date AS DATE,
+                avg_rating   AS daily_avg
+         FROM   daily_mood_summary
          WHERE  user_id = ?
-                AND DATE(created_at) BETWEEN ? AND ?
-         GROUP  BY DATE(created_at)),
+                AND summary_date BETWEEN ? AND ?),
      second_query
      AS (SELECT DATE,
                 Avg(daily_avg)
@@ -143,15 +127,20 @@ SELECT *
 FROM   second_query
 ORDER  BY DATE;`
 
-var journalEntriesQuery = `SELECT *
+var journalEntriesQuery = `SELECT mood_log_id,
+       user_id,
+       mood_rating,
+       note,
+       note_key_version IS NOT NULL AS note_encrypted,
+       created_at
 FROM   mood_log
-WHERE  user_id = ? and DATE(created_at) BETWEEN ? AND ?`
+WHERE  user_id = ? and created_at >= ? AND created_at < ?`
 
 var moodTagFrequenciesQuery = `WITH first_query
      AS (SELECT ml.mood_log_id,
                 mlmt.mood_tag_id,
                 mt.NAME,
-                Date(ml.created_at)    AS date,
+                Date(local_datetime(ml.user_id, ml.created_at))    AS date,
                 Count(mlmt.mood_tag_id) AS mood_tag_id_count
          FROM   mood_log ml
                 INNER JOIN mood_log_mood_tag mlmt
@@ -159,11 +148,11 @@ var moodTagFrequenciesQuery = `WITH first_query
                 INNER JOIN mood_tag mt
                         ON mlmt.mood_tag_id = mt.mood_tag_id
          WHERE  ml.user_id = ?
-                AND Date(ml.created_at) BETWEEN ? AND ?
+                AND ml.created_at >= ? AND ml.created_at < ?
          GROUP  BY mlmt.mood_tag_id,
                    mt.NAME,
                    ml.mood_log_id,
-                   Date(ml.created_at)),
+                   Date(local_datetime(ml.user_id, ml.created_at))),
      second_query
      AS (SELECT NAME,
                 Sum(mood_tag_id_count)                      AS mood_tag_id_count
@@ -177,15 +166,256 @@ var moodTagFrequenciesQuery = `WITH first_query
 SELECT *
 FROM   second_query;`
 
-var AvgMoodRatingQuery = `WITH first_query
-     AS (SELECT Date(created_at) AS date,
-                AVG(mood_rating) AS daily_avg_rating
+var AvgMoodRatingQuery = `SELECT Avg(avg_rating) AS period_mood_rating_avg
+FROM   daily_mood_summary
+WHERE  user_id = ?
+       AND summary_date BETWEEN ? AND ?;`
+
+var clinicalDaysQuery = `WITH first_query
+     AS (SELECT Date(local_datetime(user_id, created_at)) AS date,
+                Avg(mood_rating) AS daily_avg_rating
          FROM   mood_log
-         WHERE  user_id = ? 
-                AND Date(created_at) BETWEEN ? AND ? 
-         GROUP  BY Date(created_at)),
+         WHERE  user_id = ?
+                AND created_at >= ? AND created_at < ?
+         GROUP  BY Date(local_datetime(user_id, created_at))),
      second_query
-     AS (SELECT AVG(daily_avg_rating) AS period_mood_rating_avg
-         FROM   first_query)
-SELECT period_mood_rating_avg
-FROM   second_query;`
+     AS (SELECT Date(local_datetime(ml.user_id, ml.created_at))                                       AS date,
+                Sum(CASE WHEN mt.NAME = 'Manic' THEN 1 ELSE 0 END)       AS manic_count,
+                Sum(CASE WHEN mt.NAME = 'Hypomanic' THEN 1 ELSE 0 END)   AS hypomanic_count,
+                Sum(CASE WHEN mt.NAME = 'Depressed' THEN 1 ELSE 0 END)   AS depressed_count,
+                Sum(CASE WHEN mt.NAME = 'Mixed State' THEN 1 ELSE 0 END) AS mixed_state_count,
+                Sum(CASE WHEN mt.NAME = 'Irritable' THEN 1 ELSE 0 END)   AS irritable_count
+         FROM   mood_log ml
+                INNER JOIN mood_log_mood_tag mlmt
+                        ON ml.mood_log_id = mlmt.mood_log_id
+                INNER JOIN mood_tag mt
+                        ON mlmt.mood_tag_id = mt.mood_tag_id
+         WHERE  ml.user_id = ?
+                AND ml.created_at >= ? AND ml.created_at < ?
+         GROUP  BY Date(local_datetime(ml.user_id, ml.created_at))),
+     third_query
+     AS (SELECT sleep_date,
+                Sum(hours_slept) AS hours_slept
+         FROM   sleep_log
+         WHERE  user_id = ?
+                AND sleep_date BETWEEN ? AND ?
+         GROUP  BY sleep_date)
+SELECT fq.date,
+       fq.daily_avg_rating,
+       COALESCE(sq.manic_count, 0),
+       COALESCE(sq.hypomanic_count, 0),
+       COALESCE(sq.depressed_count, 0),
+       COALESCE(sq.mixed_state_count, 0),
+       COALESCE(sq.irritable_count, 0),
+       tq.hours_slept
+FROM   first_query fq
+       LEFT JOIN second_query sq
+              ON fq.date = sq.date
+       LEFT JOIN third_query tq
+              ON fq.date = tq.sleep_date
+ORDER  BY fq.date;`
+
+var insertMoodLogQuery = `INSERT INTO mood_log
+            (user_id,
+             mood_rating,
+             note,
+             created_at)
+VALUES      (?, ?, ?, ?);`
+
+// the note is encrypted once the log has an id to bind it to, updated_at is
+// kept so the log reads as written once
+var setMoodLogNoteQuery = `UPDATE mood_log
+SET    note = ?,
+       note_key_version = ?,
+       updated_at = updated_at
+WHERE  mood_log_id = ?;`
+
+var insertMoodLogMoodTagQuery = `INSERT INTO mood_log_mood_tag
+            (mood_log_id,
+             mood_tag_id)
+VALUES      (?, ?);`
+
+var dailyMoodAvgQuery = `SELECT summary_date AS date,
+       avg_rating   AS daily_avg
+FROM   daily_mood_summary
+WHERE  user_id = ?
+       AND summary_date BETWEEN ? AND ?
+ORDER  BY summary_date;`
+
+var moodBucketsQuery = `WITH buckets
+     AS (SELECT %[1]s AS period_start,
+                Avg(mood_rating)        AS avg_rating,
+                Stddev_pop(mood_rating) AS std_dev,
+                Count(*)                AS log_count
+         FROM   mood_log
+         WHERE  user_id = ?
+                AND created_at >= ? AND created_at < ?
+         GROUP  BY period_start),
+     tag_counts
+     AS (SELECT %[2]s AS period_start,
+                mt.name,
+                Count(*) AS tag_count
+         FROM   mood_log ml
+                JOIN mood_log_mood_tag mlmt
+                  ON ml.mood_log_id = mlmt.mood_log_id
+                JOIN mood_tag mt
+                  ON mlmt.mood_tag_id = mt.mood_tag_id
+         WHERE  ml.user_id = ?
+                AND ml.created_at >= ? AND ml.created_at < ?
+         GROUP  BY period_start,
+                   mt.name),
+     top_tags
+     AS (SELECT period_start,
+                name,
+                Row_number()
+                  OVER(
+                    partition BY period_start
+                    ORDER BY tag_count DESC, name) AS rn
+         FROM   tag_counts)
+SELECT b.period_start,
+       b.avg_rating,
+       b.std_dev,
+       b.log_count,
+       t.name
+FROM   buckets b
+       LEFT JOIN top_tags t
+              ON b.period_start = t.period_start
+                 AND t.rn = 1
+ORDER  BY b.period_start;`
+
+// time of day buckets are morning 05-11, afternoon 12-16, evening 17-21 and night 22-04,
+// the weekday is taken 5 hours earlier so a night's early hours stay on the
+// night's first day
+var moodPatternCellsQuery = `SELECT Dayname(local_datetime(user_id, created_at) - interval 5 hour) AS weekday,
+       CASE
+         WHEN Hour(local_datetime(user_id, created_at)) BETWEEN 5 AND 11 THEN 'morning'
+         WHEN Hour(local_datetime(user_id, created_at)) BETWEEN 12 AND 16 THEN 'afternoon'
+         WHEN Hour(local_datetime(user_id, created_at)) BETWEEN 17 AND 21 THEN 'evening'
+         ELSE 'night'
+       END                 AS time_of_day,
+       Avg(mood_rating)    AS avg_rating,
+       Count(*)            AS log_count
+FROM   mood_log
+WHERE  user_id = ?
+       AND created_at >= ? AND created_at < ?
+GROUP  BY weekday,
+          time_of_day;`
+
+var moodPatternTagsQuery = `SELECT Dayname(local_datetime(ml.user_id, ml.created_at) - interval 5 hour) AS weekday,
+       CASE
+         WHEN Hour(local_datetime(ml.user_id, ml.created_at)) BETWEEN 5 AND 11 THEN 'morning'
+         WHEN Hour(local_datetime(ml.user_id, ml.created_at)) BETWEEN 12 AND 16 THEN 'afternoon'
+         WHEN Hour(local_datetime(ml.user_id, ml.created_at)) BETWEEN 17 AND 21 THEN 'evening'
+         ELSE 'night'
+       END                    AS time_of_day,
+       mt.name,
+       Count(*)               AS tag_count
+FROM   mood_log ml
+       JOIN mood_log_mood_tag mlmt
+         ON ml.mood_log_id = mlmt.mood_log_id
+       JOIN mood_tag mt
+         ON mlmt.mood_tag_id = mt.mood_tag_id
+WHERE  ml.user_id = ?
+       AND ml.created_at >= ? AND ml.created_at < ?
+GROUP  BY weekday,
+          time_of_day,
+          mt.name;`
+
+var moodSwingDaysQuery = `SELECT Date(local_datetime(user_id, created_at))        AS date,
+       Count(*)                AS num_logs,
+       Min(mood_rating)        AS min_rating,
+       Max(mood_rating)        AS max_rating,
+       Stddev_pop(mood_rating) AS std_dev
+FROM   mood_log
+WHERE  user_id = ?
+       AND created_at >= ? AND created_at < ?
+GROUP  BY Date(local_datetime(user_id, created_at))
+HAVING Count(*) >= 2
+ORDER  BY date;`
+
+var moodRatingSummaryQuery = `SELECT Count(*)                          AS log_count,
+       Coalesce(Avg(mood_rating), 0)        AS avg_rating,
+       Coalesce(Stddev_pop(mood_rating), 0) AS std_dev
+FROM   mood_log
+WHERE  user_id = ?
+       AND created_at >= ? AND created_at < ?;`
+
+var moodTagRatingsQuery = `SELECT mt.name,
+       mc.name                    AS category,
+       Count(*)                   AS log_count,
+       Avg(ml.mood_rating)        AS avg_rating,
+       Stddev_pop(ml.mood_rating) AS std_dev
+FROM   mood_log ml
+       JOIN mood_log_mood_tag mlmt
+         ON ml.mood_log_id = mlmt.mood_log_id
+       JOIN mood_tag mt
+         ON mlmt.mood_tag_id = mt.mood_tag_id
+       JOIN mood_category mc
+         ON mt.mood_category_id = mc.mood_category_id
+WHERE  ml.user_id = ?
+       AND ml.created_at >= ? AND ml.created_at < ?
+GROUP  BY mt.mood_tag_id,
+          mt.name,
+          mc.name
+ORDER  BY log_count DESC,
+          mt.name;`
+
+var moodTagPairsQuery = `SELECT mt_a.name,
+       mt_b.name,
+       Count(*)            AS log_count,
+       Avg(ml.mood_rating) AS avg_rating
+FROM   mood_log ml
+       JOIN mood_log_mood_tag mlmt_a
+         ON ml.mood_log_id = mlmt_a.mood_log_id
+       JOIN mood_log_mood_tag mlmt_b
+         ON mlmt_a.mood_log_id = mlmt_b.mood_log_id
+            AND mlmt_a.mood_tag_id < mlmt_b.mood_tag_id
+       JOIN mood_tag mt_a
+         ON mlmt_a.mood_tag_id = mt_a.mood_tag_id
+       JOIN mood_tag mt_b
+         ON mlmt_b.mood_tag_id = mt_b.mood_tag_id
+WHERE  ml.user_id = ?
+       AND ml.created_at >= ? AND ml.created_at < ?
+GROUP  BY mlmt_a.mood_tag_id,
+          mlmt_b.mood_tag_id,
+          mt_a.name,
+          mt_b.name
+ORDER  BY log_count DESC;`
+
+// searchMoodLogsQuery takes the blind indexes of the search words as a comma
+// separated hex list and how many distinct words there are, a note is indexed
+// under one key version at a time so every word has to match once. The tag
+// filter is a comma separated list of names that must all be on the log
+var searchMoodLogsQuery = `SELECT ml.mood_log_id,
+       ml.user_id,
+       ml.mood_rating,
+       ml.note,
+       ml.note_key_version IS NOT NULL AS note_encrypted,
+       ml.created_at,
+       (SELECT group_concat(mt.NAME ORDER BY mt.NAME separator ',')
+        FROM   mood_log_mood_tag mlmt
+               JOIN mood_tag mt
+                 ON mlmt.mood_tag_id = mt.mood_tag_id
+        WHERE  mlmt.mood_log_id = ml.mood_log_id) AS mood_tags
+FROM   mood_log ml
+WHERE  ml.user_id = ?
+       AND ml.note IS NOT NULL
+       AND ml.note <> ''
+       AND ml.created_at >= ? AND ml.created_at < ?
+       AND ( ? = 0
+              OR ml.mood_log_id IN (SELECT mlnt.mood_log_id
+                                    FROM   mood_log_note_token mlnt
+                                    WHERE  mlnt.user_id = ?
+                                           AND Find_in_set(Lower(Hex(mlnt.token)), ?) > 0
+                                    GROUP  BY mlnt.mood_log_id
+                                    HAVING Count(*) = ?) )
+       AND ( ? = 0
+              OR ml.mood_log_id IN (SELECT mlmt.mood_log_id
+                                    FROM   mood_log_mood_tag mlmt
+                                           JOIN mood_tag mt
+                                             ON mlmt.mood_tag_id = mt.mood_tag_id
+                                    WHERE  Find_in_set(mt.NAME, ?) > 0
+                                    GROUP  BY mlmt.mood_log_id
+                                    HAVING Count(*) = ?) )
+ORDER  BY ml.created_at DESC
+LIMIT  ?;`
@@ -12,19 +12,34 @@ import (
 )
 
 type MoodLogRepository struct {
-	db *sql.DB
+	db         *sql.DB
+	noteCipher *NoteCipher
 }
 
-func NewMoodLogRepository(dbConnection *sql.DB) *MoodLogRepository {
+func NewMoodLogRepository(dbConnection *sql.DB, noteCipher *NoteCipher) *MoodLogRepository {
 	return &MoodLogRepository{
-		db: dbConnection,
+		db:         dbConnection,
+		noteCipher: noteCipher,
 	}
 }
 
 func (mlr *MoodLogRepository) Streaks(userID string, startDate string, endDate string, operator string, moodRating string, moodCategoryID string, targetPercentage string) []models.Streak {
 
+	streaks := mlr.StreakRanges(userID, startDate, endDate, operator, moodRating, moodCategoryID, targetPercentage)
+
+	for i := 0; i < len(streaks); i++ {
+		streakDays := mlr.Days(userID, streaks[i].StartDate, streaks[i].EndDate, operator, moodRating, moodCategoryID, targetPercentage)
+		streaks[i].Days = append(streaks[i].Days, streakDays...)
+	}
+
+	return streaks
+}
+
+// StreakRanges is Streaks without loading the days of each streak
+func (mlr *MoodLogRepository) StreakRanges(userID string, startDate string, endDate string, operator string, moodRating string, moodCategoryID string, targetPercentage string) []models.Streak {
+
 	query := fmt.Sprintf(streaksQuery, operator)
-	rows, queryErr := mlr.db.Query(query, moodCategoryID, moodCategoryID, userID, startDate, endDate, moodRating, targetPercentage)
+	rows, queryErr := mlr.db.Query(query, moodCategoryID, userID, startDate, endDate, moodRating, targetPercentage)
 	if queryErr != nil {
 		panic(queryErr)
 	}
@@ -42,14 +57,10 @@ func (mlr *MoodLogRepository) Streaks(userID string, startDate string, endDate s
 		if scanErr != nil {
 			panic(scanErr)
 		}
+		streak.Days = make([]models.Day, 0)
 		streaks = append(streaks, streak)
 	}
 
-	for i := 0; i < len(streaks); i++ {
-		streakDays := mlr.Days(userID, streaks[i].StartDate, streaks[i].EndDate, operator, moodRating, moodCategoryID, targetPercentage)
-		streaks[i].Days = append(streaks[i].Days, streakDays...)
-	}
-
 	if streaks == nil {
 		return make([]models.Streak, 0)
 	}
@@ -61,7 +72,7 @@ func (mlr *MoodLogRepository) Days(userID string, startDate string, endDate stri
 
 	query := fmt.Sprintf(daysQuery, operator)
 
-	rows, queryErr := mlr.db.Query(query, moodCategoryID, moodCategoryID, userID, startDate, endDate, moodRating, targetPercentage)
+	rows, queryErr := mlr.db.Query(query, moodCategoryID, userID, startDate, endDate, moodRating, targetPercentage, userID)
 	if queryErr != nil {
 		panic(queryErr)
 	}
@@ -75,6 +86,7 @@ func (mlr *MoodLogRepository) Days(userID string, startDate string, endDate stri
 		var moodLogID int
 		var moodRating int
 		var note string
+		var noteEncrypted bool
 		var moodTags string
 		var moodTagIDs string
 		var dailyAvgRating float64
@@ -88,6 +100,7 @@ func (mlr *MoodLogRepository) Days(userID string, startDate string, endDate stri
 			&moodLogID,
 			&moodRating,
 			&note,
+			&noteEncrypted,
 			&moodTags,
 			&moodTagIDs,
 			&dailyAvgRating,
@@ -115,13 +128,18 @@ func (mlr *MoodLogRepository) Days(userID string, startDate string, endDate stri
 			tags = append(tags, trimmed)
 		}
 
+		plaintext, decryptErr := mlr.noteCipher.Decrypt(userID, MoodLogNote(moodLogID), note, noteEncrypted)
+		if decryptErr != nil {
+			panic(decryptErr)
+		}
+
 		// Add journal entry to this day
 		entry := models.MoodLog{
 			CreatedAt:  createdAt,
 			UserID:     userID,
 			MoodLogID:  moodLogID,
 			MoodRating: moodRating,
-			Note:       note,
+			Note:       plaintext,
 			MoodTags:   tags,
 		}
 
@@ -162,9 +180,33 @@ func (mlr *MoodLogRepository) Days(userID string, startDate string, endDate stri
 	return days
 }
 
+// NumDays counts the days Days would return
+func (mlr *MoodLogRepository) NumDays(userID string, startDate string, endDate string, operator string, moodRating string, moodCategoryID string, targetPercentage string) int {
+
+	query := fmt.Sprintf(numDaysQuery, operator)
+
+	rows, queryErr := mlr.db.Query(query, moodCategoryID, userID, startDate, endDate, moodRating, targetPercentage)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var numDays int
+	if next := rows.Next(); next {
+		scanErr := rows.Scan(&numDays)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+	}
+
+	return numDays
+}
+
 func (mlr *MoodLogRepository) StandardDeviation(userID string, startDate string, endDate string) float64 {
 
-	rows, queryErr := mlr.db.Query(stdDevQuery, userID, startDate, endDate)
+	from, to := utcBounds(mlr.db, userID, startDate, endDate)
+
+	rows, queryErr := mlr.db.Query(stdDevQuery, userID, from, to)
 	if queryErr != nil {
 		panic(queryErr)
 	}
@@ -219,7 +261,9 @@ func (mlr *MoodLogRepository) MovingAverages(userID string, startDate string, en
 
 func (mlr *MoodLogRepository) MoodLogs(userID string, startDate string, endDate string) []models.MoodLog {
 
-	rows, err := mlr.db.Query(journalEntriesQuery, userID, startDate, endDate)
+	from, to := utcBounds(mlr.db, userID, startDate, endDate)
+
+	rows, err := mlr.db.Query(journalEntriesQuery, userID, from, to)
 	if err != nil {
 		panic(err)
 	}
@@ -229,17 +273,25 @@ func (mlr *MoodLogRepository) MoodLogs(userID string, startDate string, endDate
 	var moodLog models.MoodLog
 
 	for rows.Next() {
+		var note sql.NullString
+		var noteEncrypted bool
 		scanErr := rows.Scan(
 			&moodLog.MoodLogID,
 			&moodLog.UserID,
 			&moodLog.MoodRating,
-			&moodLog.Note,
+			&note,
+			&noteEncrypted,
 			&moodLog.CreatedAt,
 		)
 		if scanErr != nil {
 			panic(scanErr)
 		}
 
+		plaintext, decryptErr := mlr.noteCipher.Decrypt(userID, MoodLogNote(moodLog.MoodLogID), note.String, noteEncrypted)
+		if decryptErr != nil {
+			panic(decryptErr)
+		}
+		moodLog.Note = plaintext
 		moodLogs = append(moodLogs, moodLog)
 	}
 
@@ -252,7 +304,9 @@ func (mlr *MoodLogRepository) MoodLogs(userID string, startDate string, endDate
 
 func (mlr *MoodLogRepository) MoodTagFrequencies(userID string, startDate string, endDate string) []models.TagFrequency {
 
-	rows, queryErr := mlr.db.Query(moodTagFrequenciesQuery, userID, startDate, endDate)
+	from, to := utcBounds(mlr.db, userID, startDate, endDate)
+
+	rows, queryErr := mlr.db.Query(moodTagFrequenciesQuery, userID, from, to)
 	if queryErr != nil {
 		panic(queryErr)
 	}
@@ -303,3 +357,412 @@ func (mlr *MoodLogRepository) AvgMoodRating(userID string, startDate string, end
 
 	return avgMoodRatingPeriod.Float64
 }
+
+func (mlr *MoodLogRepository) ClinicalDays(userID string, startDate string, endDate string) []models.ClinicalDay {
+
+	from, to := utcBounds(mlr.db, userID, startDate, endDate)
+
+	rows, queryErr := mlr.db.Query(clinicalDaysQuery, userID, from, to, userID, from, to, userID, startDate, endDate)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var clinicalDays []models.ClinicalDay
+
+	for rows.Next() {
+		var clinicalDay models.ClinicalDay
+		var hoursSlept sql.NullFloat64
+
+		scanErr := rows.Scan(
+			&clinicalDay.Date,
+			&clinicalDay.DailyAvgRating,
+			&clinicalDay.ManicCount,
+			&clinicalDay.HypomanicCount,
+			&clinicalDay.DepressedCount,
+			&clinicalDay.MixedStateCount,
+			&clinicalDay.IrritableCount,
+			&hoursSlept,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+
+		clinicalDay.HoursSlept = hoursSlept.Float64
+		clinicalDay.SleepLogged = hoursSlept.Valid
+
+		clinicalDays = append(clinicalDays, clinicalDay)
+	}
+
+	if clinicalDays == nil {
+		return make([]models.ClinicalDay, 0)
+	}
+
+	return clinicalDays
+}
+
+// InsertMoodLogs writes the logs and their tags in a single transaction, the
+// tag ids are looked up by name in moodTagIDs. Notes are stored encrypted with
+// the blind indexes of their words, bound to the log so they're written once
+// it has an id. The import job's progress is updated in the same transaction
+// so it never counts logs that weren't written
+func (mlr *MoodLogRepository) InsertMoodLogs(userID string, moodLogs []models.MoodLog, moodTagIDs map[string]int, job models.ImportJob) {
+
+	tx, txErr := mlr.db.Begin()
+	if txErr != nil {
+		panic(txErr)
+	}
+	defer tx.Rollback()
+
+	// the trigger publishes no mood_log.created for the batch, the importer
+	// publishes import.completed once the job is done. Deferred after the
+	// rollback so it runs before it, once committed it's a no-op
+	_, startErr := tx.Exec(startImportBatchQuery, job.JobID)
+	if startErr != nil {
+		panic(startErr)
+	}
+	defer tx.Exec(endImportBatchQuery)
+
+	for _, moodLog := range moodLogs {
+		result, execErr := tx.Exec(insertMoodLogQuery, userID, moodLog.MoodRating, nil, moodLog.CreatedAt)
+		if execErr != nil {
+			panic(execErr)
+		}
+
+		moodLogID, idErr := result.LastInsertId()
+		if idErr != nil {
+			panic(idErr)
+		}
+
+		if moodLog.Note != "" {
+			note, keyVersion := mlr.noteCipher.Encrypt(userID, MoodLogNote(int(moodLogID)), moodLog.Note)
+			_, execErr := tx.Exec(setMoodLogNoteQuery, note, keyVersion, moodLogID)
+			if execErr != nil {
+				panic(execErr)
+			}
+		}
+
+		for _, token := range mlr.noteCipher.NoteTokens(userID, moodLog.Note) {
+			_, execErr := tx.Exec(insertNoteTokenQuery, moodLogID, userID, token)
+			if execErr != nil {
+				panic(execErr)
+			}
+		}
+
+		for _, tag := range moodLog.MoodTags {
+			_, execErr := tx.Exec(insertMoodLogMoodTagQuery, moodLogID, moodTagIDs[tag])
+			if execErr != nil {
+				panic(execErr)
+			}
+		}
+	}
+
+	_, execErr := tx.Exec(updateImportJobProgressQuery, job.ProcessedRows, job.ImportedCount, job.DuplicateCount, job.SkippedCount, job.JobID)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	_, endErr := tx.Exec(endImportBatchQuery)
+	if endErr != nil {
+		panic(endErr)
+	}
+
+	commitErr := tx.Commit()
+	if commitErr != nil {
+		panic(commitErr)
+	}
+}
+
+func (mlr *MoodLogRepository) DailyAverages(userID string, startDate string, endDate string) []models.DailyAverage {
+
+	rows, queryErr := mlr.db.Query(dailyMoodAvgQuery, userID, startDate, endDate)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var dailyAverage models.DailyAverage
+	var dailyAverages []models.DailyAverage
+
+	for rows.Next() {
+		scanErr := rows.Scan(
+			&dailyAverage.Date,
+			&dailyAverage.DailyAvg,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+
+		dailyAverages = append(dailyAverages, dailyAverage)
+	}
+
+	if dailyAverages == nil {
+		return make([]models.DailyAverage, 0)
+	}
+
+	return dailyAverages
+}
+
+func (mlr *MoodLogRepository) Buckets(userID string, startDate string, endDate string, granularity string) []models.Bucket {
+
+	query := fmt.Sprintf(moodBucketsQuery, periodStartExpression(granularity, "local_datetime(user_id, created_at)"), periodStartExpression(granularity, "local_datetime(ml.user_id, ml.created_at)"))
+
+	from, to := utcBounds(mlr.db, userID, startDate, endDate)
+
+	rows, queryErr := mlr.db.Query(query, userID, from, to, userID, from, to)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	return scanBuckets(rows)
+}
+
+// PatternCells returns the average rating, log count and tag counts for every
+// weekday and time of day combination that has logs
+func (mlr *MoodLogRepository) PatternCells(userID string, startDate string, endDate string) []models.MoodPattern {
+
+	from, to := utcBounds(mlr.db, userID, startDate, endDate)
+
+	rows, queryErr := mlr.db.Query(moodPatternCellsQuery, userID, from, to)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var cells []models.MoodPattern
+	cellIndex := make(map[string]int)
+
+	for rows.Next() {
+		cell := models.MoodPattern{TopMoods: make([]models.TagFrequency, 0)}
+		scanErr := rows.Scan(
+			&cell.Weekday,
+			&cell.TimeOfDay,
+			&cell.AvgMoodRating,
+			&cell.LogCount,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+
+		cellIndex[cell.Weekday+" "+cell.TimeOfDay] = len(cells)
+		cells = append(cells, cell)
+	}
+
+	tagRows, tagQueryErr := mlr.db.Query(moodPatternTagsQuery, userID, from, to)
+	if tagQueryErr != nil {
+		panic(tagQueryErr)
+	}
+	defer tagRows.Close()
+
+	for tagRows.Next() {
+		var weekday string
+		var timeOfDay string
+		var tagFrequency models.TagFrequency
+		scanErr := tagRows.Scan(
+			&weekday,
+			&timeOfDay,
+			&tagFrequency.TagName,
+			&tagFrequency.Count,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+
+		if i, exists := cellIndex[weekday+" "+timeOfDay]; exists {
+			cells[i].TopMoods = append(cells[i].TopMoods, tagFrequency)
+		}
+	}
+
+	if cells == nil {
+		return make([]models.MoodPattern, 0)
+	}
+
+	return cells
+}
+
+func (mlr *MoodLogRepository) SwingDays(userID string, startDate string, endDate string) []models.MoodSwingDay {
+
+	from, to := utcBounds(mlr.db, userID, startDate, endDate)
+
+	rows, queryErr := mlr.db.Query(moodSwingDaysQuery, userID, from, to)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var swingDays []models.MoodSwingDay
+
+	for rows.Next() {
+		var swingDay models.MoodSwingDay
+		scanErr := rows.Scan(
+			&swingDay.Date,
+			&swingDay.NumLogs,
+			&swingDay.MinRating,
+			&swingDay.MaxRating,
+			&swingDay.StdDeviation,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+
+		swingDay.Swing = swingDay.MaxRating - swingDay.MinRating
+		swingDays = append(swingDays, swingDay)
+	}
+
+	if swingDays == nil {
+		return make([]models.MoodSwingDay, 0)
+	}
+
+	return swingDays
+}
+
+// RatingSummary is the number of logs, average rating and population standard
+// deviation over the range
+func (mlr *MoodLogRepository) RatingSummary(userID string, startDate string, endDate string) (int, float64, float64) {
+
+	from, to := utcBounds(mlr.db, userID, startDate, endDate)
+
+	rows, queryErr := mlr.db.Query(moodRatingSummaryQuery, userID, from, to)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var logCount int
+	var avgRating float64
+	var stdDeviation float64
+	if next := rows.Next(); next {
+		scanErr := rows.Scan(&logCount, &avgRating, &stdDeviation)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+	}
+
+	return logCount, avgRating, stdDeviation
+}
+
+// TagRatings has the rating statistics of the logs with each tag, most used
+// tags first
+func (mlr *MoodLogRepository) TagRatings(userID string, startDate string, endDate string) []models.TagRatingAssociation {
+
+	from, to := utcBounds(mlr.db, userID, startDate, endDate)
+
+	rows, queryErr := mlr.db.Query(moodTagRatingsQuery, userID, from, to)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var tagRatings []models.TagRatingAssociation
+
+	for rows.Next() {
+		var tagRating models.TagRatingAssociation
+		scanErr := rows.Scan(
+			&tagRating.TagName,
+			&tagRating.Category,
+			&tagRating.LogCount,
+			&tagRating.AvgMoodRating,
+			&tagRating.StdDeviation,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+
+		tagRatings = append(tagRatings, tagRating)
+	}
+
+	if tagRatings == nil {
+		return make([]models.TagRatingAssociation, 0)
+	}
+
+	return tagRatings
+}
+
+// TagPairs counts the logs with each pair of tags that appear together
+func (mlr *MoodLogRepository) TagPairs(userID string, startDate string, endDate string) []models.TagPair {
+
+	from, to := utcBounds(mlr.db, userID, startDate, endDate)
+
+	rows, queryErr := mlr.db.Query(moodTagPairsQuery, userID, from, to)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var tagPairs []models.TagPair
+
+	for rows.Next() {
+		var tagPair models.TagPair
+		scanErr := rows.Scan(
+			&tagPair.TagA,
+			&tagPair.TagB,
+			&tagPair.Count,
+			&tagPair.AvgMoodRating,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+
+		tagPairs = append(tagPairs, tagPair)
+	}
+
+	if tagPairs == nil {
+		return make([]models.TagPair, 0)
+	}
+
+	return tagPairs
+}
+
+// SearchNotes returns the user's mood logs holding every one of the words in
+// their note, newest first and at most limit of them. The notes are encrypted
+// so the words are matched through their blind indexes, with no words every
+// log with a note is returned for the caller to check
+func (mlr *MoodLogRepository) SearchNotes(userID string, words []string, startDate string, endDate string, tags []string, limit int) []models.MoodLogSearchResult {
+
+	tokenList := strings.Join(mlr.noteCipher.SearchTokens(userID, words), ",")
+	tagList := strings.Join(tags, ",")
+
+	from, to := utcBounds(mlr.db, userID, startDate, endDate)
+
+	rows, queryErr := mlr.db.Query(searchMoodLogsQuery, userID, from, to, len(words), userID, tokenList, len(words), len(tags), tagList, len(tags), limit)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	results := make([]models.MoodLogSearchResult, 0)
+
+	for rows.Next() {
+		var result models.MoodLogSearchResult
+		var noteEncrypted bool
+		var moodTags sql.NullString
+
+		scanErr := rows.Scan(
+			&result.MoodLog.MoodLogID,
+			&result.MoodLog.UserID,
+			&result.MoodLog.MoodRating,
+			&result.MoodLog.Note,
+			&noteEncrypted,
+			&result.MoodLog.CreatedAt,
+			&moodTags,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+
+		plaintext, decryptErr := mlr.noteCipher.Decrypt(userID, MoodLogNote(result.MoodLog.MoodLogID), result.MoodLog.Note, noteEncrypted)
+		if decryptErr != nil {
+			panic(decryptErr)
+		}
+		result.MoodLog.Note = plaintext
+		result.MoodLog.MoodTags = make([]string, 0)
+		if moodTags.Valid {
+			result.MoodLog.MoodTags = strings.Split(moodTags.String, ",")
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
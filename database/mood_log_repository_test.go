@@ -0,0 +1,34 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+func TestInsertMoodLogsPublishesNoEvents(t *testing.T) {
+	noteCipher, db := testNoteCipher(t)
+	// one connection, so the insert after the import gets the importer's
+	db.SetMaxOpenConns(1)
+
+	moodLogRepository := NewMoodLogRepository(db, noteCipher)
+	jobID := NewImportRepository(db).CreateJob("1", "daylio", false, 2)
+
+	moodLogs := []models.MoodLog{
+		{MoodRating: 4, Note: "imported", CreatedAt: "2025-03-01 09:00:00"},
+		{MoodRating: 6, CreatedAt: "2025-03-02 09:00:00"},
+	}
+	moodLogRepository.InsertMoodLogs("1", moodLogs, nil, models.ImportJob{JobID: jobID, ProcessedRows: 2, ImportedCount: 2})
+
+	if events := queryRows(t, db, `SELECT Count(*) FROM webhook_event`); events[0] != "0" {
+		t.Errorf("the import published %s events, want none from the trigger", events[0])
+	}
+
+	// the connection doesn't stay in the import
+	if _, execErr := db.Exec(`INSERT INTO mood_log (user_id, mood_rating) VALUES (1, 5)`); execErr != nil {
+		t.Fatal(execErr)
+	}
+	if events := queryRows(t, db, `SELECT event_type FROM webhook_event`); len(events) != 1 || events[0] != models.EventMoodLogCreated {
+		t.Errorf("events after a logged mood = %q, want one mood_log.created", events)
+	}
+}
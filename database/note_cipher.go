@@ -0,0 +1,584 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/envelope"
+)
+
+// unwrapped data keys are cached for a short while, long enough to spare the
+// lookups during a request and short enough that a key shredded or rotated by
+// another process stops being used soon after
+const dataKeyCacheTTL = 1 * time.Minute
+
+var errKeysShredded = errors.New("the user's data keys have been shredded")
+
+// NoteCipher encrypts and decrypts notes with the user's data keys so the
+// repositories can store ciphertext and hand back plaintext
+type NoteCipher struct {
+	db         *sql.DB
+	masterKeys envelope.MasterKeys
+	mutex      sync.Mutex
+	dataKeys   map[string]cachedDataKeys
+}
+
+type dataKey struct {
+	version  int
+	key      []byte // nil once shredded
+	indexKey []byte
+}
+
+type cachedDataKeys struct {
+	keys     []dataKey // ascending version
+	shredded bool
+	loadedAt time.Time
+}
+
+func NewNoteCipher(dbConnection *sql.DB, masterKeys envelope.MasterKeys) *NoteCipher {
+	return &NoteCipher{
+		db:         dbConnection,
+		masterKeys: masterKeys,
+		dataKeys:   make(map[string]cachedDataKeys),
+	}
+}
+
+// NoteField is the row a note is stored on, the note's ciphertext is bound to
+// it so it can't be moved onto another row
+type NoteField struct {
+	table string
+	rowID int
+}
+
+func MoodLogNote(moodLogID int) NoteField {
+	return NoteField{table: "mood_log", rowID: moodLogID}
+}
+
+func SleepLogNotes(sleepLogID int) NoteField {
+	return NoteField{table: "sleep_log", rowID: sleepLogID}
+}
+
+// Encrypt returns the stored form of the note under the user's current data
+// key and the key version to store beside it, creating the user's first key if
+// they don't have one. An empty note stays empty with no key version
+func (nc *NoteCipher) Encrypt(userID string, field NoteField, plaintext string) (string, sql.NullInt64) {
+
+	if plaintext == "" {
+		return "", sql.NullInt64{}
+	}
+
+	stored, keyVersion := nc.seal(userID, plaintext, envelope.NoteAdditionalData(userID, field.table, field.rowID))
+	return stored, sql.NullInt64{Int64: int64(keyVersion), Valid: true}
+}
+
+// Decrypt returns the plaintext of a stored note, encrypted is whether the
+// row has a key version, plaintext that was never encrypted is returned as is.
+// A note that can't be decrypted, including one whose key was shredded, is an
+// error rather than an empty note
+func (nc *NoteCipher) Decrypt(userID string, field NoteField, stored string, encrypted bool) (string, error) {
+
+	if !encrypted || stored == "" {
+		return stored, nil
+	}
+
+	return nc.open(userID, stored, envelope.NoteAdditionalData(userID, field.table, field.rowID))
+}
+
+// EncryptKeyword is Encrypt for sentiment keywords, which are always encrypted
+func (nc *NoteCipher) EncryptKeyword(userID string, keyword string) string {
+	stored, _ := nc.seal(userID, keyword, envelope.KeywordAdditionalData(userID))
+	return stored
+}
+
+func (nc *NoteCipher) DecryptKeyword(userID string, stored string) (string, error) {
+	return nc.open(userID, stored, envelope.KeywordAdditionalData(userID))
+}
+
+func (nc *NoteCipher) seal(userID string, plaintext string, additionalData []byte) (string, int) {
+
+	current, keyErr := nc.currentKey(userID)
+	if keyErr != nil {
+		panic(keyErr)
+	}
+
+	sealed, sealErr := envelope.Seal(current.key, []byte(plaintext), additionalData)
+	if sealErr != nil {
+		panic(sealErr)
+	}
+
+	return envelope.EncodeNote(current.version, sealed), current.version
+}
+
+func (nc *NoteCipher) open(userID string, stored string, additionalData []byte) (string, error) {
+
+	keyVersion, sealed, decodeErr := envelope.DecodeNote(stored)
+	if decodeErr != nil {
+		return "", fmt.Errorf("decoding note for user %s: %w", userID, decodeErr)
+	}
+
+	cached, keysErr := nc.userKeys(userID)
+	if keysErr != nil {
+		return "", keysErr
+	}
+	if cached.shredded {
+		return "", errKeysShredded
+	}
+	for _, key := range cached.keys {
+		if key.version != keyVersion {
+			continue
+		}
+		plaintext, openErr := envelope.Open(key.key, sealed, additionalData)
+		if openErr != nil {
+			return "", fmt.Errorf("decrypting note for user %s: %w", userID, openErr)
+		}
+		return string(plaintext), nil
+	}
+
+	return "", fmt.Errorf("no data key version %d for user %s", keyVersion, userID)
+}
+
+// NoteTokens are the blind indexes of the words in the note under the user's
+// current data key, stored alongside the encrypted note for search
+func (nc *NoteCipher) NoteTokens(userID string, plaintext string) [][]byte {
+
+	words := envelope.IndexWords(plaintext)
+	if len(words) == 0 {
+		return nil
+	}
+
+	current, keyErr := nc.currentKey(userID)
+	if keyErr != nil {
+		panic(keyErr)
+	}
+
+	tokens := make([][]byte, len(words))
+	for i, word := range words {
+		tokens[i] = envelope.BlindIndex(current.indexKey, word)
+	}
+
+	return tokens
+}
+
+// SearchTokens are the blind indexes of the words under every data key version
+// the user has, as hex, while a rotation is running notes are indexed under
+// more than one
+func (nc *NoteCipher) SearchTokens(userID string, words []string) []string {
+
+	cached, keysErr := nc.userKeys(userID)
+	if keysErr != nil {
+		panic(keysErr)
+	}
+
+	var tokens []string
+	for _, key := range cached.keys {
+		if key.key == nil {
+			continue
+		}
+		for _, word := range words {
+			tokens = append(tokens, hex.EncodeToString(envelope.BlindIndex(key.indexKey, word)))
+		}
+	}
+
+	return tokens
+}
+
+// KeywordIndex is the blind index of a keyword under the user's current key
+func (nc *NoteCipher) KeywordIndex(userID string, keyword string) []byte {
+
+	current, keyErr := nc.currentKey(userID)
+	if keyErr != nil {
+		panic(keyErr)
+	}
+
+	return envelope.BlindIndex(current.indexKey, keyword)
+}
+
+// RotateDataKey adds a new data key version for the user, new notes are
+// encrypted with it straight away and the re-encryption job moves the old ones
+func (nc *NoteCipher) RotateDataKey(userID string) int {
+
+	cached, keysErr := nc.loadUserKeys(userID)
+	if keysErr != nil {
+		panic(keysErr)
+	}
+	if cached.shredded {
+		panic(errKeysShredded)
+	}
+
+	version := 1
+	if len(cached.keys) > 0 {
+		version = cached.keys[len(cached.keys)-1].version + 1
+	}
+	nc.insertDataKey(userID, version)
+
+	return version
+}
+
+// ShredDataKeys destroys the user's data keys and returns how many there were.
+// After this their notes can't be decrypted from the database or from any copy
+// of the ciphertext, backups taken before still hold the wrapped keys until
+// they expire
+func (nc *NoteCipher) ShredDataKeys(userID string) int {
+
+	result, execErr := nc.db.Exec(shredDataKeysQuery, userID)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	nc.mutex.Lock()
+	delete(nc.dataKeys, userID)
+	nc.mutex.Unlock()
+
+	rowsAffected, rowsErr := result.RowsAffected()
+	if rowsErr != nil {
+		panic(rowsErr)
+	}
+
+	return int(rowsAffected)
+}
+
+// RewrapDataKeys rewraps data keys that are still wrapped by an older master
+// key with the current one, once none are left the old master key can go
+func (nc *NoteCipher) RewrapDataKeys(limit int) int {
+
+	currentID, currentMasterKey := nc.masterKeys.Current()
+
+	rows, queryErr := nc.db.Query(staleWrappedKeysQuery, currentID, limit)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+
+	type staleKey struct {
+		userID      string
+		version     int
+		wrapped     []byte
+		masterKeyID string
+	}
+	var staleKeys []staleKey
+	for rows.Next() {
+		var stale staleKey
+		scanErr := rows.Scan(&stale.userID, &stale.version, &stale.wrapped, &stale.masterKeyID)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+		staleKeys = append(staleKeys, stale)
+	}
+	rows.Close()
+
+	rewrapped := 0
+	for _, stale := range staleKeys {
+		masterKey, found := nc.masterKeys.Key(stale.masterKeyID)
+		if !found {
+			fmt.Println("ERROR master key", stale.masterKeyID, "isn't configured, can't rewrap key of user", stale.userID)
+			continue
+		}
+		additionalData := envelope.KeyAdditionalData(stale.userID, stale.version)
+		key, openErr := envelope.Open(masterKey, stale.wrapped, additionalData)
+		if openErr != nil {
+			fmt.Println("ERROR unwrapping data key of user", stale.userID, openErr)
+			continue
+		}
+		wrapped, sealErr := envelope.Seal(currentMasterKey, key, additionalData)
+		if sealErr != nil {
+			panic(sealErr)
+		}
+		_, execErr := nc.db.Exec(rewrapDataKeyQuery, wrapped, currentID, stale.userID, stale.version, stale.masterKeyID)
+		if execErr != nil {
+			panic(execErr)
+		}
+		rewrapped++
+	}
+
+	return rewrapped
+}
+
+// ReencryptMoodNotes encrypts plaintext notes and moves notes off older data
+// key versions, rebuilding their blind indexes, and returns how many it did
+func (nc *NoteCipher) ReencryptMoodNotes(limit int) int {
+
+	reencrypted := 0
+	for _, note := range nc.pendingNotes(pendingMoodNotesQuery, limit) {
+		field := MoodLogNote(note.id)
+		// a user whose keys can't be used is skipped rather than stopping the batch
+		if _, keyErr := nc.currentKey(note.userID); keyErr != nil {
+			fmt.Println("ERROR re-encrypting note of mood log", note.id, keyErr)
+			continue
+		}
+		plaintext, decryptErr := nc.Decrypt(note.userID, field, note.stored, note.encrypted)
+		if decryptErr != nil {
+			fmt.Println("ERROR re-encrypting note of mood log", note.id, decryptErr)
+			continue
+		}
+		stored, keyVersion := nc.Encrypt(note.userID, field, plaintext)
+		tokens := nc.NoteTokens(note.userID, plaintext)
+
+		tx, txErr := nc.db.Begin()
+		if txErr != nil {
+			panic(txErr)
+		}
+
+		result, execErr := tx.Exec(updateMoodNoteQuery, stored, keyVersion, note.id, note.stored)
+		if execErr != nil {
+			tx.Rollback()
+			panic(execErr)
+		}
+		// the note changed since it was read, it'll be picked up next time
+		if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+			tx.Rollback()
+			continue
+		}
+
+		_, execErr = tx.Exec(deleteNoteTokensQuery, note.id)
+		if execErr != nil {
+			tx.Rollback()
+			panic(execErr)
+		}
+		for _, token := range tokens {
+			_, execErr = tx.Exec(insertNoteTokenQuery, note.id, note.userID, token)
+			if execErr != nil {
+				tx.Rollback()
+				panic(execErr)
+			}
+		}
+
+		commitErr := tx.Commit()
+		if commitErr != nil {
+			panic(commitErr)
+		}
+		reencrypted++
+	}
+
+	return reencrypted
+}
+
+// ReencryptSleepNotes is ReencryptMoodNotes for sleep_log.notes, which aren't
+// searchable so have no blind indexes
+func (nc *NoteCipher) ReencryptSleepNotes(limit int) int {
+
+	reencrypted := 0
+	for _, note := range nc.pendingNotes(pendingSleepNotesQuery, limit) {
+		field := SleepLogNotes(note.id)
+		// a user whose keys can't be used is skipped rather than stopping the batch
+		if _, keyErr := nc.currentKey(note.userID); keyErr != nil {
+			fmt.Println("ERROR re-encrypting notes of sleep log", note.id, keyErr)
+			continue
+		}
+		plaintext, decryptErr := nc.Decrypt(note.userID, field, note.stored, note.encrypted)
+		if decryptErr != nil {
+			fmt.Println("ERROR re-encrypting notes of sleep log", note.id, decryptErr)
+			continue
+		}
+		stored, keyVersion := nc.Encrypt(note.userID, field, plaintext)
+		result, execErr := nc.db.Exec(updateSleepNotesQuery, stored, keyVersion, note.id, note.stored)
+		if execErr != nil {
+			panic(execErr)
+		}
+		// as for mood notes, one that changed since it was read is left for next time
+		if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+			continue
+		}
+		reencrypted++
+	}
+
+	return reencrypted
+}
+
+// ReencryptKeywords moves sentiment keywords off older data key versions
+func (nc *NoteCipher) ReencryptKeywords(limit int) int {
+
+	rows, queryErr := nc.db.Query(pendingKeywordsQuery, limit)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+
+	type pendingKeyword struct {
+		moodLogID    int
+		userID       string
+		keywordIndex []byte
+		stored       string
+	}
+	var pending []pendingKeyword
+	for rows.Next() {
+		var keyword pendingKeyword
+		scanErr := rows.Scan(&keyword.moodLogID, &keyword.userID, &keyword.keywordIndex, &keyword.stored)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+		pending = append(pending, keyword)
+	}
+	rows.Close()
+
+	reencrypted := 0
+	for _, keyword := range pending {
+		plaintext, decryptErr := nc.DecryptKeyword(keyword.userID, keyword.stored)
+		if decryptErr != nil {
+			fmt.Println("ERROR re-encrypting keyword of mood log", keyword.moodLogID, decryptErr)
+			continue
+		}
+		result, execErr := nc.db.Exec(updateKeywordQuery, nc.KeywordIndex(keyword.userID, plaintext), nc.EncryptKeyword(keyword.userID, plaintext), keyword.moodLogID, keyword.keywordIndex)
+		if execErr != nil {
+			panic(execErr)
+		}
+		if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+			continue
+		}
+		reencrypted++
+	}
+
+	return reencrypted
+}
+
+// RetireDataKeys deletes data key versions nothing is encrypted under any more
+func (nc *NoteCipher) RetireDataKeys() int {
+
+	result, execErr := nc.db.Exec(retireDataKeysQuery)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	rowsAffected, rowsErr := result.RowsAffected()
+	if rowsErr != nil {
+		panic(rowsErr)
+	}
+
+	return int(rowsAffected)
+}
+
+type pendingNote struct {
+	id        int
+	userID    string
+	stored    string
+	encrypted bool
+}
+
+func (nc *NoteCipher) pendingNotes(query string, limit int) []pendingNote {
+
+	rows, queryErr := nc.db.Query(query, limit)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var pending []pendingNote
+	for rows.Next() {
+		var note pendingNote
+		scanErr := rows.Scan(&note.id, &note.userID, &note.stored, &note.encrypted)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+		pending = append(pending, note)
+	}
+
+	return pending
+}
+
+func (nc *NoteCipher) currentKey(userID string) (dataKey, error) {
+
+	cached, keysErr := nc.userKeys(userID)
+	if keysErr != nil {
+		return dataKey{}, keysErr
+	}
+	if cached.shredded {
+		return dataKey{}, errKeysShredded
+	}
+	if len(cached.keys) == 0 {
+		nc.insertDataKey(userID, 1)
+		if cached, keysErr = nc.loadUserKeys(userID); keysErr != nil {
+			return dataKey{}, keysErr
+		}
+	}
+	if len(cached.keys) == 0 {
+		return dataKey{}, fmt.Errorf("no data key for user %s", userID)
+	}
+
+	return cached.keys[len(cached.keys)-1], nil
+}
+
+// insertDataKey wraps a new random key, two processes creating the same
+// version at once is fine as the first insert wins and both reload it
+func (nc *NoteCipher) insertDataKey(userID string, version int) {
+
+	key, keyErr := envelope.NewDataKey()
+	if keyErr != nil {
+		panic(keyErr)
+	}
+
+	masterKeyID, masterKey := nc.masterKeys.Current()
+	wrapped, sealErr := envelope.Seal(masterKey, key, envelope.KeyAdditionalData(userID, version))
+	if sealErr != nil {
+		panic(sealErr)
+	}
+
+	_, execErr := nc.db.Exec(insertDataKeyQuery, userID, version, wrapped, masterKeyID)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	nc.mutex.Lock()
+	delete(nc.dataKeys, userID)
+	nc.mutex.Unlock()
+}
+
+func (nc *NoteCipher) userKeys(userID string) (cachedDataKeys, error) {
+
+	nc.mutex.Lock()
+	cached, found := nc.dataKeys[userID]
+	nc.mutex.Unlock()
+
+	if found && time.Since(cached.loadedAt) < dataKeyCacheTTL {
+		return cached, nil
+	}
+
+	return nc.loadUserKeys(userID)
+}
+
+// loadUserKeys unwraps all of the user's data keys. A key that can't be
+// unwrapped is an error rather than skipped, skipping it would leave an older
+// version looking current and new notes would be encrypted under it
+func (nc *NoteCipher) loadUserKeys(userID string) (cachedDataKeys, error) {
+
+	rows, queryErr := nc.db.Query(dataKeysQuery, userID)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	cached := cachedDataKeys{loadedAt: time.Now()}
+
+	for rows.Next() {
+		var version int
+		var wrapped []byte
+		var masterKeyID string
+		var shredded bool
+		scanErr := rows.Scan(&version, &wrapped, &masterKeyID, &shredded)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+
+		if shredded {
+			cached.shredded = true
+			cached.keys = append(cached.keys, dataKey{version: version})
+			continue
+		}
+
+		masterKey, found := nc.masterKeys.Key(masterKeyID)
+		if !found {
+			return cachedDataKeys{}, fmt.Errorf("master key %s isn't configured, data key %d of user %s can't be unwrapped", masterKeyID, version, userID)
+		}
+		key, openErr := envelope.Open(masterKey, wrapped, envelope.KeyAdditionalData(userID, version))
+		if openErr != nil {
+			return cachedDataKeys{}, fmt.Errorf("unwrapping data key %d of user %s: %w", version, userID, openErr)
+		}
+		cached.keys = append(cached.keys, dataKey{version: version, key: key, indexKey: envelope.BlindIndexKey(key)})
+	}
+
+	nc.mutex.Lock()
+	nc.dataKeys[userID] = cached
+	nc.mutex.Unlock()
+
+	return cached, nil
+}
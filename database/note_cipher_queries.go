@@ -0,0 +1,144 @@
+package database
+
+var dataKeysQuery = `SELECT key_version,
+       wrapped_key,
+       master_key_id,
+       shredded_at IS NOT NULL AS shredded
+FROM   user_data_key
+WHERE  user_id = ?
+ORDER  BY key_version;`
+
+var insertDataKeyQuery = `INSERT IGNORE INTO user_data_key
+            (user_id,
+             key_version,
+             wrapped_key,
+             master_key_id)
+VALUES      (?, ?, ?, ?);`
+
+var shredDataKeysQuery = `UPDATE user_data_key
+SET    wrapped_key = NULL,
+       shredded_at = CURRENT_TIMESTAMP
+WHERE  user_id = ?
+       AND shredded_at IS NULL;`
+
+var staleWrappedKeysQuery = `SELECT user_id,
+       key_version,
+       wrapped_key,
+       master_key_id
+FROM   user_data_key
+WHERE  master_key_id <> ?
+       AND wrapped_key IS NOT NULL
+LIMIT  ?;`
+
+var rewrapDataKeyQuery = `UPDATE user_data_key
+SET    wrapped_key = ?,
+       master_key_id = ?
+WHERE  user_id = ?
+       AND key_version = ?
+       AND master_key_id = ?;`
+
+// currentDataKeys is each user's newest key version and whether their keys
+// were shredded, a note needs (re)encrypting when it has no key version or an
+// older one
+var currentDataKeys = `(SELECT user_id,
+        Max(key_version)              AS current_version,
+        Max(shredded_at IS NOT NULL)  AS shredded
+ FROM   user_data_key
+ GROUP  BY user_id)`
+
+var pendingMoodNotesQuery = `SELECT ml.mood_log_id,
+       ml.user_id,
+       ml.note,
+       ml.note_key_version IS NOT NULL AS encrypted
+FROM   mood_log ml
+       LEFT JOIN ` + currentDataKeys + ` udk
+              ON ml.user_id = udk.user_id
+WHERE  ml.note IS NOT NULL
+       AND ml.note <> ''
+       AND Coalesce(udk.shredded, 0) = 0
+       AND ( ml.note_key_version IS NULL
+              OR ml.note_key_version < udk.current_version )
+ORDER  BY ml.mood_log_id
+LIMIT  ?;`
+
+var pendingSleepNotesQuery = `SELECT sl.sleep_log_id,
+       sl.user_id,
+       sl.notes,
+       sl.notes_key_version IS NOT NULL AS encrypted
+FROM   sleep_log sl
+       LEFT JOIN ` + currentDataKeys + ` udk
+              ON sl.user_id = udk.user_id
+WHERE  sl.notes IS NOT NULL
+       AND sl.notes <> ''
+       AND Coalesce(udk.shredded, 0) = 0
+       AND ( sl.notes_key_version IS NULL
+              OR sl.notes_key_version < udk.current_version )
+ORDER  BY sl.sleep_log_id
+LIMIT  ?;`
+
+var pendingKeywordsQuery = `SELECT mlk.mood_log_id,
+       ml.user_id,
+       mlk.keyword_index,
+       mlk.keyword
+FROM   mood_log_keyword mlk
+       JOIN mood_log ml
+         ON mlk.mood_log_id = ml.mood_log_id
+       JOIN ` + currentDataKeys + ` udk
+         ON ml.user_id = udk.user_id
+WHERE  udk.shredded = 0
+       AND Cast(Substring_index(Substring_index(mlk.keyword, ':', 2), ':', -1) AS UNSIGNED) < udk.current_version
+LIMIT  ?;`
+
+// the note is only replaced if nobody changed it since it was read, updated_at
+// is kept so the sentiment scorer doesn't see an edit
+var updateMoodNoteQuery = `UPDATE mood_log
+SET    note = ?,
+       note_key_version = ?,
+       updated_at = updated_at
+WHERE  mood_log_id = ?
+       AND note = ?;`
+
+var updateSleepNotesQuery = `UPDATE sleep_log
+SET    notes = ?,
+       notes_key_version = ?,
+       updated_at = updated_at
+WHERE  sleep_log_id = ?
+       AND notes = ?;`
+
+var updateKeywordQuery = `UPDATE mood_log_keyword
+SET    keyword_index = ?,
+       keyword = ?
+WHERE  mood_log_id = ?
+       AND keyword_index = ?;`
+
+var deleteNoteTokensQuery = `DELETE FROM mood_log_note_token
+WHERE  mood_log_id = ?;`
+
+var insertNoteTokenQuery = `INSERT IGNORE INTO mood_log_note_token
+            (mood_log_id,
+             user_id,
+             token)
+VALUES      (?, ?, ?);`
+
+// retireDataKeysQuery deletes key versions below the current one once no note
+// or keyword is encrypted under them any more
+var retireDataKeysQuery = `DELETE udk
+FROM   user_data_key udk
+       JOIN ` + currentDataKeys + ` current_keys
+         ON udk.user_id = current_keys.user_id
+WHERE  udk.key_version < current_keys.current_version
+       AND current_keys.shredded = 0
+       AND NOT EXISTS (SELECT 1
+                       FROM   mood_log ml
+                       WHERE  ml.user_id = udk.user_id
+                              AND ml.note_key_version = udk.key_version)
+       AND NOT EXISTS (SELECT 1
+                       FROM   sleep_log sl
+                       WHERE  sl.user_id = udk.user_id
+                              AND sl.notes_key_version = udk.key_version)
+       AND NOT EXISTS (SELECT 1
+                       FROM   mood_log_keyword mlk
+                              JOIN mood_log ml
+                                ON mlk.mood_log_id = ml.mood_log_id
+                       WHERE  ml.user_id = udk.user_id
+                              AND mlk.keyword LIKE Concat('hx1:', udk.key_version, ':%'));`
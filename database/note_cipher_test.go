@@ -0,0 +1,191 @@
+package database
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/envelope"
+)
+
+var (
+	masterKey2025 = "2025:" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, envelope.KeySize))
+	masterKey2026 = "2026:" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, envelope.KeySize))
+)
+
+func testMasterKeys(t *testing.T, keyList string) envelope.MasterKeys {
+	t.Helper()
+	t.Setenv("HORIZON_MASTER_KEYS", keyList)
+	masterKeys, loadErr := envelope.LoadMasterKeys()
+	if loadErr != nil {
+		t.Fatal(loadErr)
+	}
+	return masterKeys
+}
+
+// testNoteCipher is a cipher under the 2025 master key over a database with
+// users 1 and 2
+func testNoteCipher(t *testing.T) (*NoteCipher, *sql.DB) {
+	db := testDatabase(t)
+	if _, execErr := db.Exec(`INSERT INTO user (user_id, email, password_hash) VALUES (1, 'one@example.com', ''), (2, 'two@example.com', '')`); execErr != nil {
+		t.Fatal(execErr)
+	}
+	return NewNoteCipher(db, testMasterKeys(t, masterKey2025)), db
+}
+
+func TestNoteCipherEncryptDecrypt(t *testing.T) {
+	noteCipher, _ := testNoteCipher(t)
+
+	stored, keyVersion := noteCipher.Encrypt("1", MoodLogNote(5), "racing thoughts")
+	if !strings.HasPrefix(stored, "hx1:1:") || keyVersion.Int64 != 1 || strings.Contains(stored, "racing") {
+		t.Fatalf("Encrypt() = %q version %v, want ciphertext under the first key", stored, keyVersion)
+	}
+	if plaintext, decryptErr := noteCipher.Decrypt("1", MoodLogNote(5), stored, true); decryptErr != nil || plaintext != "racing thoughts" {
+		t.Errorf("Decrypt() = %q, %v, want the note", plaintext, decryptErr)
+	}
+
+	// the ciphertext only opens on its own row for its own user
+	if _, decryptErr := noteCipher.Decrypt("1", MoodLogNote(6), stored, true); decryptErr == nil {
+		t.Error("the note decrypted on another mood log")
+	}
+	if _, decryptErr := noteCipher.Decrypt("1", SleepLogNotes(5), stored, true); decryptErr == nil {
+		t.Error("the note decrypted as sleep notes")
+	}
+	if _, decryptErr := noteCipher.Decrypt("2", MoodLogNote(5), stored, true); decryptErr == nil {
+		t.Error("the note decrypted for another user")
+	}
+
+	if plaintext, decryptErr := noteCipher.Decrypt("1", MoodLogNote(7), "written before encryption", false); decryptErr != nil || plaintext != "written before encryption" {
+		t.Errorf("Decrypt() of plaintext = %q, %v, want it as is", plaintext, decryptErr)
+	}
+	if stored, keyVersion := noteCipher.Encrypt("1", MoodLogNote(8), ""); stored != "" || keyVersion.Valid {
+		t.Errorf("Encrypt() of an empty note = %q version %v, want it empty", stored, keyVersion)
+	}
+}
+
+// noteVersions is each mood log's note key version for the user, in order
+func noteVersions(t *testing.T, db *sql.DB, userID string) string {
+	return strings.Join(queryRows(t, db, `SELECT COALESCE(note_key_version, 'plain') FROM mood_log WHERE user_id = `+userID+` ORDER BY mood_log_id`), ",")
+}
+
+func TestRotateAndReencrypt(t *testing.T) {
+	noteCipher, db := testNoteCipher(t)
+
+	for _, statement := range []string{
+		`INSERT INTO mood_log (user_id, mood_rating, note) VALUES (1, 3, 'slept badly again'), (1, 5, 'better day'), (2, 6, 'fine')`,
+		`INSERT INTO sleep_log (user_id, hours_slept, sleep_quality_tag_id, notes, sleep_date) SELECT 1, 5, Min(sleep_quality_tag_id), 'woke at 4', '2025-03-01' FROM sleep_quality_tag`,
+	} {
+		if _, execErr := db.Exec(statement); execErr != nil {
+			t.Fatal(execErr)
+		}
+	}
+
+	// notes written in plaintext are encrypted under each user's first key
+	if moved := noteCipher.ReencryptMoodNotes(100); moved != 3 {
+		t.Errorf("encrypted %d mood notes, want 3", moved)
+	}
+	if moved := noteCipher.ReencryptSleepNotes(100); moved != 1 {
+		t.Errorf("encrypted %d sleep notes, want 1", moved)
+	}
+	if versions := noteVersions(t, db, "1"); versions != "1,1" {
+		t.Errorf("user 1's note versions = %s, want 1,1", versions)
+	}
+
+	if version := noteCipher.RotateDataKey("1"); version != 2 {
+		t.Fatalf("RotateDataKey() = %d, want 2", version)
+	}
+	if moved := noteCipher.ReencryptMoodNotes(100); moved != 2 {
+		t.Errorf("moved %d mood notes to the new key, want user 1's 2", moved)
+	}
+	if moved := noteCipher.ReencryptSleepNotes(100); moved != 1 {
+		t.Errorf("moved %d sleep notes to the new key, want 1", moved)
+	}
+	if versions := noteVersions(t, db, "1"); versions != "2,2" {
+		t.Errorf("user 1's note versions = %s, want 2,2", versions)
+	}
+	if versions := noteVersions(t, db, "2"); versions != "1" {
+		t.Errorf("user 2's note versions = %s, want 1", versions)
+	}
+	if moved := noteCipher.ReencryptMoodNotes(100) + noteCipher.ReencryptSleepNotes(100); moved != 0 {
+		t.Errorf("a second pass moved %d notes, want none", moved)
+	}
+
+	// the blind indexes follow the note onto the new key
+	tokens := queryRows(t, db, `SELECT Hex(token) FROM mood_log_note_token WHERE user_id = 1`)
+	want := noteCipher.SearchTokens("1", []string{"slept"})
+	if len(want) != 2 || !strings.Contains(strings.Join(tokens, ","), strings.ToUpper(want[1])) {
+		t.Errorf("tokens = %q, want one for slept under key 2", tokens)
+	}
+
+	stored := queryRows(t, db, `SELECT note FROM mood_log WHERE user_id = 1 ORDER BY mood_log_id LIMIT 1`)[0]
+	if plaintext, decryptErr := noteCipher.Decrypt("1", MoodLogNote(1), stored, true); decryptErr != nil || plaintext != "slept badly again" {
+		t.Errorf("Decrypt() after the rotation = %q, %v, want the note", plaintext, decryptErr)
+	}
+
+	if retired := noteCipher.RetireDataKeys(); retired != 1 {
+		t.Errorf("retired %d data keys, want user 1's version 1", retired)
+	}
+}
+
+func TestRewrapDataKeys(t *testing.T) {
+	noteCipher, db := testNoteCipher(t)
+	stored, _ := noteCipher.Encrypt("1", MoodLogNote(1), "under the old master key")
+
+	// 2026 is put first, the old key stays until the rewrap is done
+	rotated := NewNoteCipher(db, testMasterKeys(t, masterKey2026+","+masterKey2025))
+	if rewrapped := rotated.RewrapDataKeys(100); rewrapped != 1 {
+		t.Fatalf("rewrapped %d data keys, want 1", rewrapped)
+	}
+
+	retired := NewNoteCipher(db, testMasterKeys(t, masterKey2026))
+	if plaintext, decryptErr := retired.Decrypt("1", MoodLogNote(1), stored, true); decryptErr != nil || plaintext != "under the old master key" {
+		t.Errorf("Decrypt() without the old master key = %q, %v, want the note", plaintext, decryptErr)
+	}
+}
+
+func TestMissingMasterKey(t *testing.T) {
+	noteCipher, db := testNoteCipher(t)
+	noteCipher.Encrypt("1", MoodLogNote(1), "first")
+
+	// version 2 is wrapped by a master key this process doesn't have
+	if _, execErr := db.Exec(`INSERT INTO user_data_key (user_id, key_version, wrapped_key, master_key_id) VALUES (1, 2, Random_bytes(60), 'gone')`); execErr != nil {
+		t.Fatal(execErr)
+	}
+	if _, execErr := db.Exec(`INSERT INTO mood_log (user_id, mood_rating, note, note_key_version) VALUES (1, 4, ?, 1)`, "unused"); execErr != nil {
+		t.Fatal(execErr)
+	}
+
+	fresh := NewNoteCipher(db, testMasterKeys(t, masterKey2025))
+	if current, keyErr := fresh.currentKey("1"); keyErr == nil || !strings.Contains(keyErr.Error(), "gone") {
+		t.Errorf("currentKey() = version %d, %v, want an error naming the missing master key", current.version, keyErr)
+	}
+	if moved := fresh.ReencryptMoodNotes(100); moved != 0 {
+		t.Errorf("moved %d notes without the current key, want none", moved)
+	}
+}
+
+func TestShredDataKeys(t *testing.T) {
+	noteCipher, db := testNoteCipher(t)
+	stored, _ := noteCipher.Encrypt("1", MoodLogNote(1), "private")
+	noteCipher.RotateDataKey("1")
+
+	if shredded := noteCipher.ShredDataKeys("1"); shredded != 2 {
+		t.Errorf("shredded %d data keys, want both versions", shredded)
+	}
+	if wrapped := queryRows(t, db, `SELECT Count(*) FROM user_data_key WHERE user_id = 1 AND wrapped_key IS NOT NULL`); wrapped[0] != "0" {
+		t.Errorf("%s wrapped keys left, want none", wrapped[0])
+	}
+
+	// another process's cache doesn't bring the keys back either
+	for _, cipher := range []*NoteCipher{noteCipher, NewNoteCipher(db, testMasterKeys(t, masterKey2025))} {
+		if _, decryptErr := cipher.Decrypt("1", MoodLogNote(1), stored, true); !errors.Is(decryptErr, errKeysShredded) {
+			t.Errorf("Decrypt() after shredding error = %v, want errKeysShredded", decryptErr)
+		}
+		if _, keyErr := cipher.currentKey("1"); !errors.Is(keyErr, errKeysShredded) {
+			t.Errorf("currentKey() after shredding error = %v, want errKeysShredded", keyErr)
+		}
+	}
+}
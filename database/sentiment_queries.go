@@ -0,0 +1,75 @@
+package database
+
+// pendingSentimentQuery finds the logs created or edited since the given time
+// that haven't been scored since, through idx_updated_at, and the logs scored
+// by an older analyzer version through idx_analyzer_version, so it doesn't
+// scan every log on each tick
+var pendingSentimentQuery = `SELECT ml.mood_log_id,
+       ml.user_id,
+       ml.note,
+       ml.note_key_version IS NOT NULL AS note_encrypted
+FROM   mood_log ml
+       LEFT JOIN mood_log_sentiment mls
+              ON ml.mood_log_id = mls.mood_log_id
+WHERE  ml.updated_at >= ?
+       AND ( mls.mood_log_id IS NULL
+              OR mls.analyzed_at < ml.updated_at )
+UNION
+SELECT ml.mood_log_id,
+       ml.user_id,
+       ml.note,
+       ml.note_key_version IS NOT NULL AS note_encrypted
+FROM   mood_log_sentiment mls
+       JOIN mood_log ml
+         ON mls.mood_log_id = ml.mood_log_id
+WHERE  mls.analyzer_version < ?
+ORDER  BY mood_log_id
+LIMIT  ?;`
+
+var saveSentimentQuery = `INSERT INTO mood_log_sentiment
+            (mood_log_id,
+             user_id,
+             sentiment_score,
+             analyzer_version,
+             analyzed_at)
+VALUES      (?, ?, ?, ?, CURRENT_TIMESTAMP)
+ON DUPLICATE KEY UPDATE sentiment_score = VALUES(sentiment_score),
+                        analyzer_version = VALUES(analyzer_version),
+                        analyzed_at = CURRENT_TIMESTAMP;`
+
+var deleteKeywordsQuery = `DELETE FROM mood_log_keyword
+WHERE  mood_log_id = ?;`
+
+var insertKeywordQuery = `INSERT INTO mood_log_keyword
+            (mood_log_id,
+             keyword_index,
+             keyword,
+             keyword_count)
+VALUES      (?, ?, ?, ?);`
+
+var scoredMoodLogsQuery = `SELECT ml.mood_log_id,
+       Date(local_datetime(ml.user_id, ml.created_at)) AS date,
+       ml.created_at,
+       ml.mood_rating,
+       mls.sentiment_score,
+       ml.note,
+       ml.note_key_version IS NOT NULL AS note_encrypted
+FROM   mood_log ml
+       JOIN mood_log_sentiment mls
+         ON ml.mood_log_id = mls.mood_log_id
+WHERE  ml.user_id = ?
+       AND ml.created_at >= ? AND ml.created_at < ?
+       AND mls.sentiment_score IS NOT NULL
+ORDER  BY ml.created_at;`
+
+// keywordFrequenciesQuery counts the notes mentioning each keyword on the
+// given comma separated dates, the keyword comes back encrypted
+var keywordFrequenciesQuery = `SELECT Any_value(mlk.keyword),
+       Count(*) AS log_count
+FROM   mood_log ml
+       JOIN mood_log_keyword mlk
+         ON ml.mood_log_id = mlk.mood_log_id
+WHERE  ml.user_id = ?
+       AND ml.created_at >= ? AND ml.created_at < ?
+       AND Find_in_set(Date(local_datetime(ml.user_id, ml.created_at)), ?) > 0
+GROUP  BY mlk.keyword_index;`
@@ -0,0 +1,187 @@
+package database
+
+import (
+	"cmp"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+type SentimentRepository struct {
+	db         *sql.DB
+	noteCipher *NoteCipher
+}
+
+func NewSentimentRepository(dbConnection *sql.DB, noteCipher *NoteCipher) *SentimentRepository {
+	return &SentimentRepository{
+		db:         dbConnection,
+		noteCipher: noteCipher,
+	}
+}
+
+// PendingNotes returns logs changed since changedSince that haven't been
+// scored since, and logs scored by an older analyzer version. More is true
+// when the limit was reached and there may be others. A log whose note can't
+// be decrypted is left out until it can
+func (sr *SentimentRepository) PendingNotes(analyzerVersion int, changedSince string, limit int) (moodLogs []models.MoodLog, more bool) {
+
+	rows, queryErr := sr.db.Query(pendingSentimentQuery, changedSince, analyzerVersion, limit)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	found := 0
+
+	for rows.Next() {
+		found++
+		var moodLog models.MoodLog
+		var note sql.NullString
+		var noteEncrypted bool
+		scanErr := rows.Scan(&moodLog.MoodLogID, &moodLog.UserID, &note, &noteEncrypted)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+		plaintext, decryptErr := sr.noteCipher.Decrypt(moodLog.UserID, MoodLogNote(moodLog.MoodLogID), note.String, noteEncrypted)
+		if decryptErr != nil {
+			fmt.Println("ERROR reading note of mood log", moodLog.MoodLogID, "for scoring", decryptErr)
+			continue
+		}
+		moodLog.Note = plaintext
+		moodLogs = append(moodLogs, moodLog)
+	}
+
+	return moodLogs, found == limit
+}
+
+// SaveSentiment replaces the log's score and keywords in a single transaction,
+// a log without a note is saved unscored so it isn't picked up again. The
+// keywords are encrypted like the note they came from
+func (sr *SentimentRepository) SaveSentiment(moodLog models.MoodLog, score float64, scored bool, analyzerVersion int, keywords []models.KeywordFrequency) {
+
+	sentimentScore := sql.NullFloat64{Float64: score, Valid: scored}
+
+	tx, txErr := sr.db.Begin()
+	if txErr != nil {
+		panic(txErr)
+	}
+	defer tx.Rollback()
+
+	_, execErr := tx.Exec(saveSentimentQuery, moodLog.MoodLogID, moodLog.UserID, sentimentScore, analyzerVersion)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	_, execErr = tx.Exec(deleteKeywordsQuery, moodLog.MoodLogID)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	for _, keyword := range keywords {
+		keywordIndex := sr.noteCipher.KeywordIndex(moodLog.UserID, keyword.Keyword)
+		_, execErr = tx.Exec(insertKeywordQuery, moodLog.MoodLogID, keywordIndex, sr.noteCipher.EncryptKeyword(moodLog.UserID, keyword.Keyword), keyword.Count)
+		if execErr != nil {
+			panic(execErr)
+		}
+	}
+
+	commitErr := tx.Commit()
+	if commitErr != nil {
+		panic(commitErr)
+	}
+}
+
+func (sr *SentimentRepository) ScoredMoodLogs(userID string, startDate string, endDate string) []models.ScoredMoodLog {
+
+	from, to := utcBounds(sr.db, userID, startDate, endDate)
+
+	rows, queryErr := sr.db.Query(scoredMoodLogsQuery, userID, from, to)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var scoredMoodLogs []models.ScoredMoodLog
+
+	for rows.Next() {
+		var scoredMoodLog models.ScoredMoodLog
+		var note sql.NullString
+		var noteEncrypted bool
+		scanErr := rows.Scan(
+			&scoredMoodLog.MoodLogID,
+			&scoredMoodLog.Date,
+			&scoredMoodLog.CreatedAt,
+			&scoredMoodLog.MoodRating,
+			&scoredMoodLog.Sentiment,
+			&note,
+			&noteEncrypted,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+		plaintext, decryptErr := sr.noteCipher.Decrypt(userID, MoodLogNote(scoredMoodLog.MoodLogID), note.String, noteEncrypted)
+		if decryptErr != nil {
+			panic(decryptErr)
+		}
+		scoredMoodLog.Note = plaintext
+		scoredMoodLogs = append(scoredMoodLogs, scoredMoodLog)
+	}
+
+	if scoredMoodLogs == nil {
+		return make([]models.ScoredMoodLog, 0)
+	}
+
+	return scoredMoodLogs
+}
+
+// KeywordFrequencies counts the notes mentioning each keyword on the dates,
+// the percentage is left for the caller who knows how many notes there were.
+// Keywords are grouped by blind index, which differs between key versions, so
+// the counts are merged on the decrypted keyword before the limit is applied
+func (sr *SentimentRepository) KeywordFrequencies(userID string, startDate string, endDate string, dates []string, limit int) []models.KeywordFrequency {
+
+	if len(dates) == 0 {
+		return make([]models.KeywordFrequency, 0)
+	}
+
+	from, to := utcBounds(sr.db, userID, startDate, endDate)
+
+	rows, queryErr := sr.db.Query(keywordFrequenciesQuery, userID, from, to, strings.Join(dates, ","))
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+
+	for rows.Next() {
+		var keyword string
+		var count int
+		scanErr := rows.Scan(&keyword, &count)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+		plaintext, decryptErr := sr.noteCipher.DecryptKeyword(userID, keyword)
+		if decryptErr != nil {
+			panic(decryptErr)
+		}
+		counts[plaintext] += count
+	}
+
+	keywordFrequencies := make([]models.KeywordFrequency, 0, len(counts))
+	for keyword, count := range counts {
+		keywordFrequencies = append(keywordFrequencies, models.KeywordFrequency{Keyword: keyword, Count: count})
+	}
+
+	slices.SortFunc(keywordFrequencies, func(a, b models.KeywordFrequency) int {
+		if a.Count != b.Count {
+			return cmp.Compare(b.Count, a.Count)
+		}
+		return cmp.Compare(a.Keyword, b.Keyword)
+	})
+
+	return keywordFrequencies[:min(limit, len(keywordFrequencies))]
+}
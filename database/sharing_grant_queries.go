@@ -0,0 +1,45 @@
+package database
+
+var sharingGrantColumns = `sharing_grant_id,
+       user_id,
+       grantee_user_id,
+       scopes,
+       created_at,
+       COALESCE(expires_at, ''),
+       COALESCE(revoked_at, '')`
+
+var sharingGrantsQuery = `SELECT ` + sharingGrantColumns + `
+FROM   sharing_grant
+WHERE  user_id = ?
+ORDER  BY sharing_grant_id;`
+
+var receivedSharingGrantsQuery = `SELECT ` + sharingGrantColumns + `
+FROM   sharing_grant
+WHERE  grantee_user_id = ?
+ORDER  BY sharing_grant_id;`
+
+var sharingGrantQuery = `SELECT ` + sharingGrantColumns + `
+FROM   sharing_grant
+WHERE  sharing_grant_id = ?;`
+
+var activeSharingGrantsQuery = `SELECT ` + sharingGrantColumns + `
+FROM   sharing_grant
+WHERE  user_id = ?
+       AND grantee_user_id = ?
+       AND revoked_at IS NULL
+       AND ( expires_at IS NULL
+              OR expires_at > CURRENT_TIMESTAMP )
+ORDER  BY sharing_grant_id;`
+
+var createSharingGrantQuery = `INSERT INTO sharing_grant
+            (user_id,
+             grantee_user_id,
+             scopes,
+             expires_at)
+VALUES      (?, ?, ?, ?);`
+
+var revokeSharingGrantQuery = `UPDATE sharing_grant
+SET    revoked_at = CURRENT_TIMESTAMP
+WHERE  user_id = ?
+       AND sharing_grant_id = ?
+       AND revoked_at IS NULL;`
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+type SharingGrantRepository struct {
+	db *sql.DB
+}
+
+func NewSharingGrantRepository(dbConnection *sql.DB) *SharingGrantRepository {
+	return &SharingGrantRepository{
+		db: dbConnection,
+	}
+}
+
+// Grants are the grants the user has given, revoked and expired ones included
+func (sgr *SharingGrantRepository) Grants(userID string) []models.SharingGrant {
+	return sgr.sharingGrants(sharingGrantsQuery, userID)
+}
+
+// ReceivedGrants are the grants others have given the user
+func (sgr *SharingGrantRepository) ReceivedGrants(granteeUserID string) []models.SharingGrant {
+	return sgr.sharingGrants(receivedSharingGrantsQuery, granteeUserID)
+}
+
+// ActiveGrants are the user's grants to the grantee that are neither revoked
+// nor expired
+func (sgr *SharingGrantRepository) ActiveGrants(userID string, granteeUserID string) []models.SharingGrant {
+	return sgr.sharingGrants(activeSharingGrantsQuery, userID, granteeUserID)
+}
+
+func (sgr *SharingGrantRepository) CreateGrant(grant models.SharingGrant) models.SharingGrant {
+
+	result, execErr := sgr.db.Exec(createSharingGrantQuery,
+		grant.UserID,
+		grant.GranteeUserID,
+		strings.Join(grant.Scopes, ","),
+		sql.NullString{String: grant.ExpiresAt, Valid: grant.ExpiresAt != ""},
+	)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	sharingGrantID, idErr := result.LastInsertId()
+	if idErr != nil {
+		panic(idErr)
+	}
+
+	created := sgr.sharingGrants(sharingGrantQuery, strconv.FormatInt(sharingGrantID, 10))
+	return created[0]
+}
+
+// RevokeGrant revokes one of the user's grants, false when it isn't theirs or
+// was already revoked
+func (sgr *SharingGrantRepository) RevokeGrant(userID string, sharingGrantID string) bool {
+
+	result, execErr := sgr.db.Exec(revokeSharingGrantQuery, userID, sharingGrantID)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	rowsAffected, rowsErr := result.RowsAffected()
+	if rowsErr != nil {
+		panic(rowsErr)
+	}
+
+	return rowsAffected == 1
+}
+
+func (sgr *SharingGrantRepository) sharingGrants(query string, args ...any) []models.SharingGrant {
+
+	rows, queryErr := sgr.db.Query(query, args...)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	grants := make([]models.SharingGrant, 0)
+
+	for rows.Next() {
+		grants = append(grants, scanSharingGrant(rows))
+	}
+
+	return grants
+}
+
+func scanSharingGrant(rows *sql.Rows) models.SharingGrant {
+
+	var grant models.SharingGrant
+	var scopes string
+	scanErr := rows.Scan(
+		&grant.SharingGrantID,
+		&grant.UserID,
+		&grant.GranteeUserID,
+		&scopes,
+		&grant.CreatedAt,
+		&grant.ExpiresAt,
+		&grant.RevokedAt,
+	)
+	if scanErr != nil {
+		panic(scanErr)
+	}
+	grant.Scopes = strings.Split(scopes, ",")
+
+	return grant
+}
@@ -1,31 +1,132 @@
 package database
 
+// the sleep statistics are per night from the rollup, a night logged in parts
+// is one night of their summed hours rather than several short ones
 var avgSleepHoursQuery = `SELECT Avg(hours_slept) AS avg_sleep_hours
-FROM   sleep_log
+FROM   daily_sleep_summary
 WHERE  user_id = ?
        AND sleep_date BETWEEN ? AND ?;`
 
-var sleepMovingAvgQuery = `WITH first_query
-     AS (SELECT sleep_date AS DATE,
-                Avg(hours_slept) AS avg_sleep_hours 
+var sleepStdDevQuery = `SELECT Stddev_pop(hours_slept) AS std_dev
+FROM   daily_sleep_summary
+WHERE  user_id = ?
+       AND sleep_date BETWEEN ? AND ?;`
+
+// var sleepQualitTagFrequenciesQuery = ``
+
+// the rollup has one row per night with the hours of all its logs, so a night
+// logged in parts is only measured against the target once
+var sleepDebtQuery = `SELECT Sum(? - hours_slept) AS sleep_debt
+FROM   daily_sleep_summary
+WHERE  user_id = ?
+       AND sleep_date BETWEEN ? AND ?;`
+
+// social jet lag is how far the midpoint of sleep moves between weekday and
+// weekend nights, weekend nights are saturday and sunday (DAYOFWEEK 7 and 1).
+// sleep_date is the morning the night ends, a sleep_start after midday is the
+// evening before so midpoints are minutes from the midnight starting sleep_date.
+// Each night is averaged first so one logged in parts counts once
+var socialJetLagQuery = `WITH nightly_midpoints
+     AS (SELECT sleep_date,
+                Avg(CASE
+                      WHEN sleep_start >= '12:00:00' THEN Time_to_sec(sleep_start) / 60 - 1440
+                      ELSE Time_to_sec(sleep_start) / 60
+                    END + hours_slept * 30) AS midpoint
          FROM   sleep_log
          WHERE  user_id = ?
                 AND sleep_date BETWEEN ? AND ?
-	),
-     second_query
-     AS (SELECT DATE,
-                Avg(avg_sleep_hours)
+                AND sleep_start IS NOT NULL
+         GROUP  BY sleep_date)
+SELECT ( Avg(CASE
+               WHEN Dayofweek(sleep_date) IN ( 1, 7 ) THEN midpoint
+             END) - Avg(CASE
+                          WHEN Dayofweek(sleep_date) NOT IN ( 1, 7 ) THEN midpoint
+                        END) ) / 60 AS social_jet_lag
+FROM   nightly_midpoints;`
+
+// only nights on consecutive dates are compared, a night after a gap has no
+// neighbour to differ from
+var sleepVariabilityIndexQuery = `WITH first_query
+     AS (SELECT sleep_date,
+                hours_slept,
+                Lag(sleep_date)
+                  OVER(
+                    ORDER BY sleep_date) AS previous_date,
+                Lag(hours_slept)
                   OVER(
-                    ORDER BY DATE ROWS BETWEEN %s preceding AND CURRENT ROW) AS
-                   moving_avg
-         FROM   first_query)
-SELECT *
+                    ORDER BY sleep_date) AS previous_hours
+         FROM   daily_sleep_summary
+         WHERE  user_id = ?
+                AND sleep_date BETWEEN ? AND ?)
+SELECT Avg(Abs(hours_slept - previous_hours)) AS variability_index
+FROM   first_query
+WHERE  previous_date = sleep_date - INTERVAL 1 day;`
+
+var shortSleepStreaksQuery = `WITH first_query
+     AS (SELECT sleep_date,
+                Row_number()
+                  OVER(
+                    ORDER BY sleep_date) AS rn
+         FROM   daily_sleep_summary
+         WHERE  user_id = ?
+                AND sleep_date BETWEEN ? AND ?
+                AND hours_slept < ?),
+     second_query
+     AS (SELECT Min(sleep_date) AS start_date,
+                Max(sleep_date) AS end_date,
+                Count(*)        AS streak_length
+         FROM   first_query
+         GROUP  BY Date_add(sleep_date, interval - rn day))
+SELECT start_date,
+       end_date,
+       streak_length
 FROM   second_query
-ORDER  BY DATE;`
+WHERE  streak_length >= ?
+ORDER  BY start_date;`
 
-var sleepStdDevQuery = `SELECT Stddev_pop(hours_slept) AS std_dev
-FROM   sleep_log
+var dailySleepQuery = `SELECT sleep_date,
+       hours_slept
+FROM   daily_sleep_summary
 WHERE  user_id = ?
-       AND sleep_date BETWEEN ? AND ?;`
+       AND sleep_date BETWEEN ? AND ?
+ORDER  BY sleep_date;`
 
-// var sleepQualitTagFrequenciesQuery = ``
+// the average and spread are per night, the log count and top tag over the logs
+var sleepBucketsQuery = `WITH buckets
+     AS (SELECT %[1]s AS period_start,
+                Avg(hours_slept)        AS avg_sleep_hours,
+                Stddev_pop(hours_slept) AS std_dev,
+                Sum(log_count)          AS log_count
+         FROM   daily_sleep_summary
+         WHERE  user_id = ?
+                AND sleep_date BETWEEN ? AND ?
+         GROUP  BY period_start),
+     tag_counts
+     AS (SELECT %[2]s AS period_start,
+                sqt.name,
+                Count(*) AS tag_count
+         FROM   sleep_log sl
+                JOIN sleep_quality_tag sqt
+                  ON sl.sleep_quality_tag_id = sqt.sleep_quality_tag_id
+         WHERE  sl.user_id = ?
+                AND sl.sleep_date BETWEEN ? AND ?
+         GROUP  BY period_start,
+                   sqt.name),
+     top_tags
+     AS (SELECT period_start,
+                name,
+                Row_number()
+                  OVER(
+                    partition BY period_start
+                    ORDER BY tag_count DESC, name) AS rn
+         FROM   tag_counts)
+SELECT b.period_start,
+       b.avg_sleep_hours,
+       b.std_dev,
+       b.log_count,
+       t.name
+FROM   buckets b
+       LEFT JOIN top_tags t
+              ON b.period_start = t.period_start
+                 AND t.rn = 1
+ORDER  BY b.period_start;`
@@ -32,59 +32,162 @@ func (slr *SleepLogRepository) AvgSleepHours(userID string, startDate string, en
 	return avgSleepHours
 }
 
-func (slr *SleepLogRepository) MovingAvgSleep(userID string, startDate string, endDate string, numDaysPreceding string) []models.MovingAverage {
+func (slr *SleepLogRepository) StandardDeviation(userID string, startDate string, endDate string) float64 {
 
-	query := fmt.Sprintf(sleepMovingAvgQuery, numDaysPreceding)
-	rows, queryErr := slr.db.Query(query, userID, startDate, endDate)
+	rows, queryErr := slr.db.Query(sleepStdDevQuery, userID, startDate, endDate)
 	if queryErr != nil {
 		panic(queryErr)
 	}
+	defer rows.Close()
 
-	var movingAvg models.MovingAverage
-	var movingAverages []models.MovingAverage
+	var standardDeviation sql.NullFloat64
 
-	for rows.Next() {
-		// TODO fix bug converting null to string when sd = 21 and ed = 28
-		scanErr := rows.Scan(
-			&movingAvg.Date,
-			&movingAvg.MovingAvg,
-		)
+	if next := rows.Next(); next {
+		scanErr := rows.Scan(&standardDeviation)
 		if scanErr != nil {
 			panic(scanErr)
 		}
+	}
+
+	if !standardDeviation.Valid {
+		return 0.0
+	}
+
+	return standardDeviation.Float64
+}
 
-		movingAverages = append(movingAverages, movingAvg)
+func (slr *SleepLogRepository) SleepDebt(userID string, startDate string, endDate string, targetSleepHours float64) float64 {
+
+	rows, queryErr := slr.db.Query(sleepDebtQuery, targetSleepHours, userID, startDate, endDate)
+	if queryErr != nil {
+		panic(queryErr)
 	}
+	defer rows.Close()
+
+	var sleepDebt sql.NullFloat64
 
-	if movingAverages == nil {
-		return make([]models.MovingAverage, 0)
+	if next := rows.Next(); next {
+		scanErr := rows.Scan(&sleepDebt)
+		if scanErr != nil {
+			panic(scanErr)
+		}
 	}
 
-	return movingAverages
+	if !sleepDebt.Valid {
+		return 0.0
+	}
+
+	return sleepDebt.Float64
 }
 
-func (slr *SleepLogRepository) StandardDeviation(userID string, startDate string, endDate string) float64 {
+func (slr *SleepLogRepository) SocialJetLag(userID string, startDate string, endDate string) float64 {
 
-	rows, queryErr := slr.db.Query(sleepStdDevQuery, userID, startDate, endDate)
+	rows, queryErr := slr.db.Query(socialJetLagQuery, userID, startDate, endDate)
 	if queryErr != nil {
 		panic(queryErr)
 	}
 	defer rows.Close()
 
-	var standardDeviation sql.NullFloat64
+	var socialJetLag sql.NullFloat64
 
 	if next := rows.Next(); next {
-		scanErr := rows.Scan(&standardDeviation)
+		scanErr := rows.Scan(&socialJetLag)
 		if scanErr != nil {
 			panic(scanErr)
 		}
 	}
 
-	if !standardDeviation.Valid {
+	// null when the range has no weekend or no weekday nights with a sleep_start
+	if !socialJetLag.Valid {
 		return 0.0
 	}
 
-	return standardDeviation.Float64
+	return socialJetLag.Float64
+}
+
+func (slr *SleepLogRepository) VariabilityIndex(userID string, startDate string, endDate string) float64 {
+
+	rows, queryErr := slr.db.Query(sleepVariabilityIndexQuery, userID, startDate, endDate)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var variabilityIndex sql.NullFloat64
+
+	if next := rows.Next(); next {
+		scanErr := rows.Scan(&variabilityIndex)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+	}
+
+	if !variabilityIndex.Valid {
+		return 0.0
+	}
+
+	return variabilityIndex.Float64
+}
+
+func (slr *SleepLogRepository) ShortSleepStreaks(userID string, startDate string, endDate string, maxHours float64, minNights int) []models.Streak {
+
+	rows, queryErr := slr.db.Query(shortSleepStreaksQuery, userID, startDate, endDate, maxHours, minNights)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var streak models.Streak
+	var streaks []models.Streak
+
+	for rows.Next() {
+		scanErr := rows.Scan(
+			&streak.StartDate,
+			&streak.EndDate,
+			&streak.NumDays,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+		streaks = append(streaks, streak)
+	}
+
+	if streaks == nil {
+		return make([]models.Streak, 0)
+	}
+
+	return streaks
+}
+
+// hours slept per night, nights with several logs are summed
+func (slr *SleepLogRepository) DailySleepHours(userID string, startDate string, endDate string) []models.DailyAverage {
+
+	rows, queryErr := slr.db.Query(dailySleepQuery, userID, startDate, endDate)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var dailyAverage models.DailyAverage
+	var dailyAverages []models.DailyAverage
+
+	for rows.Next() {
+		scanErr := rows.Scan(
+			&dailyAverage.Date,
+			&dailyAverage.DailyAvg,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+
+		dailyAverages = append(dailyAverages, dailyAverage)
+	}
+
+	if dailyAverages == nil {
+		return make([]models.DailyAverage, 0)
+	}
+
+	return dailyAverages
 }
 
 /* func (slr *SleepLogRepository) SleepQualityTagFrequency(userID string, startDate string, endDate string) []models.TagFrequency {
@@ -95,3 +198,16 @@ func (slr *SleepLogRepository) StandardDeviation(userID string, startDate string
 	}
 
 } */
+
+func (slr *SleepLogRepository) Buckets(userID string, startDate string, endDate string, granularity string) []models.Bucket {
+
+	query := fmt.Sprintf(sleepBucketsQuery, periodStartExpression(granularity, "sleep_date"), periodStartExpression(granularity, "sl.sleep_date"))
+
+	rows, queryErr := slr.db.Query(query, userID, startDate, endDate, userID, startDate, endDate)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	return scanBuckets(rows)
+}
@@ -0,0 +1,111 @@
+package database
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSleepDebtAndSocialJetLag(t *testing.T) {
+	db := testDatabase(t)
+	sleepLogRepository := NewSleepLogRepository(db)
+
+	// 2030-01-05 and 06 are a saturday and sunday, the weekday nights fall
+	// asleep at 23:00 for 7 hours (midpoint 02:30) and the weekend nights at
+	// 01:00 for 8 hours (midpoint 05:00). Monday is logged in two parts
+	statements := []string{
+		`INSERT INTO sleep_log (user_id, hours_slept, sleep_start, sleep_quality_tag_id, sleep_date) VALUES
+		 (1, 7, '23:00:00', 2, '2030-01-03'),
+		 (1, 7, '23:00:00', 2, '2030-01-04'),
+		 (1, 8, '01:00:00', 2, '2030-01-05'),
+		 (1, 8, '01:00:00', 2, '2030-01-06'),
+		 (1, 4, '23:00:00', 2, '2030-01-07'),
+		 (1, 3, '23:00:00', 2, '2030-01-07')`,
+	}
+	for _, statement := range statements {
+		if _, execErr := db.Exec(statement); execErr != nil {
+			t.Fatal(execErr)
+		}
+	}
+
+	// 1 + 1 + 0 + 0 + 1, the two parts of monday count as one 7 hour night
+	if sleepDebt := sleepLogRepository.SleepDebt("1", "2030-01-03", "2030-01-07", 8); math.Abs(sleepDebt-3) > 1e-9 {
+		t.Errorf("sleep debt = %v, want 3", sleepDebt)
+	}
+
+	// monday's parts have midpoints of 01:00 and 00:30, the weekday average
+	// is (150 + 150 + 45) / 3 minutes
+	wantJetLag := (300 - 115.0) / 60
+	if socialJetLag := sleepLogRepository.SocialJetLag("1", "2030-01-03", "2030-01-07"); math.Abs(socialJetLag-wantJetLag) > 1e-4 {
+		t.Errorf("social jet lag = %v, want %v", socialJetLag, wantJetLag)
+	}
+
+	if socialJetLag := sleepLogRepository.SocialJetLag("1", "2030-01-03", "2030-01-04"); socialJetLag != 0 {
+		t.Errorf("social jet lag without weekend nights = %v, want 0", socialJetLag)
+	}
+}
+
+func TestShortSleepStreaks(t *testing.T) {
+	db := testDatabase(t)
+	sleepLogRepository := NewSleepLogRepository(db)
+
+	// the 2nd is logged in two parts that add up to a short night, the 4th in
+	// two short parts that add up to a full one
+	if _, execErr := db.Exec(`INSERT INTO sleep_log (user_id, hours_slept, sleep_quality_tag_id, sleep_date) VALUES
+		 (1, 4, 4, '2030-02-01'),
+		 (1, 2, 4, '2030-02-02'),
+		 (1, 2, 4, '2030-02-02'),
+		 (1, 4, 4, '2030-02-03'),
+		 (1, 3, 2, '2030-02-04'),
+		 (1, 4, 2, '2030-02-04'),
+		 (1, 4, 4, '2030-02-05'),
+		 (1, 4, 4, '2030-02-06')`); execErr != nil {
+		t.Fatal(execErr)
+	}
+
+	streaks := sleepLogRepository.ShortSleepStreaks("1", "2030-02-01", "2030-02-06", 5, 2)
+	if len(streaks) != 2 {
+		t.Fatalf("streaks = %+v, want the 1st to 3rd and the 5th to 6th", streaks)
+	}
+	if streak := streaks[0]; streak.StartDate != "2030-02-01" || streak.EndDate != "2030-02-03" || streak.NumDays != 3 {
+		t.Errorf("first streak = %+v, want 3 nights from the 1st to the 3rd", streak)
+	}
+	if streak := streaks[1]; streak.StartDate != "2030-02-05" || streak.NumDays != 2 {
+		t.Errorf("second streak = %+v, want 2 nights from the 5th", streak)
+	}
+}
+
+func TestNightlySleepStatistics(t *testing.T) {
+	db := testDatabase(t)
+	sleepLogRepository := NewSleepLogRepository(db)
+
+	// the 1st is logged in two parts, nothing is logged on the 3rd and 4th
+	if _, execErr := db.Exec(`INSERT INTO sleep_log (user_id, hours_slept, sleep_quality_tag_id, sleep_date) VALUES
+		 (1, 3, 3, '2030-04-01'),
+		 (1, 3, 3, '2030-04-01'),
+		 (1, 8, 1, '2030-04-02'),
+		 (1, 4, 3, '2030-04-05')`); execErr != nil {
+		t.Fatal(execErr)
+	}
+
+	// nights of 6, 8 and 4 hours
+	wantStdDev := math.Sqrt(8.0 / 3)
+	if avg := sleepLogRepository.AvgSleepHours("1", "2030-04-01", "2030-04-05"); math.Abs(avg-6) > 1e-9 {
+		t.Errorf("average = %v, want 6", avg)
+	}
+	if stdDev := sleepLogRepository.StandardDeviation("1", "2030-04-01", "2030-04-05"); math.Abs(stdDev-wantStdDev) > 1e-4 {
+		t.Errorf("standard deviation = %v, want %v", stdDev, wantStdDev)
+	}
+
+	// the 5th follows a gap, only the 1st and 2nd are compared
+	if variability := sleepLogRepository.VariabilityIndex("1", "2030-04-01", "2030-04-05"); math.Abs(variability-2) > 1e-9 {
+		t.Errorf("variability index = %v, want 2", variability)
+	}
+
+	buckets := sleepLogRepository.Buckets("1", "2030-04-01", "2030-04-05", "month")
+	if len(buckets) != 1 {
+		t.Fatalf("buckets = %+v, want one for april", buckets)
+	}
+	if bucket := buckets[0]; math.Abs(bucket.Avg-6) > 1e-9 || math.Abs(bucket.StdDeviation-wantStdDev) > 1e-4 || bucket.LogCount != 4 || bucket.TopTag != "Fair" {
+		t.Errorf("bucket = %+v, want 6 hours a night over 4 logs mostly fair", bucket)
+	}
+}
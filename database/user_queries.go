@@ -0,0 +1,21 @@
+package database
+
+var userIDsQuery = `SELECT user_id
+FROM   user
+ORDER  BY user_id;`
+
+var userProfileQuery = `SELECT user_id,
+       email,
+       created_at,
+       updated_at
+FROM   user
+WHERE  user_id = ?;`
+
+var organisationMembershipsQuery = `SELECT o.organisation_id,
+       o.NAME,
+       om.created_at
+FROM   organisation_member om
+       INNER JOIN organisation o
+               ON om.organisation_id = o.organisation_id
+WHERE  om.user_id = ?
+ORDER  BY o.organisation_id;`
@@ -0,0 +1,91 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+type UserRepository struct {
+	db *sql.DB
+}
+
+func NewUserRepository(dbConnection *sql.DB) *UserRepository {
+	return &UserRepository{
+		db: dbConnection,
+	}
+}
+
+func (ur *UserRepository) UserIDs() []string {
+
+	rows, queryErr := ur.db.Query(userIDsQuery)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var userIDs []string
+
+	for rows.Next() {
+		var userID string
+		scanErr := rows.Scan(&userID)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	if userIDs == nil {
+		return make([]string, 0)
+	}
+
+	return userIDs
+}
+
+// Profile is what's held on the user themselves, the password hash is left out
+func (ur *UserRepository) Profile(userID string) (models.UserProfile, bool) {
+
+	rows, queryErr := ur.db.Query(userProfileQuery, userID)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	if next := rows.Next(); !next {
+		return models.UserProfile{}, false
+	}
+
+	var profile models.UserProfile
+	scanErr := rows.Scan(&profile.UserID, &profile.Email, &profile.CreatedAt, &profile.UpdatedAt)
+	if scanErr != nil {
+		panic(scanErr)
+	}
+
+	return profile, true
+}
+
+func (ur *UserRepository) OrganisationMemberships(userID string) []models.OrganisationMembership {
+
+	rows, queryErr := ur.db.Query(organisationMembershipsQuery, userID)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var memberships []models.OrganisationMembership
+
+	for rows.Next() {
+		var membership models.OrganisationMembership
+		scanErr := rows.Scan(&membership.OrganisationID, &membership.Name, &membership.JoinedAt)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+		memberships = append(memberships, membership)
+	}
+
+	if memberships == nil {
+		return make([]models.OrganisationMembership, 0)
+	}
+
+	return memberships
+}
@@ -0,0 +1,28 @@
+package database
+
+var targetSleepHoursQuery = `SELECT COALESCE(
+       (SELECT target_sleep_hours
+        FROM   user_settings
+        WHERE  user_id = ?), 8.0) AS target_sleep_hours;`
+
+var timezoneQuery = `SELECT COALESCE(
+       (SELECT timezone
+        FROM   user_settings
+        WHERE  user_id = ?), 'UTC') AS timezone;`
+
+var saveUserSettingsQuery = `INSERT INTO user_settings
+            (user_id,
+             target_sleep_hours,
+             timezone)
+VALUES      (?, ?, ?)
+ON DUPLICATE KEY UPDATE target_sleep_hours = VALUES(target_sleep_hours),
+                        timezone = VALUES(timezone);`
+
+// utcBoundsQuery turns the user's local dates into the UTC instants the range
+// starts and ends at, the end is the midnight after endDate
+var utcBoundsQuery = `SELECT utc_datetime(?, Cast(? AS DATETIME)),
+       utc_datetime(?, Cast(? AS DATETIME) + interval 1 day);`
+
+// CONVERT_TZ returns NULL for a zone MySQL doesn't know, and for every named
+// zone when the time zone tables aren't loaded
+var timezoneKnownQuery = `SELECT CONVERT_TZ('2000-01-01 00:00:00', '+00:00', ?) IS NOT NULL;`
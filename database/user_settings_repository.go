@@ -0,0 +1,91 @@
+package database
+
+import (
+	"database/sql"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+type UserSettingsRepository struct {
+	db *sql.DB
+}
+
+func NewUserSettingsRepository(dbConnection *sql.DB) *UserSettingsRepository {
+	return &UserSettingsRepository{
+		db: dbConnection,
+	}
+}
+
+func (usr *UserSettingsRepository) TargetSleepHours(userID string) float64 {
+
+	rows, queryErr := usr.db.Query(targetSleepHoursQuery, userID)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var targetSleepHours float64
+	if next := rows.Next(); next {
+		scanErr := rows.Scan(&targetSleepHours)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+	}
+
+	return targetSleepHours
+}
+
+func (usr *UserSettingsRepository) Timezone(userID string) string {
+
+	rows, queryErr := usr.db.Query(timezoneQuery, userID)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	timezone := "UTC"
+	if next := rows.Next(); next {
+		scanErr := rows.Scan(&timezone)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+	}
+
+	return timezone
+}
+
+func (usr *UserSettingsRepository) Settings(userID string) models.UserSettings {
+	return models.UserSettings{
+		UserID:           userID,
+		TargetSleepHours: usr.TargetSleepHours(userID),
+		Timezone:         usr.Timezone(userID),
+	}
+}
+
+func (usr *UserSettingsRepository) SaveSettings(settings models.UserSettings) {
+
+	_, execErr := usr.db.Exec(saveUserSettingsQuery, settings.UserID, settings.TargetSleepHours, settings.Timezone)
+	if execErr != nil {
+		panic(execErr)
+	}
+}
+
+// TimezoneKnown reports whether MySQL can convert to the named zone
+func (usr *UserSettingsRepository) TimezoneKnown(timezone string) bool {
+
+	rows, queryErr := usr.db.Query(timezoneKnownQuery, timezone)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var known bool
+	if next := rows.Next(); next {
+		scanErr := rows.Scan(&known)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+	}
+
+	return known
+}
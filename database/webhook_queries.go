@@ -0,0 +1,180 @@
+package database
+
+// dedupe_key is null for events that can never repeat, e.g. mood_log.created
+var publishEventQuery = `INSERT IGNORE INTO webhook_event
+            (user_id,
+             event_type,
+             dedupe_key,
+             payload)
+VALUES      (?, ?, ?, ?);`
+
+var createSubscriptionQuery = `INSERT INTO webhook_subscription
+            (user_id,
+             organisation_id,
+             url,
+             secret,
+             event_types)
+VALUES      (?, ?, ?, ?, ?);`
+
+var subscriptionsQuery = `SELECT subscription_id,
+       COALESCE(user_id, ''),
+       COALESCE(organisation_id, ''),
+       url,
+       event_types,
+       active,
+       created_at
+FROM   webhook_subscription
+WHERE  ( user_id = ?
+          OR organisation_id = ? )
+ORDER  BY subscription_id;`
+
+var subscriptionQuery = `SELECT subscription_id,
+       COALESCE(user_id, ''),
+       COALESCE(organisation_id, ''),
+       url,
+       event_types,
+       active,
+       created_at
+FROM   webhook_subscription
+WHERE  subscription_id = ?;`
+
+var organisationAdminQuery = `SELECT Count(*)
+FROM   organisation_member
+WHERE  organisation_id = ?
+       AND user_id = ?
+       AND role = 'admin';`
+
+var deactivateSubscriptionQuery = `UPDATE webhook_subscription
+SET    active = 0
+WHERE  subscription_id = ?
+       AND active = 1;`
+
+var undispatchedEventsQuery = `SELECT event_id,
+       user_id,
+       event_type,
+       created_at,
+       payload
+FROM   webhook_event
+WHERE  dispatched = 0
+ORDER  BY event_id
+LIMIT  ?;`
+
+// a subscription matches an event for its own user or for any member of its organisation
+var fanOutEventQuery = `INSERT INTO webhook_delivery
+            (subscription_id,
+             event_id)
+SELECT ws.subscription_id,
+       ?
+FROM   webhook_subscription ws
+WHERE  ws.active = 1
+       AND Find_in_set(?, ws.event_types) > 0
+       AND ( ws.user_id = ?
+              OR ws.organisation_id IN (SELECT om.organisation_id
+                                        FROM   organisation_member om
+                                        WHERE  om.user_id = ?) );`
+
+var markEventDispatchedQuery = `UPDATE webhook_event
+SET    dispatched = 1
+WHERE  event_id = ?;`
+
+var dueDeliveriesQuery = `SELECT wd.delivery_id,
+       wd.subscription_id,
+       wd.status,
+       wd.attempt_count,
+       wd.next_attempt_at,
+       wd.created_at,
+       we.event_id,
+       we.user_id,
+       we.event_type,
+       we.created_at,
+       we.payload,
+       ws.url,
+       ws.secret
+FROM   webhook_delivery wd
+       INNER JOIN webhook_event we
+               ON wd.event_id = we.event_id
+       INNER JOIN webhook_subscription ws
+               ON wd.subscription_id = ws.subscription_id
+WHERE  wd.status = 'pending'
+       AND wd.next_attempt_at <= CURRENT_TIMESTAMP
+       AND ws.active = 1
+ORDER  BY wd.next_attempt_at
+LIMIT  ?;`
+
+var recordAttemptQuery = `INSERT INTO webhook_delivery_attempt
+            (delivery_id,
+             attempt_number,
+             status_code,
+             error,
+             duration_ms)
+VALUES      (?, ?, ?, ?, ?);`
+
+var markDeliveredQuery = `UPDATE webhook_delivery
+SET    status = 'succeeded',
+       attempt_count = attempt_count + 1
+WHERE  delivery_id = ?;`
+
+var scheduleRetryQuery = `UPDATE webhook_delivery
+SET    attempt_count = attempt_count + 1,
+       next_attempt_at = Date_add(CURRENT_TIMESTAMP, interval ? second)
+WHERE  delivery_id = ?;`
+
+var markDeadQuery = `UPDATE webhook_delivery
+SET    status = 'dead',
+       attempt_count = attempt_count + 1
+WHERE  delivery_id = ?;`
+
+var insertDeadLetterQuery = `INSERT INTO webhook_dead_letter
+            (delivery_id,
+             subscription_id,
+             event_id,
+             last_error)
+SELECT delivery_id,
+       subscription_id,
+       event_id,
+       ?
+FROM   webhook_delivery
+WHERE  delivery_id = ?;`
+
+var deliveriesQuery = `SELECT wd.delivery_id,
+       wd.subscription_id,
+       wd.status,
+       wd.attempt_count,
+       wd.next_attempt_at,
+       wd.created_at,
+       we.event_id,
+       we.user_id,
+       we.event_type,
+       we.created_at,
+       we.payload
+FROM   webhook_delivery wd
+       INNER JOIN webhook_event we
+               ON wd.event_id = we.event_id
+WHERE  wd.subscription_id = ?
+ORDER  BY wd.delivery_id DESC
+LIMIT  ?;`
+
+var deliveryAttemptsQuery = `SELECT attempt_number,
+       status_code,
+       error,
+       duration_ms,
+       attempted_at
+FROM   webhook_delivery_attempt
+WHERE  delivery_id = ?
+ORDER  BY attempt_number;`
+
+var deadLettersQuery = `SELECT wdl.dead_letter_id,
+       wdl.delivery_id,
+       wdl.subscription_id,
+       wdl.last_error,
+       wdl.created_at,
+       we.event_id,
+       we.user_id,
+       we.event_type,
+       we.created_at,
+       we.payload
+FROM   webhook_dead_letter wdl
+       INNER JOIN webhook_event we
+               ON wdl.event_id = we.event_id
+WHERE  wdl.subscription_id = ?
+ORDER  BY wdl.dead_letter_id DESC;`
@@ -0,0 +1,406 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(dbConnection *sql.DB) *WebhookRepository {
+	return &WebhookRepository{
+		db: dbConnection,
+	}
+}
+
+// PublishEvent adds an event to the outbox, events with a dedupe key that was
+// already published are ignored
+func (wr *WebhookRepository) PublishEvent(userID string, eventType string, dedupeKey string, payload []byte) {
+
+	var key sql.NullString
+	if dedupeKey != "" {
+		key = sql.NullString{String: dedupeKey, Valid: true}
+	}
+
+	_, execErr := wr.db.Exec(publishEventQuery, userID, eventType, key, payload)
+	if execErr != nil {
+		panic(execErr)
+	}
+}
+
+func (wr *WebhookRepository) CreateSubscription(subscription models.WebhookSubscription) int {
+
+	var userID sql.NullString
+	if subscription.UserID != "" {
+		userID = sql.NullString{String: subscription.UserID, Valid: true}
+	}
+
+	var organisationID sql.NullString
+	if subscription.OrganisationID != "" {
+		organisationID = sql.NullString{String: subscription.OrganisationID, Valid: true}
+	}
+
+	eventTypes := strings.Join(subscription.EventTypes, ",")
+
+	result, execErr := wr.db.Exec(createSubscriptionQuery, userID, organisationID, subscription.URL, subscription.Secret, eventTypes)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	subscriptionID, idErr := result.LastInsertId()
+	if idErr != nil {
+		panic(idErr)
+	}
+
+	return int(subscriptionID)
+}
+
+func (wr *WebhookRepository) Subscriptions(userID string, organisationID string) []models.WebhookSubscription {
+
+	rows, queryErr := wr.db.Query(subscriptionsQuery, userID, organisationID)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var subscriptions []models.WebhookSubscription
+
+	for rows.Next() {
+		subscriptions = append(subscriptions, scanSubscription(rows))
+	}
+
+	if subscriptions == nil {
+		return make([]models.WebhookSubscription, 0)
+	}
+
+	return subscriptions
+}
+
+func (wr *WebhookRepository) Subscription(subscriptionID string) (models.WebhookSubscription, bool) {
+
+	rows, queryErr := wr.db.Query(subscriptionQuery, subscriptionID)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	if next := rows.Next(); !next {
+		return models.WebhookSubscription{}, false
+	}
+
+	return scanSubscription(rows), true
+}
+
+// IsOrganisationAdmin is whether the user can manage the organisation's webhooks
+func (wr *WebhookRepository) IsOrganisationAdmin(organisationID string, userID string) bool {
+
+	rows, queryErr := wr.db.Query(organisationAdminQuery, organisationID, userID)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var count int
+	if next := rows.Next(); next {
+		if scanErr := rows.Scan(&count); scanErr != nil {
+			panic(scanErr)
+		}
+	}
+
+	return count > 0
+}
+
+func (wr *WebhookRepository) DeactivateSubscription(subscriptionID string) bool {
+
+	result, execErr := wr.db.Exec(deactivateSubscriptionQuery, subscriptionID)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	rowsAffected, rowsErr := result.RowsAffected()
+	if rowsErr != nil {
+		panic(rowsErr)
+	}
+
+	return rowsAffected == 1
+}
+
+func (wr *WebhookRepository) UndispatchedEvents(limit int) []models.WebhookEvent {
+
+	rows, queryErr := wr.db.Query(undispatchedEventsQuery, limit)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var events []models.WebhookEvent
+
+	for rows.Next() {
+		var event models.WebhookEvent
+		scanErr := rows.Scan(
+			&event.EventID,
+			&event.UserID,
+			&event.EventType,
+			&event.CreatedAt,
+			&event.Data,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+		events = append(events, event)
+	}
+
+	if events == nil {
+		return make([]models.WebhookEvent, 0)
+	}
+
+	return events
+}
+
+// FanOutEvent creates a pending delivery for every matching subscription and
+// marks the event as dispatched in a single transaction
+func (wr *WebhookRepository) FanOutEvent(event models.WebhookEvent) {
+
+	tx, txErr := wr.db.Begin()
+	if txErr != nil {
+		panic(txErr)
+	}
+	defer tx.Rollback()
+
+	_, execErr := tx.Exec(fanOutEventQuery, event.EventID, event.EventType, event.UserID, event.UserID)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	_, execErr = tx.Exec(markEventDispatchedQuery, event.EventID)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	commitErr := tx.Commit()
+	if commitErr != nil {
+		panic(commitErr)
+	}
+}
+
+func (wr *WebhookRepository) DueDeliveries(limit int) []models.WebhookDelivery {
+
+	rows, queryErr := wr.db.Query(dueDeliveriesQuery, limit)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		scanErr := rows.Scan(
+			&delivery.DeliveryID,
+			&delivery.SubscriptionID,
+			&delivery.Status,
+			&delivery.AttemptCount,
+			&delivery.NextAttemptAt,
+			&delivery.CreatedAt,
+			&delivery.Event.EventID,
+			&delivery.Event.UserID,
+			&delivery.Event.EventType,
+			&delivery.Event.CreatedAt,
+			&delivery.Event.Data,
+			&delivery.URL,
+			&delivery.Secret,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	if deliveries == nil {
+		return make([]models.WebhookDelivery, 0)
+	}
+
+	return deliveries
+}
+
+func (wr *WebhookRepository) RecordAttempt(deliveryID int, attempt models.WebhookDeliveryAttempt) {
+
+	_, execErr := wr.db.Exec(recordAttemptQuery, deliveryID, attempt.AttemptNumber, attempt.StatusCode, attempt.Error, attempt.DurationMs)
+	if execErr != nil {
+		panic(execErr)
+	}
+}
+
+func (wr *WebhookRepository) MarkDelivered(deliveryID int) {
+
+	_, execErr := wr.db.Exec(markDeliveredQuery, deliveryID)
+	if execErr != nil {
+		panic(execErr)
+	}
+}
+
+func (wr *WebhookRepository) ScheduleRetry(deliveryID int, delaySeconds int) {
+
+	_, execErr := wr.db.Exec(scheduleRetryQuery, delaySeconds, deliveryID)
+	if execErr != nil {
+		panic(execErr)
+	}
+}
+
+// DeadLetter gives up on a delivery and copies it to the dead-letter table
+func (wr *WebhookRepository) DeadLetter(deliveryID int, lastError string) {
+
+	tx, txErr := wr.db.Begin()
+	if txErr != nil {
+		panic(txErr)
+	}
+	defer tx.Rollback()
+
+	_, execErr := tx.Exec(markDeadQuery, deliveryID)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	_, execErr = tx.Exec(insertDeadLetterQuery, lastError, deliveryID)
+	if execErr != nil {
+		panic(execErr)
+	}
+
+	commitErr := tx.Commit()
+	if commitErr != nil {
+		panic(commitErr)
+	}
+}
+
+func (wr *WebhookRepository) Deliveries(subscriptionID string, limit int) []models.WebhookDelivery {
+
+	rows, queryErr := wr.db.Query(deliveriesQuery, subscriptionID, limit)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		scanErr := rows.Scan(
+			&delivery.DeliveryID,
+			&delivery.SubscriptionID,
+			&delivery.Status,
+			&delivery.AttemptCount,
+			&delivery.NextAttemptAt,
+			&delivery.CreatedAt,
+			&delivery.Event.EventID,
+			&delivery.Event.UserID,
+			&delivery.Event.EventType,
+			&delivery.Event.CreatedAt,
+			&delivery.Event.Data,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	for i := 0; i < len(deliveries); i++ {
+		deliveries[i].Attempts = wr.deliveryAttempts(deliveries[i].DeliveryID)
+	}
+
+	if deliveries == nil {
+		return make([]models.WebhookDelivery, 0)
+	}
+
+	return deliveries
+}
+
+func (wr *WebhookRepository) deliveryAttempts(deliveryID int) []models.WebhookDeliveryAttempt {
+
+	rows, queryErr := wr.db.Query(deliveryAttemptsQuery, deliveryID)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	attempts := make([]models.WebhookDeliveryAttempt, 0)
+
+	for rows.Next() {
+		var attempt models.WebhookDeliveryAttempt
+		scanErr := rows.Scan(
+			&attempt.AttemptNumber,
+			&attempt.StatusCode,
+			&attempt.Error,
+			&attempt.DurationMs,
+			&attempt.AttemptedAt,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts
+}
+
+func (wr *WebhookRepository) DeadLetters(subscriptionID string) []models.WebhookDeadLetter {
+
+	rows, queryErr := wr.db.Query(deadLettersQuery, subscriptionID)
+	if queryErr != nil {
+		panic(queryErr)
+	}
+	defer rows.Close()
+
+	var deadLetters []models.WebhookDeadLetter
+
+	for rows.Next() {
+		var deadLetter models.WebhookDeadLetter
+		scanErr := rows.Scan(
+			&deadLetter.DeadLetterID,
+			&deadLetter.DeliveryID,
+			&deadLetter.SubscriptionID,
+			&deadLetter.LastError,
+			&deadLetter.CreatedAt,
+			&deadLetter.Event.EventID,
+			&deadLetter.Event.UserID,
+			&deadLetter.Event.EventType,
+			&deadLetter.Event.CreatedAt,
+			&deadLetter.Event.Data,
+		)
+		if scanErr != nil {
+			panic(scanErr)
+		}
+		deadLetters = append(deadLetters, deadLetter)
+	}
+
+	if deadLetters == nil {
+		return make([]models.WebhookDeadLetter, 0)
+	}
+
+	return deadLetters
+}
+
+func scanSubscription(rows *sql.Rows) models.WebhookSubscription {
+	var subscription models.WebhookSubscription
+	var eventTypes string
+
+	scanErr := rows.Scan(
+		&subscription.SubscriptionID,
+		&subscription.UserID,
+		&subscription.OrganisationID,
+		&subscription.URL,
+		&eventTypes,
+		&subscription.Active,
+		&subscription.CreatedAt,
+	)
+	if scanErr != nil {
+		panic(scanErr)
+	}
+
+	subscription.EventTypes = strings.Split(eventTypes, ",")
+
+	return subscription
+}
@@ -1,7 +1,37 @@
 CREATE DATABASE IF NOT EXISTS project_horizon;
 USE project_horizon;
 
+-- TIMESTAMP columns are read and written in UTC, the server opens its
+-- connections with the same time_zone so local_datetime can convert from UTC
+SET time_zone = '+00:00';
+
 -- Optional: Clean slate (use only in dev) - drop children first, then parents
+DROP TABLE IF EXISTS sharing_grant;
+DROP TABLE IF EXISTS api_token;
+DROP TABLE IF EXISTS audit_checkpoint;
+DROP TABLE IF EXISTS audit_chain_head;
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS erasure_request;
+DROP TABLE IF EXISTS mood_log_note_token;
+DROP TABLE IF EXISTS user_data_key;
+DROP TABLE IF EXISTS mood_log_keyword;
+DROP TABLE IF EXISTS mood_log_sentiment;
+DROP TABLE IF EXISTS baseline_period;
+DROP TABLE IF EXISTS daily_sleep_summary;
+DROP TABLE IF EXISTS daily_mood_category_count;
+DROP TABLE IF EXISTS daily_mood_summary;
+DROP TABLE IF EXISTS user_data_version;
+DROP TABLE IF EXISTS import_job;
+DROP TABLE IF EXISTS import_tag_mapping;
+DROP TABLE IF EXISTS webhook_dead_letter;
+DROP TABLE IF EXISTS webhook_delivery_attempt;
+DROP TABLE IF EXISTS webhook_delivery;
+DROP TABLE IF EXISTS webhook_event;
+DROP TABLE IF EXISTS webhook_subscription;
+DROP TABLE IF EXISTS organisation_member;
+DROP TABLE IF EXISTS organisation;
+DROP TABLE IF EXISTS alert;
+DROP TABLE IF EXISTS user_settings;
 DROP TABLE IF EXISTS mood_log_mood_tag;
 DROP TABLE IF EXISTS user_medication;
 DROP TABLE IF EXISTS medication_log;
@@ -12,6 +42,12 @@ DROP TABLE IF EXISTS mood_category;
 DROP TABLE IF EXISTS medication;
 DROP TABLE IF EXISTS sleep_quality_tag;
 DROP TABLE IF EXISTS user;
+DROP FUNCTION IF EXISTS local_datetime;
+DROP FUNCTION IF EXISTS utc_datetime;
+DROP PROCEDURE IF EXISTS bump_data_version;
+DROP PROCEDURE IF EXISTS refresh_daily_mood_summary;
+DROP PROCEDURE IF EXISTS refresh_daily_sleep_summary;
+DROP PROCEDURE IF EXISTS rebuild_daily_summaries;
 
 -- User table
 CREATE TABLE IF NOT EXISTS user (
@@ -86,11 +122,13 @@ CREATE TABLE IF NOT EXISTS mood_log (
     user_id BIGINT UNSIGNED NOT NULL,
     mood_rating INT NOT NULL CHECK (mood_rating BETWEEN 1 AND 10),
     note TEXT,
+    note_key_version INT, -- the data key version the note is encrypted under, NULL while it's plaintext
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
     CONSTRAINT fk_mood_log_user FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE,
     INDEX idx_user_created (user_id, created_at),
-    INDEX idx_created_at (created_at)
+    INDEX idx_created_at (created_at),
+    INDEX idx_updated_at (updated_at)
 );
 
 -- Mood log mood tags join table
@@ -117,8 +155,10 @@ CREATE TABLE IF NOT EXISTS sleep_log (
     sleep_log_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
     user_id BIGINT UNSIGNED NOT NULL,
     hours_slept DECIMAL(4,2) NOT NULL CHECK (hours_slept >= 0 AND hours_slept <= 24),
+    sleep_start TIME, -- local clock time the user fell asleep, for the midpoint of sleep
     sleep_quality_tag_id BIGINT UNSIGNED NOT NULL,
     notes TEXT,
+    notes_key_version INT, -- as mood_log.note_key_version
     sleep_date DATE NOT NULL,
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
@@ -128,6 +168,649 @@ CREATE TABLE IF NOT EXISTS sleep_log (
     INDEX idx_sleep_date (sleep_date)
 );
 
+-- Per-user settings
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id BIGINT UNSIGNED NOT NULL PRIMARY KEY,
+    target_sleep_hours DECIMAL(4,2) NOT NULL DEFAULT 8.00 CHECK (target_sleep_hours > 0 AND target_sleep_hours <= 24),
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC', -- IANA name, e.g. America/New_York
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    CONSTRAINT fk_user_settings_user FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE
+);
+
+-- Alerts raised by the early-warning rules
+CREATE TABLE IF NOT EXISTS alert (
+    alert_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    rule VARCHAR(50) NOT NULL,
+    severity VARCHAR(20) NOT NULL,
+    message VARCHAR(255) NOT NULL,
+    state ENUM('open', 'acknowledged', 'resolved') NOT NULL DEFAULT 'open',
+    active_key VARCHAR(50),
+    -- first day of the streak or window that raised the alert
+    trigger_date DATE NOT NULL,
+    triggered_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_triggered_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    acknowledged_at TIMESTAMP NULL,
+    resolved_at TIMESTAMP NULL,
+    CONSTRAINT fk_alert_user FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE,
+    UNIQUE KEY unique_user_active_rule (user_id, active_key),
+    INDEX idx_user_state (user_id, state),
+    INDEX idx_user_rule_trigger_date (user_id, rule, trigger_date)
+);
+
+-- Organisations (care teams, partner clinics) and their members
+CREATE TABLE IF NOT EXISTS organisation (
+    organisation_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS organisation_member (
+    organisation_id BIGINT UNSIGNED NOT NULL,
+    user_id BIGINT UNSIGNED NOT NULL,
+    role VARCHAR(20) NOT NULL DEFAULT 'member', -- admins manage the organisation's webhooks
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (role IN ('member', 'admin')),
+    PRIMARY KEY (organisation_id, user_id),
+    CONSTRAINT fk_organisation_member_org FOREIGN KEY (organisation_id) REFERENCES organisation(organisation_id) ON DELETE CASCADE,
+    CONSTRAINT fk_organisation_member_user FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE,
+    INDEX idx_user (user_id)
+);
+
+-- Webhook subscriptions belong to either a user or an organisation
+CREATE TABLE IF NOT EXISTS webhook_subscription (
+    subscription_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED,
+    organisation_id BIGINT UNSIGNED,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    event_types VARCHAR(255) NOT NULL,
+    active TINYINT(1) NOT NULL DEFAULT 1,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_webhook_subscription_user FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE,
+    CONSTRAINT fk_webhook_subscription_org FOREIGN KEY (organisation_id) REFERENCES organisation(organisation_id) ON DELETE CASCADE,
+    CHECK ((user_id IS NULL) <> (organisation_id IS NULL)),
+    INDEX idx_user (user_id),
+    INDEX idx_organisation (organisation_id)
+);
+
+-- Outbox of events waiting to be fanned out to subscriptions
+CREATE TABLE IF NOT EXISTS webhook_event (
+    event_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    event_type VARCHAR(50) NOT NULL,
+    dedupe_key VARCHAR(191),
+    payload JSON NOT NULL,
+    dispatched TINYINT(1) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_webhook_event_user FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE,
+    UNIQUE KEY unique_dedupe_key (dedupe_key),
+    INDEX idx_dispatched (dispatched, event_id)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    delivery_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    subscription_id BIGINT UNSIGNED NOT NULL,
+    event_id BIGINT UNSIGNED NOT NULL,
+    status ENUM('pending', 'succeeded', 'dead') NOT NULL DEFAULT 'pending',
+    attempt_count INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_webhook_delivery_subscription FOREIGN KEY (subscription_id) REFERENCES webhook_subscription(subscription_id) ON DELETE CASCADE,
+    CONSTRAINT fk_webhook_delivery_event FOREIGN KEY (event_id) REFERENCES webhook_event(event_id) ON DELETE CASCADE,
+    UNIQUE KEY unique_subscription_event (subscription_id, event_id),
+    INDEX idx_status_next_attempt (status, next_attempt_at)
+);
+
+-- Delivery log, one row per HTTP attempt
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempt (
+    attempt_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    delivery_id BIGINT UNSIGNED NOT NULL,
+    attempt_number INT NOT NULL,
+    status_code INT NOT NULL DEFAULT 0,
+    error VARCHAR(1024) NOT NULL DEFAULT '',
+    duration_ms INT NOT NULL DEFAULT 0,
+    attempted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_webhook_attempt_delivery FOREIGN KEY (delivery_id) REFERENCES webhook_delivery(delivery_id) ON DELETE CASCADE,
+    INDEX idx_delivery (delivery_id)
+);
+
+-- Deliveries that exhausted their retries
+CREATE TABLE IF NOT EXISTS webhook_dead_letter (
+    dead_letter_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    delivery_id BIGINT UNSIGNED NOT NULL,
+    subscription_id BIGINT UNSIGNED NOT NULL,
+    event_id BIGINT UNSIGNED NOT NULL,
+    last_error VARCHAR(1024) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_webhook_dead_letter_delivery FOREIGN KEY (delivery_id) REFERENCES webhook_delivery(delivery_id) ON DELETE CASCADE,
+    INDEX idx_subscription (subscription_id)
+);
+
+-- User reviewed mapping of labels from other apps onto mood tags, a null
+-- mood_tag_id means the label is ignored
+CREATE TABLE IF NOT EXISTS import_tag_mapping (
+    user_id BIGINT UNSIGNED NOT NULL,
+    source_format VARCHAR(20) NOT NULL,
+    external_label VARCHAR(100) NOT NULL,
+    mood_tag_id BIGINT UNSIGNED,
+    PRIMARY KEY (user_id, source_format, external_label),
+    CONSTRAINT fk_import_tag_mapping_user FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE,
+    CONSTRAINT fk_import_tag_mapping_tag FOREIGN KEY (mood_tag_id) REFERENCES mood_tag(mood_tag_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS import_job (
+    import_job_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    source_format VARCHAR(20) NOT NULL,
+    dry_run TINYINT(1) NOT NULL DEFAULT 0,
+    status ENUM('pending', 'running', 'completed', 'failed') NOT NULL DEFAULT 'pending',
+    total_rows INT NOT NULL DEFAULT 0,
+    processed_rows INT NOT NULL DEFAULT 0,
+    imported_count INT NOT NULL DEFAULT 0,
+    duplicate_count INT NOT NULL DEFAULT 0,
+    skipped_count INT NOT NULL DEFAULT 0,
+    error TEXT,
+    report JSON,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    CONSTRAINT fk_import_job_user FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE,
+    INDEX idx_user (user_id)
+);
+
+-- Daily rollups of mood_log and sleep_log kept up to date by triggers, the
+-- mood day is the user's local day (see local_datetime)
+CREATE TABLE IF NOT EXISTS daily_mood_summary (
+    user_id BIGINT UNSIGNED NOT NULL,
+    summary_date DATE NOT NULL,
+    rating_sum INT NOT NULL,
+    log_count INT NOT NULL,
+    tag_count INT NOT NULL DEFAULT 0,
+    avg_rating DECIMAL(8,4) AS (rating_sum / log_count) STORED,
+    PRIMARY KEY (user_id, summary_date),
+    CONSTRAINT fk_daily_mood_summary_user FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS daily_mood_category_count (
+    user_id BIGINT UNSIGNED NOT NULL,
+    summary_date DATE NOT NULL,
+    mood_category_id BIGINT UNSIGNED NOT NULL,
+    tag_count INT NOT NULL,
+    PRIMARY KEY (user_id, summary_date, mood_category_id),
+    CONSTRAINT fk_daily_mood_category_count_summary FOREIGN KEY (user_id, summary_date) REFERENCES daily_mood_summary(user_id, summary_date) ON DELETE CASCADE,
+    CONSTRAINT fk_daily_mood_category_count_category FOREIGN KEY (mood_category_id) REFERENCES mood_category(mood_category_id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS daily_sleep_summary (
+    user_id BIGINT UNSIGNED NOT NULL,
+    sleep_date DATE NOT NULL,
+    hours_slept DECIMAL(6,2) NOT NULL, -- sum of the night's logs
+    log_count INT NOT NULL,
+    PRIMARY KEY (user_id, sleep_date),
+    CONSTRAINT fk_daily_sleep_summary_user FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE
+);
+
+-- Date ranges a user has named, e.g. a stretch they felt well, that the mood
+-- analytics can be compared against
+CREATE TABLE IF NOT EXISTS baseline_period (
+    baseline_period_id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    CONSTRAINT fk_baseline_period_user FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE,
+    CONSTRAINT chk_baseline_period_dates CHECK (start_date <= end_date),
+    UNIQUE KEY uq_user_name (user_id, name)
+);
+
+-- Sentiment of each note from the bundled lexicon (package sentiment), filled
+-- in by the background scorer. A log is scored again when it's updated after
+-- analyzed_at or the analyzer version is bumped
+CREATE TABLE IF NOT EXISTS mood_log_sentiment (
+    mood_log_id BIGINT UNSIGNED NOT NULL PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    sentiment_score DECIMAL(5,4) CHECK (sentiment_score BETWEEN -1 AND 1), -- NULL when the log has no note
+    analyzer_version INT NOT NULL,
+    analyzed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_mood_log_sentiment_log FOREIGN KEY (mood_log_id) REFERENCES mood_log(mood_log_id) ON DELETE CASCADE,
+    CONSTRAINT fk_mood_log_sentiment_user FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE,
+    INDEX idx_user (user_id),
+    INDEX idx_analyzer_version (analyzer_version)
+);
+
+-- keywords are as sensitive as the note, the text is encrypted like the note
+-- and grouped on by its blind index
+CREATE TABLE IF NOT EXISTS mood_log_keyword (
+    mood_log_id BIGINT UNSIGNED NOT NULL,
+    keyword_index BINARY(16) NOT NULL,
+    keyword VARCHAR(255) NOT NULL,
+    keyword_count INT NOT NULL,
+    PRIMARY KEY (mood_log_id, keyword_index),
+    CONSTRAINT fk_mood_log_keyword_sentiment FOREIGN KEY (mood_log_id) REFERENCES mood_log_sentiment(mood_log_id) ON DELETE CASCADE
+);
+
+-- Per-user data keys for mood_log.note and sleep_log.notes (package envelope),
+-- wrapped by the master key named in master_key_id. The highest version
+-- encrypts new notes, older versions stay until the re-encryption job has moved
+-- every note off them. Shredding clears wrapped_key so the notes, and any
+-- copies of them, can't be decrypted again
+CREATE TABLE IF NOT EXISTS user_data_key (
+    user_id BIGINT UNSIGNED NOT NULL,
+    key_version INT NOT NULL,
+    wrapped_key VARBINARY(128),
+    master_key_id VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    shredded_at TIMESTAMP NULL,
+    PRIMARY KEY (user_id, key_version),
+    CONSTRAINT fk_user_data_key_user FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE
+);
+
+-- Blind indexes of the words in each encrypted note, for journal search
+CREATE TABLE IF NOT EXISTS mood_log_note_token (
+    mood_log_id BIGINT UNSIGNED NOT NULL,
+    user_id BIGINT UNSIGNED NOT NULL,
+    token BINARY(16) NOT NULL,
+    PRIMARY KEY (mood_log_id, token),
+    CONSTRAINT fk_mood_log_note_token_log FOREIGN KEY (mood_log_id) REFERENCES mood_log(mood_log_id) ON DELETE CASCADE,
+    INDEX idx_user_token (user_id, token)
+);
+
+-- Account deletions, a request waits out the grace period as scheduled and can
+-- be cancelled until then. A failed erasure is run again after 1, 2, 4...
+-- hours and abandoned for an operator after the last attempt. There's
+-- deliberately no foreign key to user, the request and its verification
+-- report are the record that the erasure ran and outlive the user they were
+-- about
+CREATE TABLE IF NOT EXISTS erasure_request (
+    erasure_request_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    status ENUM('scheduled', 'cancelled', 'running', 'completed', 'failed', 'abandoned') NOT NULL DEFAULT 'scheduled',
+    requested_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    scheduled_for TIMESTAMP NOT NULL,
+    cancelled_at TIMESTAMP NULL,
+    completed_at TIMESTAMP NULL,
+    attempts INT UNSIGNED NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NULL, -- when a failed erasure runs again
+    report JSON,
+    INDEX idx_user (user_id),
+    INDEX idx_status_scheduled (status, scheduled_for),
+    INDEX idx_status_next_attempt (status, next_attempt_at)
+);
+
+-- Reads of a user's data by someone else (package audit). Entries are spread
+-- over 16 chains by subject_user_id % 16 so appends for different users don't
+-- wait on each other. Each entry's hash covers its fields and the previous
+-- hash in its chain, so editing, removing or reordering entries breaks the
+-- chain. Like erasure_request there are no foreign keys, the log outlives the
+-- users in it: an erasure keeps their entries, which hold ids, paths and dates
+-- but no health data, as the record of who read what and deleting them would
+-- break the chains
+CREATE TABLE IF NOT EXISTS audit_log (
+    audit_log_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    chain_id TINYINT UNSIGNED NOT NULL,
+    subject_user_id BIGINT UNSIGNED NOT NULL, -- whose data was read
+    viewer_user_id BIGINT UNSIGNED NOT NULL,
+    grant_id VARCHAR(64) NOT NULL DEFAULT '', -- the sharing_grant the read was made under
+    resource VARCHAR(255) NOT NULL,
+    start_date DATE,
+    end_date DATE,
+    accessed_at TIMESTAMP NOT NULL,
+    previous_hash CHAR(64) NOT NULL,
+    entry_hash CHAR(64) NOT NULL,
+    INDEX idx_chain (chain_id, audit_log_id),
+    INDEX idx_subject (subject_user_id, audit_log_id),
+    INDEX idx_viewer (viewer_user_id)
+);
+
+-- Hash of the newest entry in each chain, appending locks the chain's row so
+-- its entries are chained one at a time across instances
+CREATE TABLE IF NOT EXISTS audit_chain_head (
+    chain_id TINYINT UNSIGNED NOT NULL PRIMARY KEY,
+    entry_hash CHAR(64) NOT NULL
+);
+
+-- the first entry of each chain chains from the all zero hash
+INSERT INTO audit_chain_head (chain_id, entry_hash) VALUES
+    (0, REPEAT('0', 64)), (1, REPEAT('0', 64)), (2, REPEAT('0', 64)), (3, REPEAT('0', 64)),
+    (4, REPEAT('0', 64)), (5, REPEAT('0', 64)), (6, REPEAT('0', 64)), (7, REPEAT('0', 64)),
+    (8, REPEAT('0', 64)), (9, REPEAT('0', 64)), (10, REPEAT('0', 64)), (11, REPEAT('0', 64)),
+    (12, REPEAT('0', 64)), (13, REPEAT('0', 64)), (14, REPEAT('0', 64)), (15, REPEAT('0', 64));
+
+-- The newest entry of each chain a verification got to intact, the next one
+-- starts from there instead of re-hashing the whole log
+CREATE TABLE IF NOT EXISTS audit_checkpoint (
+    chain_id TINYINT UNSIGNED NOT NULL PRIMARY KEY,
+    audit_log_id BIGINT UNSIGNED NOT NULL,
+    entry_hash CHAR(64) NOT NULL,
+    entries_verified BIGINT UNSIGNED NOT NULL,
+    verified_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+-- Bumped by triggers whenever anything analytics reads for a user changes,
+-- cached analytics responses are keyed by the version
+CREATE TABLE IF NOT EXISTS user_data_version (
+    user_id BIGINT UNSIGNED NOT NULL PRIMARY KEY,
+    version BIGINT UNSIGNED NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_user_data_version_user FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE
+);
+
+-- Bearer tokens (package auth), only the sha256 of a token is stored so the
+-- table can't be used to sign in. Admin tokens are for operators, they can
+-- verify the audit chain but read nobody's data
+CREATE TABLE IF NOT EXISTS api_token (
+    token_hash CHAR(64) NOT NULL PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL,
+    admin BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NULL,
+    revoked_at TIMESTAMP NULL,
+    CONSTRAINT fk_api_token_user FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE,
+    INDEX idx_user (user_id)
+);
+
+-- A user letting someone else read their data (package sharing). The scopes
+-- are analytics (dashboards and the clinician report), journal (notes search)
+-- and export (raw export and FHIR), the personal data archive is only ever
+-- the user's own
+CREATE TABLE IF NOT EXISTS sharing_grant (
+    sharing_grant_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id BIGINT UNSIGNED NOT NULL, -- whose data is shared
+    grantee_user_id BIGINT UNSIGNED NOT NULL,
+    scopes SET('analytics', 'journal', 'export') NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NULL,
+    revoked_at TIMESTAMP NULL,
+    CONSTRAINT fk_sharing_grant_user FOREIGN KEY (user_id) REFERENCES user(user_id) ON DELETE CASCADE,
+    CONSTRAINT fk_sharing_grant_grantee FOREIGN KEY (grantee_user_id) REFERENCES user(user_id) ON DELETE CASCADE,
+    INDEX idx_user_grantee (user_id, grantee_user_id),
+    INDEX idx_grantee (grantee_user_id)
+);
+
+-- audit_log is append only
+DELIMITER //
+CREATE TRIGGER trg_audit_log_no_update BEFORE UPDATE ON audit_log
+FOR EACH ROW
+BEGIN
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append only';
+END//
+
+CREATE TRIGGER trg_audit_log_no_delete BEFORE DELETE ON audit_log
+FOR EACH ROW
+BEGIN
+    SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append only';
+END//
+DELIMITER ;
+
+-- Publish mood_log.created for every new mood log, whichever service wrote it.
+-- The importer sets @import_job_id on its connection while it writes a batch,
+-- those logs are covered by the job's single import.completed event instead
+DELIMITER //
+CREATE TRIGGER trg_mood_log_created AFTER INSERT ON mood_log
+FOR EACH ROW
+BEGIN
+    IF @import_job_id IS NULL THEN
+        INSERT INTO webhook_event (user_id, event_type, payload)
+        VALUES (NEW.user_id, 'mood_log.created', JSON_OBJECT(
+            'moodLogId', NEW.mood_log_id,
+            'userId', NEW.user_id,
+            'moodRating', NEW.mood_rating,
+            'createdAt', NEW.created_at
+        ));
+    END IF;
+END//
+DELIMITER ;
+
+-- Converts a UTC timestamp to the user's wall clock time, every mood query
+-- takes its day, weekday and hour from this so a log at 11pm in New York stays
+-- on the same day. Named zones need the time zone tables loaded
+-- (mysql_tzinfo_to_sql /usr/share/zoneinfo | mysql -u root mysql) and follow
+-- DST, without them CONVERT_TZ returns NULL and the query fails rather than
+-- silently putting the logs on UTC days
+DELIMITER //
+CREATE FUNCTION local_datetime(p_user_id BIGINT UNSIGNED, p_created_at TIMESTAMP)
+RETURNS DATETIME
+READS SQL DATA
+BEGIN
+    DECLARE v_timezone VARCHAR(64);
+    DECLARE v_local DATETIME;
+    SET v_timezone = COALESCE((SELECT timezone FROM user_settings WHERE user_id = p_user_id), 'UTC');
+    IF v_timezone = 'UTC' THEN
+        RETURN p_created_at;
+    END IF;
+    SET v_local = CONVERT_TZ(p_created_at, '+00:00', v_timezone);
+    IF v_local IS NULL THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'time zone unknown to MySQL, load the time zone tables';
+    END IF;
+    RETURN v_local;
+END//
+DELIMITER ;
+
+-- The inverse of local_datetime, mood queries filter created_at on the UTC
+-- instants of the local range's ends so the index on it is used
+DELIMITER //
+CREATE FUNCTION utc_datetime(p_user_id BIGINT UNSIGNED, p_local DATETIME)
+RETURNS DATETIME
+READS SQL DATA
+BEGIN
+    DECLARE v_timezone VARCHAR(64);
+    DECLARE v_utc DATETIME;
+    SET v_timezone = COALESCE((SELECT timezone FROM user_settings WHERE user_id = p_user_id), 'UTC');
+    IF v_timezone = 'UTC' THEN
+        RETURN p_local;
+    END IF;
+    SET v_utc = CONVERT_TZ(p_local, v_timezone, '+00:00');
+    IF v_utc IS NULL THEN
+        SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'time zone unknown to MySQL, load the time zone tables';
+    END IF;
+    RETURN v_utc;
+END//
+DELIMITER ;
+
+-- Every write to the logs, their sentiment, settings or baseline periods bumps
+-- the user's data version, rows of mood_log_mood_tag removed by the cascade
+-- from mood_log don't fire triggers but the mood_log delete already bumped the
+-- version
+DELIMITER //
+CREATE PROCEDURE bump_data_version(IN p_user_id BIGINT UNSIGNED)
+BEGIN
+    IF p_user_id IS NOT NULL THEN
+        INSERT INTO user_data_version (user_id, version)
+        VALUES (p_user_id, 1)
+        ON DUPLICATE KEY UPDATE version = version + 1, updated_at = CURRENT_TIMESTAMP;
+    END IF;
+END//
+CREATE TRIGGER trg_mood_log_version_insert AFTER INSERT ON mood_log
+FOR EACH ROW CALL bump_data_version(NEW.user_id)//
+CREATE TRIGGER trg_mood_log_version_update AFTER UPDATE ON mood_log
+FOR EACH ROW CALL bump_data_version(NEW.user_id)//
+CREATE TRIGGER trg_mood_log_version_delete AFTER DELETE ON mood_log
+FOR EACH ROW CALL bump_data_version(OLD.user_id)//
+CREATE TRIGGER trg_sleep_log_version_insert AFTER INSERT ON sleep_log
+FOR EACH ROW CALL bump_data_version(NEW.user_id)//
+CREATE TRIGGER trg_sleep_log_version_update AFTER UPDATE ON sleep_log
+FOR EACH ROW CALL bump_data_version(NEW.user_id)//
+CREATE TRIGGER trg_sleep_log_version_delete AFTER DELETE ON sleep_log
+FOR EACH ROW CALL bump_data_version(OLD.user_id)//
+CREATE TRIGGER trg_medication_log_version_insert AFTER INSERT ON medication_log
+FOR EACH ROW CALL bump_data_version(NEW.user_id)//
+CREATE TRIGGER trg_medication_log_version_update AFTER UPDATE ON medication_log
+FOR EACH ROW CALL bump_data_version(NEW.user_id)//
+CREATE TRIGGER trg_medication_log_version_delete AFTER DELETE ON medication_log
+FOR EACH ROW CALL bump_data_version(OLD.user_id)//
+CREATE TRIGGER trg_mood_log_mood_tag_version_insert AFTER INSERT ON mood_log_mood_tag
+FOR EACH ROW CALL bump_data_version((SELECT user_id FROM mood_log WHERE mood_log_id = NEW.mood_log_id))//
+CREATE TRIGGER trg_mood_log_mood_tag_version_delete AFTER DELETE ON mood_log_mood_tag
+FOR EACH ROW CALL bump_data_version((SELECT user_id FROM mood_log WHERE mood_log_id = OLD.mood_log_id))//
+CREATE TRIGGER trg_user_settings_version_insert AFTER INSERT ON user_settings
+FOR EACH ROW CALL bump_data_version(NEW.user_id)//
+CREATE TRIGGER trg_user_settings_version_update AFTER UPDATE ON user_settings
+FOR EACH ROW CALL bump_data_version(NEW.user_id)//
+CREATE TRIGGER trg_baseline_period_version_insert AFTER INSERT ON baseline_period
+FOR EACH ROW CALL bump_data_version(NEW.user_id)//
+CREATE TRIGGER trg_baseline_period_version_update AFTER UPDATE ON baseline_period
+FOR EACH ROW CALL bump_data_version(NEW.user_id)//
+CREATE TRIGGER trg_baseline_period_version_delete AFTER DELETE ON baseline_period
+FOR EACH ROW CALL bump_data_version(OLD.user_id)//
+CREATE TRIGGER trg_mood_log_sentiment_version_insert AFTER INSERT ON mood_log_sentiment
+FOR EACH ROW CALL bump_data_version(NEW.user_id)//
+CREATE TRIGGER trg_mood_log_sentiment_version_update AFTER UPDATE ON mood_log_sentiment
+FOR EACH ROW CALL bump_data_version(NEW.user_id)//
+DELIMITER ;
+
+-- Recompute one day of the rollups from the logs. A local day lies within a
+-- day either side of the same UTC date, the created_at range lets the
+-- (user_id, created_at) index narrow the scan before local_datetime is applied
+DELIMITER //
+CREATE PROCEDURE refresh_daily_mood_summary(IN p_user_id BIGINT UNSIGNED, IN p_date DATE)
+BEGIN
+    DELETE FROM daily_mood_summary WHERE user_id = p_user_id AND summary_date = p_date;
+
+    INSERT INTO daily_mood_summary (user_id, summary_date, rating_sum, log_count)
+    SELECT p_user_id, p_date, SUM(mood_rating), COUNT(*)
+    FROM   mood_log
+    WHERE  user_id = p_user_id
+           AND created_at BETWEEN p_date - INTERVAL 1 DAY AND p_date + INTERVAL 2 DAY
+           AND DATE(local_datetime(user_id, created_at)) = p_date
+    HAVING COUNT(*) > 0;
+
+    INSERT INTO daily_mood_category_count (user_id, summary_date, mood_category_id, tag_count)
+    SELECT p_user_id, p_date, mt.mood_category_id, COUNT(*)
+    FROM   mood_log ml
+           JOIN mood_log_mood_tag mlmt ON ml.mood_log_id = mlmt.mood_log_id
+           JOIN mood_tag mt ON mlmt.mood_tag_id = mt.mood_tag_id
+    WHERE  ml.user_id = p_user_id
+           AND ml.created_at BETWEEN p_date - INTERVAL 1 DAY AND p_date + INTERVAL 2 DAY
+           AND DATE(local_datetime(ml.user_id, ml.created_at)) = p_date
+    GROUP  BY mt.mood_category_id;
+
+    UPDATE daily_mood_summary
+    SET    tag_count = (SELECT COALESCE(SUM(tag_count), 0)
+                        FROM   daily_mood_category_count
+                        WHERE  user_id = p_user_id AND summary_date = p_date)
+    WHERE  user_id = p_user_id AND summary_date = p_date;
+END//
+
+CREATE PROCEDURE refresh_daily_sleep_summary(IN p_user_id BIGINT UNSIGNED, IN p_date DATE)
+BEGIN
+    DELETE FROM daily_sleep_summary WHERE user_id = p_user_id AND sleep_date = p_date;
+
+    INSERT INTO daily_sleep_summary (user_id, sleep_date, hours_slept, log_count)
+    SELECT p_user_id, p_date, SUM(hours_slept), COUNT(*)
+    FROM   sleep_log
+    WHERE  user_id = p_user_id AND sleep_date = p_date
+    HAVING COUNT(*) > 0;
+END//
+
+-- Rebuilds every day for a user, used by the backfill command and when the
+-- user's timezone changes and every local day moves
+CREATE PROCEDURE rebuild_daily_summaries(IN p_user_id BIGINT UNSIGNED)
+BEGIN
+    DELETE FROM daily_mood_summary WHERE user_id = p_user_id;
+    DELETE FROM daily_sleep_summary WHERE user_id = p_user_id;
+
+    INSERT INTO daily_mood_summary (user_id, summary_date, rating_sum, log_count)
+    SELECT p_user_id, DATE(local_datetime(user_id, created_at)), SUM(mood_rating), COUNT(*)
+    FROM   mood_log
+    WHERE  user_id = p_user_id
+    GROUP  BY DATE(local_datetime(user_id, created_at));
+
+    INSERT INTO daily_mood_category_count (user_id, summary_date, mood_category_id, tag_count)
+    SELECT p_user_id, DATE(local_datetime(ml.user_id, ml.created_at)), mt.mood_category_id, COUNT(*)
+    FROM   mood_log ml
+           JOIN mood_log_mood_tag mlmt ON ml.mood_log_id = mlmt.mood_log_id
+           JOIN mood_tag mt ON mlmt.mood_tag_id = mt.mood_tag_id
+    WHERE  ml.user_id = p_user_id
+    GROUP  BY DATE(local_datetime(ml.user_id, ml.created_at)), mt.mood_category_id;
+
+    UPDATE daily_mood_summary ds
+    SET    ds.tag_count = (SELECT COALESCE(SUM(dc.tag_count), 0)
+                           FROM   daily_mood_category_count dc
+                           WHERE  dc.user_id = ds.user_id AND dc.summary_date = ds.summary_date)
+    WHERE  ds.user_id = p_user_id;
+
+    INSERT INTO daily_sleep_summary (user_id, sleep_date, hours_slept, log_count)
+    SELECT p_user_id, sleep_date, SUM(hours_slept), COUNT(*)
+    FROM   sleep_log
+    WHERE  user_id = p_user_id
+    GROUP  BY sleep_date;
+END//
+
+CREATE TRIGGER trg_mood_log_summary_insert AFTER INSERT ON mood_log
+FOR EACH ROW CALL refresh_daily_mood_summary(NEW.user_id, DATE(local_datetime(NEW.user_id, NEW.created_at)))//
+
+-- re-encrypting a note doesn't touch the rollups
+CREATE TRIGGER trg_mood_log_summary_update AFTER UPDATE ON mood_log
+FOR EACH ROW
+BEGIN
+    IF NOT (OLD.user_id <=> NEW.user_id AND OLD.mood_rating <=> NEW.mood_rating AND OLD.created_at <=> NEW.created_at) THEN
+        CALL refresh_daily_mood_summary(OLD.user_id, DATE(local_datetime(OLD.user_id, OLD.created_at)));
+        CALL refresh_daily_mood_summary(NEW.user_id, DATE(local_datetime(NEW.user_id, NEW.created_at)));
+    END IF;
+END//
+
+CREATE TRIGGER trg_mood_log_summary_delete AFTER DELETE ON mood_log
+FOR EACH ROW CALL refresh_daily_mood_summary(OLD.user_id, DATE(local_datetime(OLD.user_id, OLD.created_at)))//
+
+CREATE TRIGGER trg_mood_log_mood_tag_summary_insert AFTER INSERT ON mood_log_mood_tag
+FOR EACH ROW
+BEGIN
+    DECLARE v_user_id BIGINT UNSIGNED;
+    DECLARE v_created_at TIMESTAMP;
+    SET v_user_id = (SELECT user_id FROM mood_log WHERE mood_log_id = NEW.mood_log_id);
+    SET v_created_at = (SELECT created_at FROM mood_log WHERE mood_log_id = NEW.mood_log_id);
+    CALL refresh_daily_mood_summary(v_user_id, DATE(local_datetime(v_user_id, v_created_at)));
+END//
+
+-- a tag removed by the cascade from mood_log doesn't fire this, the mood_log
+-- delete trigger refreshes the day instead
+CREATE TRIGGER trg_mood_log_mood_tag_summary_delete AFTER DELETE ON mood_log_mood_tag
+FOR EACH ROW
+BEGIN
+    DECLARE v_user_id BIGINT UNSIGNED;
+    DECLARE v_created_at TIMESTAMP;
+    SET v_user_id = (SELECT user_id FROM mood_log WHERE mood_log_id = OLD.mood_log_id);
+    SET v_created_at = (SELECT created_at FROM mood_log WHERE mood_log_id = OLD.mood_log_id);
+    IF v_user_id IS NOT NULL THEN
+        CALL refresh_daily_mood_summary(v_user_id, DATE(local_datetime(v_user_id, v_created_at)));
+    END IF;
+END//
+
+CREATE TRIGGER trg_sleep_log_summary_insert AFTER INSERT ON sleep_log
+FOR EACH ROW CALL refresh_daily_sleep_summary(NEW.user_id, NEW.sleep_date)//
+
+CREATE TRIGGER trg_sleep_log_summary_update AFTER UPDATE ON sleep_log
+FOR EACH ROW
+BEGIN
+    IF NOT (OLD.user_id <=> NEW.user_id AND OLD.hours_slept <=> NEW.hours_slept AND OLD.sleep_date <=> NEW.sleep_date) THEN
+        CALL refresh_daily_sleep_summary(OLD.user_id, OLD.sleep_date);
+        CALL refresh_daily_sleep_summary(NEW.user_id, NEW.sleep_date);
+    END IF;
+END//
+
+CREATE TRIGGER trg_sleep_log_summary_delete AFTER DELETE ON sleep_log
+FOR EACH ROW CALL refresh_daily_sleep_summary(OLD.user_id, OLD.sleep_date)//
+
+CREATE TRIGGER trg_user_settings_summary_insert AFTER INSERT ON user_settings
+FOR EACH ROW
+BEGIN
+    IF NEW.timezone <> 'UTC' THEN
+        CALL rebuild_daily_summaries(NEW.user_id);
+    END IF;
+END//
+
+CREATE TRIGGER trg_user_settings_summary_update AFTER UPDATE ON user_settings
+FOR EACH ROW
+BEGIN
+    IF NOT (OLD.timezone <=> NEW.timezone) THEN
+        CALL rebuild_daily_summaries(NEW.user_id);
+    END IF;
+END//
+DELIMITER ;
+
 -- Reset auto-increments
 ALTER TABLE user AUTO_INCREMENT = 1;
 ALTER TABLE mood_category AUTO_INCREMENT = 1;
@@ -139,6 +822,8 @@ ALTER TABLE medication_log AUTO_INCREMENT = 1;
 ALTER TABLE mood_log_mood_tag AUTO_INCREMENT = 1;
 ALTER TABLE sleep_log AUTO_INCREMENT = 1;
 ALTER TABLE sleep_quality_tag AUTO_INCREMENT = 1;
+ALTER TABLE alert AUTO_INCREMENT = 1;
+ALTER TABLE baseline_period AUTO_INCREMENT = 1;
 
 -- DB user
 CREATE USER IF NOT EXISTS 'demouser'@'localhost' IDENTIFIED BY 'demopassword';
@@ -176,6 +861,16 @@ INSERT INTO user (email, password_hash, created_at) VALUES
 ('bob@example.com', '$2y$10$example.hash.2', '2025-07-20 14:30:00'),
 ('carol@example.com', '$2y$10$example.hash.3', '2025-07-25 09:15:00');
 
+-- User settings
+INSERT INTO user_settings (user_id, target_sleep_hours, timezone) VALUES
+(1, 8.0, 'Europe/Dublin'),
+(2, 7.5, 'America/New_York'),
+(3, 8.0, 'UTC');
+
+-- Baseline periods
+INSERT INTO baseline_period (user_id, name, start_date, end_date) VALUES
+(1, 'well', '2025-08-01', '2025-08-14');
+
 -- Medications
 INSERT INTO medication (name, description) VALUES
 ('Sertraline', 'SSRI antidepressant used to treat depression, anxiety, OCD, and PTSD'),
@@ -0,0 +1,63 @@
+package encryption
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
+)
+
+// rows handled per step per tick, a rotation of a large account clears over a
+// few ticks
+const batchSize = 500
+
+type encryptionService struct {
+	noteCipher *database.NoteCipher
+}
+
+func NewEncryptionService(noteCipher *database.NoteCipher) *encryptionService {
+	return &encryptionService{
+		noteCipher: noteCipher,
+	}
+}
+
+// Start runs the re-encryption job on each tick. It encrypts notes written in
+// plaintext, moves notes and keywords off rotated data keys, rewraps data keys
+// still wrapped by an old master key and deletes data keys nothing uses
+func (service *encryptionService) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			service.reencryptSafely()
+		}
+	}()
+}
+
+func (service *encryptionService) reencryptSafely() {
+	if _, reencryptErr := service.Reencrypt(); reencryptErr != nil {
+		fmt.Println("ERROR", reencryptErr)
+	}
+}
+
+// Reencrypt runs one batch of every step and returns how many rows it
+// changed, zero once everything is on the current keys. Rows that can't be
+// moved, e.g. under a master key that isn't configured, are skipped and not
+// counted, a step that fails outright stops the batch with the error
+func (service *encryptionService) Reencrypt() (changed int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("re-encrypting notes: %v", r)
+		}
+	}()
+
+	changed += service.noteCipher.RewrapDataKeys(batchSize)
+	changed += service.noteCipher.ReencryptMoodNotes(batchSize)
+	changed += service.noteCipher.ReencryptSleepNotes(batchSize)
+	changed += service.noteCipher.ReencryptKeywords(batchSize)
+	if changed == 0 {
+		changed += service.noteCipher.RetireDataKeys()
+	}
+
+	return changed, nil
+}
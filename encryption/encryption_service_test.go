@@ -0,0 +1,66 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"testing"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database/dbtest"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/envelope"
+)
+
+// passes is how many batches Reencrypt took to run out of work, like
+// cmd/rotate-keys, giving up after maxPasses
+func passes(t *testing.T, service *encryptionService, maxPasses int) (int, int) {
+	t.Helper()
+
+	total := 0
+	for pass := 1; pass <= maxPasses; pass++ {
+		changed, reencryptErr := service.Reencrypt()
+		if reencryptErr != nil {
+			t.Fatal(reencryptErr)
+		}
+		if changed == 0 {
+			return pass, total
+		}
+		total += changed
+	}
+	t.Fatalf("still changing rows after %d passes", maxPasses)
+	return 0, 0
+}
+
+func TestReencryptFinishes(t *testing.T) {
+	db := dbtest.Open(t)
+
+	t.Setenv("HORIZON_MASTER_KEYS", "2025:"+base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, envelope.KeySize)))
+	masterKeys, loadErr := envelope.LoadMasterKeys()
+	if loadErr != nil {
+		t.Fatal(loadErr)
+	}
+	noteCipher := database.NewNoteCipher(db, masterKeys)
+	service := NewEncryptionService(noteCipher)
+
+	dbtest.Exec(t, db,
+		`INSERT INTO user (user_id, email, password_hash) VALUES (1, 'one@example.com', ''), (2, 'two@example.com', '')`,
+		`INSERT INTO mood_log (user_id, mood_rating, note) VALUES (1, 3, 'low'), (1, 4, 'tired'), (2, 5, 'ok')`,
+	)
+
+	// encrypting, then moving user 1 onto a rotated key, then retiring the old one
+	if _, total := passes(t, service, 5); total != 3 {
+		t.Errorf("encrypted %d rows, want the 3 notes", total)
+	}
+	noteCipher.RotateDataKey("1")
+	if _, total := passes(t, service, 5); total != 3 {
+		t.Errorf("changed %d rows after the rotation, want 2 notes moved and 1 key retired", total)
+	}
+
+	// user 2's newest key is wrapped by a master key that isn't configured,
+	// their notes can't move and mustn't keep the job busy
+	dbtest.Exec(t, db,
+		`INSERT INTO user_data_key (user_id, key_version, wrapped_key, master_key_id) VALUES (2, 2, Random_bytes(60), 'gone')`,
+	)
+	if pass, total := passes(t, service, 3); pass != 1 || total != 0 {
+		t.Errorf("took %d passes changing %d rows, want to stop straight away", pass, total)
+	}
+}
@@ -0,0 +1,154 @@
+// Package envelope encrypts journal notes with per-user data keys, which are
+// themselves encrypted (wrapped) with a master key that never leaves the
+// process. Only the wrapped data keys are stored, so rotating the master key
+// means rewrapping them rather than re-encrypting every note, and deleting a
+// user's data keys makes their notes unreadable everywhere they were copied
+package envelope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	KeySize              = 32 // AES-256
+	blindIndexSize       = 16
+	MinIndexedWordLength = 3
+	maxIndexedWordLength = 64
+
+	// stored notes look like hx1:<key version>:<base64 nonce and ciphertext>.
+	// The prefix only names the format, whether a column holds ciphertext is
+	// recorded next to it as plaintext written before encryption or by another
+	// service can start with anything
+	notePrefix = "hx1:"
+)
+
+var ErrMalformed = errors.New("envelope: malformed ciphertext")
+
+func NewDataKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, readErr := rand.Read(key); readErr != nil {
+		return nil, readErr
+	}
+	return key, nil
+}
+
+// Seal encrypts with AES-GCM, the random nonce is prepended to the ciphertext.
+// The additional data isn't stored but has to match when opening, it ties the
+// ciphertext to its owner and row so it can't be copied onto another one
+func Seal(key []byte, plaintext []byte, additionalData []byte) ([]byte, error) {
+	aead, aeadErr := newAEAD(key)
+	if aeadErr != nil {
+		return nil, aeadErr
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, readErr := rand.Read(nonce); readErr != nil {
+		return nil, readErr
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func Open(key []byte, sealed []byte, additionalData []byte) ([]byte, error) {
+	aead, aeadErr := newAEAD(key)
+	if aeadErr != nil {
+		return nil, aeadErr
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additionalData)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, blockErr := aes.NewCipher(key)
+	if blockErr != nil {
+		return nil, blockErr
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncodeNote is the stored form of a sealed note
+func EncodeNote(keyVersion int, sealed []byte) string {
+	return fmt.Sprintf("%s%d:%s", notePrefix, keyVersion, base64.StdEncoding.EncodeToString(sealed))
+}
+
+// DecodeNote splits a stored note
+func DecodeNote(stored string) (keyVersion int, sealed []byte, err error) {
+	if !strings.HasPrefix(stored, notePrefix) {
+		return 0, nil, ErrMalformed
+	}
+	version, encoded, found := strings.Cut(strings.TrimPrefix(stored, notePrefix), ":")
+	if !found {
+		return 0, nil, ErrMalformed
+	}
+	keyVersion, versionErr := strconv.Atoi(version)
+	if versionErr != nil {
+		return 0, nil, ErrMalformed
+	}
+	sealed, decodeErr := base64.StdEncoding.DecodeString(encoded)
+	if decodeErr != nil {
+		return 0, nil, ErrMalformed
+	}
+	return keyVersion, sealed, nil
+}
+
+// NoteAdditionalData binds a note to its user and the row it's stored on
+func NoteAdditionalData(userID string, table string, rowID int) []byte {
+	return []byte(fmt.Sprintf("note:user:%s:%s:%d", userID, table, rowID))
+}
+
+// KeywordAdditionalData binds a sentiment keyword to its user, keywords are
+// counted across logs so they aren't bound to one
+func KeywordAdditionalData(userID string) []byte {
+	return []byte("keyword:user:" + userID)
+}
+
+// KeyAdditionalData binds a wrapped data key to its user and version
+func KeyAdditionalData(userID string, keyVersion int) []byte {
+	return []byte(fmt.Sprintf("key:user:%s:v%d", userID, keyVersion))
+}
+
+// BlindIndexKey derives the key for a data key's blind indexes, separate from
+// the data key so an index hash reveals nothing about the encryption
+func BlindIndexKey(dataKey []byte) []byte {
+	mac := hmac.New(sha256.New, dataKey)
+	mac.Write([]byte("blind-index"))
+	return mac.Sum(nil)
+}
+
+// BlindIndex is a keyed hash of a word, equal words under the same key hash the
+// same so notes can be searched without decrypting them. The hashes still show
+// which notes share words, the price of searching
+func BlindIndex(indexKey []byte, word string) []byte {
+	mac := hmac.New(sha256.New, indexKey)
+	mac.Write([]byte(word))
+	return mac.Sum(nil)[:blindIndexSize]
+}
+
+// IndexWords are the distinct lowercased words of the text that get a blind
+// index, shorter words are too common to be worth searching
+func IndexWords(text string) []string {
+	var words []string
+	seen := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		length := utf8.RuneCountInString(word)
+		if length < MinIndexedWordLength || length > maxIndexedWordLength || seen[word] {
+			continue
+		}
+		seen[word] = true
+		words = append(words, word)
+	}
+	return words
+}
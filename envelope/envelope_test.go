@@ -0,0 +1,131 @@
+package envelope
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func testKey(t *testing.T) []byte {
+	t.Helper()
+	key, keyErr := NewDataKey()
+	if keyErr != nil {
+		t.Fatal(keyErr)
+	}
+	return key
+}
+
+func TestSealOpen(t *testing.T) {
+	key := testKey(t)
+	additionalData := NoteAdditionalData("7", "mood_log", 12)
+
+	sealed, sealErr := Seal(key, []byte("couldn't sleep"), additionalData)
+	if sealErr != nil {
+		t.Fatal(sealErr)
+	}
+	opened, openErr := Open(key, sealed, additionalData)
+	if openErr != nil || string(opened) != "couldn't sleep" {
+		t.Fatalf("Open() = %q, %v, want the plaintext", opened, openErr)
+	}
+
+	again, _ := Seal(key, []byte("couldn't sleep"), additionalData)
+	if bytes.Equal(sealed, again) {
+		t.Error("sealing twice gave the same ciphertext, the nonce isn't random")
+	}
+
+	tampered := slices.Clone(sealed)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name           string
+		key            []byte
+		sealed         []byte
+		additionalData []byte
+	}{
+		{"another user", key, sealed, NoteAdditionalData("8", "mood_log", 12)},
+		{"another row", key, sealed, NoteAdditionalData("7", "mood_log", 13)},
+		{"another table", key, sealed, NoteAdditionalData("7", "sleep_log", 12)},
+		{"another key", testKey(t), sealed, additionalData},
+		{"tampered ciphertext", key, tampered, additionalData},
+		{"shorter than a nonce", key, sealed[:4], additionalData},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if opened, openErr := Open(test.key, test.sealed, test.additionalData); openErr == nil {
+				t.Errorf("Open() = %q, want an error", opened)
+			}
+		})
+	}
+}
+
+func TestSealRejectsShortKeys(t *testing.T) {
+	if _, sealErr := Seal(make([]byte, 7), []byte("note"), nil); sealErr == nil {
+		t.Error("Seal() with a 7 byte key, want an error")
+	}
+}
+
+func TestDecodeNote(t *testing.T) {
+	sealed := []byte{1, 2, 3, 250}
+	keyVersion, decoded, decodeErr := DecodeNote(EncodeNote(3, sealed))
+	if decodeErr != nil || keyVersion != 3 || !bytes.Equal(decoded, sealed) {
+		t.Fatalf("DecodeNote(EncodeNote()) = %d %v %v, want 3 %v", keyVersion, decoded, decodeErr, sealed)
+	}
+
+	tests := []struct {
+		name   string
+		stored string
+	}{
+		{"legacy plaintext", "Felt flat all afternoon"},
+		{"plaintext that looks like the format", "hx1: a note about hx1"},
+		{"no version", "hx1:AQID"},
+		{"version isn't a number", "hx1:v2:AQID"},
+		{"not base64", "hx1:2:not base64!"},
+		{"empty", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, decodeErr := DecodeNote(test.stored); !errors.Is(decodeErr, ErrMalformed) {
+				t.Errorf("DecodeNote(%q) error = %v, want ErrMalformed", test.stored, decodeErr)
+			}
+		})
+	}
+}
+
+func TestIndexWords(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"Slept badly, slept LATE", []string{"slept", "badly", "late"}},
+		{"a to be or not", []string{"not"}},
+		{"café crème, naïve", []string{"café", "crème", "naïve"}},
+		{"day 365 of 2025", []string{"day", "365", "2025"}},
+		{"well-being/self-care", []string{"well", "being", "self", "care"}},
+		{strings.Repeat("z", 65) + " fine", []string{"fine"}},
+		{"", nil},
+	}
+	for _, test := range tests {
+		if got := IndexWords(test.text); !slices.Equal(got, test.want) {
+			t.Errorf("IndexWords(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	indexKey := BlindIndexKey(testKey(t))
+
+	if !bytes.Equal(BlindIndex(indexKey, "anxious"), BlindIndex(indexKey, "anxious")) {
+		t.Error("the same word indexes differently under the same key")
+	}
+	if bytes.Equal(BlindIndex(indexKey, "anxious"), BlindIndex(indexKey, "anxiety")) {
+		t.Error("different words index the same")
+	}
+	if bytes.Equal(BlindIndex(indexKey, "anxious"), BlindIndex(BlindIndexKey(testKey(t)), "anxious")) {
+		t.Error("the index doesn't depend on the key")
+	}
+	if length := len(BlindIndex(indexKey, "anxious")); length != blindIndexSize {
+		t.Errorf("index is %d bytes, want %d", length, blindIndexSize)
+	}
+}
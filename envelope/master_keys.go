@@ -0,0 +1,89 @@
+package envelope
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// user_data_key.master_key_id
+const maxMasterKeyIDLength = 32
+
+// MasterKeys are the master keys by id, the first one configured is current
+// and wraps new data keys. Older ones are kept so data keys they wrapped can
+// still be unwrapped until the rotation job has rewrapped them
+type MasterKeys struct {
+	currentID string
+	keys      map[string][]byte
+}
+
+// LoadMasterKeys reads HORIZON_MASTER_KEYS, a comma separated list of
+// id:base64key, or failing that the keyfile named by HORIZON_MASTER_KEY_FILE
+// with one "id base64key" per line. Keys are 32 random bytes, e.g. from
+// openssl rand -base64 32
+func LoadMasterKeys() (MasterKeys, error) {
+
+	if keyList := os.Getenv("HORIZON_MASTER_KEYS"); keyList != "" {
+		return parseMasterKeys(strings.Split(keyList, ","), ":")
+	}
+
+	if keyFile := os.Getenv("HORIZON_MASTER_KEY_FILE"); keyFile != "" {
+		contents, readErr := os.ReadFile(keyFile)
+		if readErr != nil {
+			return MasterKeys{}, readErr
+		}
+		var lines []string
+		for _, line := range strings.Split(string(contents), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				lines = append(lines, line)
+			}
+		}
+		return parseMasterKeys(lines, " ")
+	}
+
+	return MasterKeys{}, errors.New("envelope: set HORIZON_MASTER_KEYS or HORIZON_MASTER_KEY_FILE")
+}
+
+func parseMasterKeys(entries []string, separator string) (MasterKeys, error) {
+
+	masterKeys := MasterKeys{keys: make(map[string][]byte)}
+
+	for _, entry := range entries {
+		id, encoded, found := strings.Cut(strings.TrimSpace(entry), separator)
+		id, encoded = strings.TrimSpace(id), strings.TrimSpace(encoded)
+		if !found || id == "" {
+			return MasterKeys{}, fmt.Errorf("envelope: master key entry must be id%sbase64key", separator)
+		}
+		if len(id) > maxMasterKeyIDLength {
+			return MasterKeys{}, fmt.Errorf("envelope: master key id %q is longer than %d characters", id, maxMasterKeyIDLength)
+		}
+		key, decodeErr := base64.StdEncoding.DecodeString(encoded)
+		if decodeErr != nil || len(key) != KeySize {
+			return MasterKeys{}, fmt.Errorf("envelope: master key %q must be %d bytes of base64", id, KeySize)
+		}
+		if _, duplicate := masterKeys.keys[id]; duplicate {
+			return MasterKeys{}, fmt.Errorf("envelope: master key %q is listed twice", id)
+		}
+		if masterKeys.currentID == "" {
+			masterKeys.currentID = id
+		}
+		masterKeys.keys[id] = key
+	}
+
+	if masterKeys.currentID == "" {
+		return MasterKeys{}, errors.New("envelope: no master keys configured")
+	}
+
+	return masterKeys, nil
+}
+
+func (masterKeys MasterKeys) Current() (string, []byte) {
+	return masterKeys.currentID, masterKeys.keys[masterKeys.currentID]
+}
+
+func (masterKeys MasterKeys) Key(id string) ([]byte, bool) {
+	key, found := masterKeys.keys[id]
+	return key, found
+}
@@ -0,0 +1,81 @@
+package envelope
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var (
+	oldKey = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, KeySize))
+	newKey = base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{2}, KeySize))
+)
+
+func TestParseMasterKeys(t *testing.T) {
+	masterKeys, parseErr := parseMasterKeys([]string{"2026:" + newKey, " 2025 : " + oldKey}, ":")
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+	if currentID, current := masterKeys.Current(); currentID != "2026" || current[0] != 2 {
+		t.Errorf("current key = %s, want the first listed, 2026", currentID)
+	}
+	if key, found := masterKeys.Key("2025"); !found || key[0] != 1 {
+		t.Error("the older key 2025 isn't kept for unwrapping")
+	}
+
+	tests := []struct {
+		name    string
+		entries []string
+		wantErr string
+	}{
+		{"no keys", nil, "no master keys"},
+		{"no separator", []string{"2026" + newKey}, "must be id:base64key"},
+		{"no id", []string{":" + newKey}, "must be id:base64key"},
+		{"id too long", []string{strings.Repeat("k", 33) + ":" + newKey}, "longer than 32"},
+		{"not base64", []string{"2026:not-base64!"}, "32 bytes of base64"},
+		{"too short", []string{"2026:" + base64.StdEncoding.EncodeToString(make([]byte, 16))}, "32 bytes of base64"},
+		{"listed twice", []string{"2026:" + newKey, "2026:" + oldKey}, "listed twice"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, parseErr := parseMasterKeys(test.entries, ":")
+			if parseErr == nil || !strings.Contains(parseErr.Error(), test.wantErr) {
+				t.Errorf("parseMasterKeys() error = %v, want %q", parseErr, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadMasterKeys(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "master.keys")
+	contents := "# rotated 2026-01-01\n2026 " + newKey + "\n\n2025 " + oldKey + "\n"
+	if writeErr := os.WriteFile(keyFile, []byte(contents), 0o600); writeErr != nil {
+		t.Fatal(writeErr)
+	}
+
+	t.Setenv("HORIZON_MASTER_KEYS", "")
+	t.Setenv("HORIZON_MASTER_KEY_FILE", keyFile)
+	masterKeys, loadErr := LoadMasterKeys()
+	if loadErr != nil {
+		t.Fatal(loadErr)
+	}
+	if currentID, _ := masterKeys.Current(); currentID != "2026" {
+		t.Errorf("current key from the file = %s, want 2026", currentID)
+	}
+
+	// the variable wins over the file
+	t.Setenv("HORIZON_MASTER_KEYS", "2027:"+oldKey)
+	masterKeys, loadErr = LoadMasterKeys()
+	if currentID, _ := masterKeys.Current(); loadErr != nil || currentID != "2027" {
+		t.Errorf("current key = %s, %v, want 2027 from HORIZON_MASTER_KEYS", currentID, loadErr)
+	}
+
+	t.Setenv("HORIZON_MASTER_KEYS", "")
+	t.Setenv("HORIZON_MASTER_KEY_FILE", "")
+	if _, loadErr = LoadMasterKeys(); loadErr == nil {
+		t.Error("LoadMasterKeys() with nothing configured, want an error")
+	}
+}
@@ -0,0 +1,70 @@
+package export
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+type ExportHandler struct {
+	exportService *exportService
+}
+
+var usersExport string = `^/users/([0-9]+)/export$`
+
+// used when startDate or endDate are left out so the whole history is exported
+var earliestDate string = "1000-01-01"
+var latestDate string = "9999-12-31"
+
+func NewExportHandler(exportService *exportService) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+	}
+}
+
+func (handler *ExportHandler) ProcessRequest(writer http.ResponseWriter, request *http.Request) {
+	switch {
+	case utils.MatchURL(usersExport, request.URL.Path):
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+		format := request.URL.Query().Get("format")
+		startDate := request.URL.Query().Get("startDate")
+		endDate := request.URL.Query().Get("endDate")
+
+		if startDate == "" {
+			startDate = earliestDate
+		}
+		if endDate == "" {
+			endDate = latestDate
+		}
+
+		var exportErr error
+
+		switch format {
+		case "", "json":
+			writer.Header().Set("Content-Type", "application/json")
+			writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s-export.json"`, userID))
+			exportErr = handler.exportService.exportJSON(writer, userID, startDate, endDate)
+		case "csv":
+			writer.Header().Set("Content-Type", "application/zip")
+			writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s-export.zip"`, userID))
+			exportErr = handler.exportService.exportCSV(writer, userID, startDate, endDate)
+		default:
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte("format must be csv or json"))
+			return
+		}
+
+		// the response is already partly written, aborting the connection means
+		// the client sees a failed download rather than a complete looking one
+		if exportErr != nil {
+			fmt.Println("ERROR exporting user", userID, exportErr)
+			panic(http.ErrAbortHandler)
+		}
+
+	default:
+		writer.WriteHeader(http.StatusNotFound)
+		writer.Write([]byte("404 path not found"))
+	}
+}
@@ -0,0 +1,211 @@
+package export
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+type exportService struct {
+	exportRepository *database.ExportRepository
+}
+
+func NewExportService(exportRepository *database.ExportRepository) *exportService {
+	return &exportService{
+		exportRepository: exportRepository,
+	}
+}
+
+// exportJSON streams a single json document, each array element is encoded and
+// written as soon as its row is read
+func (service *exportService) exportJSON(writer io.Writer, userID string, startDate string, endDate string) error {
+
+	header, _ := json.Marshal(map[string]string{"userId": userID, "startDate": startDate, "endDate": endDate})
+	if _, writeErr := io.WriteString(writer, strings.TrimSuffix(string(header), "}")); writeErr != nil {
+		return writeErr
+	}
+
+	if writeErr := writeJSONArray(writer, "moodLogs", func(write func(any) error) error {
+		return service.exportRepository.EachMoodLog(userID, startDate, endDate, func(moodLog models.MoodLog) error {
+			return write(moodLog)
+		})
+	}); writeErr != nil {
+		return writeErr
+	}
+
+	if writeErr := writeJSONArray(writer, "sleepLogs", func(write func(any) error) error {
+		return service.exportRepository.EachSleepLog(userID, startDate, endDate, func(sleepLog models.SleepLog) error {
+			return write(sleepLog)
+		})
+	}); writeErr != nil {
+		return writeErr
+	}
+
+	if writeErr := writeJSONArray(writer, "medicationLogs", func(write func(any) error) error {
+		return service.exportRepository.EachMedicationLog(userID, startDate, endDate, func(medicationLog models.MedicationLog) error {
+			return write(medicationLog)
+		})
+	}); writeErr != nil {
+		return writeErr
+	}
+
+	if writeErr := writeJSONArray(writer, "dailyAggregates", func(write func(any) error) error {
+		return service.exportRepository.EachDailyAggregate(userID, startDate, endDate, func(dailyAggregate models.DailyAggregate) error {
+			return write(dailyAggregate)
+		})
+	}); writeErr != nil {
+		return writeErr
+	}
+
+	_, writeErr := io.WriteString(writer, "}")
+	return writeErr
+}
+
+func writeJSONArray(writer io.Writer, key string, each func(write func(any) error) error) error {
+
+	if _, writeErr := io.WriteString(writer, `,"`+key+`":[`); writeErr != nil {
+		return writeErr
+	}
+
+	first := true
+	eachErr := each(func(value any) error {
+		element, _ := json.Marshal(value)
+		if !first {
+			if _, writeErr := io.WriteString(writer, ","); writeErr != nil {
+				return writeErr
+			}
+		}
+		first = false
+		_, writeErr := writer.Write(element)
+		return writeErr
+	})
+	if eachErr != nil {
+		return eachErr
+	}
+
+	_, writeErr := io.WriteString(writer, "]")
+	return writeErr
+}
+
+// exportCSV streams a zip archive with one csv file per sheet
+func (service *exportService) exportCSV(writer io.Writer, userID string, startDate string, endDate string) error {
+
+	archive := zip.NewWriter(writer)
+
+	moodLogsCSV, createErr := csvSheet(archive, "mood_logs.csv", []string{"mood_log_id", "user_id", "mood_rating", "note", "created_at", "mood_tags"})
+	if createErr != nil {
+		return createErr
+	}
+	eachErr := service.exportRepository.EachMoodLog(userID, startDate, endDate, func(moodLog models.MoodLog) error {
+		return moodLogsCSV.Write([]string{
+			strconv.Itoa(moodLog.MoodLogID),
+			moodLog.UserID,
+			strconv.Itoa(moodLog.MoodRating),
+			csvCell(moodLog.Note),
+			moodLog.CreatedAt,
+			strings.Join(moodLog.MoodTags, ";"),
+		})
+	})
+	if flushErr := flushSheet(moodLogsCSV, eachErr); flushErr != nil {
+		return flushErr
+	}
+
+	sleepLogsCSV, createErr := csvSheet(archive, "sleep_logs.csv", []string{"sleep_log_id", "user_id", "hours_slept", "sleep_quality", "notes", "sleep_date", "created_at"})
+	if createErr != nil {
+		return createErr
+	}
+	eachErr = service.exportRepository.EachSleepLog(userID, startDate, endDate, func(sleepLog models.SleepLog) error {
+		return sleepLogsCSV.Write([]string{
+			strconv.Itoa(sleepLog.SleepLogID),
+			sleepLog.UserID,
+			strconv.FormatFloat(sleepLog.HoursSlept, 'f', 2, 64),
+			sleepLog.SleepQuality,
+			csvCell(sleepLog.Notes),
+			sleepLog.SleepDate,
+			sleepLog.CreatedAt,
+		})
+	})
+	if flushErr := flushSheet(sleepLogsCSV, eachErr); flushErr != nil {
+		return flushErr
+	}
+
+	medicationLogsCSV, createErr := csvSheet(archive, "medication_logs.csv", []string{"medication_log_id", "user_id", "medication_id", "medication_name", "taken_at", "taken", "dosage", "notes"})
+	if createErr != nil {
+		return createErr
+	}
+	eachErr = service.exportRepository.EachMedicationLog(userID, startDate, endDate, func(medicationLog models.MedicationLog) error {
+		return medicationLogsCSV.Write([]string{
+			strconv.Itoa(medicationLog.MedicationLogID),
+			medicationLog.UserID,
+			strconv.Itoa(medicationLog.MedicationID),
+			csvCell(medicationLog.MedicationName),
+			medicationLog.TakenAt,
+			strconv.FormatBool(medicationLog.Taken),
+			csvCell(medicationLog.Dosage),
+			csvCell(medicationLog.Notes),
+		})
+	})
+	if flushErr := flushSheet(medicationLogsCSV, eachErr); flushErr != nil {
+		return flushErr
+	}
+
+	dailyAggregatesCSV, createErr := csvSheet(archive, "daily_aggregates.csv", []string{"date", "daily_avg_rating", "mood_log_count", "hours_slept", "doses_taken"})
+	if createErr != nil {
+		return createErr
+	}
+	eachErr = service.exportRepository.EachDailyAggregate(userID, startDate, endDate, func(dailyAggregate models.DailyAggregate) error {
+		var dailyAvgRating string
+		if dailyAggregate.MoodLogCount > 0 {
+			dailyAvgRating = strconv.FormatFloat(dailyAggregate.DailyAvgRating, 'f', 2, 64)
+		}
+		var hoursSlept string
+		if dailyAggregate.SleepLogged {
+			hoursSlept = strconv.FormatFloat(dailyAggregate.HoursSlept, 'f', 2, 64)
+		}
+		return dailyAggregatesCSV.Write([]string{
+			dailyAggregate.Date,
+			dailyAvgRating,
+			strconv.Itoa(dailyAggregate.MoodLogCount),
+			hoursSlept,
+			strconv.Itoa(dailyAggregate.DosesTaken),
+		})
+	})
+	if flushErr := flushSheet(dailyAggregatesCSV, eachErr); flushErr != nil {
+		return flushErr
+	}
+
+	return archive.Close()
+}
+
+// csvCell guards free text against formula injection, spreadsheets run a cell
+// starting with one of these as a formula so it's prefixed with a quote to be
+// shown as text
+func csvCell(value string) string {
+	if value != "" && strings.ContainsAny(value[:1], "=+-@\t\r") {
+		return "'" + value
+	}
+	return value
+}
+
+func csvSheet(archive *zip.Writer, name string, header []string) (*csv.Writer, error) {
+	file, createErr := archive.Create(name)
+	if createErr != nil {
+		return nil, createErr
+	}
+	sheet := csv.NewWriter(file)
+	return sheet, sheet.Write(header)
+}
+
+func flushSheet(sheet *csv.Writer, eachErr error) error {
+	if eachErr != nil {
+		return eachErr
+	}
+	sheet.Flush()
+	return sheet.Error()
+}
@@ -0,0 +1,64 @@
+package fhir
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+type FHIRHandler struct {
+	fhirService *fhirService
+}
+
+var usersFHIR string = `^/users/([0-9]+)/fhir$`
+
+// used when startDate or endDate are left out so the whole history is exported
+var earliestDate string = "1000-01-01"
+var latestDate string = "9999-12-31"
+
+func NewFHIRHandler(fhirService *fhirService) *FHIRHandler {
+	return &FHIRHandler{
+		fhirService: fhirService,
+	}
+}
+
+func (handler *FHIRHandler) ProcessRequest(writer http.ResponseWriter, request *http.Request) {
+	switch {
+	case utils.MatchURL(usersFHIR, request.URL.Path):
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+		startDate := request.URL.Query().Get("startDate")
+		endDate := request.URL.Query().Get("endDate")
+
+		if startDate == "" {
+			startDate = earliestDate
+		}
+		if endDate == "" {
+			endDate = latestDate
+		}
+
+		scheme := "http"
+		if request.TLS != nil {
+			scheme = "https"
+		}
+		baseURL := scheme + "://" + request.Host + "/fhir"
+
+		bundle, bundleErr := handler.fhirService.bundle(baseURL, userID, startDate, endDate)
+		if bundleErr != nil {
+			fmt.Println("ERROR building FHIR bundle for user", userID, bundleErr)
+			writer.WriteHeader(http.StatusInternalServerError)
+			writer.Write([]byte("the bundle could not be built"))
+			return
+		}
+		body, _ := json.Marshal(bundle)
+
+		writer.Header().Set("Content-Type", "application/fhir+json")
+		writer.Write(body)
+
+	default:
+		writer.WriteHeader(http.StatusNotFound)
+		writer.Write([]byte("404 path not found"))
+	}
+}
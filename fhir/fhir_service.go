@@ -0,0 +1,239 @@
+package fhir
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+const (
+	horizonObservationSystem  = "https://projecthorizon.app/fhir/CodeSystem/observation"
+	horizonMoodTagSystem      = "https://projecthorizon.app/fhir/CodeSystem/mood-tag"
+	observationCategorySystem = "http://terminology.hl7.org/CodeSystem/observation-category"
+	loincSystem               = "http://loinc.org"
+	ucumSystem                = "http://unitsofmeasure.org"
+	mysqlDateTimeLayout       = "2006-01-02 15:04:05"
+)
+
+type fhirService struct {
+	exportRepository *database.ExportRepository
+}
+
+func NewFHIRService(exportRepository *database.ExportRepository) *fhirService {
+	return &fhirService{
+		exportRepository: exportRepository,
+	}
+}
+
+// bundle fails rather than leave out resources, a note that can't be
+// decrypted fails the whole bundle
+func (service *fhirService) bundle(baseURL string, userID string, startDate string, endDate string) (Bundle, error) {
+
+	subject := Reference{Reference: "Patient/" + userID}
+	now := time.Now().UTC()
+
+	var entries []BundleEntry
+	addEntry := func(resourceType string, id string, resource any) {
+		entries = append(entries, BundleEntry{
+			FullURL:  fmt.Sprintf("%s/%s/%s", baseURL, resourceType, id),
+			Resource: resource,
+		})
+	}
+
+	addEntry("Patient", userID, Patient{ResourceType: "Patient", ID: userID})
+
+	eachErr := service.exportRepository.EachMoodLog(userID, startDate, endDate, func(moodLog models.MoodLog) error {
+		observation := moodObservation(subject, moodLog)
+		addEntry(observation.ResourceType, observation.ID, observation)
+		return nil
+	})
+	if eachErr != nil {
+		return Bundle{}, eachErr
+	}
+
+	eachErr = service.exportRepository.EachSleepLog(userID, startDate, endDate, func(sleepLog models.SleepLog) error {
+		observation := sleepObservation(subject, sleepLog)
+		addEntry(observation.ResourceType, observation.ID, observation)
+		return nil
+	})
+	if eachErr != nil {
+		return Bundle{}, eachErr
+	}
+
+	eachErr = service.exportRepository.EachUserMedication(userID, startDate, endDate, func(userMedication models.UserMedication) error {
+		statement := medicationStatement(subject, userMedication, now.Format("2006-01-02"))
+		addEntry(statement.ResourceType, statement.ID, statement)
+		return nil
+	})
+	if eachErr != nil {
+		return Bundle{}, eachErr
+	}
+
+	eachErr = service.exportRepository.EachMedicationLog(userID, startDate, endDate, func(medicationLog models.MedicationLog) error {
+		administration := medicationAdministration(subject, medicationLog)
+		addEntry(administration.ResourceType, administration.ID, administration)
+		return nil
+	})
+	if eachErr != nil {
+		return Bundle{}, eachErr
+	}
+
+	return collectionBundle(entries, now), nil
+}
+
+func collectionBundle(entries []BundleEntry, timestamp time.Time) Bundle {
+	return Bundle{
+		ResourceType: "Bundle",
+		Type:         "collection",
+		Timestamp:    timestamp.UTC().Format(time.RFC3339),
+		Entry:        entries,
+	}
+}
+
+func moodObservation(subject Reference, moodLog models.MoodLog) Observation {
+
+	rating := moodLog.MoodRating
+
+	observation := Observation{
+		ResourceType: "Observation",
+		ID:           fmt.Sprintf("mood-log-%d", moodLog.MoodLogID),
+		Status:       "final",
+		Category:     []CodeableConcept{observationCategory("survey")},
+		Code: CodeableConcept{
+			Coding: []Coding{{System: horizonObservationSystem, Code: "mood-rating", Display: "Mood rating"}},
+			Text:   "Mood rating",
+		},
+		Subject:           subject,
+		EffectiveDateTime: fhirDateTime(moodLog.CreatedAt),
+		ValueInteger:      &rating,
+	}
+
+	// each tag is a component that was present on the log
+	present := true
+	for _, tag := range moodLog.MoodTags {
+		observation.Component = append(observation.Component, ObservationComponent{
+			Code: CodeableConcept{
+				Coding: []Coding{{System: horizonMoodTagSystem, Code: tag, Display: tag}},
+				Text:   tag,
+			},
+			ValueBoolean: &present,
+		})
+	}
+
+	if moodLog.Note != "" {
+		observation.Note = []Annotation{{Text: moodLog.Note}}
+	}
+
+	return observation
+}
+
+func sleepObservation(subject Reference, sleepLog models.SleepLog) Observation {
+
+	observation := Observation{
+		ResourceType: "Observation",
+		ID:           fmt.Sprintf("sleep-log-%d", sleepLog.SleepLogID),
+		Status:       "final",
+		Category:     []CodeableConcept{observationCategory("activity")},
+		Code: CodeableConcept{
+			Coding: []Coding{{System: loincSystem, Code: "93832-4", Display: "Sleep duration"}},
+			Text:   "Sleep duration",
+		},
+		Subject:           subject,
+		EffectiveDateTime: sleepLog.SleepDate,
+		ValueQuantity: &Quantity{
+			Value:  sleepLog.HoursSlept,
+			Unit:   "h",
+			System: ucumSystem,
+			Code:   "h",
+		},
+		Component: []ObservationComponent{{
+			Code: CodeableConcept{
+				Coding: []Coding{{System: horizonObservationSystem, Code: "sleep-quality", Display: "Sleep quality"}},
+				Text:   "Sleep quality",
+			},
+			ValueCodeableConcept: &CodeableConcept{Text: sleepLog.SleepQuality},
+		}},
+	}
+
+	if sleepLog.Notes != "" {
+		observation.Note = []Annotation{{Text: sleepLog.Notes}}
+	}
+
+	return observation
+}
+
+// medicationStatement is stopped when the user stopped taking it early,
+// completed once its end date has passed and intended before its start date
+func medicationStatement(subject Reference, userMedication models.UserMedication, today string) MedicationStatement {
+
+	// the dates are YYYY-MM-DD so they compare as strings
+	status := "active"
+	switch {
+	case userMedication.Stopped:
+		status = "stopped"
+	case userMedication.EndDate != "" && userMedication.EndDate < today:
+		status = "completed"
+	case userMedication.StartDate > today:
+		status = "intended"
+	}
+
+	statement := MedicationStatement{
+		ResourceType:              "MedicationStatement",
+		ID:                        fmt.Sprintf("user-medication-%d", userMedication.UserMedicationID),
+		Status:                    status,
+		MedicationCodeableConcept: CodeableConcept{Text: userMedication.MedicationName},
+		Subject:                   subject,
+		EffectivePeriod:           &Period{Start: userMedication.StartDate, End: userMedication.EndDate},
+	}
+
+	if userMedication.Dosage != "" {
+		statement.Dosage = []Dosage{{Text: userMedication.Dosage}}
+	}
+	if userMedication.Notes != "" {
+		statement.Note = []Annotation{{Text: userMedication.Notes}}
+	}
+
+	return statement
+}
+
+func medicationAdministration(subject Reference, medicationLog models.MedicationLog) MedicationAdministration {
+
+	status := "completed"
+	if !medicationLog.Taken {
+		status = "not-done"
+	}
+
+	administration := MedicationAdministration{
+		ResourceType:              "MedicationAdministration",
+		ID:                        fmt.Sprintf("medication-log-%d", medicationLog.MedicationLogID),
+		Status:                    status,
+		MedicationCodeableConcept: CodeableConcept{Text: medicationLog.MedicationName},
+		Subject:                   subject,
+		EffectiveDateTime:         fhirDateTime(medicationLog.TakenAt),
+		Dosage:                    &MedicationAdministrationDosage{Text: medicationLog.Dosage},
+	}
+
+	if medicationLog.Notes != "" {
+		administration.Note = []Annotation{{Text: medicationLog.Notes}}
+	}
+
+	return administration
+}
+
+func observationCategory(code string) CodeableConcept {
+	return CodeableConcept{
+		Coding: []Coding{{System: observationCategorySystem, Code: code}},
+	}
+}
+
+// FHIR dateTimes with a time component need an offset, the connection returns
+// TIMESTAMP columns in UTC
+func fhirDateTime(mysqlDateTime string) string {
+	parsed, parseErr := time.Parse(mysqlDateTimeLayout, mysqlDateTime)
+	if parseErr != nil {
+		return mysqlDateTime
+	}
+	return parsed.UTC().Format(time.RFC3339)
+}
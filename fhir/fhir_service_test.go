@@ -0,0 +1,119 @@
+package fhir
+
+import (
+	"embed"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+//go:embed testdata/*.json
+var fixtures embed.FS
+
+// assertFixture compares the resource's JSON with the fixture, both are
+// decoded first so the fixture's layout doesn't matter
+func assertFixture(t *testing.T, name string, resource any) {
+	t.Helper()
+
+	fixture, readErr := fixtures.ReadFile("testdata/" + name)
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	encoded, marshalErr := json.Marshal(resource)
+	if marshalErr != nil {
+		t.Fatal(marshalErr)
+	}
+
+	var want, got any
+	if unmarshalErr := json.Unmarshal(fixture, &want); unmarshalErr != nil {
+		t.Fatalf("%s: %v", name, unmarshalErr)
+	}
+	if unmarshalErr := json.Unmarshal(encoded, &got); unmarshalErr != nil {
+		t.Fatal(unmarshalErr)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s doesn't match:\n%s", name, encoded)
+	}
+}
+
+var testSubject = Reference{Reference: "Patient/7"}
+
+func TestMoodObservation(t *testing.T) {
+	observation := moodObservation(testSubject, models.MoodLog{
+		MoodLogID:  12,
+		MoodRating: 4,
+		Note:       "Couldn't settle after the call",
+		CreatedAt:  "2025-08-10 02:30:00",
+		MoodTags:   []string{"Anxious", "Tired"},
+	})
+	assertFixture(t, "mood_observation.json", observation)
+}
+
+func TestSleepObservation(t *testing.T) {
+	observation := sleepObservation(testSubject, models.SleepLog{
+		SleepLogID:   3,
+		HoursSlept:   6.5,
+		SleepQuality: "Restless",
+		SleepDate:    "2025-08-09",
+	})
+	assertFixture(t, "sleep_observation.json", observation)
+}
+
+func TestMedicationStatement(t *testing.T) {
+	userMedication := models.UserMedication{
+		UserMedicationID: 5,
+		MedicationName:   "Lithium carbonate",
+		Dosage:           "400mg twice daily",
+		StartDate:        "2025-01-01",
+		EndDate:          "2025-06-30",
+	}
+	assertFixture(t, "medication_statement.json", medicationStatement(testSubject, userMedication, "2025-08-11"))
+
+	tests := []struct {
+		name      string
+		startDate string
+		endDate   string
+		stopped   bool
+		today     string
+		want      string
+	}{
+		{"no end date", "2025-01-01", "", false, "2025-08-11", "active"},
+		{"on its last day", "2025-01-01", "2025-06-30", false, "2025-06-30", "active"},
+		{"after its end date", "2025-01-01", "2025-06-30", false, "2025-07-01", "completed"},
+		{"stopped early", "2025-01-01", "2025-06-30", true, "2025-03-01", "stopped"},
+		{"stopped and past its end date", "2025-01-01", "2025-06-30", true, "2025-08-11", "stopped"},
+		{"not started", "2025-09-01", "", false, "2025-08-11", "intended"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			userMedication := models.UserMedication{StartDate: test.startDate, EndDate: test.endDate, Stopped: test.stopped}
+			if got := medicationStatement(testSubject, userMedication, test.today).Status; got != test.want {
+				t.Errorf("status = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMedicationAdministration(t *testing.T) {
+	administration := medicationAdministration(testSubject, models.MedicationLog{
+		MedicationLogID: 40,
+		MedicationName:  "Lithium carbonate",
+		TakenAt:         "2025-06-01 08:00:00",
+		Taken:           false,
+		Dosage:          "400mg",
+		Notes:           "Forgot before leaving",
+	})
+	assertFixture(t, "medication_administration.json", administration)
+}
+
+func TestCollectionBundle(t *testing.T) {
+	entries := []BundleEntry{{
+		FullURL:  "https://api.example.com/fhir/Patient/7",
+		Resource: Patient{ResourceType: "Patient", ID: "7"},
+	}}
+	timestamp := time.Date(2025, 8, 11, 10, 0, 0, 0, time.FixedZone("IST", 3600))
+	assertFixture(t, "bundle.json", collectionBundle(entries, timestamp))
+}
@@ -0,0 +1,104 @@
+package fhir
+
+// subset of the FHIR R4 data types and resources used by the export,
+// see https://hl7.org/fhir/R4/
+
+type Coding struct {
+	System  string `json:"system,omitempty"`
+	Code    string `json:"code,omitempty"`
+	Display string `json:"display,omitempty"`
+}
+
+type CodeableConcept struct {
+	Coding []Coding `json:"coding,omitempty"`
+	Text   string   `json:"text,omitempty"`
+}
+
+type Reference struct {
+	Reference string `json:"reference"`
+}
+
+type Quantity struct {
+	Value  float64 `json:"value"`
+	Unit   string  `json:"unit,omitempty"`
+	System string  `json:"system,omitempty"`
+	Code   string  `json:"code,omitempty"`
+}
+
+type Period struct {
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+type Annotation struct {
+	Text string `json:"text"`
+}
+
+type ObservationComponent struct {
+	Code                 CodeableConcept  `json:"code"`
+	ValueBoolean         *bool            `json:"valueBoolean,omitempty"`
+	ValueCodeableConcept *CodeableConcept `json:"valueCodeableConcept,omitempty"`
+}
+
+type Observation struct {
+	ResourceType      string                 `json:"resourceType"`
+	ID                string                 `json:"id"`
+	Status            string                 `json:"status"`
+	Category          []CodeableConcept      `json:"category,omitempty"`
+	Code              CodeableConcept        `json:"code"`
+	Subject           Reference              `json:"subject"`
+	EffectiveDateTime string                 `json:"effectiveDateTime,omitempty"`
+	ValueInteger      *int                   `json:"valueInteger,omitempty"`
+	ValueQuantity     *Quantity              `json:"valueQuantity,omitempty"`
+	Note              []Annotation           `json:"note,omitempty"`
+	Component         []ObservationComponent `json:"component,omitempty"`
+}
+
+type Dosage struct {
+	Text string `json:"text"`
+}
+
+type MedicationStatement struct {
+	ResourceType              string          `json:"resourceType"`
+	ID                        string          `json:"id"`
+	Status                    string          `json:"status"`
+	MedicationCodeableConcept CodeableConcept `json:"medicationCodeableConcept"`
+	Subject                   Reference       `json:"subject"`
+	EffectivePeriod           *Period         `json:"effectivePeriod,omitempty"`
+	Dosage                    []Dosage        `json:"dosage,omitempty"`
+	Note                      []Annotation    `json:"note,omitempty"`
+}
+
+type MedicationAdministrationDosage struct {
+	Text string `json:"text"`
+}
+
+type MedicationAdministration struct {
+	ResourceType              string                          `json:"resourceType"`
+	ID                        string                          `json:"id"`
+	Status                    string                          `json:"status"`
+	MedicationCodeableConcept CodeableConcept                 `json:"medicationCodeableConcept"`
+	Subject                   Reference                       `json:"subject"`
+	EffectiveDateTime         string                          `json:"effectiveDateTime"`
+	Dosage                    *MedicationAdministrationDosage `json:"dosage,omitempty"`
+	Note                      []Annotation                    `json:"note,omitempty"`
+}
+
+type Patient struct {
+	ResourceType string `json:"resourceType"`
+	ID           string `json:"id"`
+}
+
+type BundleEntry struct {
+	FullURL  string `json:"fullUrl,omitempty"`
+	Resource any    `json:"resource"`
+}
+
+// Bundle has no total, FHIR only allows it on searchset and history bundles
+// (bdl-1) and the export is a collection
+type Bundle struct {
+	ResourceType string        `json:"resourceType"`
+	Type         string        `json:"type"`
+	Timestamp    string        `json:"timestamp"`
+	Entry        []BundleEntry `json:"entry"`
+}
@@ -0,0 +1,120 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bearable exports one row per tracked item with the columns
+// date, time of day, category, rating/amount, detail, notes. Rows sharing a
+// date and time of day are combined into one entry, the mood row supplies the
+// rating and emotions, symptoms and factors supply the labels
+type bearableParser struct{}
+
+var bearableTimesOfDay = map[string]time.Duration{
+	"am":      9 * time.Hour,
+	"mid":     13 * time.Hour,
+	"pm":      18 * time.Hour,
+	"all day": 12 * time.Hour,
+}
+
+var bearableLabelCategories = map[string]bool{
+	"emotions": true,
+	"symptoms": true,
+	"factors":  true,
+}
+
+func (bearableParser) Format() string {
+	return "bearable"
+}
+
+func (bearableParser) DefaultMappings() map[string]string {
+	return map[string]string{
+		"happy":      "Happy",
+		"excited":    "Excited",
+		"calm":       "Calm",
+		"grateful":   "Grateful",
+		"confident":  "Confident",
+		"sad":        "Sad",
+		"anxious":    "Anxious",
+		"angry":      "Angry",
+		"frustrated": "Frustrated",
+		"lonely":     "Lonely",
+		"content":    "Content",
+		"restless":   "Restless",
+		"bored":      "Bored",
+		"energetic":  "Energetic",
+		"tired":      "Tired",
+		// an everyday emotion without a severity, not the clinical tag
+		"irritable": "Frustrated",
+	}
+}
+
+func (bearableParser) Parse(reader io.Reader) ([]Entry, []RowError, error) {
+
+	rows, readErr := csvRows(reader)
+	if readErr != nil {
+		return nil, nil, readErr
+	}
+
+	entriesByTime := make(map[time.Time]*Entry)
+	var rowErrors []RowError
+
+	for i, row := range rows {
+		rowNumber := i + 2
+
+		date, dateErr := parseDate(row["date"])
+		if dateErr != nil {
+			rowErrors = append(rowErrors, RowError{Row: rowNumber, Err: dateErr})
+			continue
+		}
+		offset, known := bearableTimesOfDay[strings.ToLower(row["time of day"])]
+		if !known {
+			offset = bearableTimesOfDay["all day"]
+		}
+		createdAt := date.Add(offset)
+
+		entry, exists := entriesByTime[createdAt]
+		if !exists {
+			entry = &Entry{Row: rowNumber, CreatedAt: createdAt, Rating: -1, ScaleMin: 1, ScaleMax: 5}
+			entriesByTime[createdAt] = entry
+		}
+
+		category := strings.ToLower(row["category"])
+		switch {
+		case category == "mood":
+			rating, parseErr := strconv.ParseFloat(row["rating/amount"], 64)
+			if parseErr != nil {
+				rowErrors = append(rowErrors, RowError{Row: rowNumber, Err: fmt.Errorf("invalid mood rating %q", row["rating/amount"])})
+				continue
+			}
+			entry.Rating = rating
+		case bearableLabelCategories[category]:
+			entry.Labels = append(entry.Labels, splitLabels(strings.ToLower(row["detail"]), ",")...)
+		}
+
+		if row["notes"] != "" {
+			entry.Note = strings.TrimSpace(entry.Note + "\n" + row["notes"])
+		}
+	}
+
+	var entries []Entry
+	for _, entry := range entriesByTime {
+		// a time of day without a mood row cannot become a mood log
+		if entry.Rating < 0 {
+			rowErrors = append(rowErrors, RowError{Row: entry.Row, Err: fmt.Errorf("no mood rating for %s", entry.CreatedAt.Format(mysqlDateTimeLayout))})
+			continue
+		}
+		entries = append(entries, *entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.Before(entries[j].CreatedAt)
+	})
+
+	return entries, rowErrors, nil
+}
@@ -0,0 +1,88 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Daylio exports one row per entry with the columns
+// full_date, date, weekday, time, mood, activities, note_title, note
+type daylioParser struct{}
+
+var daylioMoods = map[string]float64{
+	"rad":   5,
+	"good":  4,
+	"meh":   3,
+	"bad":   2,
+	"awful": 1,
+}
+
+func (daylioParser) Format() string {
+	return "daylio"
+}
+
+// DefaultMappings keeps Daylio's moods out of the clinical tags, "awful" is the
+// worst of five everyday moods rather than a depressive episode and the rating
+// already carries how low it was
+func (daylioParser) DefaultMappings() map[string]string {
+	return map[string]string{
+		"rad":   "Excited",
+		"good":  "Happy",
+		"meh":   "Content",
+		"bad":   "Frustrated",
+		"awful": "Sad",
+	}
+}
+
+func (daylioParser) Parse(reader io.Reader) ([]Entry, []RowError, error) {
+
+	rows, readErr := csvRows(reader)
+	if readErr != nil {
+		return nil, nil, readErr
+	}
+
+	var entries []Entry
+	var rowErrors []RowError
+
+	for i, row := range rows {
+		rowNumber := i + 2 // header is row 1
+
+		date, dateErr := parseDate(row["full_date"])
+		if dateErr != nil {
+			rowErrors = append(rowErrors, RowError{Row: rowNumber, Err: dateErr})
+			continue
+		}
+		clock, clockErr := parseClock(row["time"])
+		if clockErr != nil {
+			rowErrors = append(rowErrors, RowError{Row: rowNumber, Err: clockErr})
+			continue
+		}
+
+		mood := strings.ToLower(row["mood"])
+		rating, known := daylioMoods[mood]
+		if !known {
+			rowErrors = append(rowErrors, RowError{Row: rowNumber, Err: fmt.Errorf("custom daylio mood %q has no rating", row["mood"])})
+			continue
+		}
+
+		labels := append([]string{mood}, splitLabels(row["activities"], "|")...)
+
+		note := row["note"]
+		if title := row["note_title"]; title != "" {
+			note = strings.TrimSpace(title + "\n" + note)
+		}
+
+		entries = append(entries, Entry{
+			Row:       rowNumber,
+			CreatedAt: date.Add(clock),
+			Rating:    rating,
+			ScaleMin:  1,
+			ScaleMax:  5,
+			Labels:    labels,
+			Note:      note,
+		})
+	}
+
+	return entries, rowErrors, nil
+}
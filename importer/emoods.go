@@ -0,0 +1,124 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+)
+
+// eMoods exports one row per day with the columns
+// date, elevated, depressed, irritability, anxiety (each 0-3), sleep and notes
+type eMoodsParser struct{}
+
+// eMoods only records the time of day in the app, entries are placed at noon
+// so they stay on the same day in every timezone
+const eMoodsEntryHour = 12
+
+var eMoodsSymptoms = []string{"elevated", "depressed", "irritability", "anxiety"}
+
+// eMoods rates each symptom none, mild, moderate or severe. Each level is a
+// label of its own, e.g. "depressed moderate", so that only the levels eMoods
+// describes as impairing map to a clinical tag by default
+var eMoodsSeverities = []string{"none", "mild", "moderate", "severe"}
+
+// eMoodsClinicalSeverity is the first level mapped to a clinical tag
+const eMoodsClinicalSeverity = 2
+
+func (eMoodsParser) Format() string {
+	return "emoods"
+}
+
+func (eMoodsParser) DefaultMappings() map[string]string {
+	mildTags := map[string]string{
+		"elevated":     "Energetic",
+		"depressed":    "Sad",
+		"irritability": "Frustrated",
+		"anxiety":      "Anxious",
+	}
+	clinicalTags := map[string]string{
+		"elevated":     "Hypomanic",
+		"depressed":    "Depressed",
+		"irritability": "Irritable",
+		"anxiety":      "Anxious",
+	}
+
+	mappings := make(map[string]string)
+	for _, symptom := range eMoodsSymptoms {
+		for level := 1; level < len(eMoodsSeverities); level++ {
+			tag := mildTags[symptom]
+			if level >= eMoodsClinicalSeverity {
+				tag = clinicalTags[symptom]
+			}
+			mappings[symptomLabel(symptom, level)] = tag
+		}
+	}
+	return mappings
+}
+
+func symptomLabel(symptom string, level int) string {
+	return symptom + " " + eMoodsSeverities[level]
+}
+
+func (eMoodsParser) Parse(reader io.Reader) ([]Entry, []RowError, error) {
+
+	rows, readErr := csvRows(reader)
+	if readErr != nil {
+		return nil, nil, readErr
+	}
+
+	var entries []Entry
+	var rowErrors []RowError
+
+	for i, row := range rows {
+		rowNumber := i + 2
+
+		date, dateErr := parseDate(row["date"])
+		if dateErr != nil {
+			rowErrors = append(rowErrors, RowError{Row: rowNumber, Err: dateErr})
+			continue
+		}
+
+		severities := make(map[string]float64)
+		var severityErr error
+		for _, symptom := range eMoodsSymptoms {
+			if row[symptom] == "" {
+				continue
+			}
+			severity, parseErr := strconv.ParseFloat(row[symptom], 64)
+			if parseErr != nil || severity < 0 || severity > float64(len(eMoodsSeverities)-1) {
+				severityErr = fmt.Errorf("invalid %s severity %q", symptom, row[symptom])
+				break
+			}
+			severities[symptom] = severity
+		}
+		if severityErr != nil {
+			rowErrors = append(rowErrors, RowError{Row: rowNumber, Err: severityErr})
+			continue
+		}
+
+		var labels []string
+		for _, symptom := range eMoodsSymptoms {
+			if level := int(math.Round(severities[symptom])); level > 0 {
+				labels = append(labels, symptomLabel(symptom, level))
+			}
+		}
+
+		// eMoods has no overall mood, derive one on a -3 (severely depressed)
+		// to +3 (severely elevated) scale with a euthymic day in the middle
+		rating := math.Max(-3, math.Min(3, severities["elevated"]-severities["depressed"]))
+
+		entries = append(entries, Entry{
+			Row:       rowNumber,
+			CreatedAt: date.Add(eMoodsEntryHour * time.Hour),
+			Rating:    rating,
+			ScaleMin:  -3,
+			ScaleMax:  3,
+			Labels:    labels,
+			Note:      row["notes"],
+		})
+	}
+
+	return entries, rowErrors, nil
+}
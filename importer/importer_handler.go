@@ -0,0 +1,103 @@
+package importer
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+type ImporterHandler struct {
+	importerService *importerService
+}
+
+var importsUsers string = `^/imports/users/([0-9]+)$`
+var importsUsersJob string = `^/imports/users/([0-9]+)/jobs/([0-9]+)$`
+var importsUsersMappings string = `^/imports/users/([0-9]+)/mappings$`
+
+const maxUploadBytes = 10 << 20
+
+func NewImporterHandler(importerService *importerService) *ImporterHandler {
+	return &ImporterHandler{
+		importerService: importerService,
+	}
+}
+
+func (handler *ImporterHandler) ProcessRequest(writer http.ResponseWriter, request *http.Request) {
+	switch {
+	case utils.MatchURL(importsUsers, request.URL.Path) && request.Method == http.MethodPost:
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+		format := request.URL.Query().Get("format")
+		dryRun := request.URL.Query().Get("dryRun") == "true"
+
+		upload := http.MaxBytesReader(writer, request.Body, maxUploadBytes)
+
+		job, importErr := handler.importerService.startImport(userID, format, dryRun, upload)
+		if importErr != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(importErr.Error()))
+			return
+		}
+		body, _ := json.Marshal(job)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusAccepted)
+		writer.Write(body)
+
+	case utils.MatchURL(importsUsersJob, request.URL.Path):
+
+		params := utils.PathParams(importsUsersJob, request.URL.Path)
+
+		job, found := handler.importerService.job(params[0], params[1])
+		if !found {
+			writer.WriteHeader(http.StatusNotFound)
+			writer.Write([]byte("import job not found"))
+			return
+		}
+		body, _ := json.Marshal(job)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+
+	case utils.MatchURL(importsUsersMappings, request.URL.Path) && request.Method == http.MethodPut:
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+		format := request.URL.Query().Get("format")
+
+		var tagMappings []models.TagMapping
+		decodeErr := json.NewDecoder(request.Body).Decode(&tagMappings)
+		if decodeErr != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(decodeErr.Error()))
+			return
+		}
+
+		saveErr := handler.importerService.saveTagMappings(userID, format, tagMappings)
+		if saveErr != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(saveErr.Error()))
+			return
+		}
+		body, _ := json.Marshal(handler.importerService.tagMappings(userID, format))
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+
+	case utils.MatchURL(importsUsersMappings, request.URL.Path):
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+		format := request.URL.Query().Get("format")
+
+		tagMappings := handler.importerService.tagMappings(userID, format)
+		body, _ := json.Marshal(tagMappings)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+
+	default:
+		writer.WriteHeader(http.StatusNotFound)
+		writer.Write([]byte("404 path not found"))
+	}
+}
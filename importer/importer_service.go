@@ -0,0 +1,258 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+const (
+	batchSize   = 100
+	previewSize = 20
+)
+
+type importerService struct {
+	importRepository       *database.ImportRepository
+	moodLogRepository      *database.MoodLogRepository
+	userSettingsRepository *database.UserSettingsRepository
+	webhookRepository      *database.WebhookRepository
+}
+
+func NewImporterService(importRepository *database.ImportRepository, moodLogRepository *database.MoodLogRepository, userSettingsRepository *database.UserSettingsRepository, webhookRepository *database.WebhookRepository) *importerService {
+	return &importerService{
+		importRepository:       importRepository,
+		moodLogRepository:      moodLogRepository,
+		userSettingsRepository: userSettingsRepository,
+		webhookRepository:      webhookRepository,
+	}
+}
+
+// startImport parses the upload straight away so format errors are returned to
+// the caller, the mapping, de-duplication and writes run as a background job
+func (service *importerService) startImport(userID string, format string, dryRun bool, upload io.Reader) (models.ImportJob, error) {
+
+	parser, supported := parsers[strings.ToLower(format)]
+	if !supported {
+		return models.ImportJob{}, fmt.Errorf("unsupported format %q", format)
+	}
+
+	entries, rowErrors, parseErr := parser.Parse(upload)
+	if parseErr != nil {
+		return models.ImportJob{}, parseErr
+	}
+
+	jobID := service.importRepository.CreateJob(userID, parser.Format(), dryRun, len(entries)+len(rowErrors))
+
+	go service.runSafely(jobID, userID, parser, dryRun, entries, rowErrors)
+
+	job, _ := service.importRepository.Job(userID, fmt.Sprint(jobID))
+
+	return job, nil
+}
+
+func (service *importerService) runSafely(jobID int, userID string, parser Parser, dryRun bool, entries []Entry, rowErrors []RowError) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("ERROR running import job", jobID, r)
+			service.importRepository.FailJob(jobID, fmt.Sprint(r))
+		}
+	}()
+	service.run(jobID, userID, parser, dryRun, entries, rowErrors)
+}
+
+func (service *importerService) run(jobID int, userID string, parser Parser, dryRun bool, entries []Entry, rowErrors []RowError) {
+
+	job := models.ImportJob{JobID: jobID, SkippedCount: len(rowErrors), ProcessedRows: len(rowErrors)}
+
+	report := models.ImportReport{
+		MappedLabels:   make(map[string]string),
+		UnmappedLabels: make([]string, 0),
+		RowErrors:      make([]models.ImportRowError, 0),
+		Duplicates:     make([]string, 0),
+		Preview:        make([]models.MoodLog, 0),
+	}
+	for _, rowError := range rowErrors {
+		report.RowErrors = append(report.RowErrors, models.ImportRowError{Row: rowError.Row, Error: rowError.Err.Error()})
+	}
+
+	if len(entries) == 0 {
+		service.importRepository.UpdateJobProgress(job)
+		service.importRepository.CompleteJob(jobID, report)
+		return
+	}
+
+	labelToTag, moodTagIDs := service.resolveMappings(userID, parser)
+
+	unmapped := make(map[string]bool)
+	for _, entry := range entries {
+		for _, label := range entry.Labels {
+			key := strings.ToLower(label)
+			if tag, mapped := labelToTag[key]; mapped {
+				report.MappedLabels[key] = tag
+			} else {
+				unmapped[key] = true
+			}
+		}
+	}
+	for label := range unmapped {
+		report.UnmappedLabels = append(report.UnmappedLabels, label)
+	}
+	sort.Strings(report.UnmappedLabels)
+
+	// exports hold the wall clock time the user saw, created_at is stored in UTC
+	location, loadErr := utils.LoadTimezone(service.userSettingsRepository.Timezone(userID))
+	if loadErr != nil {
+		service.importRepository.FailJob(jobID, loadErr.Error())
+		return
+	}
+	for i, entry := range entries {
+		createdAt := entry.CreatedAt
+		entries[i].CreatedAt = time.Date(createdAt.Year(), createdAt.Month(), createdAt.Day(), createdAt.Hour(), createdAt.Minute(), createdAt.Second(), 0, location).UTC()
+	}
+
+	from := entries[0].CreatedAt
+	to := entries[0].CreatedAt
+	for _, entry := range entries {
+		if entry.CreatedAt.Before(from) {
+			from = entry.CreatedAt
+		}
+		if entry.CreatedAt.After(to) {
+			to = entry.CreatedAt
+		}
+	}
+	existing := service.importRepository.ExistingMoodLogTimes(userID, from.Format(mysqlDateTimeLayout), to.Format(mysqlDateTimeLayout))
+
+	for start := 0; start < len(entries); start += batchSize {
+		end := min(start+batchSize, len(entries))
+
+		var batch []models.MoodLog
+		for _, entry := range entries[start:end] {
+			createdAt := entry.CreatedAt.Format(mysqlDateTimeLayout)
+
+			// also catches repeated timestamps within the same file
+			if existing[createdAt] {
+				job.DuplicateCount++
+				report.Duplicates = append(report.Duplicates, createdAt)
+				continue
+			}
+			existing[createdAt] = true
+
+			moodLog := models.MoodLog{
+				UserID:     userID,
+				MoodRating: rescale(entry.Rating, entry.ScaleMin, entry.ScaleMax),
+				Note:       entry.Note,
+				CreatedAt:  createdAt,
+				MoodTags:   mapLabels(entry.Labels, labelToTag),
+			}
+			batch = append(batch, moodLog)
+
+			if len(report.Preview) < previewSize {
+				report.Preview = append(report.Preview, moodLog)
+			}
+		}
+
+		job.ImportedCount += len(batch)
+		job.ProcessedRows += end - start
+		if !dryRun && len(batch) > 0 {
+			service.moodLogRepository.InsertMoodLogs(userID, batch, moodTagIDs, job)
+		} else {
+			service.importRepository.UpdateJobProgress(job)
+		}
+	}
+
+	service.importRepository.CompleteJob(jobID, report)
+
+	if !dryRun && job.ImportedCount > 0 {
+		payload, _ := json.Marshal(models.ImportCompleted{
+			JobID:          jobID,
+			UserID:         userID,
+			SourceFormat:   parser.Format(),
+			ImportedCount:  job.ImportedCount,
+			DuplicateCount: job.DuplicateCount,
+			SkippedCount:   job.SkippedCount,
+			From:           from.Format(mysqlDateTimeLayout),
+			To:             to.Format(mysqlDateTimeLayout),
+		})
+		service.webhookRepository.PublishEvent(userID, models.EventImportCompleted, fmt.Sprintf("import:%d", jobID), payload)
+	}
+}
+
+// resolveMappings maps lower-cased external labels to mood tag names, the
+// user's reviewed mappings win over the parser defaults which win over a
+// case-insensitive match on the tag name
+func (service *importerService) resolveMappings(userID string, parser Parser) (map[string]string, map[string]int) {
+
+	moodTags := service.importRepository.MoodTags()
+
+	moodTagIDs := make(map[string]int)
+	tagNames := make(map[string]string)
+	for _, moodTag := range moodTags {
+		moodTagIDs[moodTag.Name] = moodTag.MoodTagID
+		tagNames[strings.ToLower(moodTag.Name)] = moodTag.Name
+	}
+
+	labelToTag := make(map[string]string)
+	for label, tag := range tagNames {
+		labelToTag[label] = tag
+	}
+	for label, tag := range parser.DefaultMappings() {
+		if _, exists := moodTagIDs[tag]; exists {
+			labelToTag[strings.ToLower(label)] = tag
+		}
+	}
+	for _, tagMapping := range service.importRepository.TagMappings(userID, parser.Format()) {
+		label := strings.ToLower(tagMapping.ExternalLabel)
+		if tagMapping.MoodTagID == 0 {
+			delete(labelToTag, label)
+			continue
+		}
+		labelToTag[label] = tagMapping.MoodTagName
+	}
+
+	return labelToTag, moodTagIDs
+}
+
+func mapLabels(labels []string, labelToTag map[string]string) []string {
+	seen := make(map[string]bool)
+	tags := make([]string, 0)
+	for _, label := range labels {
+		tag, mapped := labelToTag[strings.ToLower(label)]
+		if !mapped || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+func (service *importerService) job(userID string, jobID string) (models.ImportJob, bool) {
+	return service.importRepository.Job(userID, jobID)
+}
+
+func (service *importerService) tagMappings(userID string, format string) []models.TagMapping {
+	return service.importRepository.TagMappings(userID, strings.ToLower(format))
+}
+
+func (service *importerService) saveTagMappings(userID string, format string, tagMappings []models.TagMapping) error {
+
+	if _, supported := parsers[strings.ToLower(format)]; !supported {
+		return fmt.Errorf("unsupported format %q", format)
+	}
+
+	for i := range tagMappings {
+		tagMappings[i].SourceFormat = strings.ToLower(format)
+		tagMappings[i].ExternalLabel = strings.ToLower(tagMappings[i].ExternalLabel)
+	}
+
+	service.importRepository.SaveTagMappings(userID, tagMappings)
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package importer
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+const mysqlDateTimeLayout = "2006-01-02 15:04:05"
+
+// Entry is a single mood entry read from another app's export before it is
+// mapped onto our mood tags and rating scale
+type Entry struct {
+	Row       int // csv row the entry was read from, for error reporting
+	CreatedAt time.Time
+	Rating    float64
+	ScaleMin  float64
+	ScaleMax  float64
+	Labels    []string // moods, emotions and activities as named by the source app
+	Note      string
+}
+
+// Parser reads the csv export of one app, new formats are added with
+// RegisterParser
+type Parser interface {
+	Format() string
+	Parse(reader io.Reader) ([]Entry, []RowError, error)
+	// DefaultMappings suggests a mood tag name for labels the user has not mapped yet
+	DefaultMappings() map[string]string
+}
+
+type RowError struct {
+	Row int
+	Err error
+}
+
+var parsers = make(map[string]Parser)
+
+func RegisterParser(parser Parser) {
+	parsers[parser.Format()] = parser
+}
+
+func init() {
+	RegisterParser(daylioParser{})
+	RegisterParser(eMoodsParser{})
+	RegisterParser(bearableParser{})
+}
+
+// rescale maps a rating from the source scale onto our 1-10 mood_rating
+func rescale(rating float64, scaleMin float64, scaleMax float64) int {
+	if scaleMax == scaleMin {
+		return 5
+	}
+	scaled := 1 + (rating-scaleMin)*9/(scaleMax-scaleMin)
+	return int(math.Max(1, math.Min(10, math.Round(scaled))))
+}
+
+// csvRows reads a csv export with a header row and returns each row keyed by
+// its lower-cased column name
+func csvRows(reader io.Reader) ([]map[string]string, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = true
+
+	header, headerErr := csvReader.Read()
+	if headerErr != nil {
+		return nil, fmt.Errorf("reading header: %w", headerErr)
+	}
+	for i := range header {
+		header[i] = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(header[i], "\ufeff")))
+	}
+
+	var rows []map[string]string
+	for {
+		record, readErr := csvReader.Read()
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+		row := make(map[string]string)
+		for i, value := range record {
+			if i < len(header) {
+				row[header[i]] = strings.TrimSpace(value)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "1/2/2006", "01/02/2006", "2006/01/02", "Jan 2, 2006", "January 2, 2006"} {
+		if parsed, parseErr := time.Parse(layout, value); parseErr == nil {
+			return parsed, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date %q", value)
+}
+
+func parseClock(value string) (time.Duration, error) {
+	for _, layout := range []string{"15:04", "15:04:05", "3:04 PM", "3:04 pm", "3:04PM", "3:04pm"} {
+		if parsed, parseErr := time.Parse(layout, value); parseErr == nil {
+			return time.Duration(parsed.Hour())*time.Hour + time.Duration(parsed.Minute())*time.Minute + time.Duration(parsed.Second())*time.Second, nil
+		}
+	}
+	return 0, fmt.Errorf("unrecognised time %q", value)
+}
+
+func splitLabels(value string, separator string) []string {
+	var labels []string
+	for _, label := range strings.Split(value, separator) {
+		if trimmed := strings.TrimSpace(label); trimmed != "" {
+			labels = append(labels, trimmed)
+		}
+	}
+	return labels
+}
@@ -0,0 +1,171 @@
+package importer
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// the tags in the clinical mood category, see db.sql
+var clinicalTags = []string{"Manic", "Hypomanic", "Depressed", "Mixed State", "Irritable"}
+
+func TestDaylioParse(t *testing.T) {
+	export := "\ufefffull_date,date,weekday,time,mood,activities,note_title,note\n" +
+		"2025-03-02,March 2,Sunday,9:15 PM,awful,work | sleep early,Long day,Too tired to cook\n" +
+		"2025-03-01,March 1,Saturday,08:30,Good,,,\n" +
+		"2025-02-30,February 30,,08:30,good,,,\n" +
+		"2025-02-28,February 28,Friday,08:30,sleepy,,,\n"
+
+	entries, rowErrors, parseErr := daylioParser{}.Parse(strings.NewReader(export))
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("parsed %d entries, want 2: %+v", len(entries), entries)
+	}
+	awful := entries[0]
+	if want := time.Date(2025, 3, 2, 21, 15, 0, 0, time.UTC); !awful.CreatedAt.Equal(want) {
+		t.Errorf("createdAt = %v, want %v", awful.CreatedAt, want)
+	}
+	if awful.Rating != 1 || awful.ScaleMin != 1 || awful.ScaleMax != 5 {
+		t.Errorf("rating = %v on %v-%v, want 1 on 1-5", awful.Rating, awful.ScaleMin, awful.ScaleMax)
+	}
+	if want := []string{"awful", "work", "sleep early"}; !slices.Equal(awful.Labels, want) {
+		t.Errorf("labels = %q, want %q", awful.Labels, want)
+	}
+	if awful.Note != "Long day\nToo tired to cook" {
+		t.Errorf("note = %q, want the title and note", awful.Note)
+	}
+	if entries[1].Labels[0] != "good" {
+		t.Errorf("mood label = %q, want it lower-cased", entries[1].Labels[0])
+	}
+
+	if len(rowErrors) != 2 || rowErrors[0].Row != 4 || rowErrors[1].Row != 5 {
+		t.Errorf("row errors = %+v, want rows 4 and 5", rowErrors)
+	}
+}
+
+func TestEMoodsParse(t *testing.T) {
+	export := "date,elevated,depressed,irritability,anxiety,sleep,notes\n" +
+		"2025-03-01,0,1,0,1,7,mild day\n" +
+		"2025-03-02,0,3,2,0,10,\n" +
+		"2025-03-03,2,0,0,0,5,\n" +
+		"2025-03-04,0,4,0,0,8,\n" +
+		"2025-03-05,0,,0,x,8,\n"
+
+	entries, rowErrors, parseErr := eMoodsParser{}.Parse(strings.NewReader(export))
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	tests := []struct {
+		date   string
+		rating float64
+		labels []string
+	}{
+		{"2025-03-01", -1, []string{"depressed mild", "anxiety mild"}},
+		{"2025-03-02", -3, []string{"depressed severe", "irritability moderate"}},
+		{"2025-03-03", 2, []string{"elevated moderate"}},
+	}
+	if len(entries) != len(tests) {
+		t.Fatalf("parsed %d entries, want %d: %+v", len(entries), len(tests), entries)
+	}
+	for i, test := range tests {
+		entry := entries[i]
+		if date := entry.CreatedAt.Format("2006-01-02"); date != test.date || entry.CreatedAt.Hour() != eMoodsEntryHour {
+			t.Errorf("entry %d createdAt = %v, want %s at noon", i, entry.CreatedAt, test.date)
+		}
+		if entry.Rating != test.rating {
+			t.Errorf("entry %d rating = %v, want %v", i, entry.Rating, test.rating)
+		}
+		if !slices.Equal(entry.Labels, test.labels) {
+			t.Errorf("entry %d labels = %q, want %q", i, entry.Labels, test.labels)
+		}
+	}
+	if entries[0].Note != "mild day" {
+		t.Errorf("note = %q, want %q", entries[0].Note, "mild day")
+	}
+
+	// a severity past severe and one that isn't a number
+	if len(rowErrors) != 2 || rowErrors[0].Row != 5 || rowErrors[1].Row != 6 {
+		t.Errorf("row errors = %+v, want rows 5 and 6", rowErrors)
+	}
+}
+
+func TestBearableParse(t *testing.T) {
+	export := "date,time of day,category,rating/amount,detail,notes\n" +
+		"2025-03-01,am,Mood,4,,\n" +
+		"2025-03-01,am,Emotions,,\"Happy, Calm\",slept well\n" +
+		"2025-03-01,pm,Symptoms,,Tired,\n" +
+		"2025-03-02,all day,Mood,two,,\n"
+
+	entries, rowErrors, parseErr := bearableParser{}.Parse(strings.NewReader(export))
+	if parseErr != nil {
+		t.Fatal(parseErr)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("parsed %d entries, want 1: %+v", len(entries), entries)
+	}
+	entry := entries[0]
+	if want := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC); !entry.CreatedAt.Equal(want) || entry.Rating != 4 {
+		t.Errorf("entry = %v rated %v, want %v rated 4", entry.CreatedAt, entry.Rating, want)
+	}
+	if want := []string{"happy", "calm"}; !slices.Equal(entry.Labels, want) || entry.Note != "slept well" {
+		t.Errorf("labels = %q note %q, want %q note %q", entry.Labels, entry.Note, want, "slept well")
+	}
+
+	// the invalid rating, and the evening and the 2nd without a mood row
+	if len(rowErrors) != 3 {
+		t.Errorf("row errors = %+v, want 3", rowErrors)
+	}
+}
+
+func TestDefaultMappings(t *testing.T) {
+	tests := []struct {
+		parser Parser
+		// the labels allowed to map to a clinical tag
+		clinical []string
+	}{
+		{daylioParser{}, nil},
+		{bearableParser{}, nil},
+		{eMoodsParser{}, []string{
+			"elevated moderate", "elevated severe",
+			"depressed moderate", "depressed severe",
+			"irritability moderate", "irritability severe",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.parser.Format(), func(t *testing.T) {
+			for label, tag := range test.parser.DefaultMappings() {
+				if isClinical := slices.Contains(clinicalTags, tag); isClinical != slices.Contains(test.clinical, label) {
+					t.Errorf("%q maps to %q, clinical %v", label, tag, isClinical)
+				}
+			}
+		})
+	}
+}
+
+func TestRescale(t *testing.T) {
+	tests := []struct {
+		rating   float64
+		scaleMin float64
+		scaleMax float64
+		want     int
+	}{
+		{1, 1, 5, 1},
+		{5, 1, 5, 10},
+		{3, 1, 5, 6},
+		{-3, -3, 3, 1},
+		{0, -3, 3, 6},
+		{3, -3, 3, 10},
+		{4, 4, 4, 5},
+	}
+	for _, test := range tests {
+		if got := rescale(test.rating, test.scaleMin, test.scaleMax); got != test.want {
+			t.Errorf("rescale(%v, %v, %v) = %v, want %v", test.rating, test.scaleMin, test.scaleMax, got, test.want)
+		}
+	}
+}
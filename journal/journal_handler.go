@@ -0,0 +1,84 @@
+package journal
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+type JournalHandler struct {
+	journalService *journalService
+}
+
+var usersMoodLogsSearch string = `^/users/([0-9]+)/mood-logs/search$`
+
+// used when startDate or endDate are left out so the whole history is searched
+var earliestDate string = "1000-01-01"
+var latestDate string = "9999-12-31"
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+func NewJournalHandler(journalService *journalService) *JournalHandler {
+	return &JournalHandler{
+		journalService: journalService,
+	}
+}
+
+func (handler *JournalHandler) ProcessRequest(writer http.ResponseWriter, request *http.Request) {
+	switch {
+	case utils.MatchURL(usersMoodLogsSearch, request.URL.Path) && request.Method == http.MethodGet:
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+		query := request.URL.Query().Get("q")
+		startDate := request.URL.Query().Get("startDate")
+		endDate := request.URL.Query().Get("endDate")
+
+		if startDate == "" {
+			startDate = earliestDate
+		}
+		if endDate == "" {
+			endDate = latestDate
+		}
+
+		tags := make([]string, 0)
+		for _, tag := range strings.Split(request.URL.Query().Get("tags"), ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+
+		// no page past the candidates can hold a result
+		page, pageErr := utils.IntParam(request, "page", 1, 1, maxSearchCandidates)
+		if pageErr != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(pageErr.Error()))
+			return
+		}
+		pageSize, pageSizeErr := utils.IntParam(request, "pageSize", defaultPageSize, 1, maxPageSize)
+		if pageSizeErr != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(pageSizeErr.Error()))
+			return
+		}
+
+		searchPage, searchErr := handler.journalService.search(userID, query, startDate, endDate, tags, page, pageSize)
+		if searchErr != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(searchErr.Error()))
+			return
+		}
+		body, _ := json.Marshal(searchPage)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+
+	default:
+		writer.WriteHeader(http.StatusNotFound)
+		writer.Write([]byte("404 path not found"))
+	}
+}
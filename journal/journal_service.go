@@ -0,0 +1,86 @@
+package journal
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+// the newest logs holding every indexed word are decrypted and checked, when
+// there are more the page says it's truncated and from where, so the client
+// can search again with an earlier endDate
+const maxSearchCandidates = 2000
+
+type journalService struct {
+	moodLogRepository *database.MoodLogRepository
+}
+
+func NewJournalService(moodLogRepository *database.MoodLogRepository) *journalService {
+	return &journalService{
+		moodLogRepository: moodLogRepository,
+	}
+}
+
+// search finds the user's mood logs whose note holds every word and phrase of
+// the query, tags narrows it to logs carrying all of the given tags. Notes are
+// encrypted so the blind index narrows the logs down and the rest of the
+// matching and the ranking happens here on the decrypted notes
+func (service *journalService) search(userID string, query string, startDate string, endDate string, tags []string, page int, pageSize int) (*models.MoodLogSearchPage, error) {
+
+	terms := parseSearchQuery(query)
+	if terms.empty() {
+		return nil, errors.New("q must contain a word of at least 3 letters or a quoted phrase")
+	}
+
+	candidates := service.moodLogRepository.SearchNotes(userID, terms.indexedWords(), startDate, endDate, tags, maxSearchCandidates+1)
+
+	truncated := len(candidates) > maxSearchCandidates
+	searchedFrom := ""
+	if truncated {
+		candidates = candidates[:maxSearchCandidates]
+		searchedFrom = candidates[len(candidates)-1].MoodLog.CreatedAt
+	}
+
+	matches := make([]models.MoodLogSearchResult, 0)
+	for _, candidate := range candidates {
+		_, found, score := matchNote(splitWords(candidate.MoodLog.Note), terms)
+		if !found {
+			continue
+		}
+		candidate.Score = float64(score)
+		matches = append(matches, candidate)
+	}
+
+	// candidates are newest first, the stable sort keeps that order among ties
+	slices.SortStableFunc(matches, func(a, b models.MoodLogSearchResult) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+
+	totalCount := len(matches)
+	start := min(totalCount, utils.PageOffset(page, pageSize))
+	results := matches[start:min(totalCount, start+pageSize)]
+	for i := range results {
+		results[i].Snippet = snippet(results[i].MoodLog.Note, terms)
+	}
+
+	searchPage := &models.MoodLogSearchPage{
+		UserID:       userID,
+		Query:        query,
+		Tags:         tags,
+		StartDate:    startDate,
+		EndDate:      endDate,
+		Page:         page,
+		PageSize:     pageSize,
+		TotalCount:   totalCount,
+		TotalPages:   (totalCount + pageSize - 1) / pageSize,
+		Truncated:    truncated,
+		SearchedFrom: searchedFrom,
+		Results:      results,
+	}
+
+	return searchPage, nil
+}
@@ -0,0 +1,192 @@
+package journal
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/envelope"
+)
+
+const (
+	minTermLength      = envelope.MinIndexedWordLength // shorter words aren't in the blind index
+	snippetWordsBefore = 8
+	snippetWords       = 30
+	ellipsis           = "…"
+)
+
+// searchTerms are the words and quoted phrases of a query, lowercased, every
+// one of them has to be in the note. Both match whole words only, the blind
+// index can't do prefixes
+type searchTerms struct {
+	words   []string
+	phrases [][]string
+}
+
+func parseSearchQuery(query string) searchTerms {
+
+	var terms searchTerms
+
+	// odd parts are inside double quotes
+	for i, part := range strings.Split(query, `"`) {
+		var partWords []string
+		for _, word := range splitWords(part) {
+			partWords = append(partWords, strings.ToLower(word.text))
+		}
+
+		if i%2 == 1 && len(partWords) > 1 {
+			terms.phrases = append(terms.phrases, partWords)
+			continue
+		}
+		for _, word := range partWords {
+			if len([]rune(word)) >= minTermLength {
+				terms.words = append(terms.words, word)
+			}
+		}
+	}
+
+	return terms
+}
+
+func (terms searchTerms) empty() bool {
+	return len(terms.words) == 0 && len(terms.phrases) == 0
+}
+
+// indexedWords are the words the note must hold for the blind index lookup,
+// including those of the phrases, which are checked in full once decrypted
+func (terms searchTerms) indexedWords() []string {
+	all := append([]string{}, terms.words...)
+	for _, phrase := range terms.phrases {
+		all = append(all, phrase...)
+	}
+	return envelope.IndexWords(strings.Join(all, " "))
+}
+
+type word struct {
+	text  string
+	start int // byte offsets into the text
+	end   int
+}
+
+func splitWords(text string) []word {
+	var words []word
+	start := -1
+	for i, r := range text {
+		isWordRune := unicode.IsLetter(r) || unicode.IsDigit(r)
+		if isWordRune && start < 0 {
+			start = i
+		}
+		if !isWordRune && start >= 0 {
+			words = append(words, word{text: text[start:i], start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, word{text: text[start:], start: start, end: len(text)})
+	}
+	return words
+}
+
+// matchNote finds the terms in the note, matched[i] is the number of words
+// from i that form a match. found is whether every term is in the note and
+// score the number of matches
+func matchNote(words []word, terms searchTerms) (matched []int, found bool, score int) {
+
+	matched = make([]int, len(words))
+	foundWords := make(map[string]bool)
+	foundPhrases := make(map[int]bool)
+
+	for i, noteWord := range words {
+		lower := strings.ToLower(noteWord.text)
+		for _, term := range terms.words {
+			if lower == term {
+				matched[i] = 1
+				foundWords[term] = true
+			}
+		}
+		for p, phrase := range terms.phrases {
+			if phraseAt(words, i, phrase) {
+				matched[i] = max(matched[i], len(phrase))
+				foundPhrases[p] = true
+			}
+		}
+		if matched[i] > 0 {
+			score++
+		}
+	}
+
+	found = len(foundPhrases) == len(terms.phrases)
+	for _, term := range terms.words {
+		found = found && foundWords[term]
+	}
+
+	return matched, found, score
+}
+
+// snippet cuts the note down to the words around the first match, escapes it
+// for html and wraps every match in <mark>
+func snippet(note string, terms searchTerms) string {
+
+	words := splitWords(note)
+	if len(words) == 0 {
+		return html.EscapeString(note)
+	}
+
+	matched, _, _ := matchNote(words, terms)
+
+	first := 0
+	for first < len(words) && matched[first] == 0 {
+		first++
+	}
+	if first == len(words) {
+		first = 0
+	}
+
+	fromWord := max(0, first-snippetWordsBefore)
+	toWord := min(len(words), fromWord+snippetWords)
+
+	var built strings.Builder
+	if fromWord > 0 {
+		built.WriteString(ellipsis)
+	}
+
+	position := words[fromWord].start
+	if fromWord == 0 {
+		position = 0
+	}
+	for i := fromWord; i < toWord; i++ {
+		if matched[i] == 0 {
+			continue
+		}
+		last := min(toWord, i+matched[i]) - 1
+		built.WriteString(html.EscapeString(note[position:words[i].start]))
+		built.WriteString("<mark>")
+		built.WriteString(html.EscapeString(note[words[i].start:words[last].end]))
+		built.WriteString("</mark>")
+		position = words[last].end
+		i = last
+	}
+
+	end := words[toWord-1].end
+	if toWord == len(words) {
+		end = len(note)
+	}
+	built.WriteString(html.EscapeString(note[position:end]))
+	if toWord < len(words) {
+		built.WriteString(ellipsis)
+	}
+
+	return built.String()
+}
+
+func phraseAt(words []word, i int, phrase []string) bool {
+	if i+len(phrase) > len(words) {
+		return false
+	}
+	for j, phraseWord := range phrase {
+		if strings.ToLower(words[i+j].text) != phraseWord {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,98 @@
+package journal
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseSearchQuery(t *testing.T) {
+	tests := []struct {
+		query       string
+		wantWords   []string
+		wantPhrases [][]string
+	}{
+		{"Anxious WORK", []string{"anxious", "work"}, nil},
+		{`"woke up early" tired`, []string{"tired"}, [][]string{{"woke", "up", "early"}}},
+		{`"alone"`, []string{"alone"}, nil},
+		{"a to it", nil, nil},
+		{`"unclosed phrase`, nil, [][]string{{"unclosed", "phrase"}}},
+	}
+	for _, test := range tests {
+		terms := parseSearchQuery(test.query)
+		if !slices.Equal(terms.words, test.wantWords) || !slices.EqualFunc(terms.phrases, test.wantPhrases, slices.Equal) {
+			t.Errorf("parseSearchQuery(%q) = %q %q, want %q %q", test.query, terms.words, terms.phrases, test.wantWords, test.wantPhrases)
+		}
+	}
+
+	// the short words of a phrase still narrow the blind index lookup
+	indexed := parseSearchQuery(`"woke up early" tired`).indexedWords()
+	if !slices.Equal(indexed, []string{"tired", "woke", "early"}) {
+		t.Errorf("indexedWords() = %q, want the words long enough for the index", indexed)
+	}
+}
+
+func TestMatchNote(t *testing.T) {
+	tests := []struct {
+		name        string
+		note        string
+		query       string
+		wantMatched []int
+		wantFound   bool
+		wantScore   int
+	}{
+		{"every word", "Tired but calm", "calm tired", []int{1, 0, 1}, true, 2},
+		{"missing word", "Tired but calm", "calm angry", []int{0, 0, 1}, false, 1},
+		{"whole words only", "calmer now", "calm", []int{0, 0}, false, 0},
+		{"phrase", "woke up early, then woke again", `"woke up early"`, []int{3, 0, 0, 0, 0, 0}, true, 1},
+		{"phrase words out of order", "up early and woke", `"woke up early"`, []int{0, 0, 0, 0}, false, 0},
+		{"repeated word", "work, work, work", "work", []int{1, 1, 1}, true, 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matched, found, score := matchNote(splitWords(test.note), parseSearchQuery(test.query))
+			if !slices.Equal(matched, test.wantMatched) || found != test.wantFound || score != test.wantScore {
+				t.Errorf("matchNote() = %v %v %d, want %v %v %d", matched, found, score, test.wantMatched, test.wantFound, test.wantScore)
+			}
+		})
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	// forty numbered words, the snippet starts eight before the match
+	var numbered []string
+	for i := range 40 {
+		numbered = append(numbered, "word"+strconv.Itoa(i))
+	}
+	numbered[20] = "panic"
+	long := strings.Join(numbered, " ")
+
+	tests := []struct {
+		name  string
+		note  string
+		query string
+		want  string
+	}{
+		{"marks the match", "Felt calm today.", "calm", "Felt <mark>calm</mark> today."},
+		{"marks a phrase whole", "woke up early again", `"woke up early"`, "<mark>woke up early</mark> again"},
+		{"escapes html", "<b>calm</b> & fine", "calm", "&lt;b&gt;<mark>calm</mark>&lt;/b&gt; &amp; fine"},
+		{"no match keeps the start", "nothing here", "calm", "nothing here"},
+		{"no words", "...", "calm", "..."},
+		{"cuts long notes", long, "panic", ellipsis + strings.Join(numbered[12:20], " ") + " <mark>panic</mark> " + strings.Join(numbered[21:40], " ")},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := snippet(test.note, parseSearchQuery(test.query)); got != test.want {
+				t.Errorf("snippet() = %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	// thirty words from the eighth before the match, then an ellipsis
+	longer := long + " " + long
+	got := snippet(longer, parseSearchQuery("panic"))
+	if !strings.HasPrefix(got, ellipsis+numbered[12]+" ") || !strings.HasSuffix(got, ellipsis) || strings.Count(got, " ") != snippetWords-1 {
+		t.Errorf("snippet() of a long note = %q, want 30 words between ellipses", got)
+	}
+}
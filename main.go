@@ -2,23 +2,136 @@ package main
 
 import (
 	"net/http"
+	"os"
+	"time"
 
+	"github.com/michaeljosephroddy/project-horizon-backend-go/alerts"
 	"github.com/michaeljosephroddy/project-horizon-backend-go/analytics"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/audit"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/auth"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/cache"
 	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/encryption"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/envelope"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/export"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/fhir"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/importer"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/journal"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/privacy"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/ratelimit"
 	"github.com/michaeljosephroddy/project-horizon-backend-go/router"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/sentiment"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/settings"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/sharing"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/webhooks"
 )
 
+// number of rendered analytics responses kept in memory
+const analyticsCacheSize = 1000
+
+// a mood analytics call runs more than 25 queries, so those routes get a much
+// smaller budget than plain reads and writes, the per IP budgets leave room for
+// a few users behind the same NAT
+var (
+	clientBudgets = ratelimit.Budgets{Expensive: ratelimit.PerMinute(30, 10), Cheap: ratelimit.PerMinute(300, 60)}
+	ipBudgets     = ratelimit.Budgets{Expensive: ratelimit.PerMinute(60, 20), Cheap: ratelimit.PerMinute(600, 120)}
+)
+
+// analytics computations allowed at once, kept well under database.maxOpenConnections
+const analyticsConcurrency = 8
+
 func main() {
 
 	dbConnection := database.NewDatabaseConnection()
 	defer dbConnection.Close()
 
-	moodLogRepository := database.NewMoodLogRepository(dbConnection)
+	masterKeys, masterKeysErr := envelope.LoadMasterKeys()
+	if masterKeysErr != nil {
+		panic(masterKeysErr)
+	}
+	noteCipher := database.NewNoteCipher(dbConnection, masterKeys)
+
+	moodLogRepository := database.NewMoodLogRepository(dbConnection, noteCipher)
 	sleepLogRepository := database.NewSleepLogRepository(dbConnection)
-	analyticsService := analytics.NewAnalyticsService(moodLogRepository, sleepLogRepository)
-	analyticsHandler := analytics.NewAnalyticsHandler(analyticsService)
-	r := router.NewRouter(analyticsHandler)
+	userSettingsRepository := database.NewUserSettingsRepository(dbConnection)
+	userRepository := database.NewUserRepository(dbConnection)
+	medicationLogRepository := database.NewMedicationLogRepository(dbConnection)
+	alertRepository := database.NewAlertRepository(dbConnection)
+	webhookRepository := database.NewWebhookRepository(dbConnection)
+	exportRepository := database.NewExportRepository(dbConnection, noteCipher)
+	importRepository := database.NewImportRepository(dbConnection)
+	dataVersionRepository := database.NewDataVersionRepository(dbConnection)
+	baselinePeriodRepository := database.NewBaselinePeriodRepository(dbConnection)
+	sentimentRepository := database.NewSentimentRepository(dbConnection, noteCipher)
+	erasureRepository := database.NewErasureRepository(dbConnection)
+	auditRepository := database.NewAuditRepository(dbConnection)
+	apiTokenRepository := database.NewApiTokenRepository(dbConnection)
+	sharingGrantRepository := database.NewSharingGrantRepository(dbConnection)
+
+	analyticsCache := cache.NewLRU(analyticsCacheSize)
+
+	analyticsService := analytics.NewAnalyticsService(moodLogRepository, sleepLogRepository, userSettingsRepository, webhookRepository, medicationLogRepository, baselinePeriodRepository, sentimentRepository, userRepository)
+	analyticsService.Start(1 * time.Hour)
+	analyticsHandler := analytics.NewAnalyticsHandler(analyticsService, analyticsCache, dataVersionRepository)
+
+	alertsService := alerts.NewAlertsService(alertRepository, userRepository, moodLogRepository, sleepLogRepository, medicationLogRepository, webhookRepository, userSettingsRepository)
+	alertsService.Start(1 * time.Hour)
+	alertsHandler := alerts.NewAlertsHandler(alertsService)
+
+	webhooksService := webhooks.NewWebhooksService(webhookRepository)
+	webhooksService.Start(10 * time.Second)
+	webhooksHandler := webhooks.NewWebhooksHandler(webhooksService)
+
+	exportService := export.NewExportService(exportRepository)
+	exportHandler := export.NewExportHandler(exportService)
+
+	fhirService := fhir.NewFHIRService(exportRepository)
+	fhirHandler := fhir.NewFHIRHandler(fhirService)
+
+	importerService := importer.NewImporterService(importRepository, moodLogRepository, userSettingsRepository, webhookRepository)
+	importerHandler := importer.NewImporterHandler(importerService)
+
+	settingsService := settings.NewSettingsService(userSettingsRepository, baselinePeriodRepository)
+	settingsHandler := settings.NewSettingsHandler(settingsService)
+
+	sentimentService := sentiment.NewSentimentService(sentimentRepository)
+	sentimentService.Start(1 * time.Minute)
+
+	encryptionService := encryption.NewEncryptionService(noteCipher)
+	encryptionService.Start(1 * time.Minute)
+
+	journalService := journal.NewJournalService(moodLogRepository)
+	journalHandler := journal.NewJournalHandler(journalService)
+
+	privacyService := privacy.NewPrivacyService(userRepository, userSettingsRepository, baselinePeriodRepository, alertRepository, webhookRepository, importRepository, sharingGrantRepository, exportRepository, erasureRepository, auditRepository, noteCipher, analyticsCache)
+	privacyService.Start(1 * time.Hour)
+	privacyHandler := privacy.NewPrivacyHandler(privacyService)
+
+	auditService := audit.NewAuditService(auditRepository, sharingGrantRepository)
+	auditHandler := audit.NewAuditHandler(auditService)
+
+	sharingService := sharing.NewSharingService(sharingGrantRepository, userRepository)
+	sharingHandler := sharing.NewSharingHandler(sharingService)
+
+	authenticator := auth.NewAuthenticator(apiTokenRepository)
+
+	r := router.NewRouter(
+		audit.NewAuditedHandler(analyticsHandler, auditService, sharing.ScopeAnalytics),
+		auth.NewOwnerHandler(alertsHandler),
+		auth.NewOwnerHandler(webhooksHandler),
+		audit.NewAuditedHandler(exportHandler, auditService, sharing.ScopeExport),
+		audit.NewAuditedHandler(fhirHandler, auditService, sharing.ScopeExport),
+		auth.NewOwnerHandler(importerHandler),
+		auth.NewOwnerHandler(settingsHandler),
+		audit.NewAuditedHandler(journalHandler, auditService, sharing.ScopeJournal),
+		auth.NewOwnerHandler(privacyHandler),
+		auth.NewOwnerHandler(auditHandler),
+		auth.NewOwnerHandler(sharingHandler),
+	)
+
+	// set HORIZON_TRUST_FORWARDED_FOR=true when running behind the gateway
+	rateLimiter := ratelimit.NewRateLimiter(clientBudgets, ipBudgets, analyticsConcurrency, os.Getenv("HORIZON_TRUST_FORWARDED_FOR") == "true")
 
-	http.HandleFunc("/", r.RouteRequests)
+	http.HandleFunc("/", rateLimiter.LimitIP(authenticator.Authenticate(rateLimiter.Limit(r.RouteRequests))))
 	http.ListenAndServe(":9095", nil)
 }
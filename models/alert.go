@@ -0,0 +1,14 @@
+package models
+
+type Alert struct {
+	AlertID         int    `json:"alertId"`
+	UserID          string `json:"userId"`
+	Rule            string `json:"rule"`
+	Severity        string `json:"severity"`
+	Message         string `json:"message"`
+	State           string `json:"state"` // "open", "acknowledged", "resolved"
+	TriggeredAt     string `json:"triggeredAt"`
+	LastTriggeredAt string `json:"lastTriggeredAt"`
+	AcknowledgedAt  string `json:"acknowledgedAt"`
+	ResolvedAt      string `json:"resolvedAt"`
+}
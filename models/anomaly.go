@@ -0,0 +1,10 @@
+package models
+
+type Anomaly struct {
+	Date      string  `json:"date"`
+	Value     float64 `json:"value"`
+	Baseline  float64 `json:"baseline"`  // median of the trailing window
+	Deviation float64 `json:"deviation"` // value minus baseline, in rating points or hours
+	RobustZ   float64 `json:"robustZ"`
+	Direction string  `json:"direction"` // "high" or "low"
+}
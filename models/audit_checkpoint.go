@@ -0,0 +1,9 @@
+package models
+
+// AuditCheckpoint is the newest entry of a chain a verification got to intact
+type AuditCheckpoint struct {
+	ChainID         int    `json:"chainId"`
+	AuditEntryID    int    `json:"auditEntryId"`
+	EntryHash       string `json:"entryHash"`
+	EntriesVerified int    `json:"entriesVerified"` // in the chain up to and including the entry
+}
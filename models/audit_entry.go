@@ -0,0 +1,15 @@
+package models
+
+type AuditEntry struct {
+	AuditEntryID  int    `json:"auditEntryId"`
+	ChainID       int    `json:"chainId"`
+	SubjectUserID string `json:"subjectUserId"` // whose data was read
+	ViewerUserID  string `json:"viewerUserId"`
+	GrantID       string `json:"grantId"` // the sharing grant the read was made under, if any
+	Resource      string `json:"resource"`
+	StartDate     string `json:"startDate"` // empty when the request left it to the default
+	EndDate       string `json:"endDate"`
+	AccessedAt    string `json:"accessedAt"`
+	PreviousHash  string `json:"previousHash"`
+	EntryHash     string `json:"entryHash"`
+}
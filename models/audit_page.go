@@ -0,0 +1,10 @@
+package models
+
+type AuditPage struct {
+	UserID     string       `json:"userId"`
+	Page       int          `json:"page"`
+	PageSize   int          `json:"pageSize"`
+	TotalCount int          `json:"totalCount"`
+	TotalPages int          `json:"totalPages"`
+	Entries    []AuditEntry `json:"entries"`
+}
@@ -0,0 +1,10 @@
+package models
+
+type AuditVerification struct {
+	Verified        bool   `json:"verified"`
+	Full            bool   `json:"full"`            // every entry was hashed rather than those after the checkpoints
+	EntriesChecked  int    `json:"entriesChecked"`  // hashed by this verification
+	EntriesVerified int    `json:"entriesVerified"` // intact in total, checkpointed ones included
+	FirstInvalidID  int    `json:"firstInvalidId"`  // 0 when the chains are intact
+	Reason          string `json:"reason"`
+}
@@ -0,0 +1,8 @@
+package models
+
+type BaselinePeriod struct {
+	UserID    string `json:"userId"`
+	Name      string `json:"name"` // e.g. "well"
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate"`
+}
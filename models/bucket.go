@@ -0,0 +1,13 @@
+package models
+
+// Bucket aggregates the logs of one day, ISO week or calendar month, StartDate
+// and EndDate are clamped to the requested range
+type Bucket struct {
+	Period       string  `json:"period"`
+	StartDate    string  `json:"startDate"`
+	EndDate      string  `json:"endDate"`
+	Avg          float64 `json:"avg"`
+	StdDeviation float64 `json:"stdDeviation"`
+	LogCount     int     `json:"logCount"`
+	TopTag       string  `json:"topTag"`
+}
@@ -0,0 +1,13 @@
+package models
+
+type ClinicalDay struct {
+	Date            string  `json:"date"`
+	DailyAvgRating  float64 `json:"dailyAvgRating"`
+	ManicCount      int     `json:"manicCount"`
+	HypomanicCount  int     `json:"hypomanicCount"`
+	DepressedCount  int     `json:"depressedCount"`
+	MixedStateCount int     `json:"mixedStateCount"`
+	IrritableCount  int     `json:"irritableCount"`
+	HoursSlept      float64 `json:"hoursSlept"`
+	SleepLogged     bool    `json:"sleepLogged"`
+}
@@ -0,0 +1,10 @@
+package models
+
+type DailyAggregate struct {
+	Date           string  `json:"date"`
+	DailyAvgRating float64 `json:"dailyAvgRating"`
+	MoodLogCount   int     `json:"moodLogCount"`
+	HoursSlept     float64 `json:"hoursSlept"`
+	SleepLogged    bool    `json:"sleepLogged"`
+	DosesTaken     int     `json:"dosesTaken"`
+}
@@ -0,0 +1,6 @@
+package models
+
+type DailyAverage struct {
+	Date     string  `json:"date"`
+	DailyAvg float64 `json:"dailyAvg"`
+}
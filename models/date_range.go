@@ -0,0 +1,6 @@
+package models
+
+type DateRange struct {
+	StartDate string `json:"startDate"`
+	EndDate   string `json:"endDate"`
+}
@@ -0,0 +1,11 @@
+package models
+
+type Episode struct {
+	EpisodeType    string        `json:"episodeType"` // "manic", "hypomanic", "depressive", "mixed"
+	StartDate      string        `json:"startDate"`
+	EndDate        string        `json:"endDate"`
+	NumDays        int           `json:"numDays"`
+	MinimumDays    int           `json:"minimumDays"`
+	Confidence     float64       `json:"confidence"` // 0.0 - 1.0
+	SupportingDays []ClinicalDay `json:"supportingDays"`
+}
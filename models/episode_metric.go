@@ -0,0 +1,8 @@
+package models
+
+type EpisodeMetric struct {
+	UserID    string    `json:"userId"`
+	StartDate string    `json:"startDate"`
+	EndDate   string    `json:"endDate"`
+	Episodes  []Episode `json:"episodes"`
+}
@@ -0,0 +1,9 @@
+package models
+
+type ErasureReport struct {
+	Steps     []ErasureStep `json:"steps"`
+	Remaining []TableCount  `json:"remaining"` // rows still held for the user after the erasure
+	Retained  []TableCount  `json:"retained"`  // rows kept on purpose, the audit log of reads of their data
+	Verified  bool          `json:"verified"`  // nothing remained
+	Error     string        `json:"error"`
+}
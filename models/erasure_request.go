@@ -0,0 +1,14 @@
+package models
+
+type ErasureRequest struct {
+	ErasureRequestID int           `json:"erasureRequestId"`
+	UserID           string        `json:"userId"`
+	Status           string        `json:"status"` // "scheduled", "cancelled", "running", "completed", "failed", "abandoned"
+	RequestedAt      string        `json:"requestedAt"`
+	ScheduledFor     string        `json:"scheduledFor"` // end of the grace period
+	CancelledAt      string        `json:"cancelledAt"`
+	CompletedAt      string        `json:"completedAt"`
+	Attempts         int           `json:"attempts"`
+	NextAttemptAt    string        `json:"nextAttemptAt"` // when a failed erasure runs again
+	Report           ErasureReport `json:"report"`
+}
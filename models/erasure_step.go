@@ -0,0 +1,7 @@
+package models
+
+type ErasureStep struct {
+	Name        string `json:"name"` // a table, or "data_keys" and "analytics_cache"
+	Removed     int    `json:"removed"`
+	CompletedAt string `json:"completedAt"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// ForecastAccuracy scores a forecast of the last HoldoutDays made from the
+// days before them, only days with a mood log are scored
+type ForecastAccuracy struct {
+	HoldoutDays      int     `json:"holdoutDays"`
+	NumScored        int     `json:"numScored"`
+	MAE              float64 `json:"mae"`
+	RMSE             float64 `json:"rmse"`
+	MAPE             float64 `json:"mape"`
+	IntervalCoverage float64 `json:"intervalCoverage"` // percentage of actuals inside the prediction interval
+	SeasonalNaiveMAE float64 `json:"seasonalNaiveMae"` // same weekday last week, for comparison
+}
@@ -0,0 +1,9 @@
+package models
+
+type ForecastPoint struct {
+	Date     string  `json:"date"`
+	Forecast float64 `json:"forecast"`
+	Lower    float64 `json:"lower"`
+	Upper    float64 `json:"upper"`
+	Dip      bool    `json:"dip"` // forecast more than one standard deviation below the recent average
+}
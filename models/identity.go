@@ -0,0 +1,7 @@
+package models
+
+// Identity is who an api token belongs to
+type Identity struct {
+	UserID string `json:"userId"`
+	Admin  bool   `json:"admin"`
+}
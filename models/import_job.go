@@ -0,0 +1,18 @@
+package models
+
+type ImportJob struct {
+	JobID          int          `json:"jobId"`
+	UserID         string       `json:"userId"`
+	SourceFormat   string       `json:"sourceFormat"`
+	DryRun         bool         `json:"dryRun"`
+	Status         string       `json:"status"` // "pending", "running", "completed", "failed"
+	TotalRows      int          `json:"totalRows"`
+	ProcessedRows  int          `json:"processedRows"`
+	ImportedCount  int          `json:"importedCount"`
+	DuplicateCount int          `json:"duplicateCount"`
+	SkippedCount   int          `json:"skippedCount"`
+	Error          string       `json:"error"`
+	Report         ImportReport `json:"report"`
+	CreatedAt      string       `json:"createdAt"`
+	UpdatedAt      string       `json:"updatedAt"`
+}
@@ -0,0 +1,14 @@
+package models
+
+type ImportReport struct {
+	MappedLabels   map[string]string `json:"mappedLabels"`   // external label -> mood tag
+	UnmappedLabels []string          `json:"unmappedLabels"` // labels without a mood tag, review them via the mappings endpoint
+	RowErrors      []ImportRowError  `json:"rowErrors"`
+	Duplicates     []string          `json:"duplicates"` // timestamps that already had a mood log
+	Preview        []MoodLog         `json:"preview"`
+}
+
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
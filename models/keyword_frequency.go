@@ -0,0 +1,7 @@
+package models
+
+type KeywordFrequency struct {
+	Keyword    string  `json:"keyword"`
+	Count      int     `json:"count"`
+	Percentage float64 `json:"percentage"` // of the notes in the period that mention it
+}
@@ -0,0 +1,12 @@
+package models
+
+type MedicationLog struct {
+	MedicationLogID int    `json:"medicationLogId"`
+	UserID          string `json:"userId"`
+	MedicationID    int    `json:"medicationId"`
+	MedicationName  string `json:"medicationName"`
+	TakenAt         string `json:"takenAt"`
+	Taken           bool   `json:"taken"`
+	Dosage          string `json:"dosage"`
+	Notes           string `json:"notes"`
+}
@@ -0,0 +1,8 @@
+package models
+
+type MoodComparison struct {
+	Compare   string   `json:"compare"` // as requested, e.g. "previous", "lastYear", "baseline:well"
+	StartDate string   `json:"startDate"`
+	EndDate   string   `json:"endDate"`
+	MoodDiffs MoodDiff `json:"moodDiffs"`
+}
@@ -12,12 +12,12 @@ type MoodDiff struct {
 	TopMoodNeutralDaysPercentChange  string  `json:"topMoodNeutralDaysPercentChange"`
 	TopMoodNegativeDaysPercentChange string  `json:"topMoodNegativeDaysPercentChange"` // "ANGER -5%"
 	TopMoodClinicalDaysPercentChange string  `josn:"topMoodClinicalDaysPercentChange"`
-	PositiveDaysChange               int     `json:"positiveDaysChange"` // +3
-	NeutralDaysChange                int     `json:"neutralDaysChange"`
-	NegativeDaysChange               int     `json:"negativeDaysChange"` // -2
-	ClinicalDaysChange               int     `json:"clinicalDaysChange"`
-	LongestPositiveStreakChange      int     `json:"longestPositiveStreakChange"` // +2
-	LongestNeutralStreakChange       int     `json:"longestNeutralStreakChange"`
-	LongestNegativeStreakChange      int     `json:"longestNegativeStreakChange"` // -1
-	LongestClinicalStreakChange      int     `json:"longestClinicalStreakChange"`
+	PositiveDaysChange               float64 `json:"positiveDaysChange"` // +12.5 percentage points of the logged days
+	NeutralDaysChange                float64 `json:"neutralDaysChange"`
+	NegativeDaysChange               float64 `json:"negativeDaysChange"` // -8.0
+	ClinicalDaysChange               float64 `json:"clinicalDaysChange"`
+	LongestPositiveStreakChange      float64 `json:"longestPositiveStreakChange"` // +6.7 percentage points of the days in the period
+	LongestNeutralStreakChange       float64 `json:"longestNeutralStreakChange"`
+	LongestNegativeStreakChange      float64 `json:"longestNegativeStreakChange"` // -3.3
+	LongestClinicalStreakChange      float64 `json:"longestClinicalStreakChange"`
 }
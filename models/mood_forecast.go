@@ -0,0 +1,16 @@
+package models
+
+type MoodForecast struct {
+	UserID           string           `json:"userId"`
+	HistoryStartDate string           `json:"historyStartDate"`
+	HistoryEndDate   string           `json:"historyEndDate"`
+	Days             int              `json:"days"`
+	Model            string           `json:"model"`
+	Alpha            float64          `json:"alpha"`
+	Beta             float64          `json:"beta"`
+	Gamma            float64          `json:"gamma"`
+	SleepRegressor   bool             `json:"sleepRegressor"`
+	SleepCoefficient float64          `json:"sleepCoefficient"` // mood points per hour slept above average
+	Forecast         []ForecastPoint  `json:"forecast"`
+	Backtest         ForecastAccuracy `json:"backtest"`
+}
@@ -0,0 +1,18 @@
+package models
+
+// Truncated says more logs matched the indexed words than a search checks,
+// only the logs created from SearchedFrom on were searched
+type MoodLogSearchPage struct {
+	UserID       string                `json:"userId"`
+	Query        string                `json:"query"`
+	Tags         []string              `json:"tags"`
+	StartDate    string                `json:"startDate"`
+	EndDate      string                `json:"endDate"`
+	Page         int                   `json:"page"`
+	PageSize     int                   `json:"pageSize"`
+	TotalCount   int                   `json:"totalCount"`
+	TotalPages   int                   `json:"totalPages"`
+	Truncated    bool                  `json:"truncated"`
+	SearchedFrom string                `json:"searchedFrom"`
+	Results      []MoodLogSearchResult `json:"results"`
+}
@@ -0,0 +1,7 @@
+package models
+
+type MoodLogSearchResult struct {
+	MoodLog MoodLog `json:"moodLog"`
+	Snippet string  `json:"snippet"` // html escaped, matches wrapped in <mark>
+	Score   float64 `json:"score"`
+}
@@ -0,0 +1,10 @@
+package models
+
+type MoodLogSentiment struct {
+	MoodLogID       int      `json:"moodLogId"`
+	Scored          bool     `json:"scored"`    // false when the log has no note
+	Sentiment       float64  `json:"sentiment"` // -1 to 1
+	AnalyzerVersion int      `json:"analyzerVersion"`
+	AnalyzedAt      string   `json:"analyzedAt"`
+	Keywords        []string `json:"keywords"`
+}
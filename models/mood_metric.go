@@ -1,27 +1,36 @@
 package models
 
 type MoodMetric struct {
-	UserID               string         `json:"userId"`
-	Granularity          string         `json:"granularity"`
-	StartDate            string         `json:"startDate"`
-	EndDate              string         `json:"endDate"`
-	MovingAvg            float64        `json:"movingAvg"`
-	MoodTrend            string         `json:"moodTrend"`
-	StdDeviation         float64        `json:"stdDeviation"`
-	Stability            string         `json:"moodStability"`
-	AvgMoodRating        float64        `json:"avgMoodRating"`
-	TopMoods             []TagFrequency `json:"topMoods"`
-	TopMoodsPositiveDays []TagFrequency `json:"topMoodsPositiveDays"`
-	TopMoodsNeutralDays  []TagFrequency `json:"topMoodsNeutralDays"`
-	TopMoodsNegativeDays []TagFrequency `json:"topMoodsNegativeDays"`
-	TopMoodsClinicalDays []TagFrequency `json:"topMoodsClinicalDays"`
-	PositiveStreaks      []Streak       `json:"positiveStreaks"`
-	NeutralStreaks       []Streak       `json:"neutralStreaks"`
-	NegativeStreaks      []Streak       `json:"negativeStreaks"`
-	ClinicalStreaks      []Streak       `json:"clinicalStreaks"`
-	PositiveDays         []Day          `json:"positiveDays"`
-	NeutralDays          []Day          `json:"neutralDays"`
-	NegativeDays         []Day          `json:"negativeDays"`
-	ClinicalDays         []Day          `json:"clinicalDays"`
-	MoodDiffs            MoodDiff       `json:"moodDiffs"`
+	UserID               string            `json:"userId"`
+	Granularity          string            `json:"granularity"`
+	StartDate            string            `json:"startDate"`
+	EndDate              string            `json:"endDate"`
+	LoggedDays           int               `json:"loggedDays"` // days with at least one log
+	NumPositiveDays      int               `json:"numPositiveDays"`
+	NumNeutralDays       int               `json:"numNeutralDays"`
+	NumNegativeDays      int               `json:"numNegativeDays"`
+	NumClinicalDays      int               `json:"numClinicalDays"`
+	MovingAvg            float64           `json:"movingAvg"`
+	TimeSeries           []TimeSeriesPoint `json:"timeSeries"`
+	Buckets              []Bucket          `json:"buckets"`
+	MoodTrend            Trend             `json:"moodTrend"`
+	StdDeviation         float64           `json:"stdDeviation"`
+	Stability            string            `json:"moodStability"`
+	Anomalies            []Anomaly         `json:"anomalies"`
+	AvgMoodRating        float64           `json:"avgMoodRating"`
+	TopMoods             []TagFrequency    `json:"topMoods"`
+	TopMoodsPositiveDays []TagFrequency    `json:"topMoodsPositiveDays"`
+	TopMoodsNeutralDays  []TagFrequency    `json:"topMoodsNeutralDays"`
+	TopMoodsNegativeDays []TagFrequency    `json:"topMoodsNegativeDays"`
+	TopMoodsClinicalDays []TagFrequency    `json:"topMoodsClinicalDays"`
+	PositiveStreaks      []Streak          `json:"positiveStreaks"`
+	NeutralStreaks       []Streak          `json:"neutralStreaks"`
+	NegativeStreaks      []Streak          `json:"negativeStreaks"`
+	ClinicalStreaks      []Streak          `json:"clinicalStreaks"`
+	PositiveDays         []Day             `json:"positiveDays"`
+	NeutralDays          []Day             `json:"neutralDays"`
+	NegativeDays         []Day             `json:"negativeDays"`
+	ClinicalDays         []Day             `json:"clinicalDays"`
+	MoodDiffs            MoodDiff          `json:"moodDiffs"` // against the previous period
+	Comparisons          []MoodComparison  `json:"comparisons"`
 }
@@ -0,0 +1,11 @@
+package models
+
+// MoodPattern aggregates the logs made on a weekday, in a time of day or both,
+// the field that isn't grouped on is empty
+type MoodPattern struct {
+	Weekday       string         `json:"weekday"`
+	TimeOfDay     string         `json:"timeOfDay"`
+	AvgMoodRating float64        `json:"avgMoodRating"`
+	LogCount      int            `json:"logCount"`
+	TopMoods      []TagFrequency `json:"topMoods"`
+}
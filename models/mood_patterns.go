@@ -0,0 +1,13 @@
+package models
+
+type MoodPatterns struct {
+	UserID                string         `json:"userId"`
+	StartDate             string         `json:"startDate"`
+	EndDate               string         `json:"endDate"`
+	ByWeekday             []MoodPattern  `json:"byWeekday"`
+	ByTimeOfDay           []MoodPattern  `json:"byTimeOfDay"`
+	ByWeekdayAndTimeOfDay []MoodPattern  `json:"byWeekdayAndTimeOfDay"`
+	LowestSlot            *MoodPattern   `json:"lowestSlot"` // weekday and time of day with the lowest average, null without enough logs
+	AvgSwing              float64        `json:"avgSwing"`
+	SwingDays             []MoodSwingDay `json:"swingDays"`
+}
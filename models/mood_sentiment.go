@@ -0,0 +1,18 @@
+package models
+
+// MoodSentiment sets the sentiment of the notes against the self-reported
+// ratings, only logs with a note are counted
+type MoodSentiment struct {
+	UserID                  string             `json:"userId"`
+	StartDate               string             `json:"startDate"`
+	EndDate                 string             `json:"endDate"`
+	NumScored               int                `json:"numScored"`
+	AvgSentiment            float64            `json:"avgSentiment"`
+	RatingCorrelation       float64            `json:"ratingCorrelation"` // pearson r of sentiment and rating
+	SentimentTrend          string             `json:"sentimentTrend"`
+	SentimentTrendStats     Trend              `json:"sentimentTrendStats"`
+	Daily                   []SentimentPoint   `json:"daily"`
+	Divergences             []ScoredMoodLog    `json:"divergences"`
+	TopKeywordsNegativeDays []KeywordFrequency `json:"topKeywordsNegativeDays"`
+	TopKeywordsClinicalDays []KeywordFrequency `json:"topKeywordsClinicalDays"`
+}
@@ -0,0 +1,10 @@
+package models
+
+type MoodSwingDay struct {
+	Date         string  `json:"date"`
+	NumLogs      int     `json:"numLogs"`
+	MinRating    int     `json:"minRating"`
+	MaxRating    int     `json:"maxRating"`
+	Swing        int     `json:"swing"` // max minus min rating
+	StdDeviation float64 `json:"stdDeviation"`
+}
@@ -0,0 +1,7 @@
+package models
+
+type MoodTag struct {
+	MoodTagID      int    `json:"moodTagId"`
+	Name           string `json:"name"`
+	MoodCategoryID int    `json:"moodCategoryId"`
+}
@@ -0,0 +1,7 @@
+package models
+
+type OrganisationMembership struct {
+	OrganisationID int    `json:"organisationId"`
+	Name           string `json:"name"`
+	JoinedAt       string `json:"joinedAt"`
+}
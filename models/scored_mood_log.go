@@ -0,0 +1,11 @@
+package models
+
+type ScoredMoodLog struct {
+	MoodLogID  int     `json:"moodLogId"`
+	Date       string  `json:"date"`
+	CreatedAt  string  `json:"createdAt"`
+	MoodRating int     `json:"moodRating"`
+	Sentiment  float64 `json:"sentiment"`  // -1 to 1
+	Divergence float64 `json:"divergence"` // sentiment minus the rating mapped onto -1 to 1
+	Note       string  `json:"note"`
+}
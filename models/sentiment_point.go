@@ -0,0 +1,8 @@
+package models
+
+type SentimentPoint struct {
+	Date          string  `json:"date"`
+	AvgSentiment  float64 `json:"avgSentiment"`
+	AvgMoodRating float64 `json:"avgMoodRating"`
+	NumLogs       int     `json:"numLogs"`
+}
@@ -0,0 +1,11 @@
+package models
+
+type SharingGrant struct {
+	SharingGrantID int      `json:"sharingGrantId"`
+	UserID         string   `json:"userId"` // whose data is shared
+	GranteeUserID  string   `json:"granteeUserId"`
+	Scopes         []string `json:"scopes"` // "analytics", "journal", "export"
+	CreatedAt      string   `json:"createdAt"`
+	ExpiresAt      string   `json:"expiresAt"` // empty when the grant doesn't expire
+	RevokedAt      string   `json:"revokedAt"`
+}
@@ -0,0 +1,11 @@
+package models
+
+type SleepLog struct {
+	SleepLogID   int     `json:"sleepLogId"`
+	UserID       string  `json:"userId"`
+	HoursSlept   float64 `json:"hoursSlept"`
+	SleepQuality string  `json:"sleepQuality"`
+	Notes        string  `json:"notes"`
+	SleepDate    string  `json:"sleepDate"`
+	CreatedAt    string  `json:"createdAt"`
+}
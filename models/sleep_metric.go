@@ -1,14 +1,21 @@
 package models
 
 type SleepMetric struct {
-	UserID              string         `json:"userId"`
-	Granularity         string         `json:"granularity"`
-	StartDate           string         `json:"startDate"`
-	EndDate             string         `json:"endDate"`
-	MovingAvg           float64        `json:"movingAvg"`
-	SleepTrend          string         `json:"sleepTrend"`
-	StdDeviation        float64        `json:"stdDeviation"`
-	Stability           string         `json:"stability"`
-	AvgSleepHours       float64        `json:"avgSleepHours"`
-	TopSleepQualityTags []TagFrequency `json:"topSleepQualityTags"`
+	UserID              string            `json:"userId"`
+	Granularity         string            `json:"granularity"`
+	StartDate           string            `json:"startDate"`
+	EndDate             string            `json:"endDate"`
+	MovingAvg           float64           `json:"movingAvg"`
+	TimeSeries          []TimeSeriesPoint `json:"timeSeries"`
+	Buckets             []Bucket          `json:"buckets"`
+	SleepTrend          Trend             `json:"sleepTrend"`
+	StdDeviation        float64           `json:"stdDeviation"`
+	Stability           string            `json:"stability"`
+	Anomalies           []Anomaly         `json:"anomalies"`
+	TargetSleepHours    float64           `json:"targetSleepHours"`
+	SleepDebt           float64           `json:"sleepDebt"`
+	SocialJetLag        float64           `json:"socialJetLag"` // hours the midpoint of sleep is later on weekend nights
+	VariabilityIndex    float64           `json:"variabilityIndex"`
+	AvgSleepHours       float64           `json:"avgSleepHours"`
+	TopSleepQualityTags []TagFrequency    `json:"topSleepQualityTags"`
 }
@@ -0,0 +1,6 @@
+package models
+
+type TableCount struct {
+	Table string `json:"table"`
+	Rows  int    `json:"rows"`
+}
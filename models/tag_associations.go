@@ -0,0 +1,13 @@
+package models
+
+type TagAssociations struct {
+	UserID             string                 `json:"userId"`
+	StartDate          string                 `json:"startDate"`
+	EndDate            string                 `json:"endDate"`
+	NumLogs            int                    `json:"numLogs"`
+	AvgMoodRating      float64                `json:"avgMoodRating"`
+	Tags               []string               `json:"tags"`
+	Matrix             [][]int                `json:"matrix"` // logs with both tags, the diagonal is the tag's own count
+	Pairs              []TagPair              `json:"pairs"`
+	RatingAssociations []TagRatingAssociation `json:"ratingAssociations"`
+}
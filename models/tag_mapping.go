@@ -0,0 +1,8 @@
+package models
+
+type TagMapping struct {
+	SourceFormat  string `json:"sourceFormat"`
+	ExternalLabel string `json:"externalLabel"`
+	MoodTagID     int    `json:"moodTagId"` // 0 ignores the label
+	MoodTagName   string `json:"moodTagName"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// TagPair counts the logs tagged with both tags, lift is how many times more
+// often they appear together than if they were independent and PMI its log2
+type TagPair struct {
+	TagA          string  `json:"tagA"`
+	TagB          string  `json:"tagB"`
+	Count         int     `json:"count"`
+	Support       float64 `json:"support"` // percentage of all logs in the range
+	Lift          float64 `json:"lift"`
+	PMI           float64 `json:"pmi"`
+	AvgMoodRating float64 `json:"avgMoodRating"`
+}
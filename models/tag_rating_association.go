@@ -0,0 +1,18 @@
+package models
+
+// TagRatingAssociation compares the rating of logs with the tag against the
+// logs without it
+type TagRatingAssociation struct {
+	TagName              string  `json:"tagName"`
+	Category             string  `json:"category"`
+	LogCount             int     `json:"logCount"`
+	AvgMoodRating        float64 `json:"avgMoodRating"`
+	StdDeviation         float64 `json:"stdDeviation"`
+	AvgMoodRatingWithout float64 `json:"avgMoodRatingWithout"`
+	Difference           float64 `json:"difference"`       // against the user's average over the range
+	TStatistic           float64 `json:"tStatistic"`       // Welch's t, tagged against untagged logs
+	DegreesOfFreedom     float64 `json:"degreesOfFreedom"` // Welch–Satterthwaite, 0 when not tested
+	PValue               float64 `json:"pValue"`           // two-sided
+	AdjustedPValue       float64 `json:"adjustedPValue"`   // Benjamini–Hochberg across the tags tested
+	Association          string  `json:"association"`      // "higher", "lower" or "none"
+}
@@ -0,0 +1,11 @@
+package models
+
+type TimeSeries struct {
+	UserID    string            `json:"userId"`
+	StartDate string            `json:"startDate"`
+	EndDate   string            `json:"endDate"`
+	Window    int               `json:"window"`
+	FillGaps  bool              `json:"fillGaps"`
+	Mood      []TimeSeriesPoint `json:"mood"`
+	Sleep     []TimeSeriesPoint `json:"sleep"`
+}
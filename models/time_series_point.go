@@ -0,0 +1,8 @@
+package models
+
+type TimeSeriesPoint struct {
+	Date      string  `json:"date"`
+	DailyAvg  float64 `json:"dailyAvg"`
+	MovingAvg float64 `json:"movingAvg"`
+	Logged    bool    `json:"logged"` // false for days added when gaps are filled
+}
@@ -0,0 +1,8 @@
+package models
+
+type Trend struct {
+	Direction    string  `json:"direction"`    // "increasing", "decreasing", "stable" or "not enough data"
+	SlopePerWeek float64 `json:"slopePerWeek"` // Theil-Sen estimate, rating or hours per week
+	PValue       float64 `json:"pValue"`       // two sided Mann-Kendall test
+	NumDays      int     `json:"numDays"`
+}
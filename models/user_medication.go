@@ -0,0 +1,13 @@
+package models
+
+type UserMedication struct {
+	UserMedicationID int    `json:"userMedicationId"`
+	UserID           string `json:"userId"`
+	MedicationID     int    `json:"medicationId"`
+	MedicationName   string `json:"medicationName"`
+	Dosage           string `json:"dosage"`
+	StartDate        string `json:"startDate"`
+	EndDate          string `json:"endDate"`
+	Stopped          bool   `json:"stopped"`
+	Notes            string `json:"notes"`
+}
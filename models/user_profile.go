@@ -0,0 +1,8 @@
+package models
+
+type UserProfile struct {
+	UserID    string `json:"userId"`
+	Email     string `json:"email"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+}
@@ -0,0 +1,7 @@
+package models
+
+type UserSettings struct {
+	UserID           string  `json:"userId"`
+	TargetSleepHours float64 `json:"targetSleepHours"`
+	Timezone         string  `json:"timezone"` // IANA name, e.g. America/New_York
+}
@@ -0,0 +1,10 @@
+package models
+
+type WebhookDeadLetter struct {
+	DeadLetterID   int          `json:"deadLetterId"`
+	DeliveryID     int          `json:"deliveryId"`
+	SubscriptionID int          `json:"subscriptionId"`
+	LastError      string       `json:"lastError"`
+	CreatedAt      string       `json:"createdAt"`
+	Event          WebhookEvent `json:"event"`
+}
@@ -0,0 +1,14 @@
+package models
+
+type WebhookDelivery struct {
+	DeliveryID     int                      `json:"deliveryId"`
+	SubscriptionID int                      `json:"subscriptionId"`
+	Status         string                   `json:"status"` // "pending", "succeeded", "dead"
+	AttemptCount   int                      `json:"attemptCount"`
+	NextAttemptAt  string                   `json:"nextAttemptAt"`
+	CreatedAt      string                   `json:"createdAt"`
+	Event          WebhookEvent             `json:"event"`
+	Attempts       []WebhookDeliveryAttempt `json:"attempts"`
+	URL            string                   `json:"-"`
+	Secret         string                   `json:"-"`
+}
@@ -0,0 +1,9 @@
+package models
+
+type WebhookDeliveryAttempt struct {
+	AttemptNumber int    `json:"attemptNumber"`
+	StatusCode    int    `json:"statusCode"`
+	Error         string `json:"error"`
+	DurationMs    int    `json:"durationMs"`
+	AttemptedAt   string `json:"attemptedAt"`
+}
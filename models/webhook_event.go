@@ -0,0 +1,31 @@
+package models
+
+import "encoding/json"
+
+const (
+	EventMoodLogCreated  = "mood_log.created"
+	EventAlertRaised     = "alert.raised"
+	EventEpisodeDetected = "episode.detected"
+	EventImportCompleted = "import.completed"
+)
+
+type WebhookEvent struct {
+	EventID   int             `json:"eventId"`
+	UserID    string          `json:"userId"`
+	EventType string          `json:"eventType"`
+	CreatedAt string          `json:"createdAt"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// ImportCompleted is the import.completed payload, the mood logs an import adds
+// don't get a mood_log.created each. From and To bound their createdAt, in UTC
+type ImportCompleted struct {
+	JobID          int    `json:"jobId"`
+	UserID         string `json:"userId"`
+	SourceFormat   string `json:"sourceFormat"`
+	ImportedCount  int    `json:"importedCount"`
+	DuplicateCount int    `json:"duplicateCount"`
+	SkippedCount   int    `json:"skippedCount"`
+	From           string `json:"from"`
+	To             string `json:"to"`
+}
@@ -0,0 +1,12 @@
+package models
+
+type WebhookSubscription struct {
+	SubscriptionID int      `json:"subscriptionId"`
+	UserID         string   `json:"userId,omitempty"`
+	OrganisationID string   `json:"organisationId,omitempty"`
+	URL            string   `json:"url"`
+	Secret         string   `json:"secret,omitempty"` // only returned when the subscription is created
+	EventTypes     []string `json:"eventTypes"`
+	Active         bool     `json:"active"`
+	CreatedAt      string   `json:"createdAt"`
+}
@@ -0,0 +1,150 @@
+// Package pdf writes simple vector PDF documents using the standard Helvetica
+// fonts so nothing has to be embedded, enough for one page summaries with text,
+// lines and boxes
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	A4Width  = 595.0
+	A4Height = 842.0
+)
+
+type Document struct {
+	pages []*Page
+}
+
+// Page coordinates are in points with the origin in the bottom left corner
+type Page struct {
+	content bytes.Buffer
+}
+
+func New() *Document {
+	return &Document{}
+}
+
+func (document *Document) AddPage() *Page {
+	page := &Page{}
+	document.pages = append(document.pages, page)
+	return page
+}
+
+func (page *Page) Text(x float64, y float64, size float64, bold bool, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(&page.content, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, escape(text))
+}
+
+func (page *Page) SetStrokeColor(r float64, g float64, b float64) {
+	fmt.Fprintf(&page.content, "%.3f %.3f %.3f RG\n", r, g, b)
+}
+
+func (page *Page) SetFillColor(r float64, g float64, b float64) {
+	fmt.Fprintf(&page.content, "%.3f %.3f %.3f rg\n", r, g, b)
+}
+
+func (page *Page) SetLineWidth(width float64) {
+	fmt.Fprintf(&page.content, "%.2f w\n", width)
+}
+
+func (page *Page) Line(x1 float64, y1 float64, x2 float64, y2 float64) {
+	fmt.Fprintf(&page.content, "%.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+}
+
+// Polyline strokes a path through the points, xs and ys must be the same length
+func (page *Page) Polyline(xs []float64, ys []float64) {
+	if len(xs) < 2 {
+		return
+	}
+	fmt.Fprintf(&page.content, "%.2f %.2f m ", xs[0], ys[0])
+	for i := 1; i < len(xs); i++ {
+		fmt.Fprintf(&page.content, "%.2f %.2f l ", xs[i], ys[i])
+	}
+	page.content.WriteString("S\n")
+}
+
+func (page *Page) Rect(x float64, y float64, width float64, height float64, fill bool) {
+	operator := "S"
+	if fill {
+		operator = "f"
+	}
+	fmt.Fprintf(&page.content, "%.2f %.2f %.2f %.2f re %s\n", x, y, width, height, operator)
+}
+
+// WriteTo writes the document, object 1 is the catalog, 2 the page tree, 3 and
+// 4 the fonts followed by a page and content stream object per page
+func (document *Document) WriteTo(writer io.Writer) (int64, error) {
+
+	var out bytes.Buffer
+	var offsets []int
+
+	startObject := func() {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n", len(offsets))
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	pageObjectIDs := make([]string, len(document.pages))
+	for i := range document.pages {
+		pageObjectIDs[i] = fmt.Sprintf("%d 0 R", 5+i*2)
+	}
+
+	startObject()
+	out.WriteString("<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+
+	startObject()
+	fmt.Fprintf(&out, "<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", strings.Join(pageObjectIDs, " "), len(document.pages))
+
+	startObject()
+	out.WriteString("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>\nendobj\n")
+
+	startObject()
+	out.WriteString("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>\nendobj\n")
+
+	for i, page := range document.pages {
+		startObject()
+		fmt.Fprintf(&out, "<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>\nendobj\n", A4Width, A4Height, 6+i*2)
+
+		startObject()
+		fmt.Fprintf(&out, "<< /Length %d >>\nstream\n", page.content.Len())
+		out.Write(page.content.Bytes())
+		out.WriteString("endstream\nendobj\n")
+	}
+
+	xrefOffset := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xrefOffset)
+
+	return out.WriteTo(writer)
+}
+
+// escape makes text safe inside a PDF string literal, characters outside
+// printable ascii are replaced because only WinAnsi is available
+func escape(text string) string {
+	var escaped strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			escaped.WriteRune('\\')
+			escaped.WriteRune(r)
+		case r == '→':
+			escaped.WriteString("->")
+		case r < 32 || r > 126:
+			escaped.WriteRune('?')
+		default:
+			escaped.WriteRune(r)
+		}
+	}
+	return escaped.String()
+}
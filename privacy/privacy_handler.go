@@ -0,0 +1,139 @@
+package privacy
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/auth"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+type PrivacyHandler struct {
+	privacyService *privacyService
+}
+
+var usersPrivacyExport string = `^/users/([0-9]+)/privacy/export$`
+var usersPrivacyDelete string = `^/users/([0-9]+)/privacy/delete$`
+var privacyErasures string = `^/privacy/erasures$`
+var privacyErasureRetry string = `^/privacy/erasures/([0-9]+)/retry$`
+
+func NewPrivacyHandler(privacyService *privacyService) *PrivacyHandler {
+	return &PrivacyHandler{
+		privacyService: privacyService,
+	}
+}
+
+func (handler *PrivacyHandler) ProcessRequest(writer http.ResponseWriter, request *http.Request) {
+	switch {
+	case utils.MatchURL(usersPrivacyExport, request.URL.Path) && request.Method == http.MethodGet:
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+
+		profile, found := handler.privacyService.profile(userID)
+		if !found {
+			writer.WriteHeader(http.StatusNotFound)
+			writer.Write([]byte("user not found"))
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/zip")
+		writer.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s-data.zip"`, userID))
+
+		// the response is already partly written, aborting the connection means
+		// the client sees a failed download rather than a complete looking zip
+		if exportErr := handler.privacyService.exportArchive(writer, profile); exportErr != nil {
+			fmt.Println("ERROR exporting personal data of user", userID, exportErr)
+			panic(http.ErrAbortHandler)
+		}
+
+	case utils.MatchURL(usersPrivacyDelete, request.URL.Path) && request.Method == http.MethodPost:
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+
+		if _, found := handler.privacyService.profile(userID); !found {
+			writer.WriteHeader(http.StatusNotFound)
+			writer.Write([]byte("user not found"))
+			return
+		}
+
+		erasureRequest, created := handler.privacyService.requestErasure(userID)
+		body, _ := json.Marshal(erasureRequest)
+
+		writer.Header().Set("Content-Type", "application/json")
+		if created {
+			writer.WriteHeader(http.StatusAccepted)
+		}
+		writer.Write(body)
+
+	case utils.MatchURL(usersPrivacyDelete, request.URL.Path) && request.Method == http.MethodGet:
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+
+		erasureRequest, found := handler.privacyService.erasureRequest(userID)
+		if !found {
+			writer.WriteHeader(http.StatusNotFound)
+			writer.Write([]byte("no erasure requested"))
+			return
+		}
+		body, _ := json.Marshal(erasureRequest)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+
+	case utils.MatchURL(usersPrivacyDelete, request.URL.Path) && request.Method == http.MethodDelete:
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+
+		if cancelled := handler.privacyService.cancelErasure(userID); !cancelled {
+			writer.WriteHeader(http.StatusConflict)
+			writer.Write([]byte("no erasure is waiting out its grace period"))
+			return
+		}
+
+		writer.WriteHeader(http.StatusNoContent)
+
+	case utils.MatchURL(privacyErasures, request.URL.Path) && request.Method == http.MethodGet:
+
+		if !isAdmin(writer, request) {
+			return
+		}
+
+		erasureRequests := handler.privacyService.failingErasures()
+		body, _ := json.Marshal(erasureRequests)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+
+	case utils.MatchURL(privacyErasureRetry, request.URL.Path) && request.Method == http.MethodPost:
+
+		if !isAdmin(writer, request) {
+			return
+		}
+
+		erasureRequestID := utils.PathParams(privacyErasureRetry, request.URL.Path)[0]
+
+		if retried := handler.privacyService.retryErasure(erasureRequestID); !retried {
+			writer.WriteHeader(http.StatusConflict)
+			writer.Write([]byte("only an abandoned erasure can be retried"))
+			return
+		}
+
+		writer.WriteHeader(http.StatusAccepted)
+
+	default:
+		writer.WriteHeader(http.StatusNotFound)
+		writer.Write([]byte("404 path not found"))
+	}
+}
+
+// isAdmin answers 403 unless the request was made with an admin token, the
+// failing erasures are for operators
+func isAdmin(writer http.ResponseWriter, request *http.Request) bool {
+	if identity, _ := auth.IdentityFrom(request); !identity.Admin {
+		writer.WriteHeader(http.StatusForbidden)
+		writer.Write([]byte("this route needs an admin token"))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,314 @@
+package privacy
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/analytics"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/cache"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+const (
+	// days between asking for deletion and the erasure, the user can cancel
+	// until then
+	gracePeriodDays = 30
+	// erasures run per tick
+	erasureBatchSize = 10
+	// a failed erasure is run again after 1, 2, 4, 8 and 16 hours, then left
+	// for an operator
+	maxErasureAttempts = 6
+)
+
+// the privacy export covers the whole history
+var earliestDate string = "1000-01-01"
+var latestDate string = "9999-12-31"
+
+type privacyService struct {
+	userRepository           *database.UserRepository
+	userSettingsRepository   *database.UserSettingsRepository
+	baselinePeriodRepository *database.BaselinePeriodRepository
+	alertRepository          *database.AlertRepository
+	webhookRepository        *database.WebhookRepository
+	importRepository         *database.ImportRepository
+	sharingGrantRepository   *database.SharingGrantRepository
+	exportRepository         *database.ExportRepository
+	erasureRepository        *database.ErasureRepository
+	auditRepository          *database.AuditRepository
+	noteCipher               *database.NoteCipher
+	analyticsCache           cache.Cache
+}
+
+func NewPrivacyService(userRepository *database.UserRepository, userSettingsRepository *database.UserSettingsRepository, baselinePeriodRepository *database.BaselinePeriodRepository, alertRepository *database.AlertRepository, webhookRepository *database.WebhookRepository, importRepository *database.ImportRepository, sharingGrantRepository *database.SharingGrantRepository, exportRepository *database.ExportRepository, erasureRepository *database.ErasureRepository, auditRepository *database.AuditRepository, noteCipher *database.NoteCipher, analyticsCache cache.Cache) *privacyService {
+	return &privacyService{
+		userRepository:           userRepository,
+		userSettingsRepository:   userSettingsRepository,
+		baselinePeriodRepository: baselinePeriodRepository,
+		alertRepository:          alertRepository,
+		webhookRepository:        webhookRepository,
+		importRepository:         importRepository,
+		sharingGrantRepository:   sharingGrantRepository,
+		exportRepository:         exportRepository,
+		erasureRepository:        erasureRepository,
+		auditRepository:          auditRepository,
+		noteCipher:               noteCipher,
+		analyticsCache:           analyticsCache,
+	}
+}
+
+func (service *privacyService) profile(userID string) (models.UserProfile, bool) {
+	return service.userRepository.Profile(userID)
+}
+
+// exportArchive streams a zip with a json file for everything held about the
+// user, notes decrypted. Shares are the organisations the user belongs to,
+// the webhooks their data is sent to and the sharing grants to and from them
+func (service *privacyService) exportArchive(writer io.Writer, profile models.UserProfile) error {
+
+	userID := profile.UserID
+	archive := zip.NewWriter(writer)
+
+	files := []struct {
+		name  string
+		value any
+	}{
+		{"profile.json", profile},
+		{"settings.json", service.userSettingsRepository.Settings(userID)},
+		{"baseline_periods.json", service.baselinePeriodRepository.BaselinePeriods(userID)},
+		{"alerts.json", service.alertRepository.Alerts(userID, "")},
+		{"shares.json", map[string]any{
+			"organisations":         service.userRepository.OrganisationMemberships(userID),
+			"webhookSubscriptions":  service.webhookRepository.Subscriptions(userID, ""),
+			"sharingGrants":         service.sharingGrantRepository.Grants(userID),
+			"receivedSharingGrants": service.sharingGrantRepository.ReceivedGrants(userID),
+		}},
+		{"import_jobs.json", service.importRepository.Jobs(userID)},
+	}
+	for _, file := range files {
+		if writeErr := writeJSONFile(archive, file.name, file.value); writeErr != nil {
+			return writeErr
+		}
+	}
+
+	arrayFiles := []struct {
+		name string
+		each func(write func(any) error) error
+	}{
+		{"mood_logs.json", func(write func(any) error) error {
+			return service.exportRepository.EachMoodLog(userID, earliestDate, latestDate, func(moodLog models.MoodLog) error {
+				return write(moodLog)
+			})
+		}},
+		{"mood_log_sentiment.json", func(write func(any) error) error {
+			return service.exportRepository.EachMoodLogSentiment(userID, func(moodLogSentiment models.MoodLogSentiment) error {
+				return write(moodLogSentiment)
+			})
+		}},
+		{"sleep_logs.json", func(write func(any) error) error {
+			return service.exportRepository.EachSleepLog(userID, earliestDate, latestDate, func(sleepLog models.SleepLog) error {
+				return write(sleepLog)
+			})
+		}},
+		{"medications.json", func(write func(any) error) error {
+			return service.exportRepository.EachUserMedication(userID, earliestDate, latestDate, func(userMedication models.UserMedication) error {
+				return write(userMedication)
+			})
+		}},
+		{"medication_logs.json", func(write func(any) error) error {
+			return service.exportRepository.EachMedicationLog(userID, earliestDate, latestDate, func(medicationLog models.MedicationLog) error {
+				return write(medicationLog)
+			})
+		}},
+		{"daily_aggregates.json", func(write func(any) error) error {
+			return service.exportRepository.EachDailyAggregate(userID, earliestDate, latestDate, func(dailyAggregate models.DailyAggregate) error {
+				return write(dailyAggregate)
+			})
+		}},
+		{"webhook_events.json", func(write func(any) error) error {
+			return service.exportRepository.EachWebhookEvent(userID, func(event models.WebhookEvent) error {
+				return write(event)
+			})
+		}},
+		{"audit_log.json", func(write func(any) error) error {
+			return service.auditRepository.EachUserAuditEntry(userID, func(entry models.AuditEntry) error {
+				return write(entry)
+			})
+		}},
+	}
+	for _, file := range arrayFiles {
+		if writeErr := writeJSONArrayFile(archive, file.name, file.each); writeErr != nil {
+			return writeErr
+		}
+	}
+
+	return archive.Close()
+}
+
+func writeJSONFile(archive *zip.Writer, name string, value any) error {
+	file, createErr := archive.Create(name)
+	if createErr != nil {
+		return createErr
+	}
+	body, _ := json.MarshalIndent(value, "", "  ")
+	_, writeErr := file.Write(body)
+	return writeErr
+}
+
+// writeJSONArrayFile streams the elements into a json array so long histories
+// aren't held in memory
+func writeJSONArrayFile(archive *zip.Writer, name string, each func(write func(any) error) error) error {
+
+	file, createErr := archive.Create(name)
+	if createErr != nil {
+		return createErr
+	}
+	if _, writeErr := io.WriteString(file, "["); writeErr != nil {
+		return writeErr
+	}
+
+	first := true
+	eachErr := each(func(value any) error {
+		element, _ := json.Marshal(value)
+		if !first {
+			if _, writeErr := io.WriteString(file, ","); writeErr != nil {
+				return writeErr
+			}
+		}
+		first = false
+		_, writeErr := file.Write(element)
+		return writeErr
+	})
+	if eachErr != nil {
+		return eachErr
+	}
+
+	_, writeErr := io.WriteString(file, "]")
+	return writeErr
+}
+
+// requestErasure schedules the user's erasure for the end of the grace period,
+// created is false when one was already scheduled
+func (service *privacyService) requestErasure(userID string) (models.ErasureRequest, bool) {
+	return service.erasureRepository.ScheduleErasure(userID, gracePeriodDays)
+}
+
+func (service *privacyService) erasureRequest(userID string) (models.ErasureRequest, bool) {
+	return service.erasureRepository.LatestErasure(userID)
+}
+
+func (service *privacyService) cancelErasure(userID string) bool {
+	return service.erasureRepository.CancelErasure(userID)
+}
+
+func (service *privacyService) failingErasures() []models.ErasureRequest {
+	return service.erasureRepository.FailingErasures()
+}
+
+func (service *privacyService) retryErasure(erasureRequestID string) bool {
+	return service.erasureRepository.RetryErasure(erasureRequestID)
+}
+
+// Start runs the erasures whose grace period is over on each tick
+func (service *privacyService) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			service.eraseDueSafely()
+		}
+	}()
+}
+
+func (service *privacyService) eraseDueSafely() {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("ERROR finding due erasures", r)
+		}
+	}()
+
+	for _, erasureRequest := range service.erasureRepository.DueErasures(erasureBatchSize) {
+		if claimed := service.erasureRepository.ClaimErasure(erasureRequest.ErasureRequestID); claimed {
+			service.erase(erasureRequest)
+		}
+	}
+}
+
+// erase crypto-shreds the user's data keys first, so their notes are
+// unreadable even if the rest fails, then deletes their rows and drops their
+// cached analytics. The erasure only completes once nothing is left for the
+// user in any table, otherwise it's marked failed and run again after a
+// backoff, up to maxErasureAttempts. The audit
+// log is kept, it's the record of who read the user's data and holds no
+// health data, the report says how many of its entries are about the user
+func (service *privacyService) erase(erasureRequest models.ErasureRequest) {
+
+	userID := erasureRequest.UserID
+	var report models.ErasureReport
+
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("ERROR erasing user", userID, r)
+			report.Error = fmt.Sprint(r)
+			service.fail(erasureRequest, report)
+		}
+	}()
+
+	shredded := service.noteCipher.ShredDataKeys(userID)
+	report.Steps = append(report.Steps, erasureStep("data_keys", shredded))
+
+	report.Steps = append(report.Steps, service.erasureRepository.EraseUserData(userID)...)
+
+	// another instance's in-process cache isn't reachable from here, its
+	// entries for the user are never served again as the user's data version
+	// is gone and they age out
+	purged := service.analyticsCache.DeletePrefix(analytics.CacheKeyPrefix(userID))
+	report.Steps = append(report.Steps, erasureStep("analytics_cache", purged))
+
+	checkRemaining(&report, service.erasureRepository.RemainingUserData(userID))
+	report.Retained = []models.TableCount{{Table: "audit_log", Rows: service.auditRepository.CountUserAuditEntries(userID)}}
+
+	if !report.Verified {
+		report.Error = "rows remained after the erasure"
+		service.fail(erasureRequest, report)
+		return
+	}
+	service.erasureRepository.CompleteErasure(erasureRequest.ErasureRequestID, report)
+}
+
+// checkRemaining lists the tables still holding rows for the user, the
+// erasure is only verified when there are none
+func checkRemaining(report *models.ErasureReport, tableCounts []models.TableCount) {
+	report.Verified = true
+	report.Remaining = make([]models.TableCount, 0)
+	for _, tableCount := range tableCounts {
+		if tableCount.Rows > 0 {
+			report.Verified = false
+			report.Remaining = append(report.Remaining, tableCount)
+		}
+	}
+}
+
+// fail records the failed attempt, the failures are listed on
+// /privacy/erasures for operators and an abandoned erasure needs one of them
+func (service *privacyService) fail(erasureRequest models.ErasureRequest, report models.ErasureReport) {
+
+	failed := service.erasureRepository.FailErasure(erasureRequest.ErasureRequestID, report, maxErasureAttempts)
+
+	if failed.Status == "abandoned" {
+		fmt.Println("ERROR erasure", failed.ErasureRequestID, "of user", failed.UserID, "abandoned after", failed.Attempts, "attempts:", report.Error)
+		return
+	}
+	fmt.Println("ERROR erasure", failed.ErasureRequestID, "of user", failed.UserID, "failed attempt", failed.Attempts, "of", maxErasureAttempts, "runs again at", failed.NextAttemptAt+":", report.Error)
+}
+
+func erasureStep(name string, removed int) models.ErasureStep {
+	return models.ErasureStep{
+		Name:        name,
+		Removed:     removed,
+		CompletedAt: time.Now().UTC().Format("2006-01-02 15:04:05"),
+	}
+}
@@ -0,0 +1,200 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/analytics"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/cache"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database/dbtest"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/envelope"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+func TestCheckRemaining(t *testing.T) {
+	tests := []struct {
+		name          string
+		tableCounts   []models.TableCount
+		wantVerified  bool
+		wantRemaining []models.TableCount
+	}{
+		{
+			name:          "nothing left",
+			tableCounts:   []models.TableCount{{Table: "user", Rows: 0}, {Table: "mood_log", Rows: 0}},
+			wantVerified:  true,
+			wantRemaining: []models.TableCount{},
+		},
+		{
+			name:          "rows left behind",
+			tableCounts:   []models.TableCount{{Table: "user", Rows: 0}, {Table: "mood_log", Rows: 2}, {Table: "sharing_grant", Rows: 1}},
+			wantVerified:  false,
+			wantRemaining: []models.TableCount{{Table: "mood_log", Rows: 2}, {Table: "sharing_grant", Rows: 1}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			report := models.ErasureReport{Verified: !test.wantVerified}
+			checkRemaining(&report, test.tableCounts)
+
+			if report.Verified != test.wantVerified || !slices.Equal(report.Remaining, test.wantRemaining) {
+				t.Errorf("report = verified %v remaining %+v, want %v %+v", report.Verified, report.Remaining, test.wantVerified, test.wantRemaining)
+			}
+			// the report is stored as json, an empty list rather than null
+			if body, _ := json.Marshal(report); !strings.Contains(string(body), `"remaining":[`) {
+				t.Errorf("report json = %s, want remaining as a list", body)
+			}
+		})
+	}
+}
+
+// testPrivacyService is the service over the test database, user 1 has a
+// little of everything, a data key and a cached analytics response, and user
+// 2 is there to check nobody else's data goes with them
+func testPrivacyService(t *testing.T) (*privacyService, *sql.DB, cache.Cache) {
+	db := dbtest.Open(t)
+
+	t.Setenv("HORIZON_MASTER_KEYS", "test:"+base64.StdEncoding.EncodeToString(make([]byte, envelope.KeySize)))
+	masterKeys, keysErr := envelope.LoadMasterKeys()
+	if keysErr != nil {
+		t.Fatal(keysErr)
+	}
+	noteCipher := database.NewNoteCipher(db, masterKeys)
+
+	dbtest.Exec(t, db,
+		`INSERT INTO user (user_id, email, password_hash) VALUES (1, 'one@example.com', ''), (2, 'two@example.com', '')`,
+		`INSERT INTO user_settings (user_id, timezone) VALUES (1, 'UTC')`,
+		`INSERT INTO mood_log (user_id, mood_rating, note, created_at) VALUES (1, 4, 'rough day', '2025-03-01 09:00:00'), (2, 6, 'fine', '2025-03-01 09:00:00')`,
+		`INSERT INTO mood_log_mood_tag (mood_log_id, mood_tag_id) SELECT mood_log_id, (SELECT Min(mood_tag_id) FROM mood_tag) FROM mood_log`,
+		`INSERT INTO sleep_log (user_id, hours_slept, sleep_quality_tag_id, sleep_date) SELECT 1, 6.5, Min(sleep_quality_tag_id), '2025-03-01' FROM sleep_quality_tag`,
+		`INSERT INTO sharing_grant (user_id, grantee_user_id, scopes) VALUES (1, 2, 'analytics'), (2, 1, 'export')`,
+		`INSERT INTO audit_log (chain_id, subject_user_id, viewer_user_id, resource, accessed_at, previous_hash, entry_hash)
+		 VALUES (1, 1, 2, '/analytics/users/1/mood', '2025-03-02 10:00:00', REPEAT('0', 64), REPEAT('1', 64))`,
+	)
+	noteCipher.RotateDataKey("1")
+
+	analyticsCache := cache.NewLRU(10)
+	analyticsCache.Set(analytics.CacheKeyPrefix("1")+"mood", cache.Entry{Body: []byte("{}")})
+	analyticsCache.Set(analytics.CacheKeyPrefix("2")+"mood", cache.Entry{Body: []byte("{}")})
+
+	service := NewPrivacyService(
+		database.NewUserRepository(db),
+		database.NewUserSettingsRepository(db),
+		database.NewBaselinePeriodRepository(db),
+		database.NewAlertRepository(db),
+		database.NewWebhookRepository(db),
+		database.NewImportRepository(db),
+		database.NewSharingGrantRepository(db),
+		database.NewExportRepository(db, noteCipher),
+		database.NewErasureRepository(db),
+		database.NewAuditRepository(db),
+		noteCipher,
+		analyticsCache,
+	)
+	return service, db, analyticsCache
+}
+
+func TestErase(t *testing.T) {
+	service, db, analyticsCache := testPrivacyService(t)
+
+	service.erasureRepository.ScheduleErasure("1", 0)
+	service.eraseDueSafely()
+
+	erasure, found := service.erasureRequest("1")
+	if !found || erasure.Status != "completed" {
+		t.Fatalf("erasure = %+v, want it completed", erasure)
+	}
+
+	report := erasure.Report
+	if !report.Verified || len(report.Remaining) != 0 || report.Error != "" {
+		t.Errorf("report = %+v, want it verified with nothing remaining", report)
+	}
+
+	removed := make(map[string]int)
+	var stepOrder []string
+	for _, step := range report.Steps {
+		removed[step.Name] = step.Removed
+		stepOrder = append(stepOrder, step.Name)
+	}
+	if stepOrder[0] != "data_keys" {
+		t.Errorf("steps start with %s, want the data keys shredded first", stepOrder[0])
+	}
+	want := map[string]int{"data_keys": 1, "mood_log": 1, "mood_log_mood_tag": 1, "sleep_log": 1, "sharing_grant": 2, "user": 1, "analytics_cache": 1}
+	for name, count := range want {
+		if removed[name] != count {
+			t.Errorf("%s removed %d rows, want %d", name, removed[name], count)
+		}
+	}
+	if !slices.Equal(report.Retained, []models.TableCount{{Table: "audit_log", Rows: 1}}) {
+		t.Errorf("retained = %+v, want the one audit entry", report.Retained)
+	}
+
+	// user 2 keeps their data, the audit log keeps the entry
+	rows := dbtest.QueryRows(t, db, `SELECT (SELECT Count(*) FROM mood_log WHERE user_id = 2), (SELECT Count(*) FROM user WHERE user_id = 2), (SELECT Count(*) FROM audit_log)`)
+	if len(rows) != 1 || rows[0] != "1|1|1" {
+		t.Errorf("after the erasure = %q, want user 2's mood log, user 2 and the audit entry", rows)
+	}
+	if _, cached := analyticsCache.Get(analytics.CacheKeyPrefix("2") + "mood"); !cached {
+		t.Error("user 2's cached analytics were purged")
+	}
+}
+
+func TestExportArchive(t *testing.T) {
+	service, _, _ := testPrivacyService(t)
+
+	profile, found := service.profile("1")
+	if !found {
+		t.Fatal("user 1 not found")
+	}
+
+	var buffer bytes.Buffer
+	if exportErr := service.exportArchive(&buffer, profile); exportErr != nil {
+		t.Fatal(exportErr)
+	}
+	archive, zipErr := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	if zipErr != nil {
+		t.Fatal(zipErr)
+	}
+
+	files := make(map[string][]byte)
+	for _, file := range archive.File {
+		reader, openErr := file.Open()
+		if openErr != nil {
+			t.Fatal(openErr)
+		}
+		files[file.Name], _ = io.ReadAll(reader)
+		reader.Close()
+
+		if !json.Valid(files[file.Name]) {
+			t.Errorf("%s isn't valid json: %s", file.Name, files[file.Name])
+		}
+	}
+
+	for _, name := range []string{"profile.json", "settings.json", "shares.json", "mood_logs.json", "sleep_logs.json", "audit_log.json"} {
+		if _, exists := files[name]; !exists {
+			t.Errorf("the archive has no %s", name)
+		}
+	}
+
+	var moodLogs []models.MoodLog
+	json.Unmarshal(files["mood_logs.json"], &moodLogs)
+	if len(moodLogs) != 1 || moodLogs[0].Note != "rough day" {
+		t.Errorf("mood logs = %+v, want user 1's one log with its note", moodLogs)
+	}
+
+	var shares struct {
+		SharingGrants         []models.SharingGrant `json:"sharingGrants"`
+		ReceivedSharingGrants []models.SharingGrant `json:"receivedSharingGrants"`
+	}
+	json.Unmarshal(files["shares.json"], &shares)
+	if len(shares.SharingGrants) != 1 || len(shares.ReceivedSharingGrants) != 1 {
+		t.Errorf("shares = %+v, want the grant to user 2 and the one from them", shares)
+	}
+}
@@ -0,0 +1,187 @@
+// Package ratelimit protects MySQL from clients sending too much. Every
+// request spends a token from its IP's bucket and, when it's signed in, from
+// its user's bucket, with smaller budgets for the expensive routes, and
+// analytics computations run a few at a time so they can't take the whole
+// connection pool
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/auth"
+)
+
+// how long an analytics request waits for a free slot before it's turned away
+const concurrencyWait = 5 * time.Second
+
+// Budgets are the buckets for the two classes of route
+type Budgets struct {
+	Expensive Budget // analytics, reports, search and exports
+	Cheap     Budget // everything else
+}
+
+type RateLimiter struct {
+	clientExpensive   *bucketLimiter
+	clientCheap       *bucketLimiter
+	ipExpensive       *bucketLimiter
+	ipCheap           *bucketLimiter
+	analyticsSlots    chan struct{}
+	trustForwardedFor bool
+	concurrencyWait   time.Duration
+	now               func() time.Time
+}
+
+// NewRateLimiter builds the limiter, trustForwardedFor takes the client IP
+// from the last X-Forwarded-For entry, only set it behind a proxy that adds it
+func NewRateLimiter(perClient Budgets, perIP Budgets, analyticsConcurrency int, trustForwardedFor bool) *RateLimiter {
+	return &RateLimiter{
+		clientExpensive:   newBucketLimiter(perClient.Expensive),
+		clientCheap:       newBucketLimiter(perClient.Cheap),
+		ipExpensive:       newBucketLimiter(perIP.Expensive),
+		ipCheap:           newBucketLimiter(perIP.Cheap),
+		analyticsSlots:    make(chan struct{}, analyticsConcurrency),
+		trustForwardedFor: trustForwardedFor,
+		concurrencyWait:   concurrencyWait,
+		now:               time.Now,
+	}
+}
+
+type ipChargeKey struct{}
+
+// LimitIP spends a token from the IP's bucket before the request is
+// authenticated, so a flood of made-up tokens is refused before each one
+// costs an api_token lookup. It goes before auth.Authenticate and Limit then
+// reuses its decision rather than charging the IP twice
+func (limiter *RateLimiter) LimitIP(next http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+
+		_, ipBuckets := limiter.buckets(request.URL.Path)
+		result := ipBuckets.take(limiter.clientIP(request), limiter.now())
+
+		setHeaders(writer, result)
+		if !result.allowed {
+			refuse(writer, result)
+			return
+		}
+
+		next(writer, request.WithContext(context.WithValue(request.Context(), ipChargeKey{}, result)))
+	}
+}
+
+// Limit wraps the request handler, requests over budget get a 429 with
+// Retry-After and every response carries the X-RateLimit headers of the
+// tighter of the two buckets. It goes after auth.Authenticate, the user's
+// bucket is only used once their token is verified so nobody can spend
+// someone else's budget, anonymous requests only have their IP's
+func (limiter *RateLimiter) Limit(next http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+
+		clientBuckets, ipBuckets := limiter.buckets(request.URL.Path)
+
+		now := limiter.now()
+		ipKey := limiter.clientIP(request)
+
+		result, charged := request.Context().Value(ipChargeKey{}).(decision)
+		if !charged {
+			result = ipBuckets.take(ipKey, now)
+		}
+
+		if identity, signedIn := auth.IdentityFrom(request); signedIn && result.allowed {
+			clientResult := clientBuckets.take("user:"+identity.UserID, now)
+			if !clientResult.allowed {
+				ipBuckets.refund(ipKey)
+			}
+			if !clientResult.allowed || clientResult.remaining < result.remaining {
+				result = clientResult
+			}
+		}
+
+		setHeaders(writer, result)
+		if !result.allowed {
+			refuse(writer, result)
+			return
+		}
+
+		if !isAnalytics(request.URL.Path) {
+			next(writer, request)
+			return
+		}
+
+		timer := time.NewTimer(limiter.concurrencyWait)
+		defer timer.Stop()
+
+		select {
+		case limiter.analyticsSlots <- struct{}{}:
+			defer func() { <-limiter.analyticsSlots }()
+			next(writer, request)
+		case <-timer.C:
+			writer.Header().Set("Retry-After", "1")
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			writer.Write([]byte("too many analytics requests in progress, retry shortly"))
+		case <-request.Context().Done():
+		}
+	}
+}
+
+// buckets are the client and IP buckets for the route's class
+func (limiter *RateLimiter) buckets(path string) (*bucketLimiter, *bucketLimiter) {
+	if isExpensive(path) {
+		return limiter.clientExpensive, limiter.ipExpensive
+	}
+	return limiter.clientCheap, limiter.ipCheap
+}
+
+func setHeaders(writer http.ResponseWriter, result decision) {
+	writer.Header().Set("X-RateLimit-Limit", strconv.Itoa(result.limit))
+	writer.Header().Set("X-RateLimit-Remaining", strconv.Itoa(result.remaining))
+	writer.Header().Set("X-RateLimit-Reset", strconv.Itoa(seconds(result.reset)))
+}
+
+func refuse(writer http.ResponseWriter, result decision) {
+	retryAfter := seconds(result.retryAfter)
+	writer.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	writer.WriteHeader(http.StatusTooManyRequests)
+	writer.Write([]byte(fmt.Sprintf("rate limit exceeded, retry in %d seconds", retryAfter)))
+}
+
+func (limiter *RateLimiter) clientIP(request *http.Request) string {
+
+	if limiter.trustForwardedFor {
+		if forwardedFor := request.Header.Get("X-Forwarded-For"); forwardedFor != "" {
+			addresses := strings.Split(forwardedFor, ",")
+			return strings.TrimSpace(addresses[len(addresses)-1])
+		}
+	}
+
+	host, _, splitErr := net.SplitHostPort(request.RemoteAddr)
+	if splitErr != nil {
+		return request.RemoteAddr
+	}
+	return host
+}
+
+// isAnalytics is whether the route computes analytics, which take a dozen or
+// more queries each
+func isAnalytics(path string) bool {
+	return strings.HasPrefix(path, "/analytics") || strings.Contains(path, "/reports/")
+}
+
+// isExpensive adds the routes that read a user's whole history or decrypt a
+// lot of notes
+func isExpensive(path string) bool {
+	return isAnalytics(path) ||
+		strings.HasSuffix(path, "/mood-logs/search") ||
+		strings.HasSuffix(path, "/export") ||
+		strings.HasSuffix(path, "/fhir")
+}
+
+func seconds(duration time.Duration) int {
+	return int(math.Ceil(duration.Seconds()))
+}
@@ -0,0 +1,318 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/auth"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+var testStart = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+func testLimiter(perClient Budgets, perIP Budgets) *RateLimiter {
+	limiter := NewRateLimiter(perClient, perIP, 1, false)
+	limiter.now = func() time.Time { return testStart }
+	return limiter
+}
+
+func ok(writer http.ResponseWriter, request *http.Request) {
+	writer.WriteHeader(http.StatusOK)
+}
+
+func testRequest(path string, userID string, remoteAddr string) *http.Request {
+	request := httptest.NewRequest(http.MethodGet, path, nil)
+	request.RemoteAddr = remoteAddr
+	if userID != "" {
+		request = auth.WithIdentity(request, models.Identity{UserID: userID})
+	}
+	return request
+}
+
+func TestLimitHeaders(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		perClient     Budgets
+		perIP         Budgets
+		path          string
+		userID        string
+		requests      int
+		wantStatus    int
+		wantLimit     string
+		wantRemaining string
+		wantReset     string
+		wantRetry     string
+	}{
+		{
+			name:      "cheap routes use the cheap budget",
+			perClient: Budgets{Expensive: PerMinute(60, 2), Cheap: PerMinute(60, 5)},
+			perIP:     Budgets{Expensive: PerMinute(60, 100), Cheap: PerMinute(60, 100)},
+			path:      "/users/1/settings", userID: "1", requests: 1,
+			wantStatus: http.StatusOK, wantLimit: "5", wantRemaining: "4", wantReset: "1",
+		},
+		{
+			name:      "analytics use the expensive budget",
+			perClient: Budgets{Expensive: PerMinute(60, 2), Cheap: PerMinute(60, 5)},
+			perIP:     Budgets{Expensive: PerMinute(60, 100), Cheap: PerMinute(60, 100)},
+			path:      "/analytics/users/1/mood", userID: "1", requests: 1,
+			wantStatus: http.StatusOK, wantLimit: "2", wantRemaining: "1", wantReset: "1",
+		},
+		{
+			name:      "exports are expensive",
+			perClient: Budgets{Expensive: PerMinute(60, 2), Cheap: PerMinute(60, 5)},
+			perIP:     Budgets{Expensive: PerMinute(60, 100), Cheap: PerMinute(60, 100)},
+			path:      "/users/1/privacy/export", userID: "1", requests: 1,
+			wantStatus: http.StatusOK, wantLimit: "2", wantRemaining: "1", wantReset: "1",
+		},
+		{
+			name:      "the tighter bucket is reported",
+			perClient: Budgets{Expensive: PerMinute(60, 10), Cheap: PerMinute(60, 10)},
+			perIP:     Budgets{Expensive: PerMinute(60, 3), Cheap: PerMinute(60, 3)},
+			path:      "/users/1/settings", userID: "1", requests: 1,
+			wantStatus: http.StatusOK, wantLimit: "3", wantRemaining: "2", wantReset: "1",
+		},
+		{
+			name:      "over the user's budget",
+			perClient: Budgets{Expensive: PerMinute(30, 2), Cheap: PerMinute(30, 2)},
+			perIP:     Budgets{Expensive: PerMinute(60, 100), Cheap: PerMinute(60, 100)},
+			path:      "/analytics/users/1/mood", userID: "1", requests: 3,
+			wantStatus: http.StatusTooManyRequests, wantLimit: "2", wantRemaining: "0", wantReset: "4", wantRetry: "2",
+		},
+		{
+			name:      "anonymous requests only have the IP budget",
+			perClient: Budgets{Expensive: PerMinute(60, 100), Cheap: PerMinute(60, 100)},
+			perIP:     Budgets{Expensive: PerMinute(60, 1), Cheap: PerMinute(60, 1)},
+			path:      "/users/1/settings", userID: "", requests: 2,
+			wantStatus: http.StatusTooManyRequests, wantLimit: "1", wantRemaining: "0", wantReset: "1", wantRetry: "1",
+		},
+	}
+
+	for _, test := range tests {
+		limiter := testLimiter(test.perClient, test.perIP)
+		handler := limiter.Limit(ok)
+
+		var recorder *httptest.ResponseRecorder
+		for i := 0; i < test.requests; i++ {
+			recorder = httptest.NewRecorder()
+			handler(recorder, testRequest(test.path, test.userID, "192.0.2.1:5000"))
+		}
+
+		if recorder.Code != test.wantStatus {
+			t.Errorf("%s: got status %d, want %d", test.name, recorder.Code, test.wantStatus)
+		}
+		headers := map[string]string{
+			"X-RateLimit-Limit":     test.wantLimit,
+			"X-RateLimit-Remaining": test.wantRemaining,
+			"X-RateLimit-Reset":     test.wantReset,
+			"Retry-After":           test.wantRetry,
+		}
+		for header, want := range headers {
+			if got := recorder.Header().Get(header); got != want {
+				t.Errorf("%s: got %s %q, want %q", test.name, header, got, want)
+			}
+		}
+	}
+}
+
+func TestLimitSparesUserWhenIPRefuses(t *testing.T) {
+
+	limiter := testLimiter(
+		Budgets{Expensive: PerMinute(60, 5), Cheap: PerMinute(60, 5)},
+		Budgets{Expensive: PerMinute(60, 1), Cheap: PerMinute(60, 1)},
+	)
+	handler := limiter.Limit(ok)
+
+	handler(httptest.NewRecorder(), testRequest("/users/1/settings", "1", "192.0.2.1:5000"))
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, testRequest("/users/2/settings", "2", "192.0.2.1:5000"))
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("got status %d, want the IP to refuse", recorder.Code)
+	}
+
+	if bucket, exists := limiter.clientCheap.buckets["user:2"]; exists && bucket.tokens != 5 {
+		t.Errorf("user 2 has %v tokens left, want the refused request not to spend any", bucket.tokens)
+	}
+}
+
+func TestLimitRefundsIPWhenUserRefuses(t *testing.T) {
+
+	limiter := testLimiter(
+		Budgets{Expensive: PerMinute(60, 1), Cheap: PerMinute(60, 1)},
+		Budgets{Expensive: PerMinute(60, 5), Cheap: PerMinute(60, 5)},
+	)
+	handler := limiter.LimitIP(limiter.Limit(ok))
+
+	handler(httptest.NewRecorder(), testRequest("/users/1/settings", "1", "192.0.2.1:5000"))
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, testRequest("/users/1/settings", "1", "192.0.2.1:5000"))
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("got status %d, want user 1 refused", recorder.Code)
+	}
+
+	// one token for the first request, the refused one is given back
+	if tokens := limiter.ipCheap.buckets["192.0.2.1"].tokens; tokens != 4 {
+		t.Errorf("the IP has %v tokens left, want 4", tokens)
+	}
+}
+
+func TestLimitIPRefusesInvalidTokenFloods(t *testing.T) {
+
+	limiter := testLimiter(
+		Budgets{Expensive: PerMinute(60, 100), Cheap: PerMinute(60, 100)},
+		Budgets{Expensive: PerMinute(60, 10), Cheap: PerMinute(60, 10)},
+	)
+
+	// stands in for auth.Authenticate turning away a token it looked up
+	lookups := 0
+	rejectToken := func(writer http.ResponseWriter, request *http.Request) {
+		lookups++
+		writer.WriteHeader(http.StatusUnauthorized)
+	}
+	handler := limiter.LimitIP(rejectToken)
+
+	var recorder *httptest.ResponseRecorder
+	for i := 0; i < 50; i++ {
+		recorder = httptest.NewRecorder()
+		request := testRequest("/users/1/settings", "", "192.0.2.1:5000")
+		request.Header.Set("Authorization", "Bearer made-up-"+strconv.Itoa(i))
+		handler(recorder, request)
+	}
+
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("got status %d after the flood, want 429", recorder.Code)
+	}
+	if lookups != 10 {
+		t.Errorf("looked up %d tokens, want only the IP's 10", lookups)
+	}
+}
+
+func TestLimitChargesIPOnce(t *testing.T) {
+
+	limiter := testLimiter(
+		Budgets{Expensive: PerMinute(60, 100), Cheap: PerMinute(60, 100)},
+		Budgets{Expensive: PerMinute(60, 5), Cheap: PerMinute(60, 5)},
+	)
+	handler := limiter.LimitIP(limiter.Limit(ok))
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, testRequest("/users/1/settings", "1", "192.0.2.1:5000"))
+
+	if got := recorder.Header().Get("X-RateLimit-Remaining"); got != "4" {
+		t.Errorf("got X-RateLimit-Remaining %q, want 4", got)
+	}
+}
+
+func TestLimitIgnoresUnverifiedIdentities(t *testing.T) {
+
+	limiter := testLimiter(
+		Budgets{Expensive: PerMinute(60, 1), Cheap: PerMinute(60, 1)},
+		Budgets{Expensive: PerMinute(60, 100), Cheap: PerMinute(60, 100)},
+	)
+	handler := limiter.Limit(ok)
+
+	// someone else asking for user 9's analytics, signed in or not, doesn't
+	// spend user 9's budget
+	handler(httptest.NewRecorder(), testRequest("/analytics/users/9/mood", "1", "192.0.2.1:5000"))
+	anonymous := testRequest("/analytics/users/9/mood", "", "192.0.2.2:5000")
+	anonymous.Header.Set("X-Horizon-Viewer", "9")
+	handler(httptest.NewRecorder(), anonymous)
+
+	if _, exists := limiter.clientExpensive.buckets["user:9"]; exists {
+		t.Fatal("user 9's bucket was spent by other callers")
+	}
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, testRequest("/analytics/users/9/mood", "9", "192.0.2.3:5000"))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("user 9 got status %d, want 200", recorder.Code)
+	}
+}
+
+func TestLimitConcurrency(t *testing.T) {
+
+	limiter := testLimiter(
+		Budgets{Expensive: PerMinute(60, 100), Cheap: PerMinute(60, 100)},
+		Budgets{Expensive: PerMinute(60, 100), Cheap: PerMinute(60, 100)},
+	)
+	limiter.concurrencyWait = 20 * time.Millisecond
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := limiter.Limit(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/analytics/users/1/mood" {
+			close(started)
+			<-release
+		}
+		writer.WriteHeader(http.StatusOK)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		handler(httptest.NewRecorder(), testRequest("/analytics/users/1/mood", "1", "192.0.2.1:5000"))
+		close(done)
+	}()
+	<-started
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantRetry  string
+	}{
+		{"analytics wait for a slot then give up", "/analytics/users/2/sleep", http.StatusServiceUnavailable, "1"},
+		{"reports share the analytics slots", "/users/2/reports/clinician.pdf", http.StatusServiceUnavailable, "1"},
+		{"other routes don't wait", "/users/2/settings", http.StatusOK, ""},
+	}
+
+	for _, test := range tests {
+		recorder := httptest.NewRecorder()
+		handler(recorder, testRequest(test.path, "2", "192.0.2.2:5000"))
+
+		if recorder.Code != test.wantStatus || recorder.Header().Get("Retry-After") != test.wantRetry {
+			t.Errorf("%s: got status %d Retry-After %q, want %d %q", test.name, recorder.Code, recorder.Header().Get("Retry-After"), test.wantStatus, test.wantRetry)
+		}
+	}
+
+	close(release)
+	<-done
+
+	recorder := httptest.NewRecorder()
+	handler(recorder, testRequest("/analytics/users/2/sleep", "2", "192.0.2.2:5000"))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("got status %d once the slot was free, want 200", recorder.Code)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+
+	tests := []struct {
+		name          string
+		trusted       bool
+		remoteAddr    string
+		forwardedFor  string
+		wantIPAddress string
+	}{
+		{"the connection's address", false, "192.0.2.1:5000", "", "192.0.2.1"},
+		{"X-Forwarded-For is ignored unless trusted", false, "192.0.2.1:5000", "198.51.100.7", "192.0.2.1"},
+		{"the proxy's entry is the last one", true, "10.0.0.1:5000", "203.0.113.9, 198.51.100.7", "198.51.100.7"},
+		{"no X-Forwarded-For behind a trusted proxy", true, "10.0.0.1:5000", "", "10.0.0.1"},
+	}
+
+	for _, test := range tests {
+		limiter := NewRateLimiter(Budgets{}, Budgets{}, 1, test.trusted)
+		request := testRequest("/users/1/settings", "", test.remoteAddr)
+		if test.forwardedFor != "" {
+			request.Header.Set("X-Forwarded-For", test.forwardedFor)
+		}
+
+		if got := limiter.clientIP(request); got != test.wantIPAddress {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.wantIPAddress)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Budget is a token bucket, Burst requests at once refilling at Rate per second
+type Budget struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute is a budget of requests per minute with the given burst
+func PerMinute(requests int, burst int) Budget {
+	return Budget{Rate: float64(requests) / 60, Burst: burst}
+}
+
+type tokenBucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+type decision struct {
+	allowed    bool
+	limit      int
+	remaining  int
+	retryAfter time.Duration // until a token is available, when not allowed
+	reset      time.Duration // until the bucket is full again
+}
+
+// bucketLimiter keeps a token bucket per key, buckets that have refilled are
+// dropped now and then so keys seen once don't pile up
+type bucketLimiter struct {
+	mutex     sync.Mutex
+	budget    Budget
+	buckets   map[string]*tokenBucket
+	sweptAt   time.Time
+	fillDelay time.Duration // how long an empty bucket takes to fill
+}
+
+func newBucketLimiter(budget Budget) *bucketLimiter {
+	return &bucketLimiter{
+		budget:    budget,
+		buckets:   make(map[string]*tokenBucket),
+		sweptAt:   time.Now(),
+		fillDelay: time.Duration(float64(budget.Burst) / budget.Rate * float64(time.Second)),
+	}
+}
+
+func (bl *bucketLimiter) take(key string, now time.Time) decision {
+	bl.mutex.Lock()
+	defer bl.mutex.Unlock()
+
+	bl.sweep(now)
+	bucket := bl.refill(key, now)
+
+	result := decision{limit: bl.budget.Burst}
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		result.allowed = true
+	} else {
+		result.retryAfter = bl.delay(1 - bucket.tokens)
+	}
+	result.remaining = int(math.Floor(bucket.tokens))
+	result.reset = bl.delay(float64(bl.budget.Burst) - bucket.tokens)
+
+	return result
+}
+
+// refund gives back a token taken for a request another limit then refused
+func (bl *bucketLimiter) refund(key string) {
+	bl.mutex.Lock()
+	defer bl.mutex.Unlock()
+
+	if bucket, exists := bl.buckets[key]; exists {
+		bucket.tokens = math.Min(float64(bl.budget.Burst), bucket.tokens+1)
+	}
+}
+
+func (bl *bucketLimiter) refill(key string, now time.Time) *tokenBucket {
+	bucket, exists := bl.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: float64(bl.budget.Burst), updatedAt: now}
+		bl.buckets[key] = bucket
+		return bucket
+	}
+
+	elapsed := now.Sub(bucket.updatedAt).Seconds()
+	bucket.tokens = math.Min(float64(bl.budget.Burst), bucket.tokens+elapsed*bl.budget.Rate)
+	bucket.updatedAt = now
+	return bucket
+}
+
+func (bl *bucketLimiter) delay(tokens float64) time.Duration {
+	return time.Duration(tokens / bl.budget.Rate * float64(time.Second))
+}
+
+// sweep drops buckets that have been idle long enough to be full, a new
+// bucket starts full so forgetting them changes nothing
+func (bl *bucketLimiter) sweep(now time.Time) {
+	if now.Sub(bl.sweptAt) < time.Minute {
+		return
+	}
+	bl.sweptAt = now
+
+	for key, bucket := range bl.buckets {
+		if now.Sub(bucket.updatedAt) >= bl.fillDelay {
+			delete(bl.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTakeRefills(t *testing.T) {
+
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// a token a second, two at once
+	limiter := newBucketLimiter(PerMinute(60, 2))
+
+	steps := []struct {
+		name           string
+		after          time.Duration
+		wantAllowed    bool
+		wantRemaining  int
+		wantRetryAfter time.Duration
+		wantReset      time.Duration
+	}{
+		{"a new bucket starts full", 0, true, 1, 0, 1 * time.Second},
+		{"the burst can be spent at once", 0, true, 0, 0, 2 * time.Second},
+		{"an empty bucket refuses", 0, false, 0, 1 * time.Second, 2 * time.Second},
+		{"part of a token isn't enough", 500 * time.Millisecond, false, 0, 500 * time.Millisecond, 1500 * time.Millisecond},
+		{"a whole token has refilled", 1 * time.Second, true, 0, 0, 2 * time.Second},
+		{"refilling stops at the burst", 1 * time.Minute, true, 1, 0, 1 * time.Second},
+	}
+
+	for _, step := range steps {
+		got := limiter.take("user:1", start.Add(step.after))
+
+		if got.allowed != step.wantAllowed || got.remaining != step.wantRemaining || got.limit != 2 {
+			t.Errorf("%s: got allowed %v remaining %d limit %d, want %v %d 2", step.name, got.allowed, got.remaining, got.limit, step.wantAllowed, step.wantRemaining)
+		}
+		if got.retryAfter != step.wantRetryAfter {
+			t.Errorf("%s: got retryAfter %v, want %v", step.name, got.retryAfter, step.wantRetryAfter)
+		}
+		if got.reset != step.wantReset {
+			t.Errorf("%s: got reset %v, want %v", step.name, got.reset, step.wantReset)
+		}
+	}
+}
+
+func TestBucketsAreKeyed(t *testing.T) {
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newBucketLimiter(PerMinute(60, 1))
+
+	if got := limiter.take("user:1", now); !got.allowed {
+		t.Fatal("first request of user 1 refused")
+	}
+	if got := limiter.take("user:1", now); got.allowed {
+		t.Fatal("second request of user 1 allowed")
+	}
+	if got := limiter.take("user:2", now); !got.allowed {
+		t.Fatal("user 2 refused because of user 1")
+	}
+}
+
+func TestRefund(t *testing.T) {
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		takes      int
+		refunds    int
+		wantTokens float64
+	}{
+		{"a refund gives the token back", 2, 1, 1},
+		{"refunds don't go past the burst", 1, 3, 2},
+		{"refunding an unknown key does nothing", 0, 1, -1},
+	}
+
+	for _, test := range tests {
+		limiter := newBucketLimiter(PerMinute(60, 2))
+		for i := 0; i < test.takes; i++ {
+			limiter.take("user:1", now)
+		}
+		for i := 0; i < test.refunds; i++ {
+			limiter.refund("user:1")
+		}
+
+		bucket, exists := limiter.buckets["user:1"]
+		if test.wantTokens < 0 {
+			if exists {
+				t.Errorf("%s: refund created a bucket", test.name)
+			}
+			continue
+		}
+		if !exists || bucket.tokens != test.wantTokens {
+			t.Errorf("%s: got %v tokens, want %v", test.name, bucket, test.wantTokens)
+		}
+	}
+}
+
+func TestSweep(t *testing.T) {
+
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// fills in 10 seconds
+	limiter := newBucketLimiter(PerMinute(60, 10))
+	limiter.sweptAt = start
+
+	limiter.take("idle", start)
+	limiter.take("busy", start.Add(55*time.Second))
+
+	// not a minute since the last sweep yet
+	limiter.take("other", start.Add(59*time.Second))
+	if len(limiter.buckets) != 3 {
+		t.Fatalf("swept before a minute passed, %d buckets left", len(limiter.buckets))
+	}
+
+	limiter.take("other", start.Add(61*time.Second))
+	if _, exists := limiter.buckets["idle"]; exists {
+		t.Error("a bucket idle long enough to be full wasn't swept")
+	}
+	if _, exists := limiter.buckets["busy"]; !exists {
+		t.Error("a bucket that's still refilling was swept")
+	}
+}
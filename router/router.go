@@ -1,18 +1,42 @@
 package router
 
 import (
-	"github.com/michaeljosephroddy/project-horizon-backend-go/analytics"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/audit"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/auth"
 	"net/http"
 	"strings"
 )
 
+// Every handler is wrapped, the audited ones let users read each other's
+// data under a sharing grant and record it, the owner ones are for the user
+// in the path alone
 type Router struct {
-	analyticsHandler *analytics.AnalyticsHandler
+	analyticsHandler *audit.AuditedHandler
+	alertsHandler    *auth.OwnerHandler
+	webhooksHandler  *auth.OwnerHandler
+	exportHandler    *audit.AuditedHandler
+	fhirHandler      *audit.AuditedHandler
+	importerHandler  *auth.OwnerHandler
+	settingsHandler  *auth.OwnerHandler
+	journalHandler   *audit.AuditedHandler
+	privacyHandler   *auth.OwnerHandler
+	auditHandler     *auth.OwnerHandler
+	sharingHandler   *auth.OwnerHandler
 }
 
-func NewRouter(handler *analytics.AnalyticsHandler) *Router {
+func NewRouter(analyticsHandler *audit.AuditedHandler, alertsHandler *auth.OwnerHandler, webhooksHandler *auth.OwnerHandler, exportHandler *audit.AuditedHandler, fhirHandler *audit.AuditedHandler, importerHandler *auth.OwnerHandler, settingsHandler *auth.OwnerHandler, journalHandler *audit.AuditedHandler, privacyHandler *auth.OwnerHandler, auditHandler *auth.OwnerHandler, sharingHandler *auth.OwnerHandler) *Router {
 	return &Router{
-		analyticsHandler: handler,
+		analyticsHandler: analyticsHandler,
+		alertsHandler:    alertsHandler,
+		webhooksHandler:  webhooksHandler,
+		exportHandler:    exportHandler,
+		fhirHandler:      fhirHandler,
+		importerHandler:  importerHandler,
+		settingsHandler:  settingsHandler,
+		journalHandler:   journalHandler,
+		privacyHandler:   privacyHandler,
+		auditHandler:     auditHandler,
+		sharingHandler:   sharingHandler,
 	}
 }
 
@@ -20,6 +44,32 @@ func (r *Router) RouteRequests(writer http.ResponseWriter, request *http.Request
 	switch {
 	case strings.HasPrefix(request.URL.Path, "/analytics"):
 		r.analyticsHandler.ProcessRequest(writer, request)
+	case strings.HasPrefix(request.URL.Path, "/alerts"):
+		r.alertsHandler.ProcessRequest(writer, request)
+	case strings.HasPrefix(request.URL.Path, "/imports"):
+		r.importerHandler.ProcessRequest(writer, request)
+	case strings.HasPrefix(request.URL.Path, "/webhooks"):
+		r.webhooksHandler.ProcessRequest(writer, request)
+	case strings.HasPrefix(request.URL.Path, "/audit"):
+		r.auditHandler.ProcessRequest(writer, request)
+	case strings.HasPrefix(request.URL.Path, "/privacy"):
+		r.privacyHandler.ProcessRequest(writer, request)
+	case strings.HasPrefix(request.URL.Path, "/users") && strings.Contains(request.URL.Path, "/privacy/"):
+		r.privacyHandler.ProcessRequest(writer, request)
+	case strings.HasPrefix(request.URL.Path, "/users") && strings.HasSuffix(request.URL.Path, "/export"):
+		r.exportHandler.ProcessRequest(writer, request)
+	case strings.HasPrefix(request.URL.Path, "/users") && strings.HasSuffix(request.URL.Path, "/fhir"):
+		r.fhirHandler.ProcessRequest(writer, request)
+	case strings.HasPrefix(request.URL.Path, "/users") && strings.Contains(request.URL.Path, "/settings"):
+		r.settingsHandler.ProcessRequest(writer, request)
+	case strings.HasPrefix(request.URL.Path, "/users") && strings.Contains(request.URL.Path, "/mood-logs/"):
+		r.journalHandler.ProcessRequest(writer, request)
+	case strings.HasPrefix(request.URL.Path, "/users") && strings.HasSuffix(request.URL.Path, "/audit"):
+		r.auditHandler.ProcessRequest(writer, request)
+	case strings.HasPrefix(request.URL.Path, "/users") && strings.Contains(request.URL.Path, "/reports/"):
+		r.analyticsHandler.ProcessRequest(writer, request)
+	case strings.HasPrefix(request.URL.Path, "/users") && strings.Contains(request.URL.Path, "/sharing-grants"):
+		r.sharingHandler.ProcessRequest(writer, request)
 	default:
 		writer.WriteHeader(http.StatusNotFound)
 		writer.Write([]byte("resouce not found"))
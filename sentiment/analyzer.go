@@ -0,0 +1,167 @@
+// Package sentiment scores journal notes offline with a bundled word list, a
+// cut down VADER: each word's valence is scaled by boosters before it, flipped
+// by a negation in the three words before and the sum is squashed into -1..1
+package sentiment
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+// Version is stored with every score, notes scored by an older version are
+// scored again
+const Version = 1
+
+const (
+	boostIncrement   = 0.293
+	negationScalar   = -0.74
+	lookBack         = 3
+	normalisingAlpha = 15.0
+	exclamationBoost = 0.292
+	maxExclamations  = 4
+	maxKeywords      = 5
+	minKeywordLength = 3
+	maxKeywordLength = 64 // mood_log_keyword.keyword
+)
+
+type Result struct {
+	Score    float64 // -1 very negative to 1 very positive
+	Keywords []models.KeywordFrequency
+}
+
+func Analyze(text string) Result {
+
+	words := tokenize(text)
+
+	// sentiment before a "but" counts for less than the sentiment after it
+	butIndex := slices.Index(words, "but")
+
+	sum := 0.0
+	for i, word := range words {
+		valence, found := lexicon[word]
+		if !found {
+			continue
+		}
+
+		for j := 1; j <= lookBack && i-j >= 0; j++ {
+			previous := words[i-j]
+			if boost, isBooster := boosters[previous]; isBooster {
+				// boosters further back count for less, 1, 0.95 and 0.9
+				boost *= 1 - 0.05*float64(j-1)
+				if valence < 0 {
+					boost = -boost
+				}
+				valence += boost
+			}
+		}
+		for j := 1; j <= lookBack && i-j >= 0; j++ {
+			if negations[words[i-j]] {
+				valence *= negationScalar
+				break
+			}
+		}
+
+		switch {
+		case butIndex >= 0 && i < butIndex:
+			valence *= 0.5
+		case butIndex >= 0 && i > butIndex:
+			valence *= 1.5
+		}
+
+		sum += valence
+	}
+
+	if sum != 0 {
+		exclamations := min(strings.Count(text, "!"), maxExclamations)
+		sum += math.Copysign(float64(exclamations)*exclamationBoost, sum)
+	}
+
+	return Result{
+		Score:    sum / math.Sqrt(sum*sum+normalisingAlpha),
+		Keywords: keywords(words),
+	}
+}
+
+// keywords are the most repeated words that aren't stopwords, ties go to the
+// word that came first
+func keywords(words []string) []models.KeywordFrequency {
+
+	var frequencies []models.KeywordFrequency
+	index := make(map[string]int)
+	for _, word := range words {
+		if length := utf8.RuneCountInString(word); length < minKeywordLength || length > maxKeywordLength || stopwords[word] || negations[word] || isNumber(word) {
+			continue
+		}
+		if _, isBooster := boosters[word]; isBooster {
+			continue
+		}
+		if i, seen := index[word]; seen {
+			frequencies[i].Count++
+			continue
+		}
+		index[word] = len(frequencies)
+		frequencies = append(frequencies, models.KeywordFrequency{Keyword: word, Count: 1})
+	}
+
+	slices.SortStableFunc(frequencies, func(a, b models.KeywordFrequency) int {
+		return b.Count - a.Count
+	})
+	if len(frequencies) > maxKeywords {
+		frequencies = frequencies[:maxKeywords]
+	}
+	if frequencies == nil {
+		return make([]models.KeywordFrequency, 0)
+	}
+	return frequencies
+}
+
+// tokenize lowercases the text and splits it into words, apostrophes are
+// dropped so "don't" and "dont" are the same word and a possessive 's goes
+func tokenize(text string) []string {
+
+	var words []string
+	var current strings.Builder
+
+	flush := func() {
+		if current.Len() == 0 {
+			return
+		}
+		word := current.String()
+		if strings.HasSuffix(word, "'s") {
+			word = strings.TrimSuffix(word, "'s")
+		}
+		word = strings.ReplaceAll(word, "'", "")
+		if word != "" {
+			words = append(words, word)
+		}
+		current.Reset()
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			current.WriteRune(r)
+		case (r == '\'' || r == '’') && current.Len() > 0:
+			current.WriteRune('\'')
+		default:
+			flush()
+		}
+	}
+	flush()
+
+	return words
+}
+
+func isNumber(word string) bool {
+	for _, r := range word {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
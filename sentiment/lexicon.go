@@ -0,0 +1,91 @@
+package sentiment
+
+// lexicon holds the valence of words common in mood journals on VADER's -4 to
+// 4 scale, the values for words VADER has are taken from it. Bump Version when
+// this changes so the stored scores are recomputed
+var lexicon = map[string]float64{
+	// positive
+	"accomplished": 1.8, "amazing": 2.8, "awesome": 3.1, "balanced": 1.2, "beautiful": 2.9,
+	"best": 3.2, "better": 1.9, "blessed": 2.9, "brilliant": 2.8, "calm": 1.3,
+	"calmer": 1.3, "cheerful": 2.5, "clear": 1.6, "comfortable": 1.5, "confident": 2.2,
+	"connected": 1.3, "content": 1.5, "delighted": 2.7, "ecstatic": 2.3, "energetic": 1.9,
+	"energised": 1.8, "energized": 1.8, "enjoy": 2.2, "enjoyed": 2.3, "excellent": 2.7,
+	"excited": 1.4, "exciting": 2.2, "fantastic": 2.6, "fine": 0.8, "focused": 1.6,
+	"free": 2.3, "friend": 2.2, "friends": 2.1, "fun": 2.3, "glad": 2.0,
+	"good": 1.9, "grateful": 2.3, "great": 3.1, "happier": 2.6, "happy": 2.7,
+	"healthy": 1.7, "hope": 1.9, "hopeful": 2.3, "improved": 2.1, "improving": 1.6,
+	"joy": 2.8, "joyful": 2.9, "laugh": 2.6, "laughed": 2.0, "laughing": 2.2,
+	"love": 3.2, "loved": 2.9, "lovely": 2.8, "motivated": 1.8, "nice": 1.8,
+	"ok": 0.9, "okay": 0.9, "optimistic": 1.9, "peaceful": 2.2, "perfect": 2.7,
+	"pleasant": 2.3, "productive": 1.8, "progress": 1.8, "proud": 2.1, "refreshed": 1.6,
+	"relaxed": 2.2, "relaxing": 2.2, "relief": 2.1, "relieved": 1.9, "rested": 1.2,
+	"safe": 1.9, "satisfied": 1.8, "smile": 1.5, "smiled": 1.5, "strong": 2.3,
+	"success": 2.7, "successful": 2.8, "support": 1.7, "supported": 1.7, "thankful": 2.7,
+	"thrilled": 2.6, "well": 1.1, "win": 2.8, "wonderful": 2.7,
+
+	// negative
+	"afraid": -2.0, "alone": -1.0, "anger": -2.7, "angry": -2.3, "annoyed": -1.6,
+	"anxiety": -0.7, "anxious": -1.0, "argument": -1.5, "ashamed": -2.1, "awful": -2.0,
+	"bad": -2.5, "bored": -1.1, "boring": -1.3, "broken": -1.8, "confused": -1.3,
+	"crash": -1.7, "crashed": -1.7, "cried": -1.6, "cry": -2.1, "crying": -2.1,
+	"depressed": -2.3, "depression": -1.9, "difficult": -1.5, "disappointed": -1.9, "disappointing": -2.2,
+	"down": -1.0, "drained": -1.2, "dread": -2.0, "dreading": -2.1, "empty": -0.8,
+	"exhausted": -1.5, "fail": -2.5, "failed": -2.3, "failure": -2.3, "fear": -2.2,
+	"fight": -1.6, "frustrated": -2.0, "frustrating": -1.9, "grief": -2.2, "grieving": -2.3,
+	"guilt": -1.1, "guilty": -1.8, "hate": -2.7, "hated": -3.2, "heartbroken": -2.4,
+	"helpless": -2.0, "hopeless": -2.0, "horrible": -2.5, "hurt": -2.4, "ignored": -1.3,
+	"ill": -1.8, "insomnia": -1.4, "irritable": -1.6, "irritated": -1.8, "isolated": -1.3,
+	"lethargic": -1.2, "lonely": -1.7, "lost": -1.3, "low": -1.1, "manic": -1.0,
+	"meh": -0.3, "miserable": -2.2, "nervous": -1.1, "numb": -1.1, "overwhelmed": -1.5,
+	"pain": -2.3, "painful": -2.2, "panic": -2.3, "paranoid": -1.0, "rejected": -1.9,
+	"restless": -1.1, "rough": -0.8, "sad": -2.1, "scared": -1.9, "shame": -2.1,
+	"sick": -2.3, "sleepless": -1.6, "sluggish": -1.1, "stress": -1.8, "stressed": -1.4,
+	"stressful": -2.1, "struggle": -1.3, "struggled": -1.4, "struggling": -1.5, "suicidal": -3.5,
+	"tears": -0.9, "tense": -1.4, "terrible": -2.1, "tired": -1.3, "ugh": -1.8,
+	"unhappy": -1.8, "upset": -1.6, "useless": -1.8, "worried": -1.2, "worry": -1.9,
+	"worse": -2.1, "worst": -3.1, "worthless": -1.9,
+}
+
+// boosters scale the next sentiment word up or down
+var boosters = map[string]float64{
+	"absolutely": boostIncrement, "completely": boostIncrement, "deeply": boostIncrement,
+	"extremely": boostIncrement, "incredibly": boostIncrement, "really": boostIncrement,
+	"so": boostIncrement, "super": boostIncrement, "totally": boostIncrement, "very": boostIncrement,
+
+	"barely": -boostIncrement, "kinda": -boostIncrement, "slightly": -boostIncrement, "somewhat": -boostIncrement,
+}
+
+var negations = map[string]bool{
+	"cannot": true, "cant": true, "didnt": true, "doesnt": true, "dont": true,
+	"hardly": true, "isnt": true, "never": true, "no": true, "nobody": true,
+	"none": true, "nor": true, "not": true, "nothing": true, "wasnt": true,
+	"without": true, "wont": true, "couldnt": true, "wouldnt": true, "shouldnt": true,
+}
+
+// stopwords are never keywords, along with the boosters and negations
+var stopwords = map[string]bool{
+	"about": true, "after": true, "again": true, "all": true, "also": true,
+	"and": true, "any": true, "are": true, "around": true, "because": true,
+	"been": true, "before": true, "being": true, "bit": true, "but": true,
+	"came": true, "can": true, "could": true, "day": true, "did": true,
+	"does": true, "doing": true, "done": true, "even": true, "ever": true,
+	"every": true, "feel": true, "feeling": true, "feels": true, "felt": true,
+	"few": true, "for": true, "from": true, "get": true, "getting": true,
+	"got": true, "had": true, "has": true, "have": true, "having": true,
+	"her": true, "here": true, "him": true, "his": true, "how": true,
+	"into": true, "its": true, "just": true, "like": true, "lot": true,
+	"made": true, "make": true, "many": true, "may": true, "more": true,
+	"most": true, "much": true, "myself": true, "now": true, "off": true,
+	"one": true, "only": true, "other": true, "our": true, "out": true,
+	"over": true, "quite": true, "said": true, "same": true, "she": true,
+	"should": true, "some": true, "still": true, "such": true, "than": true,
+	"that": true, "the": true, "their": true, "them": true, "then": true,
+	"there": true, "these": true, "they": true, "thing": true, "things": true,
+	"this": true, "those": true, "through": true, "today": true, "too": true,
+	"took": true, "under": true, "until": true, "was": true,
+	"way": true, "went": true, "were": true, "what": true, "when": true,
+	"where": true, "which": true, "while": true, "who": true, "why": true,
+	"will": true, "with": true, "would": true, "you": true, "your": true,
+	"going": true, "yesterday": true, "tonight": true, "im": true, "ive": true,
+	"whole": true, "pretty": true,
+}
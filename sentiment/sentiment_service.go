@@ -0,0 +1,66 @@
+package sentiment
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
+)
+
+const (
+	// notes scored per tick, a backlog after a version bump clears over a few
+	// ticks
+	batchSize = 500
+	// logs changed this long before a tick that caught up are looked at again
+	// on the next one, it covers clock skew between here and the database and
+	// writes that committed late
+	rescanMargin = 5 * time.Minute
+)
+
+type sentimentService struct {
+	sentimentRepository *database.SentimentRepository
+	// every log changed before this has been scored, it starts at the epoch so
+	// the first ticks after a start look at every log once
+	changedSince string
+}
+
+func NewSentimentService(sentimentRepository *database.SentimentRepository) *sentimentService {
+	return &sentimentService{
+		sentimentRepository: sentimentRepository,
+		changedSince:        "1970-01-01 00:00:01",
+	}
+}
+
+// Start scores new and edited notes on each tick, the logs are written outside
+// this service so they're found by polling
+func (service *sentimentService) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			service.scoreSafely()
+		}
+	}()
+}
+
+func (service *sentimentService) scoreSafely() {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("ERROR scoring note sentiment", r)
+		}
+	}()
+
+	tickedAt := time.Now().UTC()
+
+	moodLogs, more := service.sentimentRepository.PendingNotes(Version, service.changedSince, batchSize)
+	for _, moodLog := range moodLogs {
+		result := Analyze(moodLog.Note)
+		scored := strings.TrimSpace(moodLog.Note) != ""
+		service.sentimentRepository.SaveSentiment(moodLog, result.Score, scored, Version, result.Keywords)
+	}
+
+	if !more {
+		service.changedSince = tickedAt.Add(-rescanMargin).Format("2006-01-02 15:04:05")
+	}
+}
@@ -0,0 +1,118 @@
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+type SettingsHandler struct {
+	settingsService *settingsService
+}
+
+var usersSettings string = `^/users/([0-9]+)/settings$`
+var usersSettingsBaselines string = `^/users/([0-9]+)/settings/baselines$`
+var usersSettingsBaseline string = `^/users/([0-9]+)/settings/baselines/([A-Za-z0-9_-]+)$`
+
+func NewSettingsHandler(settingsService *settingsService) *SettingsHandler {
+	return &SettingsHandler{
+		settingsService: settingsService,
+	}
+}
+
+func (handler *SettingsHandler) ProcessRequest(writer http.ResponseWriter, request *http.Request) {
+	switch {
+	case utils.MatchURL(usersSettings, request.URL.Path) && request.Method == http.MethodGet:
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+
+		settings := handler.settingsService.settings(userID)
+		body, _ := json.Marshal(settings)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+
+	case utils.MatchURL(usersSettings, request.URL.Path) && request.Method == http.MethodPut:
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+
+		var update models.UserSettings
+		decodeErr := json.NewDecoder(request.Body).Decode(&update)
+		if decodeErr != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(decodeErr.Error()))
+			return
+		}
+
+		settings, saveErr := handler.settingsService.saveSettings(userID, update)
+		if errors.Is(saveErr, errTimezoneTablesMissing) {
+			writer.WriteHeader(http.StatusInternalServerError)
+			writer.Write([]byte(saveErr.Error()))
+			return
+		}
+		if saveErr != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(saveErr.Error()))
+			return
+		}
+		body, _ := json.Marshal(settings)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+
+	case utils.MatchURL(usersSettingsBaselines, request.URL.Path) && request.Method == http.MethodGet:
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+
+		baselinePeriods := handler.settingsService.baselinePeriods(userID)
+		body, _ := json.Marshal(baselinePeriods)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+
+	case utils.MatchURL(usersSettingsBaseline, request.URL.Path) && request.Method == http.MethodPut:
+
+		params := utils.PathParams(usersSettingsBaseline, request.URL.Path)
+		userID, name := params[0], params[1]
+
+		var baselinePeriod models.BaselinePeriod
+		decodeErr := json.NewDecoder(request.Body).Decode(&baselinePeriod)
+		if decodeErr != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(decodeErr.Error()))
+			return
+		}
+		baselinePeriod.UserID = userID
+		baselinePeriod.Name = name
+
+		saveErr := handler.settingsService.saveBaselinePeriod(baselinePeriod)
+		if saveErr != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(saveErr.Error()))
+			return
+		}
+		body, _ := json.Marshal(baselinePeriod)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+
+	case utils.MatchURL(usersSettingsBaseline, request.URL.Path) && request.Method == http.MethodDelete:
+
+		params := utils.PathParams(usersSettingsBaseline, request.URL.Path)
+
+		if deleted := handler.settingsService.deleteBaselinePeriod(params[0], params[1]); !deleted {
+			writer.WriteHeader(http.StatusNotFound)
+			writer.Write([]byte("baseline period not found"))
+			return
+		}
+
+		writer.WriteHeader(http.StatusNoContent)
+
+	default:
+		writer.WriteHeader(http.StatusNotFound)
+		writer.Write([]byte("404 path not found"))
+	}
+}
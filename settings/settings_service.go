@@ -0,0 +1,92 @@
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+// a named zone that Go knows but MySQL doesn't means the time zone tables
+// aren't loaded, every mood query for the user would fail so it's refused
+var errTimezoneTablesMissing = errors.New("the database can't convert to named time zones, load its time zone tables")
+
+type settingsService struct {
+	userSettingsRepository   *database.UserSettingsRepository
+	baselinePeriodRepository *database.BaselinePeriodRepository
+}
+
+func NewSettingsService(userSettingsRepository *database.UserSettingsRepository, baselinePeriodRepository *database.BaselinePeriodRepository) *settingsService {
+	return &settingsService{
+		userSettingsRepository:   userSettingsRepository,
+		baselinePeriodRepository: baselinePeriodRepository,
+	}
+}
+
+func (service *settingsService) settings(userID string) models.UserSettings {
+	return service.userSettingsRepository.Settings(userID)
+}
+
+// saveSettings keeps the current value for any field left out of the update
+func (service *settingsService) saveSettings(userID string, update models.UserSettings) (models.UserSettings, error) {
+
+	settings := service.userSettingsRepository.Settings(userID)
+
+	if update.TargetSleepHours != 0 {
+		if update.TargetSleepHours < 0 || update.TargetSleepHours > 24 {
+			return models.UserSettings{}, errors.New("targetSleepHours must be between 0 and 24")
+		}
+		settings.TargetSleepHours = update.TargetSleepHours
+	}
+
+	if update.Timezone != "" {
+		// "Local" would load the server's zone, which MySQL doesn't know by that name
+		if _, loadErr := utils.LoadTimezone(update.Timezone); loadErr != nil {
+			return models.UserSettings{}, loadErr
+		}
+		if update.Timezone != "UTC" && !service.userSettingsRepository.TimezoneKnown(update.Timezone) {
+			if service.userSettingsRepository.TimezoneKnown("UTC") {
+				return models.UserSettings{}, fmt.Errorf("unknown timezone %q", update.Timezone)
+			}
+			fmt.Println("ERROR " + errTimezoneTablesMissing.Error())
+			return models.UserSettings{}, errTimezoneTablesMissing
+		}
+		settings.Timezone = update.Timezone
+	}
+
+	service.userSettingsRepository.SaveSettings(settings)
+
+	return settings, nil
+}
+
+func (service *settingsService) baselinePeriods(userID string) []models.BaselinePeriod {
+	return service.baselinePeriodRepository.BaselinePeriods(userID)
+}
+
+func (service *settingsService) saveBaselinePeriod(baselinePeriod models.BaselinePeriod) error {
+
+	if len(baselinePeriod.Name) > 64 {
+		return errors.New("name must be at most 64 characters")
+	}
+
+	layout := "2006-01-02"
+	startDate, startErr := time.Parse(layout, baselinePeriod.StartDate)
+	endDate, endErr := time.Parse(layout, baselinePeriod.EndDate)
+	if startErr != nil || endErr != nil {
+		return errors.New("startDate and endDate must be dates in the format YYYY-MM-DD")
+	}
+	if endDate.Before(startDate) {
+		return errors.New("startDate must not be after endDate")
+	}
+
+	service.baselinePeriodRepository.SaveBaselinePeriod(baselinePeriod)
+
+	return nil
+}
+
+func (service *settingsService) deleteBaselinePeriod(userID string, name string) bool {
+	return service.baselinePeriodRepository.DeleteBaselinePeriod(userID, name)
+}
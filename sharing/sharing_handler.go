@@ -0,0 +1,87 @@
+package sharing
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+type SharingHandler struct {
+	sharingService *sharingService
+}
+
+var usersSharingGrants string = `^/users/([0-9]+)/sharing-grants$`
+var usersSharingGrantsReceived string = `^/users/([0-9]+)/sharing-grants/received$`
+var usersSharingGrant string = `^/users/([0-9]+)/sharing-grants/([0-9]+)$`
+
+func NewSharingHandler(sharingService *sharingService) *SharingHandler {
+	return &SharingHandler{
+		sharingService: sharingService,
+	}
+}
+
+func (handler *SharingHandler) ProcessRequest(writer http.ResponseWriter, request *http.Request) {
+	switch {
+	case utils.MatchURL(usersSharingGrants, request.URL.Path) && request.Method == http.MethodGet:
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+
+		grants := handler.sharingService.grants(userID)
+		body, _ := json.Marshal(grants)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+
+	case utils.MatchURL(usersSharingGrants, request.URL.Path) && request.Method == http.MethodPost:
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+
+		var grant models.SharingGrant
+		decodeErr := json.NewDecoder(request.Body).Decode(&grant)
+		if decodeErr != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(decodeErr.Error()))
+			return
+		}
+
+		created, createErr := handler.sharingService.createGrant(userID, grant)
+		if createErr != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(createErr.Error()))
+			return
+		}
+		body, _ := json.Marshal(created)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusCreated)
+		writer.Write(body)
+
+	case utils.MatchURL(usersSharingGrantsReceived, request.URL.Path) && request.Method == http.MethodGet:
+
+		userID := utils.GetUserIDFromPath(request.URL.Path)
+
+		grants := handler.sharingService.receivedGrants(userID)
+		body, _ := json.Marshal(grants)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+
+	case utils.MatchURL(usersSharingGrant, request.URL.Path) && request.Method == http.MethodDelete:
+
+		params := utils.PathParams(usersSharingGrant, request.URL.Path)
+
+		if revoked := handler.sharingService.revokeGrant(params[0], params[1]); !revoked {
+			writer.WriteHeader(http.StatusNotFound)
+			writer.Write([]byte("sharing grant not found or already revoked"))
+			return
+		}
+
+		writer.WriteHeader(http.StatusNoContent)
+
+	default:
+		writer.WriteHeader(http.StatusNotFound)
+		writer.Write([]byte("404 path not found"))
+	}
+}
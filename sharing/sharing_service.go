@@ -0,0 +1,84 @@
+package sharing
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+// what a grant can let the grantee read, see sharing_grant in db.sql
+const (
+	ScopeAnalytics = "analytics"
+	ScopeJournal   = "journal"
+	ScopeExport    = "export"
+)
+
+var scopes = []string{ScopeAnalytics, ScopeJournal, ScopeExport}
+var userIDPattern = regexp.MustCompile(`^[0-9]+$`)
+
+type sharingService struct {
+	sharingGrantRepository *database.SharingGrantRepository
+	userRepository         *database.UserRepository
+}
+
+func NewSharingService(sharingGrantRepository *database.SharingGrantRepository, userRepository *database.UserRepository) *sharingService {
+	return &sharingService{
+		sharingGrantRepository: sharingGrantRepository,
+		userRepository:         userRepository,
+	}
+}
+
+func (service *sharingService) grants(userID string) []models.SharingGrant {
+	return service.sharingGrantRepository.Grants(userID)
+}
+
+func (service *sharingService) receivedGrants(userID string) []models.SharingGrant {
+	return service.sharingGrantRepository.ReceivedGrants(userID)
+}
+
+func (service *sharingService) createGrant(userID string, grant models.SharingGrant) (models.SharingGrant, error) {
+
+	if !userIDPattern.MatchString(grant.GranteeUserID) {
+		return models.SharingGrant{}, errors.New("granteeUserId must be a user id")
+	}
+	if grant.GranteeUserID == userID {
+		return models.SharingGrant{}, errors.New("users can already read their own data")
+	}
+	if _, found := service.userRepository.Profile(grant.GranteeUserID); !found {
+		return models.SharingGrant{}, fmt.Errorf("user %s not found", grant.GranteeUserID)
+	}
+
+	if len(grant.Scopes) == 0 {
+		return models.SharingGrant{}, fmt.Errorf("scopes must name at least one of %v", scopes)
+	}
+	for _, scope := range grant.Scopes {
+		if !slices.Contains(scopes, scope) {
+			return models.SharingGrant{}, fmt.Errorf("unknown scope %q, scopes are %v", scope, scopes)
+		}
+	}
+
+	if grant.ExpiresAt != "" {
+		expiresAt, parseErr := time.Parse("2006-01-02 15:04:05", grant.ExpiresAt)
+		if parseErr != nil {
+			return models.SharingGrant{}, errors.New("expiresAt must be a UTC time in the format YYYY-MM-DD HH:MM:SS")
+		}
+		if !expiresAt.After(time.Now().UTC()) {
+			return models.SharingGrant{}, errors.New("expiresAt must be in the future")
+		}
+	}
+
+	grant.UserID = userID
+	slices.Sort(grant.Scopes)
+	grant.Scopes = slices.Compact(grant.Scopes)
+
+	return service.sharingGrantRepository.CreateGrant(grant), nil
+}
+
+func (service *sharingService) revokeGrant(userID string, sharingGrantID string) bool {
+	return service.sharingGrantRepository.RevokeGrant(userID, sharingGrantID)
+}
@@ -1,8 +1,12 @@
 package utils
 
 import (
+	"fmt"
+	"math"
+	"net/http"
 	"regexp"
 	"slices"
+	"strconv"
 	"strings"
 
 	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
@@ -14,6 +18,47 @@ func MatchURL(pattern string, url string) bool {
 	return re.MatchString(url)
 }
 
+func PathParams(pattern string, url string) []string {
+	re := regexp.MustCompile(pattern)
+	matches := re.FindStringSubmatch(url)
+	if matches == nil {
+		return make([]string, 0)
+	}
+	return matches[1:]
+}
+
+// IntParam parses an optional integer query parameter, a maximum of 0 means
+// there is no upper bound
+func IntParam(request *http.Request, name string, defaultValue int, minimum int, maximum int) (int, error) {
+
+	param := request.URL.Query().Get(name)
+	if param == "" {
+		return defaultValue, nil
+	}
+
+	value, parseErr := strconv.Atoi(param)
+	if parseErr != nil || value < minimum || (maximum > 0 && value > maximum) {
+		if maximum > 0 {
+			return 0, fmt.Errorf("%s must be between %d and %d", name, minimum, maximum)
+		}
+		return 0, fmt.Errorf("%s must be at least %d", name, minimum)
+	}
+
+	return value, nil
+}
+
+// PageOffset is how many items come before the page, a page so far out the
+// offset overflows starts past the end of any list
+func PageOffset(page int, pageSize int) int {
+	if page < 1 || pageSize < 1 {
+		return 0
+	}
+	if page-1 > math.MaxInt/pageSize {
+		return math.MaxInt
+	}
+	return (page - 1) * pageSize
+}
+
 func GetUserIDFromPath(path string) string {
 	splitPath := strings.Split(path, "/")
 	userIDIndex := slices.Index(splitPath, "users") + 1
@@ -74,38 +119,122 @@ func FindMood(currentMoods, previousMoods []models.TagFrequency) models.TagFrequ
 	return previousMood
 }
 
+// PreviousDates is the range of the same number of days ending the day before
 func PreviousDates(startDate string, endDate string) (string, string) {
 	layout := "2006-01-02"
 	startDateParsed, _ := time.Parse(layout, startDate)
 	endDateParsed, _ := time.Parse(layout, endDate)
 	diff := endDateParsed.Sub(startDateParsed)
-	numDays := int(diff.Hours() / 24)
+	numDays := int(diff.Hours()/24) + 1 // both dates are included
 	previousStartDate := startDateParsed.AddDate(0, 0, -numDays).Format(layout)
 	previousEndDate := startDateParsed.AddDate(0, 0, -1).Format(layout)
 	return previousStartDate, previousEndDate
 }
 
-func DetermineTrend(data []models.MovingAverage) string {
-	var trend string
-	lastIndex := len(data) - 1
-	secondLastIndex := len(data) - 2
-	if len(data) >= 2 {
-		last := data[lastIndex]
-		prev := data[secondLastIndex]
-		switch {
-		case last.MovingAvg > prev.MovingAvg:
-			trend = "increasing"
-		case last.MovingAvg < prev.MovingAvg:
-			trend = "decreasing"
-		default:
-			trend = "flat"
+// LastYearDates is the same range a year earlier, 29 February becomes 1 March
+func LastYearDates(startDate string, endDate string) (string, string) {
+	layout := "2006-01-02"
+	startDateParsed, _ := time.Parse(layout, startDate)
+	endDateParsed, _ := time.Parse(layout, endDate)
+	return startDateParsed.AddDate(-1, 0, 0).Format(layout), endDateParsed.AddDate(-1, 0, 0).Format(layout)
+}
+
+const (
+	trendSignificance = 0.05
+	trendMinDays      = 4
+	trendMaxDays      = 366 // both estimates compare every pair of days
+)
+
+// DetermineTrend estimates the trend over the whole series of daily values with
+// a Theil-Sen slope, the direction is only reported when the Mann-Kendall test
+// is significant so a single noisy day can't flip it. Longer series are thinned
+// to trendMaxDays evenly spaced days
+func DetermineTrend(data []models.DailyAverage) models.Trend {
+	layout := "2006-01-02"
+
+	trend := models.Trend{Direction: "not enough data", PValue: 1, NumDays: len(data)}
+	if len(data) < trendMinDays {
+		return trend
+	}
+	data = thinDays(data, trendMaxDays)
+
+	firstDate, _ := time.Parse(layout, data[0].Date)
+	days := make([]float64, len(data))
+	for i, dailyAverage := range data {
+		date, _ := time.Parse(layout, dailyAverage.Date)
+		days[i] = date.Sub(firstDate).Hours() / 24
+	}
+
+	var slopes []float64
+	s := 0.0
+	for i := 0; i < len(data)-1; i++ {
+		for j := i + 1; j < len(data); j++ {
+			diff := data[j].DailyAvg - data[i].DailyAvg
+			slopes = append(slopes, diff/(days[j]-days[i]))
+			switch {
+			case diff > 0:
+				s++
+			case diff < 0:
+				s--
+			}
 		}
-	} else {
-		trend = "not enough data"
 	}
+
+	slices.Sort(slopes)
+	middle := len(slopes) / 2
+	slope := slopes[middle]
+	if len(slopes)%2 == 0 {
+		slope = (slopes[middle-1] + slopes[middle]) / 2
+	}
+	trend.SlopePerWeek = slope * 7
+
+	// variance of S with the correction for tied values
+	n := float64(len(data))
+	variance := n * (n - 1) * (2*n + 5)
+	ties := make(map[float64]float64)
+	for _, dailyAverage := range data {
+		ties[dailyAverage.DailyAvg]++
+	}
+	for _, t := range ties {
+		variance -= t * (t - 1) * (2*t + 5)
+	}
+	variance /= 18
+
+	var z float64
+	switch {
+	case variance <= 0:
+		z = 0
+	case s > 0:
+		z = (s - 1) / math.Sqrt(variance)
+	case s < 0:
+		z = (s + 1) / math.Sqrt(variance)
+	}
+	trend.PValue = math.Erfc(math.Abs(z) / math.Sqrt2)
+
+	switch {
+	case trend.PValue >= trendSignificance:
+		trend.Direction = "stable"
+	case s > 0:
+		trend.Direction = "increasing"
+	default:
+		trend.Direction = "decreasing"
+	}
+
 	return trend
 }
 
+// thinDays keeps at most limit days spread evenly from the first to the last
+func thinDays(data []models.DailyAverage, limit int) []models.DailyAverage {
+	if len(data) <= limit {
+		return data
+	}
+	thinned := make([]models.DailyAverage, limit)
+	for i := range thinned {
+		thinned[i] = data[i*(len(data)-1)/(limit-1)]
+	}
+	return thinned
+}
+
 func Granularity(numDays int) string {
 	var granularity string
 	switch {
@@ -129,6 +258,225 @@ func NumDaysBetween(startDate string, endDate string) int {
 	return int(diff.Hours() / 24)
 }
 
+// MonthRanges splits the range into calendar months, the first and last month
+// are cut to the start and end date. A date that doesn't parse gives no months
+func MonthRanges(startDate string, endDate string) []models.DateRange {
+	layout := "2006-01-02"
+	startDateParsed, startErr := time.Parse(layout, startDate)
+	endDateParsed, endErr := time.Parse(layout, endDate)
+
+	monthRanges := make([]models.DateRange, 0)
+	if startErr != nil || endErr != nil {
+		return monthRanges
+	}
+	for monthStart := startDateParsed; !monthStart.After(endDateParsed); {
+		nextMonth := time.Date(monthStart.Year(), monthStart.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		monthEnd := nextMonth.AddDate(0, 0, -1)
+		if monthEnd.After(endDateParsed) {
+			monthEnd = endDateParsed
+		}
+		monthRanges = append(monthRanges, models.DateRange{
+			StartDate: monthStart.Format(layout),
+			EndDate:   monthEnd.Format(layout),
+		})
+		monthStart = nextMonth
+	}
+	return monthRanges
+}
+
+// WindowStart is the first date a window of the given number of days ending on
+// startDate covers, daily values for a time series are loaded from here
+func WindowStart(startDate string, window int) string {
+	layout := "2006-01-02"
+	startDateParsed, _ := time.Parse(layout, startDate)
+	return startDateParsed.AddDate(0, 0, -(window - 1)).Format(layout)
+}
+
+// TimeSeries computes a moving average over a calendar window of the given
+// number of days ending on each date, days without logs don't count towards the
+// average. dailyAverages should start at WindowStart so the first dates have a
+// full window, only the dates from startDate on are returned. With fillGaps
+// every day in the range is returned
+func TimeSeries(dailyAverages []models.DailyAverage, startDate string, endDate string, window int, fillGaps bool) []models.TimeSeriesPoint {
+	layout := "2006-01-02"
+
+	byDate := make(map[string]float64)
+	for _, dailyAverage := range dailyAverages {
+		byDate[dailyAverage.Date] = dailyAverage.DailyAvg
+	}
+
+	startDateParsed, _ := time.Parse(layout, startDate)
+	endDateParsed, _ := time.Parse(layout, endDate)
+
+	timeSeries := make([]models.TimeSeriesPoint, 0)
+	for day := startDateParsed; !day.After(endDateParsed); day = day.AddDate(0, 0, 1) {
+		date := day.Format(layout)
+		dailyAvg, logged := byDate[date]
+		if !logged && !fillGaps {
+			continue
+		}
+
+		sum := 0.0
+		count := 0
+		for i := 0; i < window; i++ {
+			if value, exists := byDate[day.AddDate(0, 0, -i).Format(layout)]; exists {
+				sum += value
+				count++
+			}
+		}
+		var movingAvg float64
+		if count > 0 {
+			movingAvg = sum / float64(count)
+		}
+
+		timeSeries = append(timeSeries, models.TimeSeriesPoint{
+			Date:      date,
+			DailyAvg:  dailyAvg,
+			MovingAvg: movingAvg,
+			Logged:    logged,
+		})
+	}
+
+	return timeSeries
+}
+
+// LabelBuckets names each bucket by its period (2024-03-07, 2024-W10 or
+// 2024-03) and clamps its start and end to the requested range
+func LabelBuckets(buckets []models.Bucket, granularity string, startDate string, endDate string) {
+	layout := "2006-01-02"
+
+	for i := range buckets {
+		periodStart, _ := time.Parse(layout, buckets[i].StartDate)
+
+		var periodEnd time.Time
+		switch granularity {
+		case "week":
+			year, week := periodStart.ISOWeek()
+			buckets[i].Period = fmt.Sprintf("%d-W%02d", year, week)
+			periodEnd = periodStart.AddDate(0, 0, 6)
+		case "month":
+			buckets[i].Period = periodStart.Format("2006-01")
+			periodEnd = periodStart.AddDate(0, 1, -1)
+		default:
+			buckets[i].Period = periodStart.Format(layout)
+			periodEnd = periodStart
+		}
+
+		buckets[i].StartDate = max(periodStart.Format(layout), startDate)
+		buckets[i].EndDate = min(periodEnd.Format(layout), endDate)
+	}
+}
+
+const (
+	anomalyWindowDays   = 28
+	anomalyMinBaseline  = 7
+	anomalyZThreshold   = 3.5
+	madConsistencyConst = 0.6745
+)
+
+// smallest median absolute deviation used for anomalies, in rating points and
+// hours, so a user who always logs the same value isn't flagged for a one
+// point change
+const (
+	MinMoodMAD  = 0.5
+	MinSleepMAD = 0.5
+)
+
+// AnomalyBaselineStart is how far before startDate daily values have to be
+// loaded so the first day in the range has a full trailing window
+func AnomalyBaselineStart(startDate string) string {
+	layout := "2006-01-02"
+	startDateParsed, _ := time.Parse(layout, startDate)
+	return startDateParsed.AddDate(0, 0, -anomalyWindowDays).Format(layout)
+}
+
+// DetectAnomalies flags days from startDate on whose value is far from the
+// median of the trailing window before it, using the modified z-score
+// 0.6745 * (x - median) / MAD. minMAD stops a very regular baseline from
+// flagging tiny changes
+func DetectAnomalies(dailyAverages []models.DailyAverage, startDate string, minMAD float64) []models.Anomaly {
+	layout := "2006-01-02"
+
+	anomalies := make([]models.Anomaly, 0)
+	for i, dailyAverage := range dailyAverages {
+		if dailyAverage.Date < startDate {
+			continue
+		}
+
+		date, _ := time.Parse(layout, dailyAverage.Date)
+		windowStart := date.AddDate(0, 0, -anomalyWindowDays).Format(layout)
+
+		var baseline []float64
+		for j := i - 1; j >= 0 && dailyAverages[j].Date >= windowStart; j-- {
+			baseline = append(baseline, dailyAverages[j].DailyAvg)
+		}
+		if len(baseline) < anomalyMinBaseline {
+			continue
+		}
+
+		med := median(baseline)
+		deviations := make([]float64, len(baseline))
+		for j, value := range baseline {
+			deviations[j] = math.Abs(value - med)
+		}
+		mad := math.Max(median(deviations), minMAD)
+
+		deviation := dailyAverage.DailyAvg - med
+		robustZ := madConsistencyConst * deviation / mad
+		if math.Abs(robustZ) < anomalyZThreshold {
+			continue
+		}
+
+		direction := "high"
+		if deviation < 0 {
+			direction = "low"
+		}
+		anomalies = append(anomalies, models.Anomaly{
+			Date:      dailyAverage.Date,
+			Value:     dailyAverage.DailyAvg,
+			Baseline:  med,
+			Deviation: deviation,
+			RobustZ:   robustZ,
+			Direction: direction,
+		})
+	}
+
+	return anomalies
+}
+
+func median(values []float64) float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// LocalToday is the current date in the timezone. A name the runtime doesn't
+// know is an error, settings from before the timezone was validated can hold one
+func LocalToday(timezone string) (string, error) {
+	location, loadErr := LoadTimezone(timezone)
+	if loadErr != nil {
+		return "", loadErr
+	}
+	return time.Now().In(location).Format("2006-01-02"), nil
+}
+
+// LoadTimezone loads a user's timezone, "Local" is refused as it's the
+// server's zone rather than theirs
+func LoadTimezone(timezone string) (*time.Location, error) {
+	if timezone == "Local" {
+		return nil, fmt.Errorf("unknown timezone %q", timezone)
+	}
+	location, loadErr := time.LoadLocation(timezone)
+	if loadErr != nil {
+		return nil, fmt.Errorf("unknown timezone %q", timezone)
+	}
+	return location, nil
+}
+
 func PercentChange(a, b float64) float64 {
 	return ((a - b) / b) * 100
 }
@@ -136,7 +484,3 @@ func PercentChange(a, b float64) float64 {
 func BothContainValues[T any](a, b []T) bool {
 	return len(a) != 0 && len(b) != 0
 }
-
-func DifferenceInLength[T any](a, b []T) int {
-	return len(a) - len(b)
-}
@@ -0,0 +1,311 @@
+package utils
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+// consecutiveDays gives the values one day apart from the start date
+func consecutiveDays(startDate string, values ...float64) []models.DailyAverage {
+	start, _ := time.Parse("2006-01-02", startDate)
+	dailyAverages := make([]models.DailyAverage, len(values))
+	for i, value := range values {
+		dailyAverages[i] = models.DailyAverage{Date: start.AddDate(0, 0, i).Format("2006-01-02"), DailyAvg: value}
+	}
+	return dailyAverages
+}
+
+func TestDetectAnomalies(t *testing.T) {
+	tests := []struct {
+		name          string
+		dailyAverages []models.DailyAverage
+		startDate     string
+		minMAD        float64
+		want          []models.Anomaly
+	}{
+		{
+			name:          "flat baseline below the threshold",
+			dailyAverages: consecutiveDays("2025-01-01", 5, 5, 5, 5, 5, 5, 5, 7),
+			startDate:     "2025-01-08",
+			minMAD:        MinMoodMAD,
+			want:          []models.Anomaly{},
+		},
+		{
+			name:          "flat baseline uses the minimum MAD",
+			dailyAverages: consecutiveDays("2025-01-01", 5, 5, 5, 5, 5, 5, 5, 8),
+			startDate:     "2025-01-08",
+			minMAD:        MinMoodMAD,
+			want:          []models.Anomaly{{Date: "2025-01-08", Value: 8, Baseline: 5, Deviation: 3, RobustZ: 4.047, Direction: "high"}},
+		},
+		{
+			name:          "low day",
+			dailyAverages: consecutiveDays("2025-01-01", 5, 5, 5, 5, 5, 5, 5, 2),
+			startDate:     "2025-01-08",
+			minMAD:        MinMoodMAD,
+			want:          []models.Anomaly{{Date: "2025-01-08", Value: 2, Baseline: 5, Deviation: -3, RobustZ: -4.047, Direction: "low"}},
+		},
+		{
+			name:          "spread baseline uses its own MAD",
+			dailyAverages: consecutiveDays("2025-01-01", 4, 5, 6, 4, 5, 6, 4, 5, 10, 11),
+			startDate:     "2025-01-09",
+			minMAD:        MinMoodMAD,
+			// the 10 enters the baseline of the 11, the median moves to 5 and the MAD stays 1
+			want: []models.Anomaly{{Date: "2025-01-10", Value: 11, Baseline: 5, Deviation: 6, RobustZ: 4.047, Direction: "high"}},
+		},
+		{
+			name:          "too short a baseline",
+			dailyAverages: consecutiveDays("2025-01-01", 5, 5, 5, 5, 5, 5, 9),
+			startDate:     "2025-01-07",
+			minMAD:        MinMoodMAD,
+			want:          []models.Anomaly{},
+		},
+		{
+			name:          "days before the start date are only baseline",
+			dailyAverages: consecutiveDays("2025-01-01", 5, 5, 5, 5, 5, 5, 5, 9, 5),
+			startDate:     "2025-01-09",
+			minMAD:        MinMoodMAD,
+			want:          []models.Anomaly{},
+		},
+		{
+			name: "logs older than the window aren't baseline",
+			dailyAverages: append(consecutiveDays("2025-01-01", 5, 5, 5, 5, 5, 5, 5),
+				models.DailyAverage{Date: "2025-02-15", DailyAvg: 9}),
+			startDate: "2025-02-15",
+			minMAD:    MinMoodMAD,
+			want:      []models.Anomaly{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := DetectAnomalies(test.dailyAverages, test.startDate, test.minMAD)
+			if len(got) != len(test.want) {
+				t.Fatalf("DetectAnomalies() = %+v, want %+v", got, test.want)
+			}
+			for i, anomaly := range got {
+				want := test.want[i]
+				if anomaly.Date != want.Date || anomaly.Direction != want.Direction ||
+					anomaly.Value != want.Value || anomaly.Baseline != want.Baseline ||
+					anomaly.Deviation != want.Deviation || math.Abs(anomaly.RobustZ-want.RobustZ) > 1e-3 {
+					t.Errorf("anomaly %d = %+v, want %+v", i, anomaly, want)
+				}
+			}
+		})
+	}
+}
+
+func TestMedian(t *testing.T) {
+	tests := []struct {
+		values []float64
+		want   float64
+	}{
+		{[]float64{3}, 3},
+		{[]float64{3, 1, 2}, 2},
+		{[]float64{4, 1, 3, 2}, 2.5},
+	}
+	for _, test := range tests {
+		if got := median(test.values); got != test.want {
+			t.Errorf("median(%v) = %v, want %v", test.values, got, test.want)
+		}
+	}
+}
+
+func TestAnomalyBaselineStart(t *testing.T) {
+	if got := AnomalyBaselineStart("2025-03-01"); got != "2025-02-01" {
+		t.Errorf("AnomalyBaselineStart(2025-03-01) = %v, want 2025-02-01", got)
+	}
+}
+
+func TestDetermineTrend(t *testing.T) {
+	rising := make([]float64, 30)
+	noisy := make([]float64, 30)
+	for i := range rising {
+		rising[i] = 3 + 0.1*float64(i)
+		noisy[i] = 5 + float64(i%2)
+	}
+	long := make([]float64, 1000)
+	for i := range long {
+		long[i] = 8 - 0.002*float64(i)
+	}
+
+	tests := []struct {
+		name          string
+		dailyAverages []models.DailyAverage
+		wantDirection string
+		wantSlope     float64
+	}{
+		{"too few days", consecutiveDays("2025-01-01", 5, 6, 7), "not enough data", 0},
+		{"rising", consecutiveDays("2025-01-01", rising...), "increasing", 0.7},
+		{"falling", consecutiveDays("2025-01-01", 9, 8, 7, 6, 5, 4, 3, 2), "decreasing", -7},
+		{"alternating", consecutiveDays("2025-01-01", noisy...), "stable", 0},
+		{"one outlier doesn't flip it", consecutiveDays("2025-01-01", 4, 4.1, 4.2, 4.3, 4.4, 4.5, 4.6, 4.7, 4.8, 1), "increasing", 0.7},
+		{"long series are thinned", consecutiveDays("2020-01-01", long...), "decreasing", -0.014},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			trend := DetermineTrend(test.dailyAverages)
+			if trend.Direction != test.wantDirection {
+				t.Errorf("direction = %v, want %v (p %v)", trend.Direction, test.wantDirection, trend.PValue)
+			}
+			if math.Abs(trend.SlopePerWeek-test.wantSlope) > 1e-6 {
+				t.Errorf("slope per week = %v, want %v", trend.SlopePerWeek, test.wantSlope)
+			}
+			if trend.NumDays != len(test.dailyAverages) {
+				t.Errorf("numDays = %v, want %v", trend.NumDays, len(test.dailyAverages))
+			}
+		})
+	}
+}
+
+func TestThinDays(t *testing.T) {
+	data := consecutiveDays("2025-01-01", 0, 1, 2, 3, 4, 5, 6, 7, 8, 9)
+	thinned := thinDays(data, 4)
+	want := []float64{0, 3, 6, 9}
+	for i, dailyAverage := range thinned {
+		if dailyAverage.DailyAvg != want[i] {
+			t.Fatalf("thinDays kept %+v, want values %v", thinned, want)
+		}
+	}
+	if len(thinDays(data, 20)) != len(data) {
+		t.Errorf("thinDays dropped days from a short series")
+	}
+}
+
+func TestTimeSeries(t *testing.T) {
+	// loaded from WindowStart("2025-01-04", 3), the 2nd has no log
+	dailyAverages := []models.DailyAverage{
+		{Date: "2025-01-02", DailyAvg: 2},
+		{Date: "2025-01-03", DailyAvg: 4},
+		{Date: "2025-01-04", DailyAvg: 6},
+		{Date: "2025-01-06", DailyAvg: 8},
+	}
+	if start := WindowStart("2025-01-04", 3); start != "2025-01-02" {
+		t.Fatalf("WindowStart = %v, want 2025-01-02", start)
+	}
+
+	tests := []struct {
+		name     string
+		fillGaps bool
+		want     []models.TimeSeriesPoint
+	}{
+		{
+			name: "logged days",
+			want: []models.TimeSeriesPoint{
+				{Date: "2025-01-04", DailyAvg: 6, MovingAvg: 4, Logged: true},
+				{Date: "2025-01-06", DailyAvg: 8, MovingAvg: 7, Logged: true},
+			},
+		},
+		{
+			name:     "gaps filled",
+			fillGaps: true,
+			want: []models.TimeSeriesPoint{
+				{Date: "2025-01-04", DailyAvg: 6, MovingAvg: 4, Logged: true},
+				{Date: "2025-01-05", DailyAvg: 0, MovingAvg: 5, Logged: false},
+				{Date: "2025-01-06", DailyAvg: 8, MovingAvg: 7, Logged: true},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := TimeSeries(dailyAverages, "2025-01-04", "2025-01-06", 3, test.fillGaps)
+			if len(got) != len(test.want) {
+				t.Fatalf("TimeSeries() = %+v, want %+v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("point %d = %+v, want %+v", i, got[i], test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMonthRanges(t *testing.T) {
+	tests := []struct {
+		name      string
+		startDate string
+		endDate   string
+		want      []models.DateRange
+	}{
+		{"within a month", "2025-01-05", "2025-01-20", []models.DateRange{{StartDate: "2025-01-05", EndDate: "2025-01-20"}}},
+		{"cut at both ends", "2025-01-20", "2025-03-10", []models.DateRange{
+			{StartDate: "2025-01-20", EndDate: "2025-01-31"},
+			{StartDate: "2025-02-01", EndDate: "2025-02-28"},
+			{StartDate: "2025-03-01", EndDate: "2025-03-10"},
+		}},
+		{"across the year", "2024-12-31", "2025-01-01", []models.DateRange{
+			{StartDate: "2024-12-31", EndDate: "2024-12-31"},
+			{StartDate: "2025-01-01", EndDate: "2025-01-01"},
+		}},
+		{"start after end", "2025-02-01", "2025-01-01", []models.DateRange{}},
+		{"missing dates", "", "", []models.DateRange{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := MonthRanges(test.startDate, test.endDate)
+			if len(got) != len(test.want) {
+				t.Fatalf("MonthRanges() = %+v, want %+v", got, test.want)
+			}
+			for i := range got {
+				if got[i] != test.want[i] {
+					t.Errorf("month %d = %+v, want %+v", i, got[i], test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPreviousDates(t *testing.T) {
+	tests := []struct {
+		startDate, endDate string
+		wantStart, wantEnd string
+	}{
+		{"2025-08-01", "2025-08-14", "2025-07-18", "2025-07-31"},
+		{"2025-08-01", "2025-08-01", "2025-07-31", "2025-07-31"},
+		{"2025-03-01", "2025-03-31", "2025-01-29", "2025-02-28"},
+	}
+	for _, test := range tests {
+		start, end := PreviousDates(test.startDate, test.endDate)
+		if start != test.wantStart || end != test.wantEnd {
+			t.Errorf("PreviousDates(%v, %v) = %v, %v, want %v, %v", test.startDate, test.endDate, start, end, test.wantStart, test.wantEnd)
+		}
+		if NumDaysBetween(start, end) != NumDaysBetween(test.startDate, test.endDate) {
+			t.Errorf("previous range %v to %v isn't as long as %v to %v", start, end, test.startDate, test.endDate)
+		}
+	}
+}
+
+func TestPageOffset(t *testing.T) {
+	tests := []struct {
+		page, pageSize int
+		want           int
+	}{
+		{1, 20, 0},
+		{3, 20, 40},
+		{0, 20, 0},
+		{math.MaxInt, 20, math.MaxInt},
+		{math.MaxInt/20 + 1, 20, 20 * (math.MaxInt / 20)},
+		{math.MaxInt/20 + 2, 20, math.MaxInt},
+	}
+	for _, test := range tests {
+		if got := PageOffset(test.page, test.pageSize); got != test.want {
+			t.Errorf("PageOffset(%d, %d) = %d, want %d", test.page, test.pageSize, got, test.want)
+		}
+	}
+}
+
+func TestLocalToday(t *testing.T) {
+	today, todayErr := LocalToday("Pacific/Kiritimati")
+	if want := time.Now().UTC().Add(14 * time.Hour).Format("2006-01-02"); todayErr != nil || today != want {
+		t.Errorf("LocalToday(Pacific/Kiritimati) = %q, %v, want %q", today, todayErr, want)
+	}
+
+	// a bad stored zone fails rather than quietly using UTC's day
+	for _, timezone := range []string{"Mars/Olympus_Mons", "Local", "../../etc/passwd"} {
+		if today, todayErr := LocalToday(timezone); todayErr == nil {
+			t.Errorf("LocalToday(%q) = %q, want an error", timezone, today)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/auth"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/utils"
+)
+
+type WebhooksHandler struct {
+	webhooksService *webhooksService
+}
+
+var webhooksSubscriptions string = `^/webhooks/subscriptions$`
+var webhooksSubscription string = `^/webhooks/subscriptions/([0-9]+)$`
+var webhooksSubscriptionDeliveries string = `^/webhooks/subscriptions/([0-9]+)/deliveries$`
+var webhooksSubscriptionDeadLetters string = `^/webhooks/subscriptions/([0-9]+)/dead-letters$`
+
+func NewWebhooksHandler(webhooksService *webhooksService) *WebhooksHandler {
+	return &WebhooksHandler{
+		webhooksService: webhooksService,
+	}
+}
+
+func (handler *WebhooksHandler) ProcessRequest(writer http.ResponseWriter, request *http.Request) {
+
+	identity, signedIn := auth.RequireIdentity(writer, request)
+	if !signedIn {
+		return
+	}
+
+	switch {
+	case utils.MatchURL(webhooksSubscriptions, request.URL.Path) && request.Method == http.MethodPost:
+
+		var subscription models.WebhookSubscription
+		decodeErr := json.NewDecoder(request.Body).Decode(&subscription)
+		if decodeErr != nil {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(decodeErr.Error()))
+			return
+		}
+
+		created, createErr := handler.webhooksService.createSubscription(identity, subscription)
+		if createErr != nil {
+			writeError(writer, createErr)
+			return
+		}
+		body, _ := json.Marshal(created)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.WriteHeader(http.StatusCreated)
+		writer.Write(body)
+
+	case utils.MatchURL(webhooksSubscriptions, request.URL.Path) && request.Method == http.MethodGet:
+
+		userID := request.URL.Query().Get("userId")
+		organisationID := request.URL.Query().Get("organisationId")
+
+		subscriptions, subscriptionsErr := handler.webhooksService.subscriptions(identity, userID, organisationID)
+		if subscriptionsErr != nil {
+			writeError(writer, subscriptionsErr)
+			return
+		}
+		body, _ := json.Marshal(subscriptions)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+
+	case utils.MatchURL(webhooksSubscription, request.URL.Path) && request.Method == http.MethodDelete:
+
+		subscriptionID := utils.PathParams(webhooksSubscription, request.URL.Path)[0]
+
+		if deactivateErr := handler.webhooksService.deactivateSubscription(identity, subscriptionID); deactivateErr != nil {
+			writeError(writer, deactivateErr)
+			return
+		}
+
+		writer.WriteHeader(http.StatusNoContent)
+
+	case utils.MatchURL(webhooksSubscriptionDeliveries, request.URL.Path):
+
+		subscriptionID := utils.PathParams(webhooksSubscriptionDeliveries, request.URL.Path)[0]
+		limit, limitErr := strconv.Atoi(request.URL.Query().Get("limit"))
+		if limitErr != nil || limit <= 0 {
+			limit = 100
+		}
+
+		deliveries, deliveriesErr := handler.webhooksService.deliveries(identity, subscriptionID, limit)
+		if deliveriesErr != nil {
+			writeError(writer, deliveriesErr)
+			return
+		}
+		body, _ := json.Marshal(deliveries)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+
+	case utils.MatchURL(webhooksSubscriptionDeadLetters, request.URL.Path):
+
+		subscriptionID := utils.PathParams(webhooksSubscriptionDeadLetters, request.URL.Path)[0]
+
+		deadLetters, deadLettersErr := handler.webhooksService.deadLetters(identity, subscriptionID)
+		if deadLettersErr != nil {
+			writeError(writer, deadLettersErr)
+			return
+		}
+		body, _ := json.Marshal(deadLetters)
+
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Write(body)
+
+	default:
+		writer.WriteHeader(http.StatusNotFound)
+		writer.Write([]byte("404 path not found"))
+	}
+}
+
+func writeError(writer http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errSubscriptionNotFound):
+		writer.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, errNotSubscriptionOwner):
+		writer.WriteHeader(http.StatusForbidden)
+	default:
+		writer.WriteHeader(http.StatusBadRequest)
+	}
+	writer.Write([]byte(err.Error()))
+}
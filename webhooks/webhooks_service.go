@@ -0,0 +1,316 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/database"
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+const (
+	batchSize        = 50
+	maxAttempts      = 6
+	baseRetrySeconds = 30 // 30s, 1m, 2m, 4m, 8m then dead-lettered
+	deliveryTimeout  = 10 * time.Second
+	signatureHeader  = "X-Horizon-Signature"
+	timestampHeader  = "X-Horizon-Timestamp"
+	eventTypeHeader  = "X-Horizon-Event"
+	maxErrorLength   = 1024 // webhook_delivery_attempt.error and webhook_dead_letter.last_error
+)
+
+var supportedEventTypes = []string{
+	models.EventMoodLogCreated,
+	models.EventAlertRaised,
+	models.EventEpisodeDetected,
+	models.EventImportCompleted,
+}
+
+var errSubscriptionNotFound = errors.New("subscription not found")
+var errNotSubscriptionOwner = errors.New("only the user or an admin of the organisation can manage its webhooks")
+
+type webhooksService struct {
+	webhookRepository *database.WebhookRepository
+	client            *http.Client
+	checkAddress      func(ip net.IP) error
+}
+
+func NewWebhooksService(webhookRepository *database.WebhookRepository) *webhooksService {
+	return &webhooksService{
+		webhookRepository: webhookRepository,
+		client:            deliveryClient(publicAddress),
+		checkAddress:      publicAddress,
+	}
+}
+
+// deliveryClient checks every address it connects to, so a host that
+// resolves somewhere else after the subscription was created, or redirects
+// there, is still refused
+func deliveryClient(checkAddress func(ip net.IP) error) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: deliveryTimeout,
+		Control: func(network string, address string, conn syscall.RawConn) error {
+			host, _, splitErr := net.SplitHostPort(address)
+			if splitErr != nil {
+				return splitErr
+			}
+			return checkAddress(net.ParseIP(host))
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Timeout: deliveryTimeout, Transport: transport}
+}
+
+// publicAddress refuses addresses inside our own network, webhooks are for
+// partners on the internet
+func publicAddress(ip net.IP) error {
+	if ip == nil {
+		return fmt.Errorf("webhook address isn't an ip")
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast() {
+		return fmt.Errorf("webhook address %s isn't public", ip)
+	}
+	return nil
+}
+
+// Start fans out published events and delivers due webhooks on each tick
+func (service *webhooksService) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			service.dispatchSafely()
+		}
+	}()
+}
+
+func (service *webhooksService) dispatchSafely() {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("ERROR dispatching webhooks", r)
+		}
+	}()
+
+	for _, event := range service.webhookRepository.UndispatchedEvents(batchSize) {
+		service.webhookRepository.FanOutEvent(event)
+	}
+
+	for _, delivery := range service.webhookRepository.DueDeliveries(batchSize) {
+		service.deliver(delivery)
+	}
+}
+
+func (service *webhooksService) deliver(delivery models.WebhookDelivery) {
+
+	attempt := service.send(delivery)
+	attemptNumber := attempt.AttemptNumber
+
+	service.webhookRepository.RecordAttempt(delivery.DeliveryID, attempt)
+
+	switch {
+	case attempt.Error == "":
+		service.webhookRepository.MarkDelivered(delivery.DeliveryID)
+	case attemptNumber >= maxAttempts:
+		service.webhookRepository.DeadLetter(delivery.DeliveryID, attempt.Error)
+	default:
+		service.webhookRepository.ScheduleRetry(delivery.DeliveryID, retryDelaySeconds(attemptNumber))
+	}
+}
+
+// send posts the signed event to the subscriber, a failed attempt has an error
+func (service *webhooksService) send(delivery models.WebhookDelivery) models.WebhookDeliveryAttempt {
+
+	attempt := models.WebhookDeliveryAttempt{AttemptNumber: delivery.AttemptCount + 1}
+
+	body, _ := json.Marshal(delivery.Event)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	request, requestErr := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if requestErr != nil {
+		attempt.Error = requestErr.Error()
+	} else {
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set(eventTypeHeader, delivery.Event.EventType)
+		request.Header.Set(timestampHeader, timestamp)
+		request.Header.Set(signatureHeader, "sha256="+Sign(delivery.Secret, timestamp, body))
+
+		started := time.Now()
+		response, responseErr := service.client.Do(request)
+		attempt.DurationMs = int(time.Since(started).Milliseconds())
+
+		if responseErr != nil {
+			attempt.Error = responseErr.Error()
+		} else {
+			response.Body.Close()
+			attempt.StatusCode = response.StatusCode
+			if response.StatusCode < 200 || response.StatusCode > 299 {
+				attempt.Error = fmt.Sprintf("unexpected status %d", response.StatusCode)
+			}
+		}
+	}
+
+	// transport errors quote the url, which the subscriber chooses
+	attempt.Error = truncate(attempt.Error, maxErrorLength)
+
+	return attempt
+}
+
+// truncate cuts the string to at most maxLength characters
+func truncate(value string, maxLength int) string {
+	runes := []rune(value)
+	if len(runes) <= maxLength {
+		return value
+	}
+	return string(runes[:maxLength])
+}
+
+func retryDelaySeconds(attemptNumber int) int {
+	return baseRetrySeconds * (1 << (attemptNumber - 1))
+}
+
+// Sign computes the hex encoded HMAC-SHA256 of "timestamp.body", receivers
+// recompute it with the subscription secret to verify a delivery
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// canManage is whether the caller owns the subscription, either as its user or
+// as an admin of its organisation
+func (service *webhooksService) canManage(identity models.Identity, subscription models.WebhookSubscription) bool {
+	if subscription.UserID != "" {
+		return subscription.UserID == identity.UserID
+	}
+	return service.webhookRepository.IsOrganisationAdmin(subscription.OrganisationID, identity.UserID)
+}
+
+// ownedSubscription is the subscription when the caller can manage it, others
+// get errSubscriptionNotFound so ids can't be probed
+func (service *webhooksService) ownedSubscription(identity models.Identity, subscriptionID string) (models.WebhookSubscription, error) {
+	subscription, found := service.webhookRepository.Subscription(subscriptionID)
+	if !found || !service.canManage(identity, subscription) {
+		return models.WebhookSubscription{}, errSubscriptionNotFound
+	}
+	return subscription, nil
+}
+
+// validateURL accepts http(s) urls whose host only resolves to public addresses
+func (service *webhooksService) validateURL(rawURL string) error {
+
+	parsed, parseErr := url.Parse(rawURL)
+	if parseErr != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return fmt.Errorf("url must be an http or https url")
+	}
+
+	addresses, lookupErr := net.DefaultResolver.LookupIPAddr(context.Background(), parsed.Hostname())
+	if lookupErr != nil || len(addresses) == 0 {
+		return fmt.Errorf("url host %s doesn't resolve", parsed.Hostname())
+	}
+	for _, address := range addresses {
+		if addressErr := service.checkAddress(address.IP); addressErr != nil {
+			return addressErr
+		}
+	}
+
+	return nil
+}
+
+func (service *webhooksService) createSubscription(identity models.Identity, subscription models.WebhookSubscription) (models.WebhookSubscription, error) {
+
+	if subscription.URL == "" {
+		return subscription, fmt.Errorf("url is required")
+	}
+	if (subscription.UserID == "") == (subscription.OrganisationID == "") {
+		return subscription, fmt.Errorf("exactly one of userId or organisationId is required")
+	}
+	if !service.canManage(identity, subscription) {
+		return subscription, errNotSubscriptionOwner
+	}
+	if urlErr := service.validateURL(subscription.URL); urlErr != nil {
+		return subscription, urlErr
+	}
+	if len(subscription.EventTypes) == 0 {
+		subscription.EventTypes = supportedEventTypes
+	}
+	for _, eventType := range subscription.EventTypes {
+		if !isSupportedEventType(eventType) {
+			return subscription, fmt.Errorf("unsupported event type %s", eventType)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, randErr := rand.Read(secret); randErr != nil {
+		panic(randErr)
+	}
+	subscription.Secret = hex.EncodeToString(secret)
+
+	subscriptionID := service.webhookRepository.CreateSubscription(subscription)
+
+	created, _ := service.webhookRepository.Subscription(strconv.Itoa(subscriptionID))
+	created.Secret = subscription.Secret
+
+	return created, nil
+}
+
+func isSupportedEventType(eventType string) bool {
+	for _, supported := range supportedEventTypes {
+		if eventType == supported {
+			return true
+		}
+	}
+	return false
+}
+
+func (service *webhooksService) subscriptions(identity models.Identity, userID string, organisationID string) ([]models.WebhookSubscription, error) {
+	if (userID == "") == (organisationID == "") {
+		return nil, fmt.Errorf("exactly one of userId or organisationId is required")
+	}
+	if !service.canManage(identity, models.WebhookSubscription{UserID: userID, OrganisationID: organisationID}) {
+		return nil, errNotSubscriptionOwner
+	}
+	return service.webhookRepository.Subscriptions(userID, organisationID), nil
+}
+
+func (service *webhooksService) deactivateSubscription(identity models.Identity, subscriptionID string) error {
+	if _, ownedErr := service.ownedSubscription(identity, subscriptionID); ownedErr != nil {
+		return ownedErr
+	}
+	if deactivated := service.webhookRepository.DeactivateSubscription(subscriptionID); !deactivated {
+		return errSubscriptionNotFound
+	}
+	return nil
+}
+
+func (service *webhooksService) deliveries(identity models.Identity, subscriptionID string, limit int) ([]models.WebhookDelivery, error) {
+	if _, ownedErr := service.ownedSubscription(identity, subscriptionID); ownedErr != nil {
+		return nil, ownedErr
+	}
+	return service.webhookRepository.Deliveries(subscriptionID, limit), nil
+}
+
+func (service *webhooksService) deadLetters(identity models.Identity, subscriptionID string) ([]models.WebhookDeadLetter, error) {
+	if _, ownedErr := service.ownedSubscription(identity, subscriptionID); ownedErr != nil {
+		return nil, ownedErr
+	}
+	return service.webhookRepository.DeadLetters(subscriptionID), nil
+}
@@ -0,0 +1,179 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/michaeljosephroddy/project-horizon-backend-go/models"
+)
+
+// receiver is a stand-in partner endpoint like cmd/webhook-receiver, it
+// rejects deliveries it can't verify with the secret and records the rest
+type receiver struct {
+	secret   string
+	status   int
+	received []receivedDelivery
+}
+
+type receivedDelivery struct {
+	eventType string
+	body      []byte
+}
+
+func (r *receiver) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	body, _ := io.ReadAll(request.Body)
+
+	expected := "sha256=" + Sign(r.secret, request.Header.Get(timestampHeader), body)
+	if !hmac.Equal([]byte(expected), []byte(request.Header.Get(signatureHeader))) {
+		writer.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	r.received = append(r.received, receivedDelivery{eventType: request.Header.Get(eventTypeHeader), body: body})
+	writer.WriteHeader(r.status)
+}
+
+func testDelivery(url string, secret string) models.WebhookDelivery {
+	return models.WebhookDelivery{
+		DeliveryID:   1,
+		AttemptCount: 2,
+		URL:          url,
+		Secret:       secret,
+		Event: models.WebhookEvent{
+			EventID:   9,
+			UserID:    "1",
+			EventType: models.EventEpisodeDetected,
+			CreatedAt: "2025-08-10 09:00:00",
+			Data:      json.RawMessage(`{"episodeType":"depressive","startDate":"2025-07-20"}`),
+		},
+	}
+}
+
+// testService delivers anywhere, the stub receivers listen on loopback
+func testService() *webhooksService {
+	anyAddress := func(ip net.IP) error { return nil }
+	return &webhooksService{client: deliveryClient(anyAddress), checkAddress: anyAddress}
+}
+
+func TestSend(t *testing.T) {
+	tests := []struct {
+		name           string
+		receiverSecret string
+		status         int
+		wantStatus     int
+		wantErr        string
+		wantReceived   bool
+	}{
+		{"delivered", "secret", http.StatusNoContent, http.StatusNoContent, "", true},
+		{"receiver failing", "secret", http.StatusInternalServerError, http.StatusInternalServerError, "unexpected status 500", true},
+		{"signature rejected", "another secret", http.StatusNoContent, http.StatusUnauthorized, "unexpected status 401", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			stub := &receiver{secret: test.receiverSecret, status: test.status}
+			server := httptest.NewServer(stub)
+			defer server.Close()
+
+			service := testService()
+			delivery := testDelivery(server.URL, "secret")
+			attempt := service.send(delivery)
+
+			if attempt.AttemptNumber != 3 {
+				t.Errorf("attempt number = %d, want 3", attempt.AttemptNumber)
+			}
+			if attempt.StatusCode != test.wantStatus || attempt.Error != test.wantErr {
+				t.Errorf("attempt = %d %q, want %d %q", attempt.StatusCode, attempt.Error, test.wantStatus, test.wantErr)
+			}
+
+			if !test.wantReceived {
+				if len(stub.received) != 0 {
+					t.Errorf("receiver accepted %d deliveries it couldn't verify", len(stub.received))
+				}
+				return
+			}
+			if len(stub.received) != 1 {
+				t.Fatalf("receiver got %d deliveries, want 1", len(stub.received))
+			}
+			var event models.WebhookEvent
+			if unmarshalErr := json.Unmarshal(stub.received[0].body, &event); unmarshalErr != nil {
+				t.Fatal(unmarshalErr)
+			}
+			if stub.received[0].eventType != models.EventEpisodeDetected || event.EventID != 9 || string(event.Data) != string(delivery.Event.Data) {
+				t.Errorf("received %s %+v, want the episode event", stub.received[0].eventType, event)
+			}
+		})
+	}
+}
+
+func TestSendTruncatesErrors(t *testing.T) {
+	// nothing listens on the port and the url is quoted in the error
+	url := "http://127.0.0.1:1/" + strings.Repeat("é", 2*maxErrorLength)
+
+	attempt := testService().send(testDelivery(url, "secret"))
+
+	if attempt.Error == "" {
+		t.Fatal("no error sending to a closed port")
+	}
+	if length := utf8.RuneCountInString(attempt.Error); length > maxErrorLength {
+		t.Errorf("error is %d characters, want at most %d", length, maxErrorLength)
+	}
+	if !utf8.ValidString(attempt.Error) {
+		t.Errorf("error was cut inside a character")
+	}
+}
+
+func TestSendRefusesPrivateAddresses(t *testing.T) {
+	stub := &receiver{secret: "secret", status: http.StatusNoContent}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+
+	attempt := NewWebhooksService(nil).send(testDelivery(server.URL, "secret"))
+
+	if !strings.Contains(attempt.Error, "isn't public") {
+		t.Errorf("attempt error = %q, want the address refused", attempt.Error)
+	}
+	if len(stub.received) != 0 {
+		t.Errorf("receiver on loopback got %d deliveries", len(stub.received))
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://93.184.216.34/hooks", false},
+		{"http://93.184.216.34:8080/hooks", false},
+		{"ftp://93.184.216.34/hooks", true},
+		{"https:///hooks", true},
+		{"http://127.0.0.1/hooks", true},
+		{"http://[::1]/hooks", true},
+		{"http://169.254.169.254/latest/meta-data", true},
+		{"http://10.0.0.5/hooks", true},
+		{"http://192.168.1.1/hooks", true},
+		{"http://[fd00::1]/hooks", true},
+		{"http://0.0.0.0/hooks", true},
+	}
+	service := NewWebhooksService(nil)
+	for _, test := range tests {
+		if err := service.validateURL(test.url); (err != nil) != test.wantErr {
+			t.Errorf("validateURL(%q) = %v, want error %v", test.url, err, test.wantErr)
+		}
+	}
+}
+
+func TestRetryDelaySeconds(t *testing.T) {
+	want := []int{30, 60, 120, 240, 480}
+	for i, delay := range want {
+		if got := retryDelaySeconds(i + 1); got != delay {
+			t.Errorf("retryDelaySeconds(%d) = %d, want %d", i+1, got, delay)
+		}
+	}
+}